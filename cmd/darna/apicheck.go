@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var (
+	errAPICheckUsage = errors.New("usage: darna apicheck [-dir <path>] [-json]")
+
+	// errAPIBroken is returned when the staged changes break the exported API.
+	errAPIBroken = errors.New("staged changes break the exported API")
+)
+
+// runAPICheck implements "darna apicheck": the removals and incompatible changes the
+// staged snapshot makes to the exported API of the packages, per package.
+func runAPICheck(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("apicheck", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	asJSON := fs.Bool("json", false, "print the changes as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing apicheck flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errAPICheckUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	changes, err := validator.CheckAPI(ctx, *workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("checking API: %w", err)
+	}
+
+	if *asJSON {
+		err = writeJSON(w, changes)
+		if err != nil {
+			return err
+		}
+	} else {
+		printAPIChanges(w, changes)
+	}
+
+	if len(changes) > 0 {
+		return errAPIBroken
+	}
+
+	return nil
+}
+
+// printAPIChanges prints the changes under a line per package, or confirms there are none.
+func printAPIChanges(w io.Writer, changes []validator.APIChange) {
+	if len(changes) == 0 {
+		writeString(w, "No incompatible API changes\n")
+
+		return
+	}
+
+	printAPIChangeList(w, changes)
+}
+
+// printAPIChangeList prints the changes, sorted by package, under a line per package.
+func printAPIChangeList(w io.Writer, changes []validator.APIChange) {
+	for i, change := range changes {
+		if i == 0 || change.Package != changes[i-1].Package {
+			writeString(w, change.Package+"\n")
+		}
+
+		writeString(w, "  "+change.String()+"\n")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunAPICheck(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	err := os.Mkdir(filepath.Join(dir, "store"), 0o750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/api\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "store", "store.go"),
+		"package store\n\ntype Store interface {\n\tGet() int\n}\n\ntype T struct{}\n\nfunc (T) M() {}\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "api")
+
+	var buf bytes.Buffer
+
+	err = runAPICheck(t.Context(), &buf, []string{"-dir", dir})
+	if err != nil || buf.String() != "No incompatible API changes\n" {
+		t.Fatalf("runAPICheck() = %v, printed %q, want no changes", err, buf.String())
+	}
+
+	writeFile(t, filepath.Join(dir, "store", "store.go"),
+		"package store\n\ntype Store interface {\n\tGet() int\n\tPut(v int)\n}\n\ntype T struct{}\n\nfunc (*T) M() {}\n")
+	runGit(t, dir, "add", "store")
+	buf.Reset()
+
+	err = runAPICheck(t.Context(), &buf, []string{"-dir", dir})
+	if !errors.Is(err, errAPIBroken) {
+		t.Errorf("runAPICheck() = %v, want errAPIBroken", err)
+	}
+
+	want := "example.com/api/store\n" +
+		"  Store.Put: added to the interface, breaking its implementations\n" +
+		"  T.M: now has a pointer receiver\n"
+	if buf.String() != want {
+		t.Errorf("runAPICheck() printed %q, want %q", buf.String(), want)
+	}
+}
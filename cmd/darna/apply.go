@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+var errApplyUsage = errors.New(
+	"usage: darna apply [-dir <path>] [-plan <file>] [-commit-msg <agent>] [-prompt-file <path>] [-max-commits <n>]")
+
+// messageFunc returns the commit message describing the staged changes of files.
+type messageFunc func(ctx context.Context, files []string) (string, error)
+
+// runApply implements "darna apply": it commits each group of a plan in order, with a
+// message generated by an agent. Groups already committed are skipped, so rerunning it
+// after a failure resumes where it stopped.
+func runApply(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	planFile := fs.String("plan", "", "plan file written by darna plan -json (default: plan the changeset now)")
+	commitMsg := fs.String("commit-msg", agentFromConfig, "agent generating the commit messages")
+	promptFile := fs.String("prompt-file", "", "custom prompt file for the commit messages")
+	maxCommits := fs.Int("max-commits", 0, "without -plan, plan at most n commits (0 = unlimited)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing apply flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *maxCommits < 0 {
+		return errApplyUsage
+	}
+
+	agentType, err := resolveAgent(*commitMsg, conf)
+	if err != nil {
+		return err
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	plan, err := loadPlan(ctx, *workDir, *planFile, append(opts, validator.WithMaxCommits(*maxCommits)))
+	if err != nil {
+		return err
+	}
+
+	return applyPlan(ctx, w, *workDir, plan, func(ctx context.Context, files []string) (string, error) {
+		return generateCommitMsg(ctx, agentType, *promptFile, *workDir, files)
+	})
+}
+
+// loadPlan reads the plan in path, or plans the changeset of workDir when path is empty.
+func loadPlan(ctx context.Context, workDir, path string, opts []validator.Option) (*validator.Plan, error) {
+	if path == "" {
+		plan, err := validator.CommitPlan(ctx, workDir, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("planning commits: %w", err)
+		}
+
+		return plan, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // User-provided plan file path is intentional.
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+
+	var plan validator.Plan
+
+	err = json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("decoding plan %s: %w", path, err)
+	}
+
+	return &plan, nil
+}
+
+// applyPlan stages and commits each commit of plan in order, skipping those whose files
+// have no changes left. On failure, it names the commit to rerun from.
+func applyPlan(ctx context.Context, w io.Writer, workDir string, plan *validator.Plan, message messageFunc) error {
+	total := strconv.Itoa(len(plan.Commits))
+
+	for i, files := range plan.Commits {
+		step := "[" + strconv.Itoa(i+1) + "/" + total + "] "
+
+		statuses, err := git.GetAllFileStatus(ctx, workDir)
+		if err != nil {
+			return fmt.Errorf("getting file status: %w", err)
+		}
+
+		pending := slices.DeleteFunc(slices.Clone(files), func(file string) bool {
+			_, changed := statuses[file]
+
+			return !changed
+		})
+		if len(pending) == 0 {
+			writeString(w, step+"already committed: "+strings.Join(files, " ")+"\n")
+
+			continue
+		}
+
+		err = commitFiles(ctx, workDir, pending, message)
+		if err != nil {
+			return fmt.Errorf("%scommitting %s: %w (rerun darna apply to resume)", step, strings.Join(pending, " "), err)
+		}
+
+		writeString(w, step+strings.Join(pending, " ")+"\n")
+	}
+
+	return nil
+}
+
+// commitFiles stages files and commits them alone with the message generated for them.
+func commitFiles(ctx context.Context, workDir string, files []string, message messageFunc) error {
+	err := git.StageFiles(ctx, workDir, files...)
+	if err != nil {
+		return fmt.Errorf("staging: %w", err)
+	}
+
+	msg, err := message(ctx, files)
+	if err != nil {
+		return err
+	}
+
+	//nolint:wrapcheck // Commit errors already say what failed.
+	return git.Commit(ctx, workDir, msg, files...)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errAgentDown = errors.New("agent down")
+
+func commitSubjects(t *testing.T, dir string) string {
+	t.Helper()
+
+	//nolint:gosec // Test inspects its temp repository.
+	out, err := exec.CommandContext(t.Context(), "git", "-C", dir, "log", "--format=%s").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+
+	return strings.Join(strings.Split(strings.TrimSpace(string(out)), "\n"), "|")
+}
+
+func TestApplyPlanResumes(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "d.go")
+
+	plan, err := validator.CommitPlan(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("CommitPlan: %v", err)
+	}
+
+	calls := 0
+	failSecond := func(_ context.Context, files []string) (string, error) {
+		if calls++; calls == 2 { //nolint:mnd // The second commit fails.
+			return "", errAgentDown
+		}
+
+		return "add " + strings.Join(files, " "), nil
+	}
+
+	var buf bytes.Buffer
+
+	err = applyPlan(t.Context(), &buf, dir, plan, failSecond)
+	if !errors.Is(err, errAgentDown) {
+		t.Fatalf("applyPlan() = %v, want the agent error", err)
+	}
+
+	err = applyPlan(t.Context(), &buf, dir, plan, failSecond)
+	if err != nil {
+		t.Fatalf("Resumed applyPlan: %v\n%s", err, buf.String())
+	}
+
+	if got, want := commitSubjects(t, dir), "add a.go|add b.go|add d.go|add c.go|chain|initial"; got != want {
+		t.Errorf("Commits = %q, want %q", got, want)
+	}
+
+	if !strings.Contains(buf.String(), "[1/4] already committed: c.go") {
+		t.Errorf("Expected the resumed run to skip c.go, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+var errAuditUsage = errors.New("usage: darna audit [-dir <path>] [-base <rev>] [-format text|json]")
+
+// auditReport is the JSON form of an audit.
+type auditReport struct {
+	Base    string        `json:"base"`
+	Atomic  bool          `json:"atomic"`
+	Commits []auditCommit `json:"commits"`
+}
+
+// auditCommit is the result of a single commit of an audit.
+type auditCommit struct {
+	Commit     string                `json:"commit"`
+	Subject    string                `json:"subject"`
+	Atomic     bool                  `json:"atomic"`
+	Violations []validator.Violation `json:"violations"`
+}
+
+// runAudit implements "darna audit": it checks every commit of the current branch that
+// the base lacks, oldest first, and reports on each of them. Meant for pull request CI,
+// it fails when any commit breaks bisection by depending on a later one.
+func runAudit(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	base := fs.String("base", "origin/main", "revision the branch is merged into")
+	format := fs.String("format", formatText, "output format (text, json)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing audit flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || (*format != formatText && *format != formatJSON) {
+		return errAuditUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	reports, err := validator.ValidateRefUpdate(ctx, *workDir, *base, "HEAD", opts...)
+	if err != nil {
+		return fmt.Errorf("auditing commits: %w", err)
+	}
+
+	report := auditReport{Base: *base, Atomic: true, Commits: make([]auditCommit, 0, len(reports))}
+
+	for _, cr := range reports {
+		subject, subjectErr := git.GetCommitSubject(ctx, *workDir, cr.Commit)
+		if subjectErr != nil {
+			return fmt.Errorf("auditing commits: %w", subjectErr)
+		}
+
+		report.Atomic = report.Atomic && len(cr.Violations) == 0
+		report.Commits = append(report.Commits, auditCommit{
+			Commit:     cr.Commit,
+			Subject:    subject,
+			Atomic:     len(cr.Violations) == 0,
+			Violations: append(make([]validator.Violation, 0, len(cr.Violations)), cr.Violations...),
+		})
+	}
+
+	if *format == formatJSON {
+		err = writeJSON(w, report)
+	} else {
+		printAudit(w, report)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !report.Atomic {
+		return errNotAtomic
+	}
+
+	return nil
+}
+
+// printAudit prints one status line per commit, followed by its violations, and a summary.
+func printAudit(w io.Writer, report auditReport) {
+	failed := 0
+
+	for _, ac := range report.Commits {
+		status := "ok  "
+		if !ac.Atomic {
+			status = "FAIL"
+			failed++
+		}
+
+		writeString(w, status+" "+shortHash(ac.Commit)+" "+ac.Subject+"\n")
+
+		for _, vv := range ac.Violations {
+			writeString(w, "     "+commitViolation(vv)+"\n")
+		}
+	}
+
+	writeString(w, strconv.Itoa(failed)+" of "+strconv.Itoa(len(report.Commits))+
+		" commit(s) since "+report.Base+" not atomic\n")
+}
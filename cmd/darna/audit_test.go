@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/config"
+)
+
+func TestRunAudit(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/audit\n\ngo 1.24\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "module")
+	runGit(t, dir, "branch", "main-base")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return B() }\n")
+	runGit(t, dir, "add", "a.go")
+	runGit(t, dir, "commit", "-m", "use B")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() int { return 1 }\n")
+	runGit(t, dir, "add", "b.go")
+	runGit(t, dir, "commit", "-m", "add B")
+
+	var buf bytes.Buffer
+
+	err := runAudit(t.Context(), &buf, []string{"-dir", dir, "-base", "main-base"})
+	if !errors.Is(err, errNotAtomic) {
+		t.Fatalf("runAudit() = %v, want errNotAtomic; output:\n%s", err, buf.String())
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[0], "FAIL ") || !strings.HasSuffix(lines[0], " use B") {
+		t.Errorf("Expected the first commit to fail, got:\n%s", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "ok   ") || !strings.Contains(buf.String(), "1 of 2 commit(s)") {
+		t.Errorf("Expected the second commit to pass, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+
+	err = runAudit(t.Context(), &buf, []string{"-dir", dir, "-base", "main-base", "-format", "json"})
+	if !errors.Is(err, errNotAtomic) {
+		t.Fatalf("runAudit(-format json) = %v, want errNotAtomic", err)
+	}
+
+	var report auditReport
+
+	err = json.Unmarshal(buf.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v\n%s", err, buf.String())
+	}
+
+	if report.Atomic || len(report.Commits) != 2 || report.Commits[0].Atomic || !report.Commits[1].Atomic {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	if report.Commits[1].Subject != "add B" {
+		t.Errorf("Subject = %q, want %q", report.Commits[1].Subject, "add B")
+	}
+
+	buf.Reset()
+
+	err = runAudit(t.Context(), &buf, []string{"-dir", dir, "-base", "HEAD~1"})
+	if err != nil {
+		t.Errorf("runAudit(-base HEAD~1): %v\n%s", err, buf.String())
+	}
+}
+
+func TestRunAuditIgnoresConfiguredFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/audit\n\ngo 1.24\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "module")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return 1 }\n")
+	runGit(t, dir, "add", "a.go")
+	runGit(t, dir, "commit", "-m", "add A")
+
+	// The validation format is not one audit supports.
+	writeFile(t, filepath.Join(dir, config.FileName), "format = \"github\"\n")
+
+	var buf bytes.Buffer
+
+	err := runAudit(t.Context(), &buf, []string{"-dir", dir, "-base", "HEAD~1"})
+	if err != nil {
+		t.Fatalf("runAudit() with a configured format: %v\n%s", err, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "ok   ") {
+		t.Errorf("Expected a text report, got:\n%s", buf.String())
+	}
+}
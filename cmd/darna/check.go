@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errCheckUsage = errors.New(
+	"usage: darna check [-dir <path>] [-timeout <d>] [-timeout-policy <closed|open>] <rev> | <from>..<to>")
+
+// runCheck implements "darna check <rev>": it verifies an existing commit is atomic
+// relative to its parent, given the later commits and uncommitted changes of the work dir.
+// Given a range "<from>..<to>", it replays every commit of the range instead, each
+// checked against the commits that follow it up to <to>.
+func runCheck(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	timeout, timeoutPolicy := deadlineFlags(fs)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing check flags: %w", err)
+	}
+
+	dl, err := newDeadline(*timeout, *timeoutPolicy)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := dl.context(ctx)
+	defer cancel()
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errCheckUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	if from, to, ok := strings.Cut(fs.Arg(0), ".."); ok {
+		err = checkRange(ctx, w, *workDir, from, to, opts)
+		if errors.Is(err, errNotAtomic) {
+			return err
+		}
+
+		return dl.failure(ctx, os.Stderr, err)
+	}
+
+	report, err := validator.ValidateCommit(ctx, *workDir, fs.Arg(0), opts...)
+	if err != nil {
+		return dl.failure(ctx, os.Stderr, fmt.Errorf("checking commit: %w", err))
+	}
+
+	if !printCommitReports(w, []validator.CommitReport{report}) {
+		return errNotAtomic
+	}
+
+	writeString(w, "Commit "+shortHash(report.Commit)+" is atomic\n")
+
+	return nil
+}
+
+// checkRange validates the commits reachable from to but not from from, where an empty
+// side stands for HEAD as in git.
+func checkRange(ctx context.Context, w io.Writer, workDir, from, to string, opts []validator.Option) error {
+	if strings.HasPrefix(to, ".") {
+		return errCheckUsage // Symmetric differences have no replay order.
+	}
+
+	if from == "" {
+		from = "HEAD"
+	}
+
+	if to == "" {
+		to = "HEAD"
+	}
+
+	reports, err := validator.ValidateRefUpdate(ctx, workDir, from, to, opts...)
+	if err != nil {
+		return fmt.Errorf("checking commits: %w", err)
+	}
+
+	if !printCommitReports(w, reports) {
+		return errNotAtomic
+	}
+
+	writeString(w, "Checked "+strconv.Itoa(len(reports))+" commit(s): all atomic\n")
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/check\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return B() }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() int { return 1 }\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "self-contained")
+
+	var buf bytes.Buffer
+
+	err := runCheck(t.Context(), &buf, []string{"-dir", dir, "HEAD"})
+	if err != nil {
+		t.Fatalf("runCheck(HEAD): %v\n%s", err, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "is atomic") {
+		t.Errorf("Expected an atomic commit, got:\n%s", buf.String())
+	}
+
+	// C only exists in the working tree, so the commit using it does not stand alone.
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return B() + C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 2 }\n")
+	runGit(t, dir, "add", "a.go")
+	runGit(t, dir, "commit", "-m", "uses C")
+	buf.Reset()
+
+	err = runCheck(t.Context(), &buf, []string{"-dir", dir, "HEAD"})
+	if !errors.Is(err, errNotAtomic) {
+		t.Fatalf("runCheck(HEAD) = %v, want errNotAtomic; output:\n%s", err, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "uses example.com/check.C (c.go)") {
+		t.Errorf("Expected the use of C to be reported, got:\n%s", buf.String())
+	}
+
+	err = runCheck(t.Context(), &buf, nil)
+	if !errors.Is(err, errCheckUsage) {
+		t.Errorf("runCheck() = %v, want errCheckUsage", err)
+	}
+}
+
+func TestRunCheckRange(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/check\n\ngo 1.24\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "module")
+	runGit(t, dir, "branch", "base")
+
+	// The first commit uses B, which only the second one introduces.
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return B() }\n")
+	runGit(t, dir, "add", "a.go")
+	runGit(t, dir, "commit", "-m", "uses B")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() int { return 1 }\n")
+	runGit(t, dir, "add", "b.go")
+	runGit(t, dir, "commit", "-m", "adds B")
+
+	var buf bytes.Buffer
+
+	err := runCheck(t.Context(), &buf, []string{"-dir", dir, "base.."})
+	if !errors.Is(err, errNotAtomic) {
+		t.Fatalf("runCheck(base..) = %v, want errNotAtomic; output:\n%s", err, buf.String())
+	}
+
+	if got := strings.Count(buf.String(), "is not atomic"); got != 1 {
+		t.Errorf("Expected exactly one non-atomic commit, got:\n%s", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "uses example.com/check.B (b.go)") {
+		t.Errorf("Expected the use of B to be reported, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+
+	err = runCheck(t.Context(), &buf, []string{"-dir", dir, "HEAD~1..HEAD"})
+	if err != nil {
+		t.Fatalf("runCheck(HEAD~1..HEAD): %v\n%s", err, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "Checked 1 commit(s): all atomic") {
+		t.Errorf("Expected an atomic range, got:\n%s", buf.String())
+	}
+
+	err = runCheck(t.Context(), &buf, []string{"-dir", dir, "base...HEAD"})
+	if !errors.Is(err, errCheckUsage) {
+		t.Errorf("runCheck(base...HEAD) = %v, want errCheckUsage", err)
+	}
+}
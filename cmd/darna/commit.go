@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errCommitUsage = errors.New(
+	"usage: darna commit [-dir <path>] [-dependants] [-commit-msg <agent>] [-prompt-file <path>] [-dry-run]")
+
+// runCommit implements "darna commit": it stages the next committable set, generates
+// its message and commits it, in one step.
+func runCommit(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("commit", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	dependants := fs.Bool("dependants", false, "include direct dependants in the committed set")
+	commitMsg := fs.String("commit-msg", agentFromConfig, "agent generating the commit message")
+	promptFile := fs.String("prompt-file", "", "custom prompt file for the commit message")
+	dryRun := fs.Bool("dry-run", false, "print the files that would be committed without staging or committing")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing commit flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errCommitUsage
+	}
+
+	agentType, err := resolveAgent(*commitMsg, conf)
+	if err != nil {
+		return err
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	files, err := validator.FindCommittableSet(ctx, *workDir, *dependants, opts...)
+	if err != nil {
+		return fmt.Errorf("finding committable files: %w", err)
+	}
+
+	return commitSet(ctx, w, *workDir, files, *dryRun, func(ctx context.Context, files []string) (string, error) {
+		return generateCommitMsg(ctx, agentType, *promptFile, *workDir, files)
+	})
+}
+
+// commitSet commits files with the message generated for them, or with dryRun only
+// prints them.
+//
+//nolint:revive // dryRun mirrors the -dry-run flag.
+func commitSet(
+	ctx context.Context, w io.Writer, workDir string, files []string, dryRun bool, message messageFunc,
+) error {
+	switch {
+	case len(files) == 0:
+		writeString(w, "Nothing to commit\n")
+
+		return nil
+	case dryRun:
+		writeString(w, "Would commit: "+strings.Join(files, " ")+"\n")
+
+		return nil
+	}
+
+	err := commitFiles(ctx, workDir, files, message)
+	if err != nil {
+		return fmt.Errorf("committing %s: %w", strings.Join(files, " "), err)
+	}
+
+	writeString(w, "Committed: "+strings.Join(files, " ")+"\n")
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCommitSet(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	message := func(_ context.Context, files []string) (string, error) {
+		return "add " + strings.Join(files, " "), nil
+	}
+
+	var buf bytes.Buffer
+
+	err := commitSet(t.Context(), &buf, dir, []string{"c.go"}, true, message)
+	if err != nil {
+		t.Fatalf("Dry run commitSet: %v", err)
+	}
+
+	if got := stagedNames(t, dir); got != "" || buf.String() != "Would commit: c.go\n" {
+		t.Errorf("Dry run staged %q and printed %q", got, buf.String())
+	}
+
+	err = commitSet(t.Context(), &buf, dir, []string{"c.go"}, false, message)
+	if err != nil {
+		t.Fatalf("commitSet: %v", err)
+	}
+
+	if got, want := commitSubjects(t, dir), "add c.go|chain|initial"; got != want {
+		t.Errorf("Commits = %q, want %q", got, want)
+	}
+}
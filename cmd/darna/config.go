@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/config"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+// agentFromConfig is the --commit-msg value selecting the agent set in the config.
+const agentFromConfig = "default"
+
+var errNoConfiguredAgent = errors.New(
+	"--commit-msg=default needs an agent set in " + config.FileName + " or " + config.EnvAgent)
+
+// envDir is the environment variable providing the default of -dir.
+const envDir = "DARNA_DIR"
+
+// applyConfig resolves the settings of fs, already parsed, through the precedence chain
+// shared by all modes, from lowest to highest: the user config, the repository config,
+// the DARNA_* environment variables and the flags set on the command line. Flags of fs
+// not set on the command line take their value from the config. It returns the config
+// and the names of the flags set on the command line.
+func applyConfig(
+	ctx context.Context, fs *flag.FlagSet, getenv func(string) string,
+) (config.Config, map[string]bool, error) {
+	explicit := explicitFlags(fs)
+
+	err := setDefaults(fs, explicit, map[string]string{"dir": getenv(envDir)})
+	if err != nil {
+		return config.Config{}, explicit, err
+	}
+
+	workDir := "."
+	if f := fs.Lookup("dir"); f != nil {
+		workDir = f.Value.String()
+	}
+
+	conf, err := loadConfig(ctx, workDir, getenv)
+	if err != nil {
+		return conf, explicit, err
+	}
+
+	_, err = parsePairRules(conf.Pairs)
+	if err != nil {
+		return conf, explicit, err
+	}
+
+	_, err = parsePlatforms(conf.Platforms)
+	if err != nil {
+		return conf, explicit, err
+	}
+
+	skipGenerated := ""
+	if conf.SkipGenerated != nil {
+		skipGenerated = strconv.FormatBool(*conf.SkipGenerated)
+	}
+
+	err = setDefaults(fs, explicit, map[string]string{
+		"prompt-file":    conf.PromptFile,
+		"color":          conf.Color,
+		"theme":          conf.Theme,
+		"exclude":        strings.Join(conf.Exclude, ","),
+		"include":        strings.Join(conf.Include, ","),
+		"test-policy":    conf.TestPolicy,
+		"pairs":          strings.Join(conf.Pairs, ","),
+		"tags":           strings.Join(conf.Tags, ","),
+		"platforms":      strings.Join(conf.Platforms, ","),
+		"skip-generated": skipGenerated,
+	})
+
+	return conf, explicit, err
+}
+
+// applyValidateConfig is applyConfig for the flags of the validation, which also take
+// the configured format. Subcommands have output formats of their own, so it does not
+// apply to them.
+func applyValidateConfig(
+	ctx context.Context, fs *flag.FlagSet, getenv func(string) string,
+) (config.Config, map[string]bool, error) {
+	conf, explicit, err := applyConfig(ctx, fs, getenv)
+	if err != nil {
+		return conf, explicit, err
+	}
+
+	err = setDefaults(fs, explicit, map[string]string{"format": conf.Format})
+
+	return conf, explicit, err
+}
+
+// setDefaults sets the flags of fs named in values to their non-empty value, unless
+// they were set on the command line or fs does not define them.
+func setDefaults(fs *flag.FlagSet, explicit map[string]bool, values map[string]string) error {
+	for name, value := range values {
+		if value == "" || explicit[name] || fs.Lookup(name) == nil {
+			continue
+		}
+
+		err := fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("applying configured %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// loadConfig resolves the config for workDir: the user config, then the config file at
+// the root of the repository containing workDir (or in workDir itself outside a
+// repository), then the DARNA_* environment variables.
+func loadConfig(ctx context.Context, workDir string, getenv func(string) string) (config.Config, error) {
+	root, err := git.GetTopLevel(ctx, workDir)
+	if err != nil {
+		root = workDir
+	}
+
+	//nolint:wrapcheck // Config errors already name the file.
+	return config.Resolve(config.GlobalPath(getenv), filepath.Join(root, config.FileName), getenv)
+}
+
+// explicitFlags returns the names of the flags set on the command line, whose values
+// take precedence over the config.
+func explicitFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return set
+}
+
+// configOptions returns the validator options set by conf, for subcommands without
+// their own flags for them, or the error of its first invalid pair rule or platform.
+func configOptions(conf config.Config) ([]validator.Option, error) {
+	rules, err := parsePairRules(conf.Pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	platforms, err := parsePlatforms(conf.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []validator.Option{
+		validator.WithExclude(conf.Exclude...), validator.WithInclude(conf.Include...),
+		validator.WithTestPolicy(validator.TestPolicy(conf.TestPolicy)), validator.WithBuildTags(conf.Tags...),
+		validator.WithPairedFiles(rules...), validator.WithPlatforms(platforms...),
+	}
+
+	if conf.SkipGenerated != nil && *conf.SkipGenerated {
+		opts = append(opts, validator.WithSkipGenerated())
+	}
+
+	return opts, nil
+}
+
+// goBuildFlags returns the flags of the go command building with the tags of conf, so
+// that the go commands darna runs see the files it analyzes.
+func goBuildFlags(conf config.Config) []string {
+	if len(conf.Tags) == 0 {
+		return nil
+	}
+
+	return []string{"-tags=" + strings.Join(conf.Tags, ",")}
+}
+
+// parsePairRules parses the paired-file rules of the config or of --pairs.
+func parsePairRules(rules []string) ([]validator.PairRule, error) {
+	parsed := make([]validator.PairRule, 0, len(rules))
+
+	for _, rule := range rules {
+		r, err := validator.ParsePairRule(rule)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Already names the rule.
+		}
+
+		parsed = append(parsed, r)
+	}
+
+	return parsed, nil
+}
+
+// parsePlatforms parses the GOOS/GOARCH combinations of the config or of --platforms.
+func parsePlatforms(platforms []string) ([]validator.Platform, error) {
+	parsed := make([]validator.Platform, 0, len(platforms))
+
+	for _, platform := range platforms {
+		p, err := validator.ParsePlatform(platform)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Already names the platform.
+		}
+
+		parsed = append(parsed, p)
+	}
+
+	return parsed, nil
+}
+
+// resolveAgent maps agentFromConfig to the configured agent type.
+func resolveAgent(agentType string, conf config.Config) (string, error) {
+	if agentType != agentFromConfig {
+		return agentType, nil
+	}
+
+	if conf.Agent == "" {
+		return "", errNoConfiguredAgent
+	}
+
+	return conf.Agent, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/config"
+)
+
+func TestLoadConfigSeverityWarning(t *testing.T) {
+	t.Parallel()
+
+	dir := initViolatingRepo(t)
+	writeFile(t, filepath.Join(dir, config.FileName), "severity = \"warning\"\nagent = \"codex\"\n")
+
+	conf, err := loadConfig(t.Context(), dir, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: conf.Severity, failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+	})
+	if code != exitAtomic {
+		t.Errorf("runValidate() = %d, want %d with severity warning", code, exitAtomic)
+	}
+
+	if !strings.Contains(stdout.String(), "b.go") {
+		t.Errorf("Expected violations to be printed, stdout:\n%s", stdout.String())
+	}
+
+	if agentType, _ := resolveAgent(agentFromConfig, conf); agentType != "codex" {
+		t.Errorf("resolveAgent(%q) = %q, want codex", agentFromConfig, agentType)
+	}
+}
+
+func TestResolveAgentWithoutConfiguredAgent(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+
+	if agentType, err := resolveAgent("claude", conf); err != nil || agentType != "claude" {
+		t.Errorf("resolveAgent(claude) = %q, %v, want claude", agentType, err)
+	}
+
+	if _, err := resolveAgent(agentFromConfig, conf); err == nil {
+		t.Errorf("resolveAgent(%q) succeeded without a configured agent", agentFromConfig)
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, config.FileName), "agent = \"codex\"\nformat = \"json\"\n")
+
+	env := map[string]string{config.EnvAgent: "opencode"}
+
+	conf, err := loadConfig(t.Context(), dir, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	if conf.Agent != "opencode" || conf.Format != "json" {
+		t.Errorf("loadConfig() = %+v, want the agent from %s and the format from the file", conf, config.EnvAgent)
+	}
+}
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	t.Parallel()
+
+	home := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(home, ".config", "darna"), 0o750)
+	if err != nil {
+		t.Fatalf("Creating the user config dir: %v", err)
+	}
+
+	writeFile(t, filepath.Join(home, ".config", "darna", "config.toml"), "theme = \"mono\"\nformat = \"json\"\n")
+
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, config.FileName), "format = \"sarif\"\n")
+
+	env := map[string]string{"HOME": home, envDir: dir}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "")
+	format := fs.String("format", formatText, "")
+	themeName := fs.String("theme", "default", "")
+	color := fs.String("color", colorAuto, "")
+
+	err = fs.Parse([]string{"-color", colorNever})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	_, explicit, err := applyValidateConfig(t.Context(), fs, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("applyValidateConfig: %v", err)
+	}
+
+	if *workDir != dir || *format != formatSARIF || *themeName != "mono" || *color != colorNever {
+		t.Errorf("applyValidateConfig() resolved dir %q, format %q, theme %q, color %q",
+			*workDir, *format, *themeName, *color)
+	}
+
+	if !explicit["color"] || explicit["format"] {
+		t.Errorf("explicit = %v, want only color", explicit)
+	}
+}
+
+func TestApplyConfigKeepsSubcommandFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+	env := map[string]string{envDir: dir, config.EnvFormat: formatGitHub}
+
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	format := fs.String("format", "dot", "")
+	fs.String("dir", ".", "")
+
+	err := fs.Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	_, _, err = applyConfig(t.Context(), fs, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+
+	if *format != "dot" {
+		t.Errorf("applyConfig() set the subcommand format to %q, want its own default %q", *format, "dot")
+	}
+}
+
+func TestConfigOptionsInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	for _, conf := range []config.Config{
+		{
+			Agent: "", PromptFile: "", Exclude: nil, Include: nil, Format: "", Severity: "", Color: "", Theme: "",
+			TestPolicy: "", Pairs: []string{"*.proto"}, Tags: nil, Platforms: nil, SkipGenerated: nil,
+		},
+		{
+			Agent: "", PromptFile: "", Exclude: nil, Include: nil, Format: "", Severity: "", Color: "", Theme: "",
+			TestPolicy: "", Pairs: nil, Tags: nil, Platforms: []string{"plan9"}, SkipGenerated: nil,
+		},
+	} {
+		if _, err := configOptions(conf); err == nil {
+			t.Errorf("configOptions(%+v) succeeded, want the error of the invalid rule", conf)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+var (
+	errDaemonUsage = errors.New("usage: darna daemon [-dir <path>]")
+
+	// errDaemonRunning is returned when starting a daemon for a repository that has one.
+	errDaemonRunning = errors.New("a darna daemon is already running for this repository")
+
+	// errDaemonWorkDir is returned to clients asking about another work dir.
+	errDaemonWorkDir = errors.New("daemon serves another work dir")
+)
+
+// daemonSocket is the name of the daemon socket inside the git directory.
+const daemonSocket = "darna.sock"
+
+// daemonDialTimeout bounds how long the CLI waits for a daemon before working alone.
+const daemonDialTimeout = 100 * time.Millisecond
+
+// daemonService is the name of the JSON-RPC service of Daemon.
+const daemonService = "Darna"
+
+// Daemon is the JSON-RPC service of darna daemon. It keeps the packages and dependency
+// graph of a work dir loaded, and refreshes the files that changed before each query.
+type Daemon struct {
+	ctx      context.Context //nolint:containedctx // net/rpc methods take no context.
+	workDir  string          // Absolute, with symlinks resolved.
+	opts     []validator.Option
+	mu       sync.Mutex           // Serializes queries.
+	analysis *validator.Analysis  // Shared by all queries, nil until the first one.
+	files    map[string]fileState // Changed files at the last query.
+}
+
+// DaemonArgs are the arguments of Daemon.Validate.
+type DaemonArgs struct {
+	Dir string `json:"dir"` // Work dir of the client, which must be the one served.
+}
+
+// ValidateReply is the reply of Daemon.Validate.
+type ValidateReply struct {
+	Violations []validator.Violation `json:"violations"`
+}
+
+// CommittableArgs are the arguments of Daemon.Committable.
+type CommittableArgs struct {
+	Dir        string `json:"dir"` // Work dir of the client, which must be the one served.
+	Dependants bool   `json:"dependants"`
+}
+
+// CommittableReply is the reply of Daemon.Committable.
+type CommittableReply struct {
+	Files []string `json:"files"`
+}
+
+// Validate answers like validator.ValidateAtomicCommit.
+func (d *Daemon) Validate(args DaemonArgs, reply *ValidateReply) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changed, err := d.refresh(args.Dir)
+	if err != nil {
+		return err
+	}
+
+	violations, analysis, err := validator.ValidateIncremental(d.ctx, d.workDir, changed, d.analysis, d.opts...)
+	d.analysis = analysis
+
+	if err != nil {
+		return fmt.Errorf("validating: %w", err)
+	}
+
+	reply.Violations = violations
+
+	return nil
+}
+
+// Committable answers like validator.FindCommittableSet.
+func (d *Daemon) Committable(args CommittableArgs, reply *CommittableReply) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changed, err := d.refresh(args.Dir)
+	if err != nil {
+		return err
+	}
+
+	files, analysis, err := validator.FindCommittableSetIncremental(
+		d.ctx, d.workDir, changed, d.analysis, args.Dependants, d.opts...)
+	d.analysis = analysis
+
+	if err != nil {
+		return fmt.Errorf("finding committable files: %w", err)
+	}
+
+	reply.Files = files
+
+	return nil
+}
+
+// refresh checks that dir is the work dir served and returns the files changed since
+// the previous query.
+func (d *Daemon) refresh(dir string) ([]string, error) {
+	if resolved, err := resolveDir(dir); err != nil || resolved != d.workDir {
+		return nil, errDaemonWorkDir
+	}
+
+	files, err := snapshotFiles(d.ctx, d.workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := changedFiles(d.files, files)
+	d.files = files
+
+	return changed, nil
+}
+
+// runDaemon implements "darna daemon": it serves validate and committable queries about
+// a work dir over a unix socket in its git directory, keeping the analysis warm between
+// them. The CLI asks the daemon first when one is running.
+func runDaemon(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing daemon flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errDaemonUsage
+	}
+
+	if client := dialDaemon(ctx, *workDir); client != nil {
+		_ = client.Close()
+
+		return errDaemonRunning
+	}
+
+	path, err := git.GetGitPath(ctx, *workDir, daemonSocket)
+	if err != nil {
+		return fmt.Errorf("locating socket: %w", err)
+	}
+
+	_ = os.Remove(path) // Left behind by a daemon that did not exit cleanly.
+
+	var lc net.ListenConfig
+
+	ln, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("listening: %w", err)
+	}
+
+	defer func() { _ = ln.Close() }()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	d, err := newDaemon(ctx, *workDir, opts)
+	if err != nil {
+		return err
+	}
+
+	writeString(w, "Serving "+d.workDir+" on "+path+"\n")
+
+	return serveDaemon(ctx, ln, d)
+}
+
+// newDaemon returns a daemon for workDir with its analysis loaded.
+func newDaemon(ctx context.Context, workDir string, opts []validator.Option) (*Daemon, error) {
+	resolved, err := resolveDir(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	d := &Daemon{ctx: ctx, workDir: resolved, opts: opts, mu: sync.Mutex{}, analysis: nil, files: nil}
+
+	// Warm up; a failure only means the first query loads everything again.
+	_ = d.Validate(DaemonArgs{Dir: resolved}, &ValidateReply{Violations: nil})
+
+	return d, nil
+}
+
+// serveDaemon answers JSON-RPC requests for d on ln until ctx is done.
+func serveDaemon(ctx context.Context, ln net.Listener, d *Daemon) error {
+	server := rpc.NewServer()
+
+	err := server.RegisterName(daemonService, d)
+	if err != nil {
+		return fmt.Errorf("registering service: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("accepting connection: %w", err)
+		}
+
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// dialDaemon connects to the daemon of the repository of workDir, or returns nil when
+// none is running.
+func dialDaemon(ctx context.Context, workDir string) *rpc.Client {
+	path, err := git.GetGitPath(ctx, workDir, daemonSocket)
+	if err != nil {
+		return nil
+	}
+
+	dialer := net.Dialer{Timeout: daemonDialTimeout} //nolint:exhaustruct // Defaults for the rest.
+
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil
+	}
+
+	return jsonrpc.NewClient(conn)
+}
+
+// daemonValidate asks the daemon of workDir for the violations of the staged changes.
+// It reports false when no daemon answered, leaving the validation to the caller.
+func daemonValidate(ctx context.Context, workDir string) ([]validator.Violation, bool) {
+	client := dialDaemon(ctx, workDir)
+	if client == nil {
+		return nil, false
+	}
+
+	defer func() { _ = client.Close() }()
+
+	dir, err := resolveDir(workDir)
+	if err != nil {
+		return nil, false
+	}
+
+	var reply ValidateReply
+
+	err = client.Call(daemonService+".Validate", DaemonArgs{Dir: dir}, &reply)
+	if err != nil {
+		return nil, false
+	}
+
+	return reply.Violations, true
+}
+
+// daemonCommittable asks the daemon of workDir for the committable set. It reports
+// false when no daemon answered, leaving the selection to the caller.
+func daemonCommittable(ctx context.Context, workDir string, dependants bool) ([]string, bool) {
+	client := dialDaemon(ctx, workDir)
+	if client == nil {
+		return nil, false
+	}
+
+	defer func() { _ = client.Close() }()
+
+	dir, err := resolveDir(workDir)
+	if err != nil {
+		return nil, false
+	}
+
+	var reply CommittableReply
+
+	err = client.Call(daemonService+".Committable", CommittableArgs{Dir: dir, Dependants: dependants}, &reply)
+	if err != nil {
+		return nil, false
+	}
+
+	return reply.Files, true
+}
+
+// resolveDir returns dir as an absolute path with symlinks resolved.
+func resolveDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", dir, err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", dir, err)
+	}
+
+	return resolved, nil
+}
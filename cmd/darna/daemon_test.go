@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+func TestDaemon(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/daemon\n\ngo 1.24\n")
+	runGit(t, dir, "add", "go.mod")
+	runGit(t, dir, "commit", "-m", "module")
+
+	if _, ok := daemonValidate(t.Context(), dir); ok {
+		t.Fatal("daemonValidate answered without a daemon")
+	}
+
+	path, err := git.GetGitPath(t.Context(), dir, daemonSocket)
+	if err != nil {
+		t.Fatalf("GetGitPath: %v", err)
+	}
+
+	var lc net.ListenConfig
+
+	ln, err := lc.Listen(t.Context(), "unix", path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	d, err := newDaemon(ctx, dir, nil)
+	if err != nil {
+		t.Fatalf("newDaemon: %v", err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() { done <- serveDaemon(ctx, ln, d) }()
+
+	t.Cleanup(func() {
+		cancel()
+
+		if err := <-done; err != nil {
+			t.Errorf("serveDaemon: %v", err)
+		}
+	})
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 1 }\n")
+	runGit(t, dir, "add", "a.go")
+
+	violations, ok := daemonValidate(t.Context(), dir)
+	if !ok {
+		t.Fatal("daemonValidate did not reach the daemon")
+	}
+
+	want, err := validator.ValidateAtomicCommit(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit: %v", err)
+	}
+
+	if len(violations) != 1 || len(want) != 1 || !reflect.DeepEqual(violations, want) {
+		t.Errorf("daemonValidate = %+v, want %+v", violations, want)
+	}
+
+	files, ok := daemonCommittable(t.Context(), dir, false)
+	if !ok {
+		t.Fatal("daemonCommittable did not reach the daemon")
+	}
+
+	wantFiles, err := validator.FindCommittableSet(t.Context(), dir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableSet: %v", err)
+	}
+
+	if !slices.Equal(files, wantFiles) {
+		t.Errorf("daemonCommittable = %v, want %v", files, wantFiles)
+	}
+
+	// The daemon answers without the closure, which would load everything again.
+	var loads atomic.Int32
+
+	code := runValidate(t.Context(), io.Discard, io.Discard, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false,
+		deadCode: false, unusedExports: false, daemon: true, deadline: deadline{},
+		opts: []validator.Option{validator.WithProgress(func(validator.Progress) { loads.Add(1) })},
+	})
+	if code != exitViolations || loads.Load() != 0 {
+		t.Errorf("runValidate with the daemon = %d after %d in-process loads, want %d after none",
+			code, loads.Load(), exitViolations)
+	}
+
+	runGit(t, dir, "add", "c.go")
+
+	if violations, ok = daemonValidate(t.Context(), dir); !ok || len(violations) != 0 {
+		t.Errorf("daemonValidate after staging c.go = %+v, %v, want no violations", violations, ok)
+	}
+
+	if _, ok = daemonValidate(t.Context(), t.TempDir()); ok {
+		t.Error("daemonValidate answered for another work dir")
+	}
+}
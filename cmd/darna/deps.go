@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errDepsUsage = errors.New("usage: darna deps [-dir <path>] [-changed] [-json] <file|symbol>")
+
+// runDeps implements "darna deps": the symbols a file or symbol transitively depends on.
+func runDeps(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("deps", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	changed := fs.Bool("changed", false, "only list symbols of files with staged, unstaged or untracked changes")
+	asJSON := fs.Bool("json", false, "print the symbols as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing deps flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errDepsUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	deps, err := validator.Deps(ctx, *workDir, fs.Arg(0), opts...)
+	if err != nil {
+		return fmt.Errorf("listing dependencies: %w", err)
+	}
+
+	return printSymbols(w, deps, *changed, *asJSON)
+}
+
+// printSymbols prints one "file:line ID" line per symbol, followed by the state of
+// its file unless committed, or the symbols as JSON. With changedOnly, symbols of
+// committed files are left out.
+func printSymbols(w io.Writer, nodes []validator.GraphNode, changedOnly, asJSON bool) error {
+	if changedOnly {
+		kept := nodes[:0]
+
+		for _, node := range nodes {
+			if node.Status != validator.StateCommitted {
+				kept = append(kept, node)
+			}
+		}
+
+		nodes = kept
+	}
+
+	if asJSON {
+		return writeJSON(w, nodes)
+	}
+
+	for _, node := range nodes {
+		line := node.File + ":" + strconv.Itoa(node.Line) + " " + node.ID
+		if node.Status != validator.StateCommitted {
+			line += " (" + string(node.Status) + ")"
+		}
+
+		writeString(w, line+"\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestRunDeps(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go", "b.go", "c.go", "d.go")
+	runGit(t, dir, "commit", "-m", "changes")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\n// Changed again.\nfunc C() {}\n")
+
+	var buf bytes.Buffer
+
+	err := runDeps(t.Context(), &buf, []string{"-dir", dir, "a.go"})
+	if err != nil {
+		t.Fatalf("runDeps: %v", err)
+	}
+
+	if want := "b.go:4 example.com/fix.B\nc.go:4 example.com/fix.C (unstaged)\n"; buf.String() != want {
+		t.Errorf("runDeps() printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+
+	err = runDeps(t.Context(), &buf, []string{"-dir", dir, "-changed", "-json", "main"})
+	if err != nil {
+		t.Fatalf("runDeps -changed -json: %v", err)
+	}
+
+	var deps []validator.GraphNode
+
+	err = json.Unmarshal(buf.Bytes(), &deps)
+	if err != nil {
+		t.Fatalf("Decoding %q: %v", buf.String(), err)
+	}
+
+	if len(deps) != 1 || deps[0].ID != "example.com/fix.C" || deps[0].Status != validator.StateUnstaged {
+		t.Errorf("runDeps() listed %+v, want only the unstaged example.com/fix.C", deps)
+	}
+
+	err = runDeps(t.Context(), &buf, []string{"-dir", dir, "Missing"})
+	if !errors.Is(err, validator.ErrUnknownTarget) {
+		t.Errorf("runDeps(Missing) = %v, want %v", err, validator.ErrUnknownTarget)
+	}
+}
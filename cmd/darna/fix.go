@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+var errUnknownFixStrategy = errors.New("unknown --fix-strategy (want stage or unstage)")
+
+// fixStrategy resolves violations by changing what is staged.
+type fixStrategy struct {
+	file    func(vv validator.Violation) string                          // File to act on for a violation.
+	apply   func(ctx context.Context, dir string, paths ...string) error // Changes the index.
+	summary string                                                       // Summary header, with a %d for the file count.
+	command string                                                       // Equivalent git command, printed per file.
+
+	// closure, if set, computes all files to act on upfront.
+	closure func(ctx context.Context, workDir string, opts ...validator.Option) ([]string, error)
+}
+
+// fixStrategies maps --fix-strategy names to strategies. "stage" makes the commit
+// complete by staging what it needs; "unstage" makes it smaller by dropping what
+// cannot be committed yet.
+var fixStrategies = map[string]fixStrategy{
+	"stage": {
+		file:    func(vv validator.Violation) string { return vv.MissingFile },
+		apply:   git.StageFiles,
+		summary: "Staged %d missing file(s):",
+		command: "git add",
+		closure: validator.FixClosure,
+	},
+	"unstage": {
+		file:    func(vv validator.Violation) string { return vv.StagedFile },
+		apply:   git.UnstageFiles,
+		summary: "Unstaged %d file(s) depending on unstaged changes:",
+		command: "git restore --staged",
+		closure: nil,
+	},
+}
+
+// fixViolations applies strategy to the files of violations, then revalidates, until
+// the commit is atomic or no new file can be acted on. Each step can expose further
+// violations (a newly staged file's own dependencies, or staged files depending on a
+// newly unstaged one), so this converges on the full closure.
+// It returns the files it acted on and the violations that remain.
+func fixViolations(
+	ctx context.Context,
+	workDir string,
+	violations []validator.Violation,
+	strategy fixStrategy,
+	opts []validator.Option,
+) ([]string, []validator.Violation, error) {
+	var fixed []string
+
+	if strategy.closure != nil && len(violations) > 0 {
+		files, err := strategy.closure(ctx, workDir, opts...)
+		if err != nil {
+			return nil, violations, fmt.Errorf("computing fix: %w", err)
+		}
+
+		if len(files) > 0 {
+			err = strategy.apply(ctx, workDir, files...)
+			if err != nil {
+				return nil, violations, fmt.Errorf("fixing violations: %w", err)
+			}
+
+			fixed = files
+
+			violations, err = validator.ValidateAtomicCommit(ctx, workDir, opts...)
+			if err != nil {
+				return fixed, nil, fmt.Errorf("revalidating: %w", err)
+			}
+		}
+	}
+
+	// Revalidating catches whatever the closure, if any, could not foresee.
+	for len(violations) > 0 {
+		var files []string
+
+		for _, vv := range violations {
+			file := strategy.file(vv)
+			if !slices.Contains(files, file) && !slices.Contains(fixed, file) {
+				files = append(files, file)
+			}
+		}
+
+		if len(files) == 0 {
+			break // The strategy did not help; report what is left.
+		}
+
+		err := strategy.apply(ctx, workDir, files...)
+		if err != nil {
+			return fixed, violations, fmt.Errorf("fixing violations: %w", err)
+		}
+
+		fixed = append(fixed, files...)
+
+		violations, err = validator.ValidateAtomicCommit(ctx, workDir, opts...)
+		if err != nil {
+			return fixed, nil, fmt.Errorf("revalidating: %w", err)
+		}
+	}
+
+	slices.Sort(fixed)
+
+	return fixed, violations, nil
+}
+
+func printFixSummary(w io.Writer, fixed []string, strategy fixStrategy, th theme) {
+	writeString(w, th.success(fmt.Sprintf(strategy.summary, len(fixed)))+"\n")
+
+	for _, file := range fixed {
+		writeString(w, "   "+strategy.command+" "+file+"\n")
+	}
+}
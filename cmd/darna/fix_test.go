@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initChainRepo creates a repository where a.go -> b.go -> c.go and d.go is
+// independent, all four with uncommitted changes and nothing staged.
+func initChainRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/fix\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc main() { B() }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() { C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() {}\n")
+	writeFile(t, filepath.Join(dir, "d.go"), "package main\n\nfunc D() {}\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "chain")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\n// Changed.\nfunc main() { B() }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\n// Changed.\nfunc B() { C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\n// Changed.\nfunc C() {}\n")
+	writeFile(t, filepath.Join(dir, "d.go"), "package main\n\n// Changed.\nfunc D() {}\n")
+
+	return dir
+}
+
+func stagedNames(t *testing.T, dir string) string {
+	t.Helper()
+
+	//nolint:gosec // Test inspects its temp repository.
+	out, err := exec.CommandContext(t.Context(), "git", "-C", dir, "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatalf("git diff --cached: %v", err)
+	}
+
+	return strings.Join(strings.Fields(string(out)), " ")
+}
+
+func TestRunValidateFixStagesClosure(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go")
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: true,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+	})
+	if code != 0 {
+		t.Fatalf("runValidate() = %d, want 0; stdout:\n%s\nstderr:\n%s", code, stdout.String(), stderr.String())
+	}
+
+	if got := stagedNames(t, dir); got != "a.go b.go c.go" {
+		t.Errorf("Staged files = %q, want %q", got, "a.go b.go c.go")
+	}
+
+	if !strings.Contains(stderr.String(), "Staged 2 missing file(s)") {
+		t.Errorf("Expected a fix summary, stderr:\n%s", stderr.String())
+	}
+}
+
+func TestRunValidateFixUnstagesDependants(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go", "b.go", "d.go")
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: true,
+		fixWith: fixStrategies["unstage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+	})
+	if code != 0 {
+		t.Fatalf("runValidate() = %d, want 0; stdout:\n%s\nstderr:\n%s", code, stdout.String(), stderr.String())
+	}
+
+	if got := stagedNames(t, dir); got != "d.go" {
+		t.Errorf("Staged files = %q, want %q", got, "d.go")
+	}
+
+	if !strings.Contains(stderr.String(), "git restore --staged a.go") {
+		t.Errorf("Expected an unstage summary, stderr:\n%s", stderr.String())
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// writeGitHub prints one GitHub Actions ::error workflow command per violation so
+// they show up as annotations on the pull request diff. Violations failOn lets pass
+// are ::warning commands instead.
+func writeGitHub(w io.Writer, violations []validator.Violation, failOn string) {
+	for _, vv := range violations {
+		props := "file=" + escapeGitHubProperty(filepath.ToSlash(vv.StagedFile))
+
+		if vv.Line > 0 {
+			props += ",line=" + strconv.Itoa(vv.Line)
+		}
+
+		if vv.Column > 0 {
+			props += ",col=" + strconv.Itoa(vv.Column)
+		}
+
+		props += ",title=" + escapeGitHubProperty("Not atomic: stage "+vv.MissingFile)
+
+		writeString(w, "::"+violationLevel(vv, failOn)+" "+props+"::"+escapeGitHubData(violationMessage(vv))+"\n")
+	}
+}
+
+// escapeGitHubData escapes a workflow command message.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a workflow command property value.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeGitHubData(s))
+}
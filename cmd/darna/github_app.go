@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"dario.cat/darna/internal/githubapp"
+)
+
+var (
+	errGitHubAppUsage = errors.New("usage: darna github-app -app-id <id> -private-key <path> [-addr <host:port>] " +
+		"[-cache-dir <path>] [-api-url <url>]")
+
+	// errNoWebhookSecret is returned when the webhook secret is not set.
+	errNoWebhookSecret = errors.New(envWebhookSecret + " must hold the webhook secret of the app")
+)
+
+// envWebhookSecret is the environment variable holding the webhook secret, kept out of
+// the flags so that it does not show in the process list.
+const envWebhookSecret = "DARNA_GITHUB_WEBHOOK_SECRET"
+
+// gitHubAppReadTimeout bounds how long a webhook delivery may take to arrive.
+const gitHubAppReadTimeout = 30 * time.Second
+
+// runGitHubApp implements "darna github-app": an HTTP server receiving the webhooks of a
+// GitHub App, which checks every commit of each pull request and reports the result as a
+// check run. Installing the app on an organization enforces atomic commits in all its
+// repositories without configuring their CI.
+func runGitHubApp(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("github-app", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "address to listen on for webhook deliveries")
+	appID := fs.Int64("app-id", 0, "ID of the GitHub App")
+	keyFile := fs.String("private-key", "", "PEM file with the private key of the GitHub App")
+	cacheDir := fs.String("cache-dir", "", "where repositories are fetched (default: user cache dir)")
+	apiURL := fs.String("api-url", githubapp.DefaultAPIURL, "GitHub REST API URL, for GitHub Enterprise Server")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing github-app flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *appID <= 0 || *keyFile == "" {
+		return errGitHubAppUsage
+	}
+
+	secret := os.Getenv(envWebhookSecret)
+	if secret == "" {
+		return errNoWebhookSecret
+	}
+
+	pemData, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return fmt.Errorf("reading private key: %w", err)
+	}
+
+	key, err := githubapp.ParsePrivateKey(pemData)
+	if err != nil {
+		return fmt.Errorf("reading private key: %w", err)
+	}
+
+	if *cacheDir == "" {
+		userCache, cacheErr := os.UserCacheDir()
+		if cacheErr != nil {
+			return fmt.Errorf("locating cache dir: %w", cacheErr)
+		}
+
+		*cacheDir = filepath.Join(userCache, "darna", "github-app")
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	app := githubapp.New(githubapp.Config{
+		AppID:         *appID,
+		PrivateKey:    key,
+		WebhookSecret: []byte(secret),
+		CacheDir:      *cacheDir,
+		APIURL:        *apiURL,
+		HTTPClient:    nil,
+		ErrorLog:      nil,
+		Options:       opts,
+	})
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var lc net.ListenConfig
+
+	ln, err := lc.Listen(ctx, "tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("listening: %w", err)
+	}
+
+	server := &http.Server{ //nolint:exhaustruct // Defaults for the rest.
+		Handler:           app,
+		ReadHeaderTimeout: gitHubAppReadTimeout,
+		ReadTimeout:       gitHubAppReadTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = server.Shutdown(context.WithoutCancel(ctx))
+	}()
+
+	writeString(w, "Receiving webhooks on "+ln.Addr().String()+"\n")
+
+	err = server.Serve(ln)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+
+	// Let the checks in progress conclude their check runs.
+	app.Wait()
+
+	return nil
+}
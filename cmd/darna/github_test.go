@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestWriteGitHub(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	writeGitHub(&buf, []validator.Violation{
+		{
+			StagedFile:    "pkg/a.go",
+			StagedSymbol:  "example.com/x.A",
+			StagedKind:    "",
+			MissingFile:   "pkg/b.go",
+			MissingSymbol: "example.com/x.B",
+			MissingKind:   "",
+			MissingLine:   0,
+			MissingColumn: 0,
+			Reason:        "",
+			Line:          4,
+			Column:        15,
+			Chain:         nil,
+			Direct:        true,
+			Fingerprint:   "",
+		},
+		{
+			StagedFile:    "c.go",
+			StagedSymbol:  "C",
+			StagedKind:    "",
+			MissingFile:   "d.go",
+			MissingSymbol: "C",
+			MissingKind:   "",
+			MissingLine:   0,
+			MissingColumn: 0,
+			Reason:        "moved to d.go which is not staged",
+			Line:          0,
+			Column:        0,
+			Chain:         nil,
+			Direct:        true,
+			Fingerprint:   "",
+		},
+	}, failOnAll)
+
+	want := "::error file=pkg/a.go,line=4,col=15,title=Not atomic%3A stage pkg/b.go::" +
+		"example.com/x.A uses example.com/x.B from pkg/b.go, which is not staged\n" +
+		"::error file=c.go,title=Not atomic%3A stage d.go::C moved to d.go which is not staged\n"
+	if buf.String() != want {
+		t.Errorf("writeGitHub() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
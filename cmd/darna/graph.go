@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// Graph formats accepted by "darna graph -format".
+const (
+	formatDOT     = "dot"
+	formatMermaid = "mermaid"
+	formatGraphML = "graphml"
+)
+
+var errGraphUsage = errors.New("usage: darna graph [-dir <path>] [-format dot|mermaid|json|graphml] " +
+	"[-scope changed|all] [-serve] [-addr <host:port>]")
+
+// graphScopes maps each graph format to its default scope: diagrams show the changeset,
+// machine-readable exports the whole graph.
+var graphScopes = map[string]validator.GraphScope{
+	formatDOT:     validator.ScopeChanged,
+	formatMermaid: validator.ScopeChanged,
+	formatJSON:    validator.ScopeAll,
+	formatGraphML: validator.ScopeAll,
+}
+
+// dotStyles maps the git state of a symbol's file to Graphviz node attributes.
+var dotStyles = map[validator.FileState]string{
+	validator.StateCommitted: `color="gray50", fontcolor="gray50"`,
+	validator.StateStaged:    `style="filled", fillcolor="palegreen"`,
+	validator.StateUnstaged:  `style="filled", fillcolor="orange"`,
+	validator.StatePartial:   `style="filled", fillcolor="khaki"`,
+	validator.StateUntracked: `style="filled,dashed", fillcolor="lightpink"`,
+}
+
+// mermaidClasses maps the git state of a symbol's file to a Mermaid class, defined in
+// the same colors as dotStyles.
+var mermaidClasses = []struct {
+	state validator.FileState
+	name  string
+	style string
+}{
+	{validator.StateCommitted, "committed", "fill:#ffffff,stroke:#7f7f7f,color:#7f7f7f"},
+	{validator.StateStaged, "staged", "fill:#98fb98,stroke:#333333"},
+	{validator.StateUnstaged, "unstaged", "fill:#ffa500,stroke:#333333"},
+	{validator.StatePartial, "partial", "fill:#f0e68c,stroke:#333333"},
+	{validator.StateUntracked, "untracked", "fill:#ffb6c1,stroke:#333333,stroke-dasharray:4 2"},
+}
+
+// runGraph implements "darna graph": the symbol dependency graph of the working tree,
+// as a diagram restricted to the changeset by default, or as a machine-readable export.
+func runGraph(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	format := fs.String("format", formatDOT, "output format: dot, mermaid, json or graphml")
+	scope := fs.String("scope", "",
+		"symbols to export: changed (changed files and the paths between them) or all "+
+			"(default: changed for dot and mermaid, all for json and graphml)")
+	serve := fs.Bool("serve", false, "serve an interactive view of the graph instead of printing it")
+	addr := fs.String("addr", "localhost:0", "address to serve the interactive view on (default: a free port)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing graph flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	graphScope, ok := graphScopes[*format]
+	if *scope != "" {
+		graphScope = validator.GraphScope(*scope)
+	}
+
+	if fs.NArg() != 0 || !ok || (graphScope != validator.ScopeChanged && graphScope != validator.ScopeAll) {
+		return errGraphUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	if *serve {
+		return serveGraph(ctx, w, *addr, *workDir, graphScope, opts)
+	}
+
+	graph, err := validator.ExportGraph(ctx, *workDir, graphScope, opts...)
+	if err != nil {
+		return fmt.Errorf("exporting graph: %w", err)
+	}
+
+	switch *format {
+	case formatJSON:
+		return writeJSON(w, graph)
+	case formatGraphML:
+		return writeGraphML(w, graph)
+	case formatMermaid:
+		printMermaid(w, graph)
+	default:
+		printDOT(w, graph)
+	}
+
+	return nil
+}
+
+// printDOT prints graph in the Graphviz DOT language, one cluster per file, with nodes
+// colored by the git state of their file.
+func printDOT(w io.Writer, graph *validator.Graph) {
+	writeString(w, "digraph darna {\n\trankdir=LR;\n\tnode [shape=box, fontname=\"Helvetica\"];\n")
+
+	for i, node := range graph.Nodes {
+		if i == 0 || node.File != graph.Nodes[i-1].File {
+			if i > 0 {
+				writeString(w, "\t}\n")
+			}
+
+			writeString(w, fmt.Sprintf("\tsubgraph \"cluster_%d\" {\n\t\tlabel=%s;\n", i, dotQuote(node.File)))
+		}
+
+		writeString(w, "\t\t"+dotQuote(node.ID)+" [label="+dotQuote(node.Name)+", "+dotStyles[node.Status]+"];\n")
+	}
+
+	if len(graph.Nodes) > 0 {
+		writeString(w, "\t}\n")
+	}
+
+	for _, edge := range graph.Edges {
+		writeString(w, "\t"+dotQuote(edge.From)+" -> "+dotQuote(edge.To)+";\n")
+	}
+
+	writeString(w, "}\n")
+}
+
+// dotQuote quotes s as a DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// printMermaid prints graph as a Mermaid flowchart, one subgraph per file, with nodes
+// classed by the git state of their file. Symbol IDs are not valid Mermaid node IDs, so
+// nodes are numbered and labeled with their name.
+func printMermaid(w io.Writer, graph *validator.Graph) {
+	writeString(w, "flowchart LR\n")
+
+	classes := make(map[validator.FileState]string, len(mermaidClasses))
+	for _, class := range mermaidClasses {
+		classes[class.state] = class.name
+		writeString(w, "  classDef "+class.name+" "+class.style+"\n")
+	}
+
+	nodeIDs := make(map[string]string, len(graph.Nodes))
+
+	for i, node := range graph.Nodes {
+		if i == 0 || node.File != graph.Nodes[i-1].File {
+			if i > 0 {
+				writeString(w, "  end\n")
+			}
+
+			writeString(w, "  subgraph f"+strconv.Itoa(i)+"["+mermaidQuote(node.File)+"]\n")
+		}
+
+		nodeIDs[node.ID] = "n" + strconv.Itoa(i)
+		writeString(w, "    "+nodeIDs[node.ID]+"["+mermaidQuote(node.Name)+"]:::"+classes[node.Status]+"\n")
+	}
+
+	if len(graph.Nodes) > 0 {
+		writeString(w, "  end\n")
+	}
+
+	for _, edge := range graph.Edges {
+		writeString(w, "  "+nodeIDs[edge.From]+" --> "+nodeIDs[edge.To]+"\n")
+	}
+}
+
+// mermaidQuote quotes s as a Mermaid label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
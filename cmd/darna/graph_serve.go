@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// graphPage is the interactive graph viewer served by "darna graph -serve". It has no
+// external dependencies, so it works offline.
+//
+//go:embed graph.html
+var graphPage []byte
+
+// graphServeTimeout bounds how long a request to the graph viewer may take to arrive.
+const graphServeTimeout = 10 * time.Second
+
+// graphData is what the graph viewer fetches: the exported graph and the violations of
+// the staged changes, whose chains it highlights.
+type graphData struct {
+	Graph      *validator.Graph      `json:"graph"`
+	Violations []validator.Violation `json:"violations"`
+}
+
+// serveGraph serves the graph viewer on addr until ctx is done or the process is
+// interrupted. The graph is exported again on every page load, so reloading the page
+// shows the current working tree.
+func serveGraph(
+	ctx context.Context, w io.Writer, addr, workDir string, scope validator.GraphScope, opts []validator.Option,
+) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var lc net.ListenConfig
+
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening: %w", err)
+	}
+
+	server := &http.Server{ //nolint:exhaustruct // Defaults for the rest.
+		Handler:           graphHandler(workDir, scope, opts),
+		ReadHeaderTimeout: graphServeTimeout,
+		ReadTimeout:       graphServeTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = server.Shutdown(context.WithoutCancel(ctx))
+	}()
+
+	writeString(w, "Serving the dependency graph on http://"+ln.Addr().String()+"/\n")
+
+	err = server.Serve(ln)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+
+	return nil
+}
+
+// graphHandler serves the graph viewer at "/" and the data it renders at "/graph.json".
+func graphHandler(workDir string, scope validator.GraphScope, opts []validator.Option) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(graphPage)
+	})
+
+	mux.HandleFunc("GET /graph.json", func(w http.ResponseWriter, r *http.Request) {
+		graph, err := validator.ExportGraph(r.Context(), workDir, scope, opts...)
+		if err != nil {
+			http.Error(w, "exporting graph: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		violations, err := validator.ValidateAtomicCommit(r.Context(), workDir, opts...)
+		if err != nil {
+			http.Error(w, "validating staged changes: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		if violations == nil {
+			violations = []validator.Violation{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = writeJSON(w, graphData{Graph: graph, Violations: violations})
+	})
+
+	return mux
+}
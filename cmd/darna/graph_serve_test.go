@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestGraphHandler(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go")
+
+	handler := graphHandler(dir, validator.ScopeChanged, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `fetch("graph.json")`) {
+		t.Fatalf("GET / = %d %q, want the graph viewer", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/graph.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /graph.json = %d %q, want 200", rec.Code, rec.Body.String())
+	}
+
+	var data graphData
+
+	err := json.Unmarshal(rec.Body.Bytes(), &data)
+	if err != nil {
+		t.Fatalf("Decoding %q: %v", rec.Body.String(), err)
+	}
+
+	if len(data.Graph.Nodes) != 4 {
+		t.Errorf("Graph has %d nodes, want 4: %+v", len(data.Graph.Nodes), data.Graph.Nodes)
+	}
+
+	want := []string{"example.com/fix.main", "example.com/fix.B", "example.com/fix.C"}
+	if len(data.Violations) != 2 || !slices.Equal(data.Violations[1].Chain, want) {
+		t.Errorf("Violations = %+v, want two, the last with chain %v", data.Violations, want)
+	}
+}
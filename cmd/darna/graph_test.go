@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestRunGraph(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go")
+
+	var buf bytes.Buffer
+
+	err := runGraph(t.Context(), &buf, []string{"-dir", dir})
+	if err != nil {
+		t.Fatalf("runGraph: %v", err)
+	}
+
+	want := `digraph darna {
+	rankdir=LR;
+	node [shape=box, fontname="Helvetica"];
+	subgraph "cluster_0" {
+		label="a.go";
+		"example.com/fix.main" [label="main", style="filled", fillcolor="palegreen"];
+	}
+	subgraph "cluster_1" {
+		label="b.go";
+		"example.com/fix.B" [label="B", style="filled", fillcolor="orange"];
+	}
+	subgraph "cluster_2" {
+		label="c.go";
+		"example.com/fix.C" [label="C", style="filled", fillcolor="orange"];
+	}
+	subgraph "cluster_3" {
+		label="d.go";
+		"example.com/fix.D" [label="D", style="filled", fillcolor="orange"];
+	}
+	"example.com/fix.B" -> "example.com/fix.C";
+	"example.com/fix.main" -> "example.com/fix.B";
+}
+`
+	if buf.String() != want {
+		t.Errorf("runGraph() printed:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRunGraphJSON(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go", "b.go", "c.go", "d.go")
+	runGit(t, dir, "commit", "-m", "changes")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\n// Changed again.\nfunc C() {}\n")
+
+	var buf bytes.Buffer
+
+	// Machine-readable formats export the whole graph, not just the changeset.
+	err := runGraph(t.Context(), &buf, []string{"-dir", dir, "-format", "json"})
+	if err != nil {
+		t.Fatalf("runGraph: %v", err)
+	}
+
+	var graph validator.Graph
+
+	err = json.Unmarshal(buf.Bytes(), &graph)
+	if err != nil {
+		t.Fatalf("Decoding %q: %v", buf.String(), err)
+	}
+
+	if graph.Version != validator.GraphVersion || len(graph.Nodes) != 4 || len(graph.Edges) != 2 {
+		t.Fatalf("runGraph() exported %+v, want version %d with 4 nodes and 2 edges", graph, validator.GraphVersion)
+	}
+
+	want := validator.GraphNode{
+		ID: "example.com/fix.C", Name: "C", Kind: "func", Package: "example.com/fix",
+		File: "c.go", Line: 4, Status: validator.StateUnstaged,
+	}
+	if graph.Nodes[2] != want {
+		t.Errorf("Nodes[2] = %+v, want %+v", graph.Nodes[2], want)
+	}
+}
+
+func TestPrintMermaid(t *testing.T) {
+	t.Parallel()
+
+	graph := &validator.Graph{
+		Nodes: []validator.GraphNode{
+			{ID: "ex.A", Name: "A", Kind: "func", Package: "ex", File: "a.go", Line: 3, Status: validator.StateStaged},
+			{ID: "ex.T", Name: "T", Kind: "type", Package: "ex", File: "a.go", Line: 5, Status: validator.StateStaged},
+			{ID: "ex.B", Name: "B", Kind: "func", Package: "ex", File: "b.go", Line: 3, Status: validator.StateUntracked},
+		},
+		Edges: []validator.GraphEdge{{From: "ex.A", To: "ex.B"}, {From: "ex.A", To: "ex.T"}},
+	}
+
+	var buf bytes.Buffer
+
+	printMermaid(&buf, graph)
+
+	want := `flowchart LR
+  classDef committed fill:#ffffff,stroke:#7f7f7f,color:#7f7f7f
+  classDef staged fill:#98fb98,stroke:#333333
+  classDef unstaged fill:#ffa500,stroke:#333333
+  classDef partial fill:#f0e68c,stroke:#333333
+  classDef untracked fill:#ffb6c1,stroke:#333333,stroke-dasharray:4 2
+  subgraph f0["a.go"]
+    n0["A"]:::staged
+    n1["T"]:::staged
+  end
+  subgraph f2["b.go"]
+    n2["B"]:::untracked
+  end
+  n0 --> n2
+  n0 --> n1
+`
+	if buf.String() != want {
+		t.Errorf("printMermaid() printed:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRunGraphUsage(t *testing.T) {
+	t.Parallel()
+
+	usages := [][]string{{"-format", "svg"}, {"-scope", "staged"}, {"-format", "json", "-scope", "staged"}, {"extra"}}
+	for _, args := range usages {
+		err := runGraph(t.Context(), &bytes.Buffer{}, args)
+		if !errors.Is(err, errGraphUsage) {
+			t.Errorf("runGraph(%q) = %v, want %v", args, err, errGraphUsage)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"dario.cat/darna/internal/validator"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// The GraphML types below declare one key per validator.GraphNode field, so that
+// GraphML and JSON exports carry the same data.
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Data        []graphMLData `xml:"data"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "version", For: "graph", Name: "version", Type: "int"},
+	{ID: "name", For: "node", Name: "name", Type: "string"},
+	{ID: "kind", For: "node", Name: "kind", Type: "string"},
+	{ID: "package", For: "node", Name: "package", Type: "string"},
+	{ID: "file", For: "node", Name: "file", Type: "string"},
+	{ID: "line", For: "node", Name: "line", Type: "int"},
+	{ID: "status", For: "node", Name: "status", Type: "string"},
+}
+
+// writeGraphML writes graph as a GraphML document, nodes identified by symbol ID.
+func writeGraphML(w io.Writer, graph *validator.Graph) error {
+	doc := graphML{
+		XMLName: xml.Name{Space: "", Local: "graphml"},
+		XMLNS:   graphMLNamespace,
+		Keys:    graphMLKeys,
+		Graph: graphMLGraph{
+			ID:          "darna",
+			EdgeDefault: "directed",
+			Data:        []graphMLData{{Key: "version", Value: strconv.Itoa(graph.Version)}},
+			Nodes:       make([]graphMLNode, 0, len(graph.Nodes)),
+			Edges:       make([]graphMLEdge, 0, len(graph.Edges)),
+		},
+	}
+
+	for _, node := range graph.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "name", Value: node.Name},
+				{Key: "kind", Value: node.Kind},
+				{Key: "package", Value: node.Package},
+				{Key: "file", Value: node.File},
+				{Key: "line", Value: strconv.Itoa(node.Line)},
+				{Key: "status", Value: string(node.Status)},
+			},
+		})
+	}
+
+	for _, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: edge.From, Target: edge.To})
+	}
+
+	writeString(w, xml.Header)
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	err := enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("encoding GraphML: %w", err)
+	}
+
+	writeString(w, "\n")
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"slices"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestWriteGraphML(t *testing.T) {
+	t.Parallel()
+
+	graph := &validator.Graph{
+		Version: validator.GraphVersion,
+		Nodes: []validator.GraphNode{
+			{ID: "ex.A", Name: "A", Kind: "func", Package: "ex", File: "a.go", Line: 3, Status: validator.StateStaged},
+			{ID: "ex.B", Name: "B", Kind: "func", Package: "ex", File: "b&c.go", Line: 5, Status: validator.StateCommitted},
+		},
+		Edges: []validator.GraphEdge{{From: "ex.A", To: "ex.B"}},
+	}
+
+	var buf bytes.Buffer
+
+	err := writeGraphML(&buf, graph)
+	if err != nil {
+		t.Fatalf("writeGraphML: %v", err)
+	}
+
+	var doc graphML
+
+	err = xml.Unmarshal(buf.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("Decoding %q: %v", buf.String(), err)
+	}
+
+	if len(doc.Keys) != len(graphMLKeys) || doc.Graph.EdgeDefault != "directed" {
+		t.Errorf("Decoded keys %v and edge default %q", doc.Keys, doc.Graph.EdgeDefault)
+	}
+
+	if len(doc.Graph.Nodes) != 2 || doc.Graph.Nodes[1].ID != "ex.B" {
+		t.Fatalf("Decoded nodes %v, want ex.A and ex.B", doc.Graph.Nodes)
+	}
+
+	want := []graphMLData{
+		{Key: "name", Value: "B"}, {Key: "kind", Value: "func"}, {Key: "package", Value: "ex"},
+		{Key: "file", Value: "b&c.go"}, {Key: "line", Value: "5"}, {Key: "status", Value: "committed"},
+	}
+	if !slices.Equal(doc.Graph.Nodes[1].Data, want) {
+		t.Errorf("Decoded data %v, want %v", doc.Graph.Nodes[1].Data, want)
+	}
+
+	if edges := []graphMLEdge{{Source: "ex.A", Target: "ex.B"}}; !slices.Equal(doc.Graph.Edges, edges) {
+		t.Errorf("Decoded edges %v, want %v", doc.Graph.Edges, edges)
+	}
+}
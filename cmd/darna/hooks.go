@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dario.cat/darna/internal/git"
+)
+
+var (
+	errHooksUsage = errors.New("usage: darna hooks install [-dir <path>] [-pre-commit] [-prepare-commit-msg] " +
+		"[-pre-push] [-agent <name>] [-force] | darna hooks uninstall [-dir <path>]")
+
+	// errForeignHook is returned when installing over a hook darna did not write.
+	errForeignHook = errors.New("hook not installed by darna exists; use -force to replace it")
+)
+
+// hookMarker identifies the hook scripts written by darna, which uninstall removes.
+const hookMarker = "# Installed by darna."
+
+// Names of the hooks darna can install.
+const (
+	hookPreCommit        = "pre-commit"
+	hookPrepareCommitMsg = "prepare-commit-msg"
+	hookPrePush          = "pre-push"
+)
+
+// runHooks implements "darna hooks": install writes the selected hook scripts, the
+// pre-commit one by default, into the hooks directory of the repository, and uninstall
+// removes every hook darna wrote.
+func runHooks(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errHooksUsage
+	}
+
+	switch args[0] {
+	case "install":
+		return installHooks(ctx, w, args[1:])
+	case "uninstall":
+		return uninstallHooks(ctx, w, args[1:])
+	default:
+		return errHooksUsage
+	}
+}
+
+func installHooks(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("hooks install", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	preCommit := fs.Bool("pre-commit", false, "validate the staged changes before each commit")
+	prepareMsg := fs.Bool("prepare-commit-msg", false, "generate the message of commits started without one")
+	prePush := fs.Bool("pre-push", false, "check every pushed commit")
+	agent := fs.String("agent", agentFromConfig, "agent generating commit messages for -prepare-commit-msg")
+	force := fs.Bool("force", false, "replace existing hooks not installed by darna")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing hooks flags: %w", err)
+	}
+
+	_, _, err = applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errHooksUsage
+	}
+
+	scripts := make(map[string]string)
+
+	if *preCommit || !*prepareMsg && !*prePush {
+		scripts[hookPreCommit] = preCommitScript
+	}
+
+	if *prepareMsg {
+		scripts[hookPrepareCommitMsg] = strings.ReplaceAll(prepareCommitMsgScript, "AGENT", shellQuote(*agent))
+	}
+
+	if *prePush {
+		scripts[hookPrePush] = prePushScript
+	}
+
+	hooksDir, err := git.GetHooksDir(ctx, *workDir)
+	if err != nil {
+		return fmt.Errorf("locating hooks: %w", err)
+	}
+
+	// Check every hook first so that a conflict leaves none half installed.
+	for _, name := range []string{hookPreCommit, hookPrepareCommitMsg, hookPrePush} {
+		if _, ok := scripts[name]; ok && !*force && isForeignHook(filepath.Join(hooksDir, name)) {
+			return fmt.Errorf("%s: %w", name, errForeignHook)
+		}
+	}
+
+	err = os.MkdirAll(hooksDir, 0o755) //nolint:mnd,gosec // Hooks dirs are usually world-readable.
+	if err != nil {
+		return fmt.Errorf("creating hooks dir: %w", err)
+	}
+
+	for _, name := range []string{hookPreCommit, hookPrepareCommitMsg, hookPrePush} {
+		script, ok := scripts[name]
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(hooksDir, name)
+
+		err = os.WriteFile(path, []byte(script), 0o755) //nolint:gosec,mnd // Hooks must be executable.
+		if err == nil {
+			err = os.Chmod(path, 0o755) //nolint:gosec,mnd // WriteFile keeps the mode of an existing file.
+		}
+
+		if err != nil {
+			return fmt.Errorf("installing %s: %w", name, err)
+		}
+
+		writeString(w, "Installed "+path+"\n")
+	}
+
+	return nil
+}
+
+func uninstallHooks(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("hooks uninstall", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing hooks flags: %w", err)
+	}
+
+	_, _, err = applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errHooksUsage
+	}
+
+	hooksDir, err := git.GetHooksDir(ctx, *workDir)
+	if err != nil {
+		return fmt.Errorf("locating hooks: %w", err)
+	}
+
+	for _, name := range []string{hookPreCommit, hookPrepareCommitMsg, hookPrePush} {
+		path := filepath.Join(hooksDir, name)
+
+		content, readErr := os.ReadFile(path) //nolint:gosec // Path is inside the hooks dir.
+		if readErr != nil || !bytes.Contains(content, []byte(hookMarker)) {
+			continue // Missing, or someone else's.
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return fmt.Errorf("removing %s: %w", name, err)
+		}
+
+		writeString(w, "Removed "+path+"\n")
+	}
+
+	return nil
+}
+
+// isForeignHook reports whether path holds a hook that darna did not install.
+func isForeignHook(path string) bool {
+	content, err := os.ReadFile(path) //nolint:gosec // Path is inside the hooks dir.
+	if err != nil {
+		return false
+	}
+
+	return !bytes.Contains(content, []byte(hookMarker))
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+const preCommitScript = `#!/bin/sh
+` + hookMarker + `
+exec darna
+`
+
+// prepareCommitMsgScript only fills in messages that git did not get from -m, -F, a
+// template, a merge or an amend. Generation failures never block the commit. AGENT is
+// replaced with the quoted agent name.
+const prepareCommitMsgScript = `#!/bin/sh
+` + hookMarker + `
+[ -z "$2" ] || exit 0
+msg=$(darna --commit-msg AGENT) || exit 0
+[ -n "$msg" ] || exit 0
+{ printf '%s\n' "$msg"; cat "$1"; } > "$1.darna" && mv "$1.darna" "$1"
+`
+
+// prePushScript checks the commits each pushed ref adds to the remote. New refs are
+// checked from the first commit no remote-tracking ref has, or from the one after a root
+// commit, which has no parent to range from; deletions are skipped.
+const prePushScript = `#!/bin/sh
+` + hookMarker + `
+zero=$(git hash-object --stdin </dev/null | tr '[0-9a-f]' '0')
+while read -r local_ref local_sha remote_ref remote_sha; do
+    [ "$local_sha" = "$zero" ] && continue
+    if [ "$remote_sha" = "$zero" ]; then
+        first=$(git rev-list --reverse "$local_sha" --not --remotes | head -n 1)
+        [ -n "$first" ] || continue
+        remote_sha=$(git rev-parse -q --verify "$first^" || echo "$first")
+    fi
+    darna check "$remote_sha..$local_sha" || exit 1
+done
+`
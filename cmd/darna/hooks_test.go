@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHooks(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+	hooksDir := filepath.Join(dir, ".git", "hooks")
+
+	var buf bytes.Buffer
+
+	err := runHooks(t.Context(), &buf, []string{"install", "-dir", dir})
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(hooksDir, hookPreCommit))
+	if err != nil || info.Mode()&0o111 == 0 {
+		t.Fatalf("Expected an executable pre-commit hook, got %v, %v", info, err)
+	}
+
+	if _, err = os.Stat(filepath.Join(hooksDir, hookPrePush)); err == nil {
+		t.Errorf("Expected no pre-push hook without -pre-push")
+	}
+
+	// A hook written by someone else is only replaced with -force.
+	writeFile(t, filepath.Join(hooksDir, hookPrePush), "#!/bin/sh\nexit 0\n")
+
+	args := []string{"install", "-dir", dir, "-pre-push", "-prepare-commit-msg", "-agent", "claude"}
+
+	err = runHooks(t.Context(), &buf, args)
+	if !errors.Is(err, errForeignHook) {
+		t.Fatalf("install over a foreign hook = %v, want errForeignHook", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(hooksDir, hookPrepareCommitMsg)); err == nil {
+		t.Errorf("Expected no hook installed when one conflicts")
+	}
+
+	err = runHooks(t.Context(), &buf, append(args, "-force"))
+	if err != nil {
+		t.Fatalf("install -force: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(hooksDir, hookPrepareCommitMsg))
+	if err != nil || !strings.Contains(string(content), "darna --commit-msg 'claude'") {
+		t.Errorf("Unexpected prepare-commit-msg hook: %q, %v", content, err)
+	}
+
+	// Uninstall keeps hooks darna did not write.
+	writeFile(t, filepath.Join(hooksDir, "post-commit"), "#!/bin/sh\nexit 0\n")
+
+	err = runHooks(t.Context(), &buf, []string{"uninstall", "-dir", dir})
+	if err != nil {
+		t.Fatalf("uninstall: %v", err)
+	}
+
+	for _, name := range []string{hookPreCommit, hookPrepareCommitMsg, hookPrePush} {
+		if _, err = os.Stat(filepath.Join(hooksDir, name)); err == nil {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+
+	if _, err = os.Stat(filepath.Join(hooksDir, "post-commit")); err != nil {
+		t.Errorf("Expected post-commit to be kept: %v", err)
+	}
+
+	err = runHooks(t.Context(), &buf, []string{"reinstall"})
+	if !errors.Is(err, errHooksUsage) {
+		t.Errorf("runHooks(reinstall) = %v, want errHooksUsage", err)
+	}
+}
+
+func TestRunHooksHooksPath(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+	runGit(t, dir, "config", "core.hooksPath", "githooks")
+
+	var buf bytes.Buffer
+
+	err := runHooks(t.Context(), &buf, []string{"install", "-dir", dir})
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "githooks", hookPreCommit)); err != nil {
+		t.Errorf("Expected the hook in core.hooksPath: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errHunksUsage = errors.New("usage: darna hunks [-dir <path>] [-patch | -json] <file|symbol>")
+
+// runHunks implements "darna hunks": the unstaged hunks a file or symbol needs staged
+// for its commit to be atomic, as file:line ranges, JSON, or a patch for
+// "git apply --cached --unidiff-zero".
+func runHunks(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("hunks", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	asPatch := fs.Bool("patch", false, "print the hunks as a patch for git apply --cached --unidiff-zero")
+	asJSON := fs.Bool("json", false, "print the hunks as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing hunks flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *asPatch && *asJSON {
+		return errHunksUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	hunks, err := validator.SuggestHunks(ctx, *workDir, fs.Arg(0), opts...)
+	if err != nil {
+		return fmt.Errorf("suggesting hunks: %w", err)
+	}
+
+	switch {
+	case *asJSON:
+		if hunks == nil {
+			hunks = []validator.Hunk{}
+		}
+
+		return writeJSON(w, hunks)
+	case *asPatch:
+		writeString(w, validator.FormatPatch(hunks))
+	default:
+		for _, h := range hunks {
+			writeString(w, h.Range()+"\n")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunHunks(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+
+	var buf bytes.Buffer
+
+	err := runHunks(t.Context(), &buf, []string{"-dir", dir, "B"})
+	if err != nil {
+		t.Fatalf("runHunks: %v", err)
+	}
+
+	if want := "b.go:3\nc.go:3\n"; buf.String() != want {
+		t.Errorf("runHunks() printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+
+	err = runHunks(t.Context(), &buf, []string{"-dir", dir, "-patch", "b.go"})
+	if err != nil {
+		t.Fatalf("runHunks -patch: %v", err)
+	}
+
+	cmd := exec.CommandContext(t.Context(), "git", "-C", dir, "apply", "--cached", "--unidiff-zero", "-")
+	cmd.Stdin = strings.NewReader(buf.String())
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git apply: %v\n%s", err, out)
+	}
+
+	if got := stagedNames(t, dir); got != "b.go c.go" {
+		t.Errorf("Staged %q, want %q", got, "b.go c.go")
+	}
+
+	err = runHunks(t.Context(), &buf, []string{"-dir", dir, "-patch", "-json", "b.go"})
+	if err == nil {
+		t.Error("runHunks accepted -patch with -json")
+	}
+}
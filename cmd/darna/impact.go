@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errImpactUsage = errors.New("usage: darna impact [-dir <path>] [-top <n>] <file> | " +
+	"darna impact [-dir <path>] [-json] -staged")
+
+// runImpact implements "darna impact <file>": a blast-radius estimate of changing file,
+// or with -staged, what the staged changes transitively affect.
+func runImpact(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("impact", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: current directory)")
+	top := fs.Int("top", 10, "number of most affected files to list (0 = all)") //nolint:mnd // Sensible default.
+	staged := fs.Bool("staged", false, "list the files, packages and exported symbols the staged changes affect")
+	asJSON := fs.Bool("json", false, "print the staged impact as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing impact flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	if *staged {
+		if fs.NArg() != 0 {
+			return errImpactUsage
+		}
+
+		return runStagedImpact(ctx, w, *workDir, *asJSON, opts)
+	}
+
+	if fs.NArg() != 1 || *asJSON {
+		return errImpactUsage
+	}
+
+	report, err := validator.Impact(ctx, *workDir, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("computing impact: %w", err)
+	}
+
+	printImpact(w, report, *top)
+
+	return nil
+}
+
+func printImpact(w io.Writer, report *validator.ImpactReport, top int) {
+	writeString(w, report.File+" impacts "+strconv.Itoa(report.Symbols)+" symbols in "+
+		strconv.Itoa(len(report.Files))+" files\n")
+
+	files := report.Files
+	if top > 0 && len(files) > top {
+		files = files[:top]
+	}
+
+	for _, fi := range files {
+		writeString(w, "  "+fi.File+" ("+strconv.Itoa(fi.Symbols)+")\n")
+	}
+}
+
+// runStagedImpact prints what the staged changes transitively affect.
+func runStagedImpact(ctx context.Context, w io.Writer, workDir string, asJSON bool, opts []validator.Option) error {
+	report, err := validator.StagedImpact(ctx, workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("computing staged impact: %w", err)
+	}
+
+	if asJSON {
+		return writeJSON(w, report)
+	}
+
+	printStagedImpact(w, report)
+
+	return nil
+}
+
+func printStagedImpact(w io.Writer, report *validator.StagedImpactReport) {
+	writeString(w, "Staged changes touch "+strconv.Itoa(len(report.Changed))+" symbols and affect "+
+		strconv.Itoa(len(report.Files))+" files in "+strconv.Itoa(len(report.Packages))+" packages\n")
+
+	for _, section := range []struct {
+		title string
+		items []string
+	}{
+		{"Files", report.Files},
+		{"Packages", report.Packages},
+		{"Exported symbols", report.Exported},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+
+		writeString(w, section.title+":\n")
+
+		for _, item := range section.items {
+			writeString(w, "  "+item+"\n")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dario.cat/darna/internal/agent"
+)
+
+var (
+	errLintMsgUsage = errors.New("usage: darna lint-msg <msgfile|->")
+
+	// errInvalidMessage is returned when the commit message breaks the rules.
+	errInvalidMessage = errors.New("commit message does not follow Conventional Commits")
+)
+
+// runLintMsg implements "darna lint-msg": it checks a commit message file, or standard
+// input given "-", against the rules the default prompt gives agents, so that it fits
+// a commit-msg hook.
+func runLintMsg(_ context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("lint-msg", flag.ContinueOnError)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing lint-msg flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		return errLintMsgUsage
+	}
+
+	var msg []byte
+
+	if fs.Arg(0) == "-" {
+		msg, err = io.ReadAll(os.Stdin)
+	} else {
+		msg, err = os.ReadFile(fs.Arg(0))
+	}
+
+	if err != nil {
+		return fmt.Errorf("reading commit message: %w", err)
+	}
+
+	problems := agent.LintMessage(string(msg))
+	for _, problem := range problems {
+		writeString(w, fs.Arg(0)+": "+problem+"\n")
+	}
+
+	if len(problems) > 0 {
+		return errInvalidMessage
+	}
+
+	return nil
+}
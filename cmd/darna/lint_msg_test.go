@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLintMsg(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid")
+	invalid := filepath.Join(dir, "invalid")
+
+	writeFile(t, valid, "feat(cli): add lint-msg\n\n# Please enter the commit message.\n")
+	writeFile(t, invalid, "Added lint-msg.\n")
+
+	var buf bytes.Buffer
+
+	err := runLintMsg(t.Context(), &buf, []string{valid})
+	if err != nil || buf.Len() != 0 {
+		t.Errorf("runLintMsg(valid) = %v, output %q", err, buf.String())
+	}
+
+	err = runLintMsg(t.Context(), &buf, []string{invalid})
+	if !errors.Is(err, errInvalidMessage) {
+		t.Errorf("runLintMsg(invalid) = %v, want errInvalidMessage", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), invalid+": subject must follow") {
+		t.Errorf("Expected the problem prefixed with the file, got:\n%s", buf.String())
+	}
+
+	err = runLintMsg(t.Context(), &buf, nil)
+	if !errors.Is(err, errLintMsgUsage) {
+		t.Errorf("runLintMsg() = %v, want errLintMsgUsage", err)
+	}
+}
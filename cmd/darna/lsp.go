@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var (
+	errLSPUsage = errors.New("usage: darna lsp [-dir <path>] [-interval <duration>]")
+
+	// errLSPHeader is returned for messages without a valid Content-Length header.
+	errLSPHeader = errors.New("missing or invalid Content-Length header")
+)
+
+// lspSeverityError is the DiagnosticSeverity of violations, and rpcMessageError the
+// MessageType of validation errors.
+const (
+	lspSeverityError = 1
+	rpcMessageError  = 1
+)
+
+type lspPublishDiagnostics struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspDiagnostic struct {
+	Range              lspRange                `json:"range"`
+	Severity           int                     `json:"severity"`
+	Code               string                  `json:"code"`
+	Source             string                  `json:"source"`
+	Message            string                  `json:"message"`
+	RelatedInformation []lspRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type lspRelatedInformation struct {
+	Location lspLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspServer publishes the violations of a working tree as diagnostics, re-validating
+// incrementally whenever a file changes or is staged.
+type lspServer struct {
+	w         io.Writer
+	workDir   string // Absolute, to build file URIs.
+	opts      []validator.Option
+	analysis  *validator.Analysis  // Reused between validations, nil until the first one.
+	files     map[string]fileState // Changed files at the last poll, nil before it.
+	published map[string]bool      // URIs with diagnostics, to clear once fixed.
+	shutdown  bool                 // Whether the client asked to shut down.
+}
+
+// runLSP implements "darna lsp": a Language Server Protocol server on standard input
+// and output that publishes diagnostics on the staged symbols depending on unstaged
+// changes. Editors never tell servers about staging, so the server polls the git status
+// of the working tree like darna watch does, and also re-validates on each save.
+func runLSP(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	interval := fs.Duration("interval", defaultWatchInterval, "how often to look for changes")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing lsp flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *interval <= 0 {
+		return errLSPUsage
+	}
+
+	abs, err := filepath.Abs(*workDir)
+	if err != nil {
+		return fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	s := &lspServer{
+		w:         w,
+		workDir:   abs,
+		opts:      opts,
+		analysis:  nil,
+		files:     nil,
+		published: make(map[string]bool),
+		shutdown:  false,
+	}
+
+	return s.serve(ctx, os.Stdin, *interval)
+}
+
+// serve answers the messages read from r until the client exits or r is closed,
+// polling for changes every interval meanwhile.
+func (s *lspServer) serve(ctx context.Context, r io.Reader, interval time.Duration) error {
+	messages := make(chan []byte)
+	readErr := make(chan error, 1)
+
+	go func() {
+		br := bufio.NewReader(r)
+
+		for {
+			body, err := readLSPMessage(br)
+			if err != nil {
+				readErr <- err
+
+				return
+			}
+
+			select {
+			case messages <- body:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		case <-ticker.C:
+			err := s.poll(ctx, false)
+			if err != nil {
+				return err
+			}
+		case body := <-messages:
+			done, err := s.handle(ctx, body)
+			if done || err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle answers a message, reporting whether the client asked to exit.
+func (s *lspServer) handle(ctx context.Context, body []byte) (bool, error) {
+	var msg rpcMessage
+
+	err := json.Unmarshal(body, &msg)
+	if err != nil {
+		return false, s.reply(nil, nil, &rpcError{Code: rpcInvalidRequest, Message: err.Error()})
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return false, s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{"openClose": true, "change": 0, "save": true},
+			},
+			"serverInfo": map[string]string{"name": "darna"},
+		}, nil)
+	case "initialized", "textDocument/didSave":
+		return false, s.poll(ctx, true)
+	case "shutdown":
+		s.shutdown = true
+
+		return false, s.reply(msg.ID, nil, nil)
+	case "exit":
+		return true, nil
+	}
+
+	if len(msg.ID) == 0 {
+		return false, nil // Notifications the server has no use for.
+	}
+
+	return false, s.reply(msg.ID, nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + msg.Method})
+}
+
+// poll validates again and publishes the diagnostics when files changed since the last
+// poll, or regardless of changes when forced.
+func (s *lspServer) poll(ctx context.Context, force bool) error {
+	if s.shutdown {
+		return nil
+	}
+
+	files, err := snapshotFiles(ctx, s.workDir)
+	if err != nil {
+		return nil //nolint:nilerr // E.g. index.lock held by a concurrent git command; retried next poll.
+	}
+
+	changed := changedFiles(s.files, files)
+	if s.files != nil && len(changed) == 0 && !force {
+		return nil
+	}
+
+	s.files = files
+
+	violations, analysis, err := validator.ValidateIncremental(ctx, s.workDir, changed, s.analysis, s.opts...)
+
+	// A failed validation starts afresh next time, keeping the diagnostics published.
+	s.analysis = analysis
+	if err != nil {
+		return s.notify("window/logMessage", map[string]any{"type": rpcMessageError, "message": "darna: " + err.Error()})
+	}
+
+	return s.publish(violations)
+}
+
+// publish sends the diagnostics of each file with violations, and empty ones for the
+// files that had diagnostics and have none now.
+func (s *lspServer) publish(violations []validator.Violation) error {
+	diagnostics := make(map[string][]lspDiagnostic)
+
+	for _, vv := range violations {
+		uri := s.fileURI(vv.StagedFile)
+		diagnostics[uri] = append(diagnostics[uri], s.diagnostic(vv))
+	}
+
+	for uri := range s.published {
+		if _, ok := diagnostics[uri]; !ok {
+			diagnostics[uri] = []lspDiagnostic{}
+		}
+	}
+
+	for uri, list := range diagnostics {
+		err := s.notify("textDocument/publishDiagnostics", lspPublishDiagnostics{URI: uri, Diagnostics: list})
+		if err != nil {
+			return err
+		}
+
+		if len(list) == 0 {
+			delete(s.published, uri)
+		} else {
+			s.published[uri] = true
+		}
+	}
+
+	return nil
+}
+
+// diagnostic returns the diagnostic of vv at its usage, relating the definition of the
+// missing symbol when its position is known.
+func (s *lspServer) diagnostic(vv validator.Violation) lspDiagnostic {
+	d := lspDiagnostic{
+		Range:              lspPoint(vv.Line, vv.Column),
+		Severity:           lspSeverityError,
+		Code:               sarifRuleID,
+		Source:             "darna",
+		Message:            violationMessage(vv),
+		RelatedInformation: nil,
+	}
+
+	if vv.MissingLine > 0 {
+		d.RelatedInformation = []lspRelatedInformation{{
+			Location: lspLocation{
+				URI:   s.fileURI(vv.MissingFile),
+				Range: lspPoint(vv.MissingLine, vv.MissingColumn),
+			},
+			Message: vv.MissingSymbol + " has unstaged changes",
+		}}
+	}
+
+	return d
+}
+
+// lspPoint returns an empty range at the 1-based line and column, or at the start of
+// the file when the line is unknown. Columns count bytes, not the UTF-16 units of LSP,
+// which only differ on lines with non-ASCII text before the usage.
+func lspPoint(line, column int) lspRange {
+	pos := lspPosition{Line: max(line-1, 0), Character: max(column-1, 0)}
+
+	return lspRange{Start: pos, End: pos}
+}
+
+// fileURI returns the file URI of a path relative to the work dir.
+func (s *lspServer) fileURI(file string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(s.workDir, file))} //nolint:exhaustruct // Path only.
+
+	return u.String()
+}
+
+func (s *lspServer) reply(id json.RawMessage, result any, rpcErr *rpcError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	if rpcErr != nil {
+		return writeLSPMessage(s.w, rpcErrorResponse{JSONRPC: "2.0", ID: id, Error: *rpcErr})
+	}
+
+	return writeLSPMessage(s.w, rpcResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *lspServer) notify(method string, params any) error {
+	return writeLSPMessage(s.w, rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// readLSPMessage reads the body of the next message, framed by a Content-Length header.
+func readLSPMessage(br *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, errLSPHeader
+	}
+
+	body := make([]byte, n)
+
+	_, err = io.ReadFull(br, body)
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+
+	return body, nil
+}
+
+// writeLSPMessage writes v as a message framed by a Content-Length header.
+func writeLSPMessage(w io.Writer, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	_, err = io.WriteString(w, "Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+string(body))
+	if err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return nil
+}
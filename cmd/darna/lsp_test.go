@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLSPServer(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/lsp\n\ngo 1.24\n")
+	runGit(t, dir, "add", "go.mod")
+	runGit(t, dir, "commit", "-m", "module")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 1 }\n")
+	runGit(t, dir, "add", "a.go")
+
+	var in, out bytes.Buffer
+
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":2}}`,
+	} {
+		err := writeLSPMessage(&in, json.RawMessage(msg))
+		if err != nil {
+			t.Fatalf("writeLSPMessage: %v", err)
+		}
+	}
+
+	s := &lspServer{
+		w: &out, workDir: dir, opts: nil, analysis: nil, files: nil, published: make(map[string]bool), shutdown: false,
+	}
+
+	err := s.serve(t.Context(), &in, time.Hour)
+	if err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	got := readLSPOutput(t, &out)
+	if len(got) != 3 {
+		t.Fatalf("Got %d messages, want 3: %s", len(got), got)
+	}
+
+	if !strings.Contains(got[0], `"id":1`) || !strings.Contains(got[0], `"capabilities"`) {
+		t.Errorf("Initialize reply = %s", got[0])
+	}
+
+	var published struct {
+		Params lspPublishDiagnostics `json:"params"`
+	}
+
+	err = json.Unmarshal([]byte(got[1]), &published)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	diags := published.Params.Diagnostics
+	if !strings.HasSuffix(published.Params.URI, "/a.go") || len(diags) != 1 {
+		t.Fatalf("Published %+v, want a diagnostic on a.go", published.Params)
+	}
+
+	if diags[0].Range.Start != (lspPosition{Line: 2, Character: 22}) || !strings.Contains(diags[0].Message, "c.go") {
+		t.Errorf("Diagnostic = %+v, want one at the call of C naming c.go", diags[0])
+	}
+
+	if !strings.Contains(got[2], `"id":2`) || !strings.Contains(got[2], `-32601`) {
+		t.Errorf("Hover reply = %s, want method not found", got[2])
+	}
+
+	runGit(t, dir, "add", "c.go")
+
+	err = s.poll(t.Context(), false)
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	got = readLSPOutput(t, &out)
+	if len(got) != 1 || !strings.Contains(got[0], `"diagnostics":[]`) || !strings.Contains(got[0], "/a.go") {
+		t.Errorf("Poll after staging c.go sent %s, want the diagnostics of a.go cleared", got)
+	}
+}
+
+// readLSPOutput returns the bodies of the messages in out, draining it.
+func readLSPOutput(t *testing.T, out *bytes.Buffer) []string {
+	t.Helper()
+
+	br := bufio.NewReader(out)
+
+	var bodies []string
+
+	for br.Buffered() > 0 || out.Len() > 0 {
+		body, err := readLSPMessage(br)
+		if err != nil {
+			t.Fatalf("readLSPMessage: %v", err)
+		}
+
+		bodies = append(bodies, string(body))
+	}
+
+	return bodies
+}
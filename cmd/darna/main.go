@@ -2,94 +2,731 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"sort"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"dario.cat/darna/internal/agent"
+	"dario.cat/darna/internal/config"
 	"dario.cat/darna/internal/git"
 	"dario.cat/darna/internal/validator"
 )
 
+// subcommands maps subcommand names to their implementations.
+var subcommands = map[string]func(ctx context.Context, w io.Writer, args []string) error{
+	"apicheck":     runAPICheck,
+	"apply":        runApply,
+	"audit":        runAudit,
+	"check":        runCheck,
+	"commit":       runCommit,
+	"daemon":       runDaemon,
+	"deps":         runDeps,
+	"github-app":   runGitHubApp,
+	"graph":        runGraph,
+	"hooks":        runHooks,
+	"hunks":        runHunks,
+	"impact":       runImpact,
+	"lint-msg":     runLintMsg,
+	"lsp":          runLSP,
+	"mcp":          runMCP,
+	"plan":         runPlan,
+	"rdeps":        runRdeps,
+	"semver":       runSemver,
+	"test":         runTest,
+	"tui":          runTUI,
+	"validate-ref": runValidateRef,
+	"verify":       runVerify,
+	"watch":        runWatch,
+}
+
+// Output formats accepted by --format.
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatSARIF    = "sarif"
+	formatGitHub   = "github"
+	formatRDJSON   = "rdjson"
+	formatStatus   = "status"
+	formatQuickfix = "quickfix"
+
+	// formatPorcelain is version 1 of the porcelain output, which formatPorcelainV1 names
+	// explicitly; see porcelainVersion.
+	formatPorcelain   = "porcelain"
+	formatPorcelainV1 = "porcelain=v1"
+)
+
+// errNotAtomic is returned by subcommands that found violations; it maps to exit code 1
+// without an extra error message since the violations were already printed.
+var errNotAtomic = errors.New("not atomic")
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			err := run(context.Background(), os.Stdout, os.Args[2:])
+			if err != nil && !errors.Is(err, errNotAtomic) {
+				writeString(os.Stderr, "Error: "+err.Error()+"\n")
+			}
+
+			os.Exit(subcommandExitCode(err))
+		}
+	}
+
 	verbose := flag.Bool("v", false, "show detailed analysis")
-	workDir := flag.String("dir", ".", "working directory (default: current directory)")
+	quiet := flag.Bool("quiet", false, "print nothing, report only through the exit code")
+	workDir := flag.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
 	committable := flag.Bool("committable", false, "output files that can be committed atomically")
 	selectFlag := flag.Bool("select", false, "alias for --committable")
 	dependants := flag.Bool("dependants", false, "include direct dependants when using --committable")
-	commitMsg := flag.String("commit-msg", "", "generate commit message using agent (claude, codex, mistral, opencode)")
+	order := flag.Bool("order", false,
+		"with --committable, print the whole changeset in commit order, one group of independent files per line")
+	all := flag.Bool("all", false, "with --committable, list every independent file, one per line")
+	maxFiles := flag.Int("max-files", 0, "max files in the --committable set (0 = unlimited)")
+	withTests := flag.Bool("with-tests", false, "include the test files of the --committable set")
+	cluster := flag.Bool("cluster", false, "grow the --committable set with cohesive files (shared symbols, same package)")
+	commitMsg := flag.String("commit-msg", "",
+		"generate commit message using agent (claude, codex, mistral, opencode, or default from config)")
 	promptFile := flag.String("prompt-file", "", "custom prompt file for --commit-msg")
+	agentDryRun := flag.Bool("agent-dry-run", false,
+		"print the prompt and diff --commit-msg would send instead of running the agent")
+	commitMsgFiles := flag.String("commit-msg-files", "", "comma-separated files to limit the --commit-msg diff to")
+	decouple := flag.String("decouple", "", "comma-separated functions whose calls do not create dependencies")
+	implements := flag.Bool("implements", false,
+		"link types and the interfaces they implement, so that changing either needs the other staged")
+	ignoreMain := flag.Bool("ignore-main", false, "exempt files of package main from atomicity checks")
+	exclude := flag.String("exclude", "", "comma-separated globs of files exempt from atomicity checks")
+	include := flag.String("include", "", "comma-separated globs of the staged files to validate (default: all)")
+	tags := flag.String("tags", "", "comma-separated build tags to load packages with, as go build -tags")
+	platforms := flag.String("platforms", "",
+		"comma-separated GOOS/GOARCH combinations to analyze, reporting the violations of any (default: host)")
+	skipGenerated := flag.Bool("skip-generated", false,
+		"exempt generated files (\"// Code generated ... DO NOT EDIT.\") from atomicity checks")
+	pairs := flag.String("pairs", "",
+		"comma-separated rules of files committed together, such as \"*.proto -> *.pb.go\"")
+	testPolicy := flag.String("test-policy", string(validator.TestPolicyStrict),
+		"how _test.go files are validated: strict, or lenient to never block production code on tests")
+	failOn := flag.String("fail-on", failOnAll,
+		"violations failing the run: all, or direct to report transitive ones as warnings")
+	format := flag.String("format", formatText,
+		"output format for violations (text, json, sarif, github, rdjson, status, quickfix, porcelain)")
+	webhook := flag.String("webhook", "", "POST the JSON violation report to this URL")
+	fix := flag.Bool("fix", false, "stage the missing files needed to make the commit atomic")
+	fixStrategyName := flag.String("fix-strategy", "", "how --fix resolves violations: stage (default) or unstage")
+	color := flag.String("color", colorAuto, "colorize output: auto, always, never")
+	noColor := flag.Bool("no-color", false, "disable colors (same as --color never)")
+	themeName := flag.String("theme", "default", "output theme when colorized: default, color-blind, mono")
+	maxDepth := flag.Int("max-depth", 0,
+		"max dependency edges followed from staged symbols (0 = unlimited, 1 = direct uses only)")
+	directOnly := flag.Bool("direct-only", false, "only check first-degree dependencies, same as --max-depth 1")
+	fromIndex := flag.Bool("from-index", false,
+		"analyze a snapshot of the index built from git blobs instead of the checkout")
+	buildCheck := flag.Bool("build-check", false,
+		"also type-check HEAD plus the staged content alone, proving the commit compiles on its own")
+	deadCode := flag.Bool("dead-code", false,
+		"also report symbols whose last use the staged changes remove, as advisories that never fail the run")
+	unusedExports := flag.Bool("unused-exports", false,
+		"also report new exported symbols that no staged or committed code uses, as advisories")
+	changedSymbols := flag.Bool("changed-symbols", false,
+		"only report uses of symbols the unstaged diff changes, not of any symbol of a modified file")
+	untrackedDepth := flag.Int("untracked-depth", 0, "max directory depth scanned in untracked trees (0 = unlimited)")
+	noDaemon := flag.Bool("no-daemon", false, "never ask a running darna daemon, always analyze in-process")
+	noCache := flag.Bool("no-cache", false, "analyze every package, neither reading nor writing the analysis cache")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile of the run to this file")
+	memProfile := flag.String("memprofile", "", "write a heap profile at the end of the run to this file")
+	traceFile := flag.String("trace", "", "write an execution trace of the run to this file")
+	timeout, timeoutPolicy := deadlineFlags(flag.CommandLine)
+	noProgress := flag.Bool("no-progress", false, "hide the analysis progress shown when stderr is a terminal")
 
 	flag.Parse()
 
-	ctx := context.Background()
+	stopProfiling, err := startProfiling(profileConfig{cpu: *cpuProfile, mem: *memProfile, trace: *traceFile})
+	if err != nil {
+		writeString(os.Stderr, "Error: "+err.Error()+"\n")
+		os.Exit(exitError)
+	}
+
+	progress := newProgressLine(os.Stderr)
+
+	dl, err := newDeadline(*timeout, *timeoutPolicy)
+	if err != nil {
+		writeString(os.Stderr, "Error: "+err.Error()+"\n")
+		os.Exit(exitError)
+	}
+
+	ctx, cancel := dl.context(context.Background())
+
+	// exit writes the profiles before exiting: deferred calls do not run on os.Exit.
+	// Profiles are a diagnostic aid, so failing to write them does not change the exit code.
+	exit := func(code int) {
+		cancel()
+		progress.clear()
+
+		if profErr := stopProfiling(); profErr != nil {
+			writeString(os.Stderr, "Warning: "+profErr.Error()+"\n")
+		}
+
+		os.Exit(code)
+	}
+
+	conf, explicit, err := applyValidateConfig(ctx, flag.CommandLine, os.Getenv)
+	if err != nil {
+		writeString(os.Stderr, "Error: "+err.Error()+"\n")
+		exit(exitError)
+	}
 
 	// Handle commit message generation mode.
 	if *commitMsg != "" {
-		msg, err := generateCommitMsg(ctx, *commitMsg, *promptFile, *workDir)
+		generate := generateCommitMsg
+		if *agentDryRun {
+			generate = dryRunCommitMsg
+		}
+
+		agentType, err := resolveAgent(*commitMsg, conf)
+		if err != nil {
+			writeString(os.Stderr, "Error: "+err.Error()+"\n")
+			exit(exitError)
+		}
+
+		msg, err := generate(ctx, agentType, *promptFile, *workDir, splitList(*commitMsgFiles))
 		if err != nil {
 			writeString(os.Stderr, "Error: "+err.Error()+"\n")
-			os.Exit(1)
+			exit(exitError)
 		}
 
 		writeString(os.Stdout, msg+"\n")
-		os.Exit(0)
+		exit(0)
 	}
 
-	if *promptFile != "" {
+	if explicit["prompt-file"] {
 		writeString(os.Stderr, "Error: --prompt-file can only be used with --commit-msg\n")
-		os.Exit(1)
+		exit(exitError)
+	}
+
+	if *commitMsgFiles != "" {
+		writeString(os.Stderr, "Error: --commit-msg-files can only be used with --commit-msg\n")
+		exit(exitError)
+	}
+
+	if *agentDryRun {
+		writeString(os.Stderr, "Error: --agent-dry-run can only be used with --commit-msg\n")
+		exit(exitError)
+	}
+
+	if *untrackedDepth < 0 {
+		writeString(os.Stderr, "Error: --untracked-depth must not be negative\n")
+		exit(exitError)
+	}
+
+	if *maxFiles < 0 {
+		writeString(os.Stderr, "Error: --max-files must not be negative\n")
+		exit(exitError)
+	}
+
+	if *maxDepth < 0 {
+		writeString(os.Stderr, "Error: --max-depth must not be negative\n")
+		exit(exitError)
+	}
+
+	depth, depthErr := dependencyDepth(*maxDepth, *directOnly)
+	if depthErr != nil {
+		writeString(os.Stderr, "Error: "+depthErr.Error()+"\n")
+		exit(exitError)
+	}
+
+	*maxDepth = depth
+
+	if !slices.Contains([]string{
+		formatText, formatJSON, formatSARIF, formatGitHub, formatRDJSON, formatStatus, formatQuickfix,
+		formatPorcelain, formatPorcelainV1,
+	}, *format) {
+		writeString(os.Stderr, "Error: unknown --format "+*format+"\n")
+		exit(exitError)
+	}
+
+	if *format == formatPorcelainV1 {
+		*format = formatPorcelain
+	}
+
+	if !slices.Contains([]validator.TestPolicy{validator.TestPolicyStrict, validator.TestPolicyLenient},
+		validator.TestPolicy(*testPolicy)) {
+		writeString(os.Stderr, "Error: unknown --test-policy "+*testPolicy+" (want strict or lenient)\n")
+		exit(exitError)
+	}
+
+	pairRules, err := parsePairRules(splitList(*pairs))
+	if err != nil {
+		writeString(os.Stderr, "Error: "+err.Error()+"\n")
+		exit(exitError)
+	}
+
+	targets, err := parsePlatforms(splitList(*platforms))
+	if err != nil {
+		writeString(os.Stderr, "Error: "+err.Error()+"\n")
+		exit(exitError)
+	}
+
+	if !slices.Contains([]string{failOnAll, failOnDirect}, *failOn) {
+		writeString(os.Stderr, "Error: unknown --fail-on "+*failOn+" (want all or direct)\n")
+		exit(exitError)
+	}
+
+	if *fixStrategyName != "" && !*fix {
+		writeString(os.Stderr, "Error: --fix-strategy can only be used with --fix\n")
+		exit(exitError)
+	}
+
+	strategy, ok := fixStrategies[cmp.Or(*fixStrategyName, "stage")]
+	if !ok {
+		writeString(os.Stderr, "Error: "+errUnknownFixStrategy.Error()+"\n")
+		exit(exitError)
+	}
+
+	if *noColor {
+		*color = colorNever
+	}
+
+	th, err := selectTheme(*themeName, *color, os.Stdout)
+	if err != nil {
+		writeString(os.Stderr, "Error: "+err.Error()+"\n")
+		exit(exitError)
+	}
+
+	opts := []validator.Option{validator.WithUntrackedDepth(*untrackedDepth), validator.WithMaxDepth(*maxDepth)}
+	if *ignoreMain {
+		opts = append(opts, validator.WithIgnoreMain())
+	}
+
+	if names := splitList(*decouple); len(names) > 0 {
+		opts = append(opts, validator.WithDecouplingFuncs(names...))
+	}
+
+	if *implements {
+		opts = append(opts, validator.WithImplementations())
+	}
+
+	if globs := splitList(*exclude); len(globs) > 0 {
+		opts = append(opts, validator.WithExclude(globs...))
+	}
+
+	if globs := splitList(*include); len(globs) > 0 {
+		opts = append(opts, validator.WithInclude(globs...))
+	}
+
+	if len(pairRules) > 0 {
+		opts = append(opts, validator.WithPairedFiles(pairRules...))
+	}
+
+	if names := splitList(*tags); len(names) > 0 {
+		opts = append(opts, validator.WithBuildTags(names...))
+	}
+
+	if len(targets) > 0 {
+		opts = append(opts, validator.WithPlatforms(targets...))
+	}
+
+	// Files given as arguments, as the pre-commit framework or lint-staged pass them,
+	// narrow validation.
+	if flag.NArg() > 0 {
+		opts = append(opts, validator.WithFiles(relativeFiles(*workDir, flag.Args())...))
+	}
+
+	if *skipGenerated {
+		opts = append(opts, validator.WithSkipGenerated())
+	}
+
+	if *changedSymbols {
+		opts = append(opts, validator.WithChangedSymbolsOnly())
+	}
+
+	if *fromIndex {
+		opts = append(opts, validator.WithIndexSnapshot())
+	}
+
+	opts = append(opts, validator.WithTestPolicy(validator.TestPolicy(*testPolicy)))
+
+	if *cluster {
+		opts = append(opts, validator.WithClustering())
+	}
+
+	if *withTests {
+		opts = append(opts, validator.WithTests())
+	}
+
+	if *maxFiles > 0 {
+		opts = append(opts, validator.WithMaxFiles(*maxFiles))
+	}
+
+	if !*noCache {
+		opts = append(opts, cacheOptions()...)
+	}
+
+	if !*noProgress && !*quiet && isTerminal(os.Stderr) {
+		opts = append(opts, validator.WithProgress(progress.report))
+	}
+
+	if *all && (!(*committable || *selectFlag) || *dependants || *cluster) {
+		writeString(os.Stderr, "Error: --all can only be used with --committable, without --dependants or --cluster\n")
+		exit(exitError)
+	}
+
+	if *order && (!(*committable || *selectFlag) || *all || *dependants || *cluster) {
+		writeString(os.Stderr, "Error: --order can only be used with --committable, without other selection flags\n")
+		exit(exitError)
 	}
 
 	// Handle committable mode.
+	if *order {
+		err := printCommitOrder(ctx, os.Stdout, *workDir, *format, opts)
+		if err != nil {
+			writeString(os.Stderr, "Error: "+err.Error()+"\n")
+			exit(exitError)
+		}
+
+		exit(0)
+	}
+
+	if *all {
+		files, err := validator.FindIndependentFiles(ctx, *workDir, opts...)
+		if err == nil {
+			err = printFileList(os.Stdout, files, *format)
+		}
+
+		if err != nil {
+			writeString(os.Stderr, "Error: "+err.Error()+"\n")
+			exit(exitError)
+		}
+
+		exit(0)
+	}
+
+	// A daemon analyzes with the options it was started with, so it only stands in for
+	// runs that change nothing about the analysis.
+	useDaemon := !*noDaemon && onlyDaemonFlags(flag.CommandLine)
+
 	if *committable || *selectFlag {
-		files, err := validator.FindCommittableSet(ctx, *workDir, *dependants)
+		files, ok := []string(nil), false
+		if useDaemon {
+			files, ok = daemonCommittable(ctx, *workDir, *dependants)
+		}
+
+		if !ok {
+			files, err = validator.FindCommittableSet(ctx, *workDir, *dependants, opts...)
+		}
+
 		if err != nil {
 			writeString(os.Stderr, "Error: "+err.Error()+"\n")
-			os.Exit(1)
+			exit(exitError)
 		}
 
-		if len(files) > 0 {
+		switch {
+		case *format == formatPorcelain:
+			writePorcelainFiles(os.Stdout, files)
+		case len(files) > 0:
 			writeString(os.Stdout, strings.Join(files, " ")+"\n")
 		}
 
-		os.Exit(0)
+		exit(0)
+	}
+
+	exit(runValidate(ctx, os.Stdout, os.Stderr, validateConfig{
+		workDir:       *workDir,
+		verbose:       *verbose,
+		quiet:         *quiet,
+		format:        *format,
+		webhook:       *webhook,
+		fix:           *fix,
+		fixWith:       strategy,
+		theme:         th,
+		severity:      conf.Severity,
+		failOn:        *failOn,
+		build:         *buildCheck,
+		deadCode:      *deadCode,
+		unusedExports: *unusedExports,
+		daemon:        useDaemon,
+		deadline:      dl,
+		opts:          opts,
+	}))
+}
+
+// Exit codes of a validation run.
+const (
+	exitAtomic     = 0
+	exitViolations = 1
+	exitError      = 2
+)
+
+// subcommandExitCode maps the error a subcommand returned to the exit code of the run.
+// Findings, such as violations or a broken API, exit with exitViolations; any other
+// error, such as a bad flag or usage, exits with exitError.
+func subcommandExitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitAtomic
+	case errors.Is(err, errNotAtomic), errors.Is(err, errAPIBroken), errors.Is(err, errInvalidMessage),
+		errors.Is(err, errTestsFailed), errors.Is(err, errVerifyFailed):
+		return exitViolations
+	default:
+		return exitError
+	}
+}
+
+// Levels violations are reported at.
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+)
+
+// violationLevel returns the level of vv under failOn: transitive violations are only
+// warnings with failOnDirect, as they do not fail the run.
+func violationLevel(vv validator.Violation, failOn string) string {
+	if failOn == failOnDirect && !vv.Direct {
+		return levelWarning
+	}
+
+	return levelError
+}
+
+// Violations failing the run, accepted by --fail-on.
+const (
+	failOnAll    = "all"
+	failOnDirect = "direct"
+)
+
+// validateConfig holds the settings of a plain validation run.
+type validateConfig struct {
+	workDir       string
+	verbose       bool
+	quiet         bool        // Print nothing; the exit code is the only result.
+	format        string      // Output format: text, json, sarif, github, rdjson, status, quickfix or porcelain.
+	webhook       string      // URL the JSON report is POSTed to, if set.
+	fix           bool        // Resolve violations with fixWith instead of only reporting them.
+	fixWith       fixStrategy // How fix resolves violations.
+	theme         theme       // Decorations of text output.
+	severity      string      // With config.SeverityWarning, violations do not fail the run.
+	failOn        string      // With failOnDirect, only direct violations fail the run.
+	build         bool        // Also type-check the staged snapshot, failing the run if it does not build.
+	deadCode      bool        // Also report the symbols the staged changes leave unused, as advisories.
+	unusedExports bool        // Also report the new exported symbols nothing staged or committed uses.
+	daemon        bool        // Ask a running darna daemon before analyzing in-process.
+	deadline      deadline    // How long the run may take, and what happens past it.
+	opts          []validator.Option
+}
+
+// runValidate validates the staged changes and returns the process exit code:
+// exitAtomic, exitViolations or exitError. Webhook delivery failures are only warned
+// about and never change the exit code.
+func runValidate(ctx context.Context, stdout, stderr io.Writer, cfg validateConfig) int {
+	if cfg.quiet {
+		stdout, stderr = io.Discard, io.Discard
+	}
+
+	violations, fromDaemon := []validator.Violation(nil), false
+	if cfg.daemon {
+		violations, fromDaemon = daemonValidate(ctx, cfg.workDir)
+	}
+
+	var err error
+
+	if !fromDaemon {
+		violations, err = validator.ValidateAtomicCommit(ctx, cfg.workDir, cfg.opts...)
+	}
+
+	if err != nil {
+		return cfg.fail(ctx, stderr, err)
+	}
+
+	if cfg.fix && len(violations) > 0 {
+		var fixed []string
+
+		fixed, violations, err = fixViolations(ctx, cfg.workDir, violations, cfg.fixWith, cfg.opts)
+		if len(fixed) > 0 {
+			printFixSummary(stderr, fixed, cfg.fixWith, cfg.theme)
+		}
+
+		if err != nil {
+			return cfg.fail(ctx, stderr, err)
+		}
+	}
+
+	report := newViolationReport(violations)
+
+	if cfg.webhook != "" {
+		whErr := postWebhook(ctx, cfg.webhook, newWebhookPayload(ctx, cfg.workDir, report))
+		if whErr != nil {
+			writeString(stderr, "Warning: "+whErr.Error()+"\n")
+		}
+	}
+
+	switch {
+	case cfg.quiet:
+		// The exit code is the only output.
+	case cfg.format == formatJSON:
+		err = writeJSON(stdout, report)
+	case cfg.format == formatSARIF:
+		err = writeSARIF(stdout, violations, cfg.failOn)
+	case cfg.format == formatGitHub:
+		writeGitHub(stdout, violations, cfg.failOn)
+	case cfg.format == formatRDJSON:
+		err = writeRDJSON(stdout, violations, cfg.failOn)
+	case cfg.format == formatQuickfix:
+		writeQuickfix(stdout, violations)
+	case cfg.format == formatPorcelain:
+		writePorcelain(stdout, violations)
+	case cfg.format == formatStatus:
+		var files []string
+
+		files, err = validator.ValidatedFiles(ctx, cfg.workDir, cfg.opts...)
+		if err == nil {
+			writeStatus(stdout, files, violations)
+		}
+	case len(violations) > 0:
+		// Without the closure, the missing files of the violations are still a useful hint.
+		// The daemon answers without one, and computing it would load everything again.
+		var closure []string
+
+		if !fromDaemon {
+			var closureErr error
+
+			closure, closureErr = validator.FixClosure(ctx, cfg.workDir, cfg.opts...)
+			if closureErr != nil {
+				writeString(stderr, "Warning: "+closureErr.Error()+"\n")
+			}
+		}
+
+		limit := pairSymbolLimit
+		if cfg.verbose {
+			limit = 0
+		}
+
+		printViolations(stdout, violations, closure, cfg.theme, limit, cfg.failOn)
+	case cfg.verbose:
+		writeString(stdout, cfg.theme.success("Commit is atomic")+"\n")
 	}
 
-	// Run validation.
-	violations, err := validator.ValidateAtomicCommit(ctx, *workDir)
 	if err != nil {
-		writeString(os.Stderr, "Error: "+err.Error()+"\n")
-		os.Exit(1)
+		return cfg.fail(ctx, stderr, err)
+	}
+
+	failing := slices.ContainsFunc(violations, func(vv validator.Violation) bool {
+		return violationLevel(vv, cfg.failOn) == levelError
+	})
+
+	if cfg.build {
+		buildErrs, err := validator.CheckStagedBuild(ctx, cfg.workDir, cfg.opts...)
+		if err != nil {
+			return cfg.fail(ctx, stderr, err)
+		}
+
+		// Build errors go to stderr so that machine-readable reports stay valid.
+		if len(buildErrs) > 0 {
+			writeString(stderr, cfg.theme.failure("Staged snapshot does not build:")+"\n")
+
+			for _, be := range buildErrs {
+				writeString(stderr, "  "+be.String()+"\n")
+			}
+
+			failing = true
+		}
 	}
 
-	if len(violations) > 0 {
-		printViolations(os.Stdout, violations)
-		os.Exit(1)
+	if cfg.deadCode {
+		advisories, err := validator.DeadCode(ctx, cfg.workDir, cfg.opts...)
+		if err != nil {
+			return cfg.fail(ctx, stderr, err)
+		}
+
+		printAdvisories(stderr, advisories)
+	}
+
+	if cfg.unusedExports {
+		advisories, err := validator.UnusedExports(ctx, cfg.workDir, cfg.opts...)
+		if err != nil {
+			return cfg.fail(ctx, stderr, err)
+		}
+
+		printAdvisories(stderr, advisories)
 	}
 
-	if *verbose {
-		writeString(os.Stdout, "Commit is atomic\n")
+	if failing && cfg.severity != config.SeverityWarning {
+		return exitViolations
 	}
 
-	os.Exit(0)
+	return exitAtomic
+}
+
+// printAdvisories prints one "Advisory:" line per advisory. They go to stderr, like
+// build errors, so that machine-readable reports stay valid.
+func printAdvisories(stderr io.Writer, advisories []validator.Advisory) {
+	for _, a := range advisories {
+		writeString(stderr, "Advisory: "+a.String()+"\n")
+	}
+}
+
+// fail reports err, which a run under ctx failed with, and returns the exit code of
+// the run: exitError, or exitAtomic when cut short by a deadline failing open.
+func (cfg validateConfig) fail(ctx context.Context, stderr io.Writer, err error) int {
+	err = cfg.deadline.failure(ctx, stderr, err)
+	if err == nil {
+		return exitAtomic
+	}
+
+	writeString(stderr, "Error: "+err.Error()+"\n")
+
+	return exitError
+}
+
+var errDirectOnlyDepth = errors.New("--direct-only conflicts with --max-depth")
+
+// dependencyDepth returns the max dependency depth set by --max-depth and --direct-only,
+// which is --max-depth 1 and conflicts with any deeper one.
+func dependencyDepth(maxDepth int, directOnly bool) (int, error) {
+	if !directOnly {
+		return maxDepth, nil
+	}
+
+	if maxDepth > 1 {
+		return 0, fmt.Errorf("%w %d", errDirectOnlyDepth, maxDepth)
+	}
+
+	return 1, nil
 }
 
 var errNoStagedChanges = errors.New("no staged changes (stage files with git add first)")
 
 // generateCommitMsg produces a commit message from staged changes using an LLM agent.
-func generateCommitMsg(ctx context.Context, agentType, promptPath, workDir string) (string, error) {
+// When files is non-empty, only the staged diff of those files is described.
+func generateCommitMsg(ctx context.Context, agentType, promptPath, workDir string, files []string) (string, error) {
 	ag, err := agent.NewAgent(agentType)
 	if err != nil {
 		return "", fmt.Errorf("creating agent: %w", err)
 	}
 
-	diff, err := git.GetStagedDiff(ctx, workDir)
+	return generateWithAgent(ctx, ag, promptPath, workDir, files)
+}
+
+// dryRunCommitMsg returns the full prompt and staged diff the agent would receive,
+// without invoking it.
+func dryRunCommitMsg(ctx context.Context, agentType, promptPath, workDir string, files []string) (string, error) {
+	_, err := agent.NewAgent(agentType)
+	if err != nil {
+		return "", fmt.Errorf("creating agent: %w", err)
+	}
+
+	return generateWithAgent(ctx, agent.DryRunAgent{}, promptPath, workDir, files)
+}
+
+// generateWithAgent feeds the (optionally file-scoped) staged diff and prompt to ag.
+func generateWithAgent(
+	ctx context.Context, ag agent.Agent, promptPath, workDir string, files []string,
+) (string, error) {
+	diff, err := git.GetStagedDiff(ctx, workDir, files...)
 	if err != nil {
 		return "", fmt.Errorf("getting staged diff: %w", err)
 	}
@@ -117,6 +754,115 @@ func generateCommitMsg(ctx context.Context, agentType, promptPath, workDir strin
 	return msg, nil
 }
 
+// printCommitOrder prints the changeset of workDir as groups in commit order, one per
+// line, as a JSON array of arrays with formatJSON or as porcelain records. Files of a
+// group only depend on earlier groups; the files of a dependency cycle share one.
+func printCommitOrder(ctx context.Context, stdout io.Writer, workDir, format string, opts []validator.Option) error {
+	// A limit of one merges every group of mutually independent files.
+	plan, err := validator.CommitPlan(ctx, workDir, append(opts, validator.WithMaxCommits(1))...)
+	if err != nil {
+		return fmt.Errorf("ordering changeset: %w", err)
+	}
+
+	if format == formatPorcelain {
+		writePorcelainOrder(stdout, plan.Commits)
+
+		return nil
+	}
+
+	if format == formatJSON {
+		return writeJSON(stdout, append(make([][]string, 0, len(plan.Commits)), plan.Commits...))
+	}
+
+	for _, group := range plan.Commits {
+		writeString(stdout, strings.Join(group, " ")+"\n")
+	}
+
+	return nil
+}
+
+// printFileList prints files one per line, as a JSON array with formatJSON or as
+// porcelain records.
+func printFileList(w io.Writer, files []string, format string) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, append(make([]string, 0, len(files)), files...))
+	case formatPorcelain:
+		writePorcelainFiles(w, files)
+
+		return nil
+	}
+
+	for _, file := range files {
+		writeString(w, file+"\n")
+	}
+
+	return nil
+}
+
+// daemonFlags are the flags a darna daemon can serve runs with, since they do not
+// change the analysis.
+var daemonFlags = []string{
+	"v", "quiet", "dir", "format", "webhook", "color", "no-color", "theme", "fail-on", "committable", "select",
+	"dependants", "no-cache", "no-progress", "timeout", "timeout-policy",
+}
+
+// onlyDaemonFlags reports whether fs, parsed, sets daemonFlags only and no file arguments.
+func onlyDaemonFlags(fs *flag.FlagSet) bool {
+	only := fs.NArg() == 0
+
+	fs.Visit(func(f *flag.Flag) {
+		only = only && slices.Contains(daemonFlags, f.Name)
+	})
+
+	return only
+}
+
+// cacheOptions returns the option caching the analysis under the user cache dir, or
+// none if there is no such dir.
+func cacheOptions() []validator.Option {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+
+	return []validator.Option{validator.WithCacheDir(filepath.Join(dir, "darna"))}
+}
+
+// relativeFiles returns files relative to workDir, converting the absolute paths hook
+// runners such as lint-staged pass.
+func relativeFiles(workDir string, files []string) []string {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return files
+	}
+
+	result := make([]string, 0, len(files))
+
+	for _, file := range files {
+		if rel, relErr := filepath.Rel(absWorkDir, file); relErr == nil && filepath.IsAbs(file) {
+			file = rel
+		}
+
+		result = append(result, file)
+	}
+
+	return result
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
 func writeString(w io.Writer, s string) {
 	_, err := io.WriteString(w, s)
 	if err != nil {
@@ -124,41 +870,87 @@ func writeString(w io.Writer, s string) {
 	}
 }
 
-func printViolations(w io.Writer, violations []validator.Violation) {
-	writeString(w, "Commit is not atomic. Missing files need to be staged:\n\n")
+// chainSuffix describes the intermediate symbols of a transitive violation,
+// or returns an empty string when the staged symbol uses the missing one directly.
+func chainSuffix(vv validator.Violation) string {
+	if vv.Direct || len(vv.Chain) == 0 {
+		return ""
+	}
 
-	// Group violations by missing file for cleaner output.
-	byFile := groupByMissingFile(violations)
+	names := make([]string, 0, len(vv.Chain))
+	for _, id := range vv.Chain {
+		names = append(names, id[strings.LastIndex(id, "/")+1:])
+	}
 
-	// Sort files for consistent output.
-	files := make([]string, 0, len(byFile))
-	for file := range byFile {
-		files = append(files, file)
+	return " (via " + strings.Join(names, " → ") + ")"
+}
+
+// pairSymbolLimit is how many usages a file pair lists before being summarized.
+const pairSymbolLimit = 2
+
+// printViolations prints violations grouped by missing file, followed by the git add
+// commands for toStage, or for the missing files when toStage is empty. Each staged
+// file is reported once per missing file, listing at most limit usages; a limit of 0
+// lists them all, one per line. When failOn lets every violation pass, they are
+// reported as a warning.
+func printViolations(
+	w io.Writer, violations []validator.Violation, toStage []string, th theme, limit int, failOn string,
+) {
+	if slices.ContainsFunc(violations, func(vv validator.Violation) bool {
+		return violationLevel(vv, failOn) == levelError
+	}) {
+		writeString(w, th.failure("Commit is not atomic. Missing files need to be staged:")+"\n\n")
+	} else {
+		writeString(w, "Warning: only transitive dependencies are missing. They should be staged too:\n\n")
 	}
 
-	sort.Strings(files)
+	var files []string
 
-	for _, file := range files {
-		viols := byFile[file]
-		writeString(w, "  "+file+"\n")
+	for _, pair := range validator.AggregateViolations(violations) {
+		if len(files) == 0 || files[len(files)-1] != pair.MissingFile {
+			files = append(files, pair.MissingFile)
+			writeString(w, "  "+th.failure(pair.MissingFile)+"\n")
+		}
+
+		if limit == 0 || len(pair.Violations) == 1 {
+			for _, vv := range pair.Violations {
+				writeString(w, "     - "+th.staged(vv.Location())+": "+usageText(vv)+"\n")
+			}
 
-		for _, vv := range viols {
-			writeString(w, "     - "+vv.StagedSymbol+" uses "+vv.MissingSymbol+"\n")
+			continue
 		}
+
+		shown := pair.Violations[:min(limit, len(pair.Violations))]
+
+		usages := make([]string, 0, len(shown))
+		for _, vv := range shown {
+			usages = append(usages, usageText(vv))
+		}
+
+		line := "     - " + th.staged(shown[0].Location()) + ": " + strings.Join(usages, ", ")
+		if more := len(pair.Violations) - len(shown); more > 0 {
+			line += " and " + strconv.Itoa(more) + " more"
+		}
+
+		writeString(w, line+th.dim(" ("+strconv.Itoa(len(pair.Violations))+" usages, -v to list all)")+"\n")
 	}
 
-	writeString(w, "\nTo fix, run:\n")
+	writeString(w, "\n"+th.dim("To fix, run:")+"\n")
+
+	if len(toStage) > 0 {
+		files = toStage
+	}
 
 	for _, file := range files {
-		writeString(w, "   git add "+file+"\n")
+		writeString(w, th.dim("   git add "+file)+"\n")
 	}
 }
 
-func groupByMissingFile(violations []validator.Violation) map[string][]validator.Violation {
-	byFile := make(map[string][]validator.Violation)
-	for _, vv := range violations {
-		byFile[vv.MissingFile] = append(byFile[vv.MissingFile], vv)
+// usageText describes why the staged symbol of vv needs the missing file.
+func usageText(vv validator.Violation) string {
+	if vv.Reason != "" {
+		return vv.StagedSymbol + " " + vv.Reason
 	}
 
-	return byFile
+	return vv.StagedSymbol + " uses " + vv.MissingSymbol + chainSuffix(vv)
 }
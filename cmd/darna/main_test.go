@@ -0,0 +1,595 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// recordingAgent captures the diff it is asked to describe.
+type recordingAgent struct {
+	diff string
+}
+
+func (ra *recordingAgent) Generate(_ context.Context, diff, _ string) (string, error) {
+	ra.diff = diff
+
+	return "chore: recorded", nil
+}
+
+func TestGenerateWithAgentScopedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\n// A changed.\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\n// B changed.\n")
+	runGit(t, dir, "add", "a.go", "b.go")
+
+	ag := &recordingAgent{diff: ""}
+
+	msg, err := generateWithAgent(t.Context(), ag, "", dir, []string{"a.go"})
+	if err != nil {
+		t.Fatalf("generateWithAgent: %v", err)
+	}
+
+	if msg != "chore: recorded" {
+		t.Errorf("generateWithAgent() = %q, want %q", msg, "chore: recorded")
+	}
+
+	if !strings.Contains(ag.diff, "a.go") {
+		t.Errorf("Scoped diff does not contain a.go:\n%s", ag.diff)
+	}
+
+	if strings.Contains(ag.diff, "b.go") {
+		t.Errorf("Scoped diff leaked b.go:\n%s", ag.diff)
+	}
+}
+
+func TestGenerateWithAgentScopedFilesUnstaged(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\n// A changed.\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\n// B changed.\n")
+	runGit(t, dir, "add", "b.go")
+
+	ag := &recordingAgent{diff: ""}
+
+	_, err := generateWithAgent(t.Context(), ag, "", dir, []string{"a.go"})
+	if !errors.Is(err, errNoStagedChanges) {
+		t.Errorf("generateWithAgent() error = %v, want %v", err, errNoStagedChanges)
+	}
+}
+
+func TestDryRunCommitMsg(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\n// A changed.\n")
+	runGit(t, dir, "add", "a.go")
+
+	promptPath := filepath.Join(t.TempDir(), "prompt.txt")
+	writeFile(t, promptPath, "Describe this change in haiku.")
+
+	// A spawned agent would only return the first line of its answer; the dry run
+	// returns the whole prompt and diff instead.
+	out, err := dryRunCommitMsg(t.Context(), "claude", promptPath, dir, nil)
+	if err != nil {
+		t.Fatalf("dryRunCommitMsg: %v", err)
+	}
+
+	if !strings.HasPrefix(out, "Describe this change in haiku.") {
+		t.Errorf("Dry run output does not start with the prompt:\n%s", out)
+	}
+
+	if !strings.Contains(out, "+// A changed.") {
+		t.Errorf("Dry run output does not contain the staged diff:\n%s", out)
+	}
+
+	_, err = dryRunCommitMsg(t.Context(), "unknown", "", dir, nil)
+	if err == nil {
+		t.Error("Expected an error for an unknown agent")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	t.Parallel()
+
+	got := splitList(" a.go, ,b.go,")
+	if len(got) != 2 || got[0] != "a.go" || got[1] != "b.go" {
+		t.Errorf("splitList() = %v, want [a.go b.go]", got)
+	}
+
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList(\"\") = %v, want nil", got)
+	}
+}
+
+// initRepo creates a git repository with committed a.go and b.go files.
+func initRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	runGit(t, dir, "config", "commit.gpgsign", "false")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "initial")
+
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.CommandContext(t.Context(), "git", args...) //nolint:gosec // Test helper for git commands.
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+func TestPrintImpactTop(t *testing.T) {
+	t.Parallel()
+
+	report := &validator.ImpactReport{
+		File:    "utils.go",
+		Symbols: 3,
+		Files: []validator.FileImpact{
+			{File: "service.go", Symbols: 2},
+			{File: "main.go", Symbols: 1},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	printImpact(&buf, report, 1)
+
+	want := "utils.go impacts 3 symbols in 2 files\n  service.go (2)\n"
+	if buf.String() != want {
+		t.Errorf("printImpact() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintStagedImpact(t *testing.T) {
+	t.Parallel()
+
+	report := &validator.StagedImpactReport{
+		Changed:  []string{"ex.Alpha"},
+		Files:    []string{"alpha.go", "beta.go"},
+		Packages: []string{"ex"},
+		Exported: nil,
+	}
+
+	var buf bytes.Buffer
+
+	printStagedImpact(&buf, report)
+
+	want := "Staged changes touch 1 symbols and affect 2 files in 1 packages\n" +
+		"Files:\n  alpha.go\n  beta.go\nPackages:\n  ex\n"
+	if buf.String() != want {
+		t.Errorf("printStagedImpact() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintViolationsLocation(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	printViolations(&buf, []validator.Violation{{
+		StagedFile:    "a.go",
+		StagedSymbol:  "example.com/x.A",
+		StagedKind:    "",
+		MissingFile:   "b.go",
+		MissingSymbol: "example.com/x.B",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          42,
+		Column:        10,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}}, nil, plainTheme, pairSymbolLimit, failOnAll)
+
+	if want := "     - a.go:42:10: example.com/x.A uses example.com/x.B\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("printViolations() = %q, want a line %q", buf.String(), want)
+	}
+}
+
+func TestPrintViolationsChain(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	printViolations(&buf, []validator.Violation{{
+		StagedFile:    "a.go",
+		StagedSymbol:  "example.com/x.A",
+		StagedKind:    "",
+		MissingFile:   "c.go",
+		MissingSymbol: "example.com/x.C",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          3,
+		Column:        1,
+		Chain:         []string{"example.com/x.A", "example.com/x.B", "example.com/x.C"},
+		Direct:        false,
+		Fingerprint:   "",
+	}}, nil, plainTheme, pairSymbolLimit, failOnAll)
+
+	if want := "uses example.com/x.C (via x.A → x.B → x.C)\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("printViolations() = %q, want a line ending in %q", buf.String(), want)
+	}
+}
+
+func TestPrintViolationsAggregatesFilePairs(t *testing.T) {
+	t.Parallel()
+
+	violations := make([]validator.Violation, 0, 4)
+	for i, sym := range []string{"A", "B", "C", "D"} {
+		violations = append(violations, validator.Violation{
+			StagedFile:    "a.go",
+			StagedSymbol:  "example.com/x." + sym,
+			StagedKind:    "",
+			MissingFile:   "b.go",
+			MissingSymbol: "example.com/x.Z",
+			MissingKind:   "",
+			MissingLine:   0,
+			MissingColumn: 0,
+			Reason:        "",
+			Line:          i + 1,
+			Column:        1,
+			Chain:         nil,
+			Direct:        true,
+			Fingerprint:   "",
+		})
+	}
+
+	var buf bytes.Buffer
+
+	printViolations(&buf, violations, nil, plainTheme, pairSymbolLimit, failOnAll)
+
+	want := "     - a.go:1:1: example.com/x.A uses example.com/x.Z, example.com/x.B uses example.com/x.Z " +
+		"and 2 more (4 usages, -v to list all)\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("printViolations() = %q, want a line %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	printViolations(&buf, violations, nil, plainTheme, 0, failOnAll)
+
+	if got := strings.Count(buf.String(), "     - a.go:"); got != len(violations) {
+		t.Errorf("Verbose printViolations() printed %d usages, want %d:\n%s", got, len(violations), buf.String())
+	}
+}
+
+func TestRunValidateFailOnDirect(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "checkout", "b.go")
+	runGit(t, dir, "add", "a.go")
+
+	for _, tt := range []struct {
+		failOn string
+		want   int
+	}{
+		{failOn: failOnAll, want: exitViolations},
+		{failOn: failOnDirect, want: exitAtomic},
+	} {
+		var stdout, stderr bytes.Buffer
+
+		code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+			workDir: dir, verbose: false, quiet: false, format: formatJSON, webhook: "", fix: false,
+			fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: tt.failOn, build: false, daemon: false,
+			deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+		})
+		if code != tt.want {
+			t.Errorf("runValidate() with --fail-on=%s = %d, want %d; stderr:\n%s", tt.failOn, code, tt.want, stderr.String())
+		}
+
+		if !strings.Contains(stdout.String(), `"direct": false`) {
+			t.Errorf("Expected a transitive violation reported, got:\n%s", stdout.String())
+		}
+	}
+}
+
+func TestRunValidateFailOnDirectLevels(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "checkout", "b.go")
+	runGit(t, dir, "add", "a.go")
+
+	for _, tt := range []struct {
+		format string
+		failOn string
+		want   string
+		reject string
+	}{
+		{format: formatSARIF, failOn: failOnAll, want: `"level": "error"`, reject: `"level": "warning"`},
+		{format: formatSARIF, failOn: failOnDirect, want: `"level": "warning"`, reject: `"level": "error"`},
+		{format: formatGitHub, failOn: failOnAll, want: "::error ", reject: "::warning "},
+		{format: formatGitHub, failOn: failOnDirect, want: "::warning ", reject: "::error "},
+		{format: formatText, failOn: failOnAll, want: "Commit is not atomic", reject: "Warning:"},
+		{format: formatText, failOn: failOnDirect, want: "Warning:", reject: "not atomic"},
+	} {
+		var stdout, stderr bytes.Buffer
+
+		runValidate(t.Context(), &stdout, &stderr, validateConfig{
+			workDir: dir, verbose: false, quiet: false, format: tt.format, webhook: "", fix: false,
+			fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: tt.failOn, build: false, daemon: false,
+			deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+		})
+
+		if out := stdout.String(); !strings.Contains(out, tt.want) || strings.Contains(out, tt.reject) {
+			t.Errorf("-format %s --fail-on=%s printed\n%s\nwant %q and no %q", tt.format, tt.failOn, out, tt.want, tt.reject)
+		}
+	}
+}
+
+func TestRunValidateDirectOnly(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "checkout", "b.go")
+	runGit(t, dir, "add", "a.go")
+
+	for _, tt := range []struct {
+		maxDepth   int
+		directOnly bool
+		want       int
+	}{
+		{maxDepth: 0, directOnly: false, want: exitViolations},
+		{maxDepth: 2, directOnly: false, want: exitViolations},
+		{maxDepth: 0, directOnly: true, want: exitAtomic},
+		{maxDepth: 1, directOnly: true, want: exitAtomic},
+		{maxDepth: 2, directOnly: true, want: exitError},
+	} {
+		depth, err := dependencyDepth(tt.maxDepth, tt.directOnly)
+		if err != nil {
+			if tt.want != exitError || !errors.Is(err, errDirectOnlyDepth) {
+				t.Errorf("--max-depth %d --direct-only=%t: %v", tt.maxDepth, tt.directOnly, err)
+			}
+
+			continue
+		}
+
+		var stdout, stderr bytes.Buffer
+
+		code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+			workDir: dir, verbose: false, quiet: false, format: formatJSON, webhook: "", fix: false,
+			fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+			deadCode: false, unusedExports: false, deadline: deadline{},
+			opts: []validator.Option{validator.WithMaxDepth(depth)},
+		})
+		if code != tt.want {
+			t.Errorf("runValidate() with --max-depth %d --direct-only=%t = %d, want %d; stdout:\n%s",
+				tt.maxDepth, tt.directOnly, code, tt.want, stdout.String())
+		}
+	}
+}
+
+func TestSubcommandExitCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: nil, want: exitAtomic},
+		{err: errNotAtomic, want: exitViolations},
+		{err: errAPIBroken, want: exitViolations},
+		{err: fmt.Errorf("%w: a_test.go", errTestsFailed), want: exitViolations},
+		{err: errPlanUsage, want: exitError},
+		{err: fmt.Errorf("parsing plan flags: %w", flag.ErrHelp), want: exitError},
+		{err: errDirectOnlyDepth, want: exitError},
+	}
+
+	for _, tt := range tests {
+		if got := subcommandExitCode(tt.err); got != tt.want {
+			t.Errorf("subcommandExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRunValidateDeadCode(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "changes")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc main() {}\n")
+	runGit(t, dir, "add", "a.go")
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: true, unusedExports: false, deadline: deadline{}, opts: nil,
+	})
+	if code != exitAtomic {
+		t.Fatalf("runValidate() = %d, want %d; stderr:\n%s", code, exitAtomic, stderr.String())
+	}
+
+	want := "Advisory: b.go:4: example.com/fix.B is no longer used: the staged changes remove its last use, from a.go\n"
+	if stderr.String() != want {
+		t.Errorf("runValidate() printed %q to stderr, want %q", stderr.String(), want)
+	}
+}
+
+func TestRunValidateUnusedExports(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "changes")
+	writeFile(t, filepath.Join(dir, "e.go"), "package main\n\nfunc E() {}\n")
+	runGit(t, dir, "add", "e.go")
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: true, deadline: deadline{}, opts: nil,
+	})
+	if code != exitAtomic {
+		t.Fatalf("runValidate() = %d, want %d; stderr:\n%s", code, exitAtomic, stderr.String())
+	}
+
+	want := "Advisory: e.go:3: example.com/fix.E is new but unused: no staged or committed code references it\n"
+	if stderr.String() != want {
+		t.Errorf("runValidate() printed %q to stderr, want %q", stderr.String(), want)
+	}
+}
+
+func TestPrintFileList(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := printFileList(&buf, []string{"a.go", "pkg/b.go"}, formatText)
+	if err != nil || buf.String() != "a.go\npkg/b.go\n" {
+		t.Errorf("printFileList() = %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+
+	err = printFileList(&buf, nil, formatJSON)
+	if err != nil || buf.String() != "[]\n" {
+		t.Errorf("printFileList() of no files as JSON = %q, %v", buf.String(), err)
+	}
+}
+
+func TestRunValidateBuildCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/build\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc main() { B() }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() {}\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "code")
+
+	// The graph check is told to ignore c.go; only the build check sees that the
+	// staged call needs the untracked file.
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc main() { B(); C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() {}\n")
+	runGit(t, dir, "add", "a.go")
+
+	for _, build := range []bool{false, true} {
+		var stdout, stderr bytes.Buffer
+
+		code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+			workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: false,
+			fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: build, daemon: false,
+			deadline: deadline{}, opts: []validator.Option{validator.WithExclude("c.go")},
+		})
+
+		want := exitAtomic
+		if build {
+			want = exitViolations
+		}
+
+		if code != want {
+			t.Errorf("runValidate() with build check %t = %d, want %d; stderr:\n%s", build, code, want, stderr.String())
+		}
+
+		if build && !strings.Contains(stderr.String(), "a.go:3:") {
+			t.Errorf("Expected the build error of a.go, got:\n%s", stderr.String())
+		}
+	}
+}
+
+func TestRunValidateOutputIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/order\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc main() { B(); C(); D() }\n")
+	writeFile(t, filepath.Join(dir, "e.go"), "package main\n\nfunc E() { D(); B() }\n")
+
+	for _, name := range []string{"b.go", "c.go", "d.go"} {
+		symbol := strings.ToUpper(strings.TrimSuffix(name, ".go"))
+		writeFile(t, filepath.Join(dir, name), "package main\n\nfunc "+symbol+"() {}\n")
+	}
+
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "code")
+
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go", "e.go"} {
+		data, err := os.ReadFile(filepath.Join(dir, name)) //nolint:gosec // Test reads its temp repository.
+		if err != nil {
+			t.Fatalf("Reading %s: %v", name, err)
+		}
+
+		writeFile(t, filepath.Join(dir, name), strings.Replace(string(data), "\n\n", "\n\n// Changed.\n", 1))
+	}
+
+	runGit(t, dir, "add", "a.go", "e.go")
+
+	formats := []string{
+		formatText, formatJSON, formatSARIF, formatGitHub, formatRDJSON, formatQuickfix, formatPorcelain,
+	}
+
+	for _, format := range formats {
+		var first string
+
+		for run := range 3 {
+			var stdout, stderr bytes.Buffer
+
+			runValidate(t.Context(), &stdout, &stderr, validateConfig{
+				workDir: dir, verbose: true, quiet: false, format: format, webhook: "", fix: false,
+				fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false,
+				deadCode: false, unusedExports: false, daemon: false, deadline: deadline{}, opts: nil,
+			})
+
+			if run == 0 {
+				first = stdout.String()
+			} else if stdout.String() != first {
+				t.Errorf("-format %s printed\n%s\nthen\n%s", format, first, stdout.String())
+			}
+		}
+
+		if strings.Count(first, "b.go") < 2 {
+			t.Errorf("-format %s: expected violations of a.go and e.go on b.go, got:\n%s", format, first)
+		}
+	}
+}
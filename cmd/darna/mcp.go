@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"slices"
+
+	"dario.cat/darna/internal/config"
+	"dario.cat/darna/internal/validator"
+)
+
+var errMCPUsage = errors.New("usage: darna mcp [-dir <path>] [-prompt-file <path>]")
+
+// mcpProtocolVersions are the MCP revisions darna mcp speaks, latest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// Names of the tools of darna mcp.
+const (
+	mcpToolValidate    = "validate_commit"
+	mcpToolCommittable = "find_committable_set"
+	mcpToolMessage     = "generate_commit_message"
+)
+
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type mcpCallParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpArguments are the arguments of every tool, each using its own.
+type mcpArguments struct {
+	Files      []string `json:"files"`
+	Dependants bool     `json:"dependants"`
+	Agent      string   `json:"agent"`
+}
+
+// mcpValidation is the result of validate_commit.
+type mcpValidation struct {
+	Atomic     bool                  `json:"atomic"`
+	Violations []validator.Violation `json:"violations"`
+}
+
+// mcpTools describes the tools of darna mcp to clients.
+var mcpTools = []mcpTool{
+	{
+		Name: mcpToolValidate,
+		Description: "Check whether the staged changes can be committed alone: report each staged symbol " +
+			"that uses a symbol with unstaged changes, and the file to stage for it.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"files": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Only validate these staged files, relative to the repository root.",
+				},
+			},
+		},
+	},
+	{
+		Name: mcpToolCommittable,
+		Description: "List the largest set of changed files that can be committed together without depending " +
+			"on other uncommitted changes.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"dependants": map[string]any{
+					"type":        "boolean",
+					"description": "Also include the changed files depending on the set.",
+				},
+			},
+		},
+	},
+	{
+		Name:        mcpToolMessage,
+		Description: "Generate a Conventional Commits message for the staged changes with a coding agent CLI.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"agent": map[string]any{
+					"type":        "string",
+					"description": "Agent to run, such as claude or codex; defaults to the configured one.",
+				},
+				"files": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Only describe the staged changes of these files.",
+				},
+			},
+		},
+	},
+}
+
+// mcpServer answers Model Context Protocol requests about a working tree.
+type mcpServer struct {
+	w          io.Writer
+	workDir    string
+	promptFile string
+	conf       config.Config
+	opts       []validator.Option
+}
+
+// runMCP implements "darna mcp": a Model Context Protocol server on standard input and
+// output, exposing validation, committable set selection and commit message generation
+// as tools for coding agents.
+func runMCP(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	promptFile := fs.String("prompt-file", "", "custom prompt file for generate_commit_message")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing mcp flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errMCPUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	s := &mcpServer{w: w, workDir: *workDir, promptFile: *promptFile, conf: conf, opts: opts}
+
+	return s.serve(ctx, os.Stdin)
+}
+
+// serve answers the messages read from r, one JSON object per line, until r is closed.
+func (s *mcpServer) serve(ctx context.Context, r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			handleErr := s.handle(ctx, line)
+			if handleErr != nil {
+				return handleErr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading message: %w", err)
+		}
+	}
+}
+
+// handle answers a message.
+func (s *mcpServer) handle(ctx context.Context, body []byte) error {
+	var msg rpcMessage
+
+	err := json.Unmarshal(body, &msg)
+	if err != nil {
+		return s.reply(nil, nil, &rpcError{Code: rpcInvalidRequest, Message: err.Error()})
+	}
+
+	if len(msg.ID) == 0 {
+		return nil // Notifications, such as notifications/initialized, need no answer.
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+
+		_ = json.Unmarshal(msg.Params, &params)
+
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+
+		return s.reply(msg.ID, map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "darna", "version": buildVersion()},
+		}, nil)
+	case "ping":
+		return s.reply(msg.ID, map[string]any{}, nil)
+	case "tools/list":
+		return s.reply(msg.ID, map[string]any{"tools": mcpTools}, nil)
+	case "tools/call":
+		return s.call(ctx, msg)
+	default:
+		return s.reply(msg.ID, nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + msg.Method})
+	}
+}
+
+// call runs a tool. Tool failures are results flagged as errors, for the agent to read.
+func (s *mcpServer) call(ctx context.Context, msg rpcMessage) error {
+	var (
+		params mcpCallParams
+		args   mcpArguments
+	)
+
+	err := json.Unmarshal(msg.Params, &params)
+	if err == nil && len(params.Arguments) > 0 {
+		err = json.Unmarshal(params.Arguments, &args)
+	}
+
+	if err != nil {
+		return s.reply(msg.ID, nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()})
+	}
+
+	var text string
+
+	switch params.Name {
+	case mcpToolValidate:
+		text, err = s.validate(ctx, args)
+	case mcpToolCommittable:
+		text, err = s.committable(ctx, args)
+	case mcpToolMessage:
+		text, err = s.message(ctx, args)
+	default:
+		return s.reply(msg.ID, nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + params.Name})
+	}
+
+	if err != nil {
+		text = "Error: " + err.Error()
+	}
+
+	return s.reply(msg.ID, mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}, IsError: err != nil}, nil)
+}
+
+func (s *mcpServer) validate(ctx context.Context, args mcpArguments) (string, error) {
+	opts := s.opts
+	if len(args.Files) > 0 {
+		opts = append(slices.Clip(opts), validator.WithFiles(args.Files...))
+	}
+
+	violations, err := validator.ValidateAtomicCommit(ctx, s.workDir, opts...)
+	if err != nil {
+		return "", fmt.Errorf("validating: %w", err)
+	}
+
+	return mcpJSON(mcpValidation{
+		Atomic:     len(violations) == 0,
+		Violations: append(make([]validator.Violation, 0, len(violations)), violations...),
+	})
+}
+
+func (s *mcpServer) committable(ctx context.Context, args mcpArguments) (string, error) {
+	files, err := validator.FindCommittableSet(ctx, s.workDir, args.Dependants, s.opts...)
+	if err != nil {
+		return "", fmt.Errorf("finding committable files: %w", err)
+	}
+
+	return mcpJSON(map[string][]string{"files": append(make([]string, 0, len(files)), files...)})
+}
+
+func (s *mcpServer) message(ctx context.Context, args mcpArguments) (string, error) {
+	agentType := args.Agent
+	if agentType == "" {
+		agentType = agentFromConfig
+	}
+
+	agentType, err := resolveAgent(agentType, s.conf)
+	if err != nil {
+		return "", err
+	}
+
+	return generateCommitMsg(ctx, agentType, s.promptFile, s.workDir, args.Files)
+}
+
+func (s *mcpServer) reply(id json.RawMessage, result any, rpcErr *rpcError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	if rpcErr != nil {
+		return writeMCPMessage(s.w, rpcErrorResponse{JSONRPC: "2.0", ID: id, Error: *rpcErr})
+	}
+
+	return writeMCPMessage(s.w, rpcResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// buildVersion returns the module version darna was built from, "(devel)" when unknown.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "(devel)"
+}
+
+// mcpJSON returns v as indented JSON text for a tool result.
+func mcpJSON(v any) (string, error) {
+	var buf bytes.Buffer
+
+	err := writeJSON(&buf, v)
+
+	return buf.String(), err
+}
+
+// writeMCPMessage writes v as a single line.
+func writeMCPMessage(w io.Writer, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	_, err = w.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/config"
+)
+
+func TestMCPServer(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/mcp\n\ngo 1.24\n")
+	runGit(t, dir, "add", "go.mod")
+	runGit(t, dir, "commit", "-m", "module")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 1 }\n")
+	runGit(t, dir, "add", "a.go")
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"validate_commit","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"find_committable_set"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"generate_commit_message"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"push"}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+
+	var conf config.Config
+
+	s := &mcpServer{w: &out, workDir: dir, promptFile: "", conf: conf, opts: nil}
+
+	err := s.serve(t.Context(), strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	type reply struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+
+	var replies []reply
+
+	for line := range strings.Lines(out.String()) {
+		var r reply
+
+		err = json.Unmarshal([]byte(line), &r)
+		if err != nil {
+			t.Fatalf("Unmarshal %q: %v", line, err)
+		}
+
+		replies = append(replies, r)
+	}
+
+	if len(replies) != 6 {
+		t.Fatalf("Got %d replies, want 6: %s", len(replies), out.String())
+	}
+
+	if got := string(replies[0].Result); !strings.Contains(got, `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("Initialize result = %s, want the requested protocol version", got)
+	}
+
+	for _, tool := range []string{mcpToolValidate, mcpToolCommittable, mcpToolMessage} {
+		if !strings.Contains(string(replies[1].Result), `"name":"`+tool+`"`) {
+			t.Errorf("tools/list result lacks %s: %s", tool, replies[1].Result)
+		}
+	}
+
+	toolText := func(i int) (string, bool) {
+		t.Helper()
+
+		var result mcpToolResult
+
+		err := json.Unmarshal(replies[i].Result, &result)
+		if err != nil || len(result.Content) != 1 {
+			t.Fatalf("Reply %d = %s, %v, want a tool result", replies[i].ID, replies[i].Result, err)
+		}
+
+		return result.Content[0].Text, result.IsError
+	}
+
+	var validation mcpValidation
+
+	text, isError := toolText(2)
+
+	err = json.Unmarshal([]byte(text), &validation)
+	if err != nil || isError || validation.Atomic || len(validation.Violations) != 1 ||
+		validation.Violations[0].MissingFile != "c.go" {
+		t.Errorf("validate_commit = %s, want a violation on c.go", text)
+	}
+
+	if text, isError = toolText(3); isError || !strings.Contains(text, `"files"`) {
+		t.Errorf("find_committable_set = %s, want files", text)
+	}
+
+	if text, isError = toolText(4); !isError || !strings.Contains(text, "agent") {
+		t.Errorf("generate_commit_message without an agent = %s, want an error result", text)
+	}
+
+	if replies[5].Error == nil || replies[5].Error.Code != rpcInvalidParams {
+		t.Errorf("Unknown tool reply = %+v, want invalid params", replies[5])
+	}
+}
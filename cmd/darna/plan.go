@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errPlanUsage = errors.New("usage: darna plan [-dir <path>] [-max-commits <n>] [-json]")
+
+// runPlan implements "darna plan": the whole changeset split into atomic commits,
+// dependencies first.
+func runPlan(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	maxCommits := fs.Int("max-commits", 0,
+		"merge commits of the same dependency layer to plan at most n commits (0 = unlimited)")
+	asJSON := fs.Bool("json", false, "print the plan as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing plan flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *maxCommits < 0 {
+		return errPlanUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts, validator.WithMaxCommits(*maxCommits))
+
+	plan, err := validator.CommitPlan(ctx, *workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("planning commits: %w", err)
+	}
+
+	if *asJSON {
+		return writeJSON(w, plan)
+	}
+
+	printPlan(w, plan)
+
+	return nil
+}
+
+// printPlan prints one numbered line per commit.
+func printPlan(w io.Writer, plan *validator.Plan) {
+	for i, commit := range plan.Commits {
+		writeString(w, strconv.Itoa(i+1)+". "+strings.Join(commit, " ")+"\n")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestRunPlan(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+
+	var buf bytes.Buffer
+
+	err := runPlan(t.Context(), &buf, []string{"-dir", dir})
+	if err != nil {
+		t.Fatalf("runPlan: %v", err)
+	}
+
+	if want := "1. c.go\n2. d.go\n3. b.go\n4. a.go\n"; buf.String() != want {
+		t.Errorf("runPlan() printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+
+	err = runPlan(t.Context(), &buf, []string{"-dir", dir, "-max-commits", "1", "-json"})
+	if err != nil {
+		t.Fatalf("runPlan -json: %v", err)
+	}
+
+	var plan validator.Plan
+
+	err = json.Unmarshal(buf.Bytes(), &plan)
+	if err != nil {
+		t.Fatalf("Decoding %q: %v", buf.String(), err)
+	}
+
+	// The chain keeps three commits; only the independent d.go can merge.
+	want := [][]string{{"c.go", "d.go"}, {"b.go"}, {"a.go"}}
+	if !reflect.DeepEqual(plan.Commits, want) {
+		t.Errorf("Commits = %v, want %v", plan.Commits, want)
+	}
+}
+
+func TestPrintCommitOrder(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+
+	var buf bytes.Buffer
+
+	err := printCommitOrder(t.Context(), &buf, dir, formatText, nil)
+	if err != nil {
+		t.Fatalf("printCommitOrder: %v", err)
+	}
+
+	if want := "c.go d.go\nb.go\na.go\n"; buf.String() != want {
+		t.Errorf("printCommitOrder() printed %q, want %q", buf.String(), want)
+	}
+}
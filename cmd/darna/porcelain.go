@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// porcelainVersion is the version of the --format porcelain output, meant for scripts
+// and the custom commands of git clients such as lazygit and magit.
+//
+// Within a version, the output keeps these guarantees:
+//   - The first line is "version", a tab and the version number.
+//   - Every other line is a record: its type, then its fields, separated by tabs.
+//   - Record types keep the order and meaning of their fields.
+//   - New record types and new fields at the end of a record may be added, so consumers
+//     must skip the records they do not know and the fields past those they read.
+//   - Backslashes, tabs and newlines in fields are written as \\, \t and \n.
+//
+// Any other change needs a new version, selected with --format porcelain=v<n>; plain
+// "porcelain" stays version 1.
+const porcelainVersion = 1
+
+// porcelainEscaper escapes porcelain fields.
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`)
+
+// writePorcelainRecord writes a record of typ with fields.
+func writePorcelainRecord(w io.Writer, typ string, fields ...string) {
+	line := typ
+
+	for _, field := range fields {
+		line += "\t" + porcelainEscaper.Replace(field)
+	}
+
+	writeString(w, line+"\n")
+}
+
+// writePorcelain writes the version record, then a violation record per violation:
+//
+//	violation <file> <line> <column> <symbol> <missing file> <missing symbol> <direct|indirect>
+//
+// with 0 for unknown positions, and a final "result <atomic|not-atomic> <violations>".
+func writePorcelain(w io.Writer, violations []validator.Violation) {
+	writePorcelainRecord(w, "version", strconv.Itoa(porcelainVersion))
+
+	for _, vv := range violations {
+		direct := "indirect"
+		if vv.Direct {
+			direct = "direct"
+		}
+
+		writePorcelainRecord(w, "violation", vv.StagedFile, strconv.Itoa(vv.Line), strconv.Itoa(vv.Column),
+			vv.StagedSymbol, vv.MissingFile, vv.MissingSymbol, direct)
+	}
+
+	result := "atomic"
+	if len(violations) > 0 {
+		result = "not-atomic"
+	}
+
+	writePorcelainRecord(w, "result", result, strconv.Itoa(len(violations)))
+}
+
+// writePorcelainFiles writes the version record, then "file <path>" per file, for the
+// committable set and the independent files.
+func writePorcelainFiles(w io.Writer, files []string) {
+	writePorcelainRecord(w, "version", strconv.Itoa(porcelainVersion))
+
+	for _, file := range files {
+		writePorcelainRecord(w, "file", file)
+	}
+}
+
+// writePorcelainOrder writes the version record, then "file <path> <group>" per file of
+// the commit order, groups numbered from 1.
+func writePorcelainOrder(w io.Writer, groups [][]string) {
+	writePorcelainRecord(w, "version", strconv.Itoa(porcelainVersion))
+
+	for i, group := range groups {
+		for _, file := range group {
+			writePorcelainRecord(w, "file", file, strconv.Itoa(i+1))
+		}
+	}
+}
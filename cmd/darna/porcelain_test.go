@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestWritePorcelain(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	writePorcelain(&buf, []validator.Violation{{
+		StagedFile:    "dir\twith tab/a.go",
+		StagedSymbol:  "example.com/x.A",
+		StagedKind:    "",
+		MissingFile:   "b.go",
+		MissingSymbol: "example.com/x.B",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          4,
+		Column:        15,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}})
+
+	want := "version\t1\n" +
+		"violation\tdir\\twith tab/a.go\t4\t15\texample.com/x.A\tb.go\texample.com/x.B\tdirect\n" +
+		"result\tnot-atomic\t1\n"
+	if buf.String() != want {
+		t.Errorf("writePorcelain printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	writePorcelain(&buf, nil)
+
+	if want = "version\t1\nresult\tatomic\t0\n"; buf.String() != want {
+		t.Errorf("writePorcelain without violations printed %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWritePorcelainFiles(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := printFileList(&buf, []string{"a.go", `pkg\b.go`}, formatPorcelain)
+	if want := "version\t1\nfile\ta.go\nfile\tpkg\\\\b.go\n"; err != nil || buf.String() != want {
+		t.Errorf("printFileList() = %q, %v, want %q", buf.String(), err, want)
+	}
+
+	buf.Reset()
+	writePorcelainOrder(&buf, [][]string{{"a.go", "b.go"}, {"c.go"}})
+
+	want := "version\t1\nfile\ta.go\t1\nfile\tb.go\t1\nfile\tc.go\t2\n"
+	if buf.String() != want {
+		t.Errorf("writePorcelainOrder printed %q, want %q", buf.String(), want)
+	}
+}
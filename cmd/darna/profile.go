@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+)
+
+// profileConfig names the files the profiles of a run are written to, for users to
+// share when darna is slow on their repository. Profiles with no file are not collected.
+type profileConfig struct {
+	cpu   string // --cpuprofile: CPU profile, for go tool pprof.
+	mem   string // --memprofile: heap profile at the end of the run, for go tool pprof.
+	trace string // --trace: execution trace, for go tool trace.
+}
+
+// startProfiling creates the files of cfg and starts collecting their profiles. The
+// returned function stops collecting and writes them out; it must be called before exit.
+func startProfiling(cfg profileConfig) (func() error, error) {
+	var stops []func() error
+
+	stop := func() error {
+		var errs []error
+
+		for _, s := range slices.Backward(stops) {
+			errs = append(errs, s())
+		}
+
+		return errors.Join(errs...)
+	}
+
+	if cfg.cpu != "" {
+		f, err := os.Create(cfg.cpu)
+		if err != nil {
+			return nil, fmt.Errorf("creating CPU profile: %w", err)
+		}
+
+		err = pprof.StartCPUProfile(f)
+		if err != nil {
+			_ = f.Close()
+
+			return nil, fmt.Errorf("starting CPU profile: %w", err)
+		}
+
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+
+			return closeProfile(f)
+		})
+	}
+
+	if cfg.trace != "" {
+		f, err := os.Create(cfg.trace)
+		if err == nil {
+			err = trace.Start(f)
+			if err != nil {
+				_ = f.Close()
+			}
+		}
+
+		if err != nil {
+			_ = stop()
+
+			return nil, fmt.Errorf("starting trace: %w", err)
+		}
+
+		stops = append(stops, func() error {
+			trace.Stop()
+
+			return closeProfile(f)
+		})
+	}
+
+	if cfg.mem != "" {
+		f, err := os.Create(cfg.mem)
+		if err != nil {
+			_ = stop()
+
+			return nil, fmt.Errorf("creating memory profile: %w", err)
+		}
+
+		stops = append(stops, func() error {
+			runtime.GC() // Up-to-date statistics of what is still in use.
+
+			err := pprof.Lookup("heap").WriteTo(f, 0)
+			if err != nil {
+				_ = f.Close()
+
+				return fmt.Errorf("writing memory profile: %w", err)
+			}
+
+			return closeProfile(f)
+		})
+	}
+
+	return stop, nil
+}
+
+// closeProfile closes the file of a profile, which only then is complete.
+func closeProfile(f *os.File) error {
+	err := f.Close()
+	if err != nil {
+		return fmt.Errorf("closing %s: %w", f.Name(), err)
+	}
+
+	return nil
+}
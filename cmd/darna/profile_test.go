@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProfiling(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := profileConfig{
+		cpu:   filepath.Join(dir, "cpu.pprof"),
+		mem:   filepath.Join(dir, "mem.pprof"),
+		trace: filepath.Join(dir, "trace.out"),
+	}
+
+	stop, err := startProfiling(cfg)
+	if err != nil {
+		t.Fatalf("startProfiling() error = %v", err)
+	}
+
+	err = stop()
+	if err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+
+	for _, file := range []string{cfg.cpu, cfg.mem, cfg.trace} {
+		info, err := os.Stat(file)
+		if err != nil || info.Size() == 0 {
+			t.Errorf("Expected a non-empty profile at %s, got %v (err %v)", file, info, err)
+		}
+	}
+}
+
+func TestStartProfiling_BadPath(t *testing.T) {
+	t.Parallel()
+
+	_, err := startProfiling(profileConfig{cpu: "", mem: filepath.Join(t.TempDir(), "missing", "mem.pprof"), trace: ""})
+	if err == nil {
+		t.Error("Expected an error for a profile in a missing directory")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"dario.cat/darna/internal/validator"
+)
+
+const (
+	progressInterval = 100 * time.Millisecond // Minimum delay between redraws of a stage.
+	ansiEraseLine    = "\033[K"               // Erases from the cursor to the end of the line.
+)
+
+// progressLine shows validator progress on a single terminal line, redrawn in place
+// and erased at the end of each stage so that it never mixes with the output.
+type progressLine struct {
+	mu    sync.Mutex
+	w     io.Writer
+	stage validator.ProgressStage
+	drawn time.Time // Zero while nothing is shown.
+}
+
+func newProgressLine(w io.Writer) *progressLine {
+	return &progressLine{mu: sync.Mutex{}, w: w, stage: "", drawn: time.Time{}}
+}
+
+// report draws p, or erases the line once its stage is over.
+func (l *progressLine) report(p validator.Progress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case p.Stage == validator.StageLoading:
+		l.draw(p.Stage, "darna: loading packages...")
+	case p.Stage == validator.StageAnalyzing && p.Done < p.Total:
+		// Stage changes are drawn at once, updates of a stage at most every interval.
+		if l.stage != p.Stage || time.Since(l.drawn) >= progressInterval {
+			l.draw(p.Stage, fmt.Sprintf("darna: analyzing %d/%d packages", p.Done, p.Total))
+		}
+	default: // Loaded, or analyzed.
+		l.erase()
+	}
+}
+
+// clear erases the line, if shown.
+func (l *progressLine) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.erase()
+}
+
+func (l *progressLine) draw(stage validator.ProgressStage, msg string) {
+	writeString(l.w, "\r"+msg+ansiEraseLine)
+	l.stage, l.drawn = stage, time.Now()
+}
+
+func (l *progressLine) erase() {
+	if !l.drawn.IsZero() {
+		writeString(l.w, "\r"+ansiEraseLine)
+		l.stage, l.drawn = "", time.Time{}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestProgressLine(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	line := newProgressLine(&buf)
+
+	line.report(validator.Progress{Stage: validator.StageLoading, Done: 0, Total: 0})
+
+	if !strings.Contains(buf.String(), "loading packages") {
+		t.Errorf("Expected loading to be shown, got %q", buf.String())
+	}
+
+	line.report(validator.Progress{Stage: validator.StageLoaded, Done: 0, Total: 3})
+	line.report(validator.Progress{Stage: validator.StageAnalyzing, Done: 1, Total: 3})
+	line.report(validator.Progress{Stage: validator.StageAnalyzing, Done: 2, Total: 3}) // Throttled.
+
+	if !strings.Contains(buf.String(), "analyzing 1/3 packages") || strings.Contains(buf.String(), "2/3") {
+		t.Errorf("Expected only the first analyzing report to be drawn, got %q", buf.String())
+	}
+
+	line.report(validator.Progress{Stage: validator.StageAnalyzing, Done: 3, Total: 3})
+
+	if !strings.HasSuffix(buf.String(), "\r"+ansiEraseLine) {
+		t.Errorf("Expected the line to be erased once analyzed, got %q", buf.String())
+	}
+
+	buf.Reset()
+	line.clear()
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected clear to write nothing when no line is shown, got %q", buf.String())
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// writeQuickfix prints one "file:line:col: message" line per violation, which Vim's
+// default errorformat parses, so that :cexpr system('darna --format quickfix') fills
+// the quickfix list. Violations without a known position point at the first line.
+func writeQuickfix(w io.Writer, violations []validator.Violation) {
+	for _, vv := range violations {
+		loc := vv.StagedFile + ":" + strconv.Itoa(max(vv.Line, 1)) + ":"
+		if vv.Line > 0 && vv.Column > 0 {
+			loc += strconv.Itoa(vv.Column) + ":"
+		}
+
+		writeString(w, loc+" "+strings.ReplaceAll(violationMessage(vv), "\n", " ")+"\n")
+	}
+}
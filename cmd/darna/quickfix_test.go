@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestWriteQuickfix(t *testing.T) {
+	t.Parallel()
+
+	violation := validator.Violation{
+		StagedFile:    "pkg/a.go",
+		StagedSymbol:  "example.com/x.A",
+		StagedKind:    "",
+		MissingFile:   "b.go",
+		MissingSymbol: "example.com/x.B",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          4,
+		Column:        15,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}
+
+	unknown := violation
+	unknown.Line, unknown.Column = 0, 0
+
+	var buf bytes.Buffer
+
+	writeQuickfix(&buf, []validator.Violation{violation, unknown})
+
+	want := "pkg/a.go:4:15: example.com/x.A uses example.com/x.B from b.go, which is not staged\n" +
+		"pkg/a.go:1: example.com/x.A uses example.com/x.B from b.go, which is not staged\n"
+	if buf.String() != want {
+		t.Errorf("writeQuickfix printed\n%s\nwant\n%s", buf.String(), want)
+	}
+}
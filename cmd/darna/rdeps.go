@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errRdepsUsage = errors.New("usage: darna rdeps [-dir <path>] [-changed] [-json] <file|symbol>")
+
+// runRdeps implements "darna rdeps": the symbols transitively depending on a file or symbol.
+func runRdeps(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("rdeps", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	changed := fs.Bool("changed", false, "only list symbols of files with staged, unstaged or untracked changes")
+	asJSON := fs.Bool("json", false, "print the symbols as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing rdeps flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errRdepsUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	rdeps, err := validator.Rdeps(ctx, *workDir, fs.Arg(0), opts...)
+	if err != nil {
+		return fmt.Errorf("listing dependents: %w", err)
+	}
+
+	return printSymbols(w, rdeps, *changed, *asJSON)
+}
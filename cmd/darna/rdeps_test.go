@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunRdeps(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	runGit(t, dir, "add", "a.go", "b.go", "c.go", "d.go")
+	runGit(t, dir, "commit", "-m", "changes")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\n// Changed again.\nfunc main() { B() }\n")
+
+	var buf bytes.Buffer
+
+	err := runRdeps(t.Context(), &buf, []string{"-dir", dir, "C"})
+	if err != nil {
+		t.Fatalf("runRdeps: %v", err)
+	}
+
+	if want := "a.go:4 example.com/fix.main (unstaged)\nb.go:4 example.com/fix.B\n"; buf.String() != want {
+		t.Errorf("runRdeps() printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+
+	err = runRdeps(t.Context(), &buf, []string{"-dir", dir, "-changed", "c.go"})
+	if err != nil {
+		t.Fatalf("runRdeps -changed: %v", err)
+	}
+
+	if want := "a.go:4 example.com/fix.main (unstaged)\n"; buf.String() != want {
+		t.Errorf("runRdeps(-changed) printed %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// The rdjson types below cover the subset of reviewdog's DiagnosticResult darna emits.
+
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Severity    string             `json:"severity"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type rdjsonDiagnostic struct {
+	Message  string         `json:"message"`
+	Location rdjsonLocation `json:"location"`
+	Severity string         `json:"severity"`
+	Code     rdjsonCode     `json:"code"`
+}
+
+type rdjsonLocation struct {
+	Path  string       `json:"path"`
+	Range *rdjsonRange `json:"range,omitempty"`
+}
+
+type rdjsonRange struct {
+	Start rdjsonPosition `json:"start"`
+}
+
+type rdjsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+// writeRDJSON writes violations in reviewdog's Diagnostic JSON format (-f=rdjson), at
+// the severity failOn gives them.
+func writeRDJSON(w io.Writer, violations []validator.Violation, failOn string) error {
+	diagnostics := make([]rdjsonDiagnostic, 0, len(violations))
+
+	for _, vv := range violations {
+		loc := rdjsonLocation{Path: filepath.ToSlash(vv.StagedFile), Range: nil}
+
+		if vv.Line > 0 {
+			loc.Range = &rdjsonRange{Start: rdjsonPosition{Line: vv.Line, Column: vv.Column}}
+		}
+
+		diagnostics = append(diagnostics, rdjsonDiagnostic{
+			Message:  violationMessage(vv),
+			Location: loc,
+			Severity: strings.ToUpper(violationLevel(vv, failOn)),
+			Code:     rdjsonCode{Value: sarifRuleID},
+		})
+	}
+
+	return writeJSON(w, rdjsonResult{
+		Source:      rdjsonSource{Name: "darna", URL: "https://dario.cat/darna"},
+		Severity:    "ERROR",
+		Diagnostics: diagnostics,
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestWriteRDJSON(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := writeRDJSON(&buf, []validator.Violation{{
+		StagedFile:    "a.go",
+		StagedSymbol:  "example.com/x.A",
+		StagedKind:    "",
+		MissingFile:   "b.go",
+		MissingSymbol: "example.com/x.B",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          4,
+		Column:        15,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}}, failOnAll)
+	if err != nil {
+		t.Fatalf("writeRDJSON: %v", err)
+	}
+
+	var result rdjsonResult
+
+	err = json.Unmarshal(buf.Bytes(), &result)
+	if err != nil {
+		t.Fatalf("Decoding rdjson: %v\n%s", err, buf.String())
+	}
+
+	if result.Source.Name != "darna" || len(result.Diagnostics) != 1 {
+		t.Fatalf("Unexpected rdjson result: %s", buf.String())
+	}
+
+	diag := result.Diagnostics[0]
+	if diag.Location.Path != "a.go" || diag.Location.Range == nil ||
+		diag.Location.Range.Start != (rdjsonPosition{Line: 4, Column: 15}) {
+		t.Errorf("Unexpected location %+v", diag.Location)
+	}
+
+	if diag.Severity != "ERROR" {
+		t.Errorf("diag.Severity = %q, want ERROR", diag.Severity)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// violationReport is the JSON form of a validation run.
+type violationReport struct {
+	Atomic     bool                  `json:"atomic"`
+	Violations []validator.Violation `json:"violations"`
+}
+
+func newViolationReport(violations []validator.Violation) violationReport {
+	return violationReport{
+		Atomic:     len(violations) == 0,
+		Violations: append(make([]validator.Violation, 0, len(violations)), violations...),
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	err := enc.Encode(v)
+	if err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+
+	"dario.cat/darna/internal/validator"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRuleID  = "non-atomic-commit"
+)
+
+// The SARIF types below cover the subset of SARIF 2.1.0 darna emits.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// writeSARIF writes violations as a SARIF log with one result per violation,
+// located at the usage in the staged file, at the level failOn gives it.
+func writeSARIF(w io.Writer, violations []validator.Violation, failOn string) error {
+	results := make([]sarifResult, 0, len(violations))
+
+	for _, vv := range violations {
+		loc := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(vv.StagedFile)},
+			Region:           nil,
+		}
+
+		if vv.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: vv.Line, StartColumn: vv.Column}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    sarifRuleID,
+			Level:     violationLevel(vv, failOn),
+			Message:   sarifMessage{Text: violationMessage(vv)},
+			Locations: []sarifLocation{{PhysicalLocation: loc}},
+		})
+	}
+
+	return writeJSON(w, sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "darna",
+				InformationURI: "https://dario.cat/darna",
+				Rules: []sarifRule{{
+					ID:               sarifRuleID,
+					ShortDescription: sarifMessage{Text: "Staged code depends on changes that are not staged"},
+				}},
+			}},
+			Results: results,
+		}},
+	})
+}
+
+// violationMessage describes a violation in one line, naming the file to stage.
+func violationMessage(vv validator.Violation) string {
+	if vv.Reason != "" {
+		return vv.StagedSymbol + " " + vv.Reason
+	}
+
+	return vv.StagedSymbol + " uses " + vv.MissingSymbol + " from " + vv.MissingFile + ", which is not staged"
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRunValidateSARIF(t *testing.T) {
+	t.Parallel()
+
+	dir := initViolatingRepo(t)
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatSARIF, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+	})
+	if code != 1 {
+		t.Fatalf("runValidate() = %d, want 1; stderr:\n%s", code, stderr.String())
+	}
+
+	var log sarifLog
+
+	err := json.Unmarshal(stdout.Bytes(), &log)
+	if err != nil {
+		t.Fatalf("Decoding SARIF: %v\n%s", err, stdout.String())
+	}
+
+	if log.Version != sarifVersion || len(log.Runs) != 1 || len(log.Runs[0].Results) == 0 {
+		t.Fatalf("Unexpected SARIF log: %s", stdout.String())
+	}
+
+	result := log.Runs[0].Results[0]
+	if result.RuleID != sarifRuleID {
+		t.Errorf("result.RuleID = %q, want %q", result.RuleID, sarifRuleID)
+	}
+
+	// a.go line 4 is "func main() { B() }": the location points at the call to B.
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "a.go" || loc.Region == nil ||
+		loc.Region.StartLine != 4 || loc.Region.StartColumn != 15 {
+		t.Errorf("Unexpected location %+v (region %+v), want a.go:4:15", loc, loc.Region)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errSemverUsage = errors.New("usage: darna semver [-dir <path>] [-json]")
+
+// runSemver implements "darna semver": the version bump the changes of the staged
+// snapshot to the exported API call for.
+func runSemver(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("semver", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	asJSON := fs.Bool("json", false, "print the recommendation and the API changes as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing semver flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errSemverUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	report, err := validator.SuggestSemver(ctx, *workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("suggesting version: %w", err)
+	}
+
+	if *asJSON {
+		return writeJSON(w, report)
+	}
+
+	line := report.Bump
+	if report.Next != "" {
+		line += " (" + report.Current + " -> " + report.Next + ")"
+	}
+
+	writeString(w, line+"\n")
+
+	if len(report.Incompatible) > 0 {
+		writeString(w, "Incompatible changes:\n")
+		printAPIChangeList(w, report.Incompatible)
+	}
+
+	if len(report.Compatible) > 0 {
+		writeString(w, "Compatible changes:\n")
+		printAPIChangeList(w, report.Compatible)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestRunSemver(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	err := os.Mkdir(filepath.Join(dir, "store"), 0o750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/api\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "store", "store.go"), "package store\n\nfunc Get() int { return 1 }\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "api")
+	runGit(t, dir, "tag", "v1.2.3")
+
+	writeFile(t, filepath.Join(dir, "store", "store.go"),
+		"package store\n\nfunc Get() int { return 1 }\n\nfunc Put(int) {}\n")
+	runGit(t, dir, "add", "store")
+
+	var buf bytes.Buffer
+
+	err = runSemver(t.Context(), &buf, []string{"-dir", dir})
+	if err != nil {
+		t.Fatalf("runSemver: %v", err)
+	}
+
+	want := "minor (v1.2.3 -> v1.3.0)\nCompatible changes:\nexample.com/api/store\n  Put: added\n"
+	if buf.String() != want {
+		t.Errorf("runSemver() printed %q, want %q", buf.String(), want)
+	}
+
+	writeFile(t, filepath.Join(dir, "store", "store.go"), "package store\n\nfunc Get() string { return \"\" }\n")
+	runGit(t, dir, "add", "store")
+	buf.Reset()
+
+	err = runSemver(t.Context(), &buf, []string{"-dir", dir, "-json"})
+	if err != nil {
+		t.Fatalf("runSemver -json: %v", err)
+	}
+
+	var report validator.SemverReport
+
+	err = json.Unmarshal(buf.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", buf.String(), err)
+	}
+
+	if report.Bump != validator.BumpMajor || report.Next != "v2.0.0" || len(report.Incompatible) != 1 {
+		t.Errorf("runSemver -json = %+v, want a major bump to v2.0.0 for the Get signature", report)
+	}
+}
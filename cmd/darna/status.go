@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+// writeStatus prints one line per validated file, "PASS <file>" or
+// "FAIL <file> requires <missing>,...", followed by a "RESULT PASS <n> files" or
+// "RESULT FAIL <failed> of <n> files" line. The lines are stable, for hook runners such
+// as lint-staged or lefthook to parse.
+func writeStatus(w io.Writer, files []string, violations []validator.Violation) {
+	missing := make(map[string][]string)
+
+	for _, vv := range violations {
+		if !slices.Contains(missing[vv.StagedFile], vv.MissingFile) {
+			missing[vv.StagedFile] = append(missing[vv.StagedFile], vv.MissingFile)
+		}
+
+		if !slices.Contains(files, vv.StagedFile) {
+			files = append(files, vv.StagedFile)
+		}
+	}
+
+	files = slices.Sorted(slices.Values(files))
+
+	for _, file := range files {
+		if needed := missing[file]; len(needed) > 0 {
+			slices.Sort(needed)
+			writeString(w, "FAIL "+file+" requires "+strings.Join(needed, ",")+"\n")
+		} else {
+			writeString(w, "PASS "+file+"\n")
+		}
+	}
+
+	if len(missing) == 0 {
+		writeString(w, "RESULT PASS "+strconv.Itoa(len(files))+" files\n")
+	} else {
+		writeString(w, "RESULT FAIL "+strconv.Itoa(len(missing))+" of "+strconv.Itoa(len(files))+" files\n")
+	}
+}
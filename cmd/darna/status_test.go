@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestRunValidateStatus(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/status\n\ngo 1.24\n")
+	runGit(t, dir, "add", "go.mod")
+	runGit(t, dir, "commit", "-m", "module")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return C() + D() }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() int { return 1 }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 2 }\n")
+	writeFile(t, filepath.Join(dir, "d.go"), "package main\n\nfunc D() int { return 3 }\n")
+	runGit(t, dir, "add", "a.go", "b.go")
+
+	var stdout, stderr bytes.Buffer
+
+	code := runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatStatus, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: false, deadline: deadline{}, opts: nil,
+	})
+	if code != exitViolations {
+		t.Fatalf("runValidate() = %d, want %d; stderr:\n%s", code, exitViolations, stderr.String())
+	}
+
+	want := "FAIL a.go requires c.go,d.go\nPASS b.go\nRESULT FAIL 1 of 2 files\n"
+	if stdout.String() != want {
+		t.Errorf("runValidate() printed:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+
+	stdout.Reset()
+
+	// lint-staged passes absolute paths.
+	opts := []validator.Option{validator.WithFiles(relativeFiles(dir, []string{filepath.Join(dir, "b.go")})...)}
+
+	code = runValidate(t.Context(), &stdout, &stderr, validateConfig{
+		workDir: dir, verbose: false, quiet: false, format: formatStatus, webhook: "", fix: false,
+		fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+		deadCode: false, unusedExports: false, deadline: deadline{}, opts: opts,
+	})
+	if code != exitAtomic || stdout.String() != "PASS b.go\nRESULT PASS 1 files\n" {
+		t.Errorf("runValidate() of b.go = %d, printed:\n%s", code, stdout.String())
+	}
+}
+
+func TestRelativeFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	got := relativeFiles(dir, []string{filepath.Join(dir, "pkg", "a.go"), "b.go"})
+	if want := []string{filepath.Join("pkg", "a.go"), "b.go"}; !slices.Equal(got, want) {
+		t.Errorf("relativeFiles() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var (
+	errTestUsage = errors.New("usage: darna test [-dir <path>] [-list [-json]] [-- <go test flags>]")
+
+	// errTestsFailed is returned when an impacted test fails.
+	errTestsFailed = errors.New("impacted tests failed")
+)
+
+// runTest implements "darna test": it runs, with go test -run, only the tests that
+// transitively depend on symbols the staged changes touch, or lists them with -list.
+func runTest(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	list := fs.Bool("list", false, "list the impacted tests as \"package name\" lines instead of running them")
+	asJSON := fs.Bool("json", false, "with -list, print the impacted tests as JSON")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing test flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON && !*list || *list && fs.NArg() != 0 {
+		return errTestUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	tests, err := validator.ImpactedTests(ctx, *workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("selecting impacted tests: %w", err)
+	}
+
+	switch {
+	case *asJSON:
+		return writeJSON(w, tests)
+	case *list:
+		for _, test := range tests {
+			writeString(w, test.Package+" "+test.Name+"\n")
+		}
+
+		return nil
+	case len(tests) == 0:
+		writeString(w, "No tests depend on the staged changes\n")
+
+		return nil
+	default:
+		return runImpactedTests(ctx, w, *workDir, tests, append(goBuildFlags(conf), fs.Args()...))
+	}
+}
+
+// runImpactedTests runs go test once per package, selecting its impacted tests with
+// -run, and streams the output to w. goTestArgs are passed to every run.
+func runImpactedTests(
+	ctx context.Context, w io.Writer, workDir string, tests []validator.ImpactedTest, goTestArgs []string,
+) error {
+	var failed []string
+
+	for start := 0; start < len(tests); {
+		pkg := tests[start].Package
+
+		var names []string
+
+		end := start
+		for ; end < len(tests) && tests[end].Package == pkg; end++ {
+			names = append(names, regexp.QuoteMeta(tests[end].Name))
+		}
+
+		start = end
+
+		args := append(append([]string{"test"}, goTestArgs...), "-run", "^("+strings.Join(names, "|")+")$", pkg)
+
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = workDir
+		cmd.Stdout = w
+		cmd.Stderr = w
+
+		err := cmd.Run()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			failed = append(failed, pkg)
+
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("running go test: %w", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", errTestsFailed, strings.Join(failed, " "))
+	}
+
+	return nil
+}
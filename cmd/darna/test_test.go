@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestRunTest(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/impacted\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package impacted\n\nfunc A() int { return 1 }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package impacted\n\nfunc B() int { return 1 }\n")
+	writeFile(t, filepath.Join(dir, "a_test.go"), "package impacted\n\nimport \"testing\"\n\n"+
+		"func TestA(t *testing.T) {\n\tif A() != 1 {\n\t\tt.Fatal(A())\n\t}\n}\n\n"+
+		"func TestB(t *testing.T) {\n\tif B() != 1 {\n\t\tt.Fatal(B())\n\t}\n}\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "code")
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package impacted\n\nfunc A() int { return 2 }\n")
+	runGit(t, dir, "add", "a.go")
+
+	var buf bytes.Buffer
+
+	err := runTest(t.Context(), &buf, []string{"-dir", dir, "-list"})
+	if err != nil {
+		t.Fatalf("runTest -list: %v", err)
+	}
+
+	if want := ". TestA\n"; buf.String() != want {
+		t.Errorf("runTest -list printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+
+	err = runTest(t.Context(), &buf, []string{"-dir", dir, "-list", "-json"})
+	if err != nil {
+		t.Fatalf("runTest -list -json: %v", err)
+	}
+
+	var tests []validator.ImpactedTest
+
+	err = json.Unmarshal(buf.Bytes(), &tests)
+	if err != nil {
+		t.Fatalf("Decoding %q: %v", buf.String(), err)
+	}
+
+	want := validator.ImpactedTest{Package: ".", Name: "TestA", File: "a_test.go"}
+	if len(tests) != 1 || tests[0] != want {
+		t.Errorf("runTest -list -json listed %+v, want only %+v", tests, want)
+	}
+
+	buf.Reset()
+
+	// Only TestA runs, and fails on the staged change; TestB would pass.
+	err = runTest(t.Context(), &buf, []string{"-dir", dir, "--", "-v"})
+	if !errors.Is(err, errTestsFailed) {
+		t.Errorf("runTest() = %v, want %v; output:\n%s", err, errTestsFailed, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "--- FAIL: TestA") || strings.Contains(buf.String(), "TestB") {
+		t.Errorf("Expected only TestA to run, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// Values accepted by --color.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var (
+	errUnknownColor = errors.New("unknown --color mode (want auto, always or never)")
+	errUnknownTheme = errors.New("unknown --theme (want default, color-blind or mono)")
+)
+
+const (
+	ansiReset = "\033[0m"
+	ansiDim   = "\033[2m"
+)
+
+// theme decorates the text output: failures and missing files, successes, staged
+// file locations and secondary hints such as fix commands. The zero theme prints plain text.
+type theme struct {
+	failureColor string // ANSI sequence for failures, empty for none.
+	successColor string // ANSI sequence for successes, empty for none.
+	stagedColor  string // ANSI sequence for staged file locations, empty for none.
+	dimColor     string // ANSI sequence for secondary hints, empty for none.
+	failureMark  string // Marker prefixed to failures.
+	successMark  string // Marker prefixed to successes.
+}
+
+// plainTheme prints without colors or markers.
+var plainTheme theme
+
+// themes maps --theme names to their decorations.
+var themes = map[string]theme{
+	"default": {
+		failureColor: "\033[31m", successColor: "\033[32m", stagedColor: "\033[36m", dimColor: ansiDim,
+		failureMark: "", successMark: "",
+	},
+	// Blue and orange stay distinguishable under the common red-green deficiencies.
+	"color-blind": {
+		failureColor: "\033[38;5;208m", successColor: "\033[34m", stagedColor: "\033[1m", dimColor: ansiDim,
+		failureMark: "", successMark: "",
+	},
+	"mono": {failureColor: "", successColor: "", stagedColor: "", dimColor: "", failureMark: "✗ ", successMark: "✓ "},
+}
+
+// selectTheme resolves --theme and --color into the theme to print with. Themes only
+// apply when color is enabled; "auto" enables it for terminals unless NO_COLOR is set.
+func selectTheme(name, color string, stdout *os.File) (theme, error) {
+	th, ok := themes[name]
+	if !ok {
+		return plainTheme, errUnknownTheme
+	}
+
+	switch color {
+	case colorAlways:
+		return th, nil
+	case colorNever:
+		return plainTheme, nil
+	case colorAuto:
+		if os.Getenv("NO_COLOR") == "" && isTerminal(stdout) {
+			return th, nil
+		}
+
+		return plainTheme, nil
+	default:
+		return plainTheme, errUnknownColor
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (th theme) failure(s string) string {
+	return decorate(th.failureColor, th.failureMark, s)
+}
+
+func (th theme) success(s string) string {
+	return decorate(th.successColor, th.successMark, s)
+}
+
+func (th theme) staged(s string) string {
+	return decorate(th.stagedColor, "", s)
+}
+
+func (th theme) dim(s string) string {
+	return decorate(th.dimColor, "", s)
+}
+
+func decorate(color, mark, s string) string {
+	if color == "" {
+		return mark + s
+	}
+
+	return color + mark + s + ansiReset
+}
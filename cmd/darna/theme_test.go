@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var themeViolations = []validator.Violation{{
+	StagedFile:    "a.go",
+	StagedSymbol:  "example.com/x.A",
+	StagedKind:    "",
+	MissingFile:   "b.go",
+	MissingSymbol: "example.com/x.B",
+	MissingKind:   "",
+	MissingLine:   0,
+	MissingColumn: 0,
+	Reason:        "",
+	Line:          0,
+	Column:        0,
+	Chain:         nil,
+	Direct:        true,
+	Fingerprint:   "",
+}}
+
+func TestSelectThemeColorBlindPalette(t *testing.T) {
+	t.Parallel()
+
+	th, err := selectTheme("color-blind", colorAlways, os.Stdout)
+	if err != nil {
+		t.Fatalf("selectTheme: %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	printViolations(&buf, themeViolations, nil, th, pairSymbolLimit, failOnAll)
+
+	out := buf.String()
+	if !strings.Contains(out, themes["color-blind"].failureColor) {
+		t.Errorf("Expected the color-blind failure color, got %q", out)
+	}
+
+	if strings.Contains(out, themes["default"].failureColor) {
+		t.Errorf("Color-blind output uses the default red, got %q", out)
+	}
+}
+
+func TestSelectThemeMonoUsesMarkers(t *testing.T) {
+	t.Parallel()
+
+	th, err := selectTheme("mono", colorAlways, os.Stdout)
+	if err != nil {
+		t.Fatalf("selectTheme: %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	printViolations(&buf, themeViolations, nil, th, pairSymbolLimit, failOnAll)
+
+	out := buf.String()
+	if strings.Contains(out, "\033[") {
+		t.Errorf("Mono output contains ANSI codes: %q", out)
+	}
+
+	if !strings.Contains(out, "✗ Commit is not atomic") {
+		t.Errorf("Expected a failure marker, got %q", out)
+	}
+
+	if got := th.success("Commit is atomic"); got != "✓ Commit is atomic" {
+		t.Errorf("th.success() = %q, want a success marker", got)
+	}
+}
+
+func TestSelectThemeColorNever(t *testing.T) {
+	t.Parallel()
+
+	th, err := selectTheme("color-blind", colorNever, os.Stdout)
+	if err != nil {
+		t.Fatalf("selectTheme: %v", err)
+	}
+
+	if th != plainTheme {
+		t.Errorf("selectTheme() with --color never = %+v, want plain", th)
+	}
+
+	_, err = selectTheme("sepia", colorAlways, os.Stdout)
+	if err == nil {
+		t.Error("Expected an error for an unknown theme")
+	}
+}
+
+func TestPrintViolationsColorsRoles(t *testing.T) {
+	t.Parallel()
+
+	th := themes["default"]
+
+	var buf bytes.Buffer
+
+	printViolations(&buf, themeViolations, nil, th, pairSymbolLimit, failOnAll)
+
+	out := buf.String()
+	for _, want := range []string{
+		th.failure("b.go"),
+		th.staged("a.go"),
+		th.dim("   git add b.go"),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the output, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Policies accepted by --timeout-policy.
+const (
+	timeoutClosed = "closed" // Runs cut short fail, as on any other error.
+	timeoutOpen   = "open"   // Runs cut short pass with a warning, never blocking the commit.
+)
+
+var (
+	errTimedOut             = errors.New("timed out")
+	errNegativeTimeout      = errors.New("--timeout must not be negative")
+	errUnknownTimeoutPolicy = errors.New("unknown --timeout-policy (want closed or open)")
+)
+
+// deadline bounds how long a run may take, for hooks that must not hold up a commit.
+type deadline struct {
+	limit  time.Duration // Zero for no limit.
+	policy string        // What a run cut short does: timeoutClosed, the default, or timeoutOpen.
+}
+
+// deadlineFlags defines --timeout and --timeout-policy on fs.
+func deadlineFlags(fs *flag.FlagSet) (*time.Duration, *string) {
+	limit := fs.Duration("timeout", 0, "give up after this long, e.g. 10s (0 = no limit)")
+	policy := fs.String("timeout-policy", timeoutClosed,
+		"when --timeout runs out: closed fails the run, open lets it pass with a warning")
+
+	return limit, policy
+}
+
+// newDeadline validates the values of deadlineFlags.
+func newDeadline(limit time.Duration, policy string) (deadline, error) {
+	switch {
+	case limit < 0:
+		return deadline{}, errNegativeTimeout
+	case policy != timeoutClosed && policy != timeoutOpen:
+		return deadline{}, errUnknownTimeoutPolicy
+	default:
+		return deadline{limit: limit, policy: policy}, nil
+	}
+}
+
+// context returns ctx, cancelled once the limit is over. Git, the package loader and
+// the graph analysis all stop when it is.
+func (d deadline) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.limit == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, d.limit)
+}
+
+// failure returns err, the error a run under ctx failed with, or errTimedOut when ctx
+// ran out of time first. Under timeoutOpen, such runs warn on stderr and return nil.
+func (d deadline) failure(ctx context.Context, stderr io.Writer, err error) error {
+	if err == nil || d.limit == 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	if d.policy == timeoutOpen {
+		writeString(stderr, "Warning: gave up after "+d.limit.String()+", not blocking the commit\n")
+
+		return nil
+	}
+
+	return fmt.Errorf("%w after %s", errTimedOut, d.limit)
+}
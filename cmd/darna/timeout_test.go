@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDeadline(t *testing.T) {
+	t.Parallel()
+
+	_, err := newDeadline(-time.Second, timeoutClosed)
+	if !errors.Is(err, errNegativeTimeout) {
+		t.Errorf("newDeadline(-1s) error = %v, want %v", err, errNegativeTimeout)
+	}
+
+	_, err = newDeadline(time.Second, "ajar")
+	if !errors.Is(err, errUnknownTimeoutPolicy) {
+		t.Errorf("newDeadline(ajar) error = %v, want %v", err, errUnknownTimeoutPolicy)
+	}
+}
+
+func TestRunValidateTimeout(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/timeout\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return B() }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package main\n\nfunc B() int { return 1 }\n")
+	runGit(t, dir, "add", "go.mod", "a.go")
+
+	tests := []struct {
+		policy string
+		want   int
+		output string
+	}{
+		{policy: timeoutClosed, want: exitError, output: "Error: timed out after 1ns"},
+		{policy: timeoutOpen, want: exitAtomic, output: "Warning: gave up after 1ns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Parallel()
+
+			dl, err := newDeadline(time.Nanosecond, tt.policy)
+			if err != nil {
+				t.Fatalf("newDeadline() error = %v", err)
+			}
+
+			ctx, cancel := dl.context(t.Context())
+			defer cancel()
+
+			<-ctx.Done()
+
+			var stdout, stderr bytes.Buffer
+
+			code := runValidate(ctx, &stdout, &stderr, validateConfig{
+				workDir: dir, verbose: false, quiet: false, format: formatText, webhook: "", fix: false,
+				fixWith: fixStrategies["stage"], theme: plainTheme, severity: "", failOn: "", build: false, daemon: false,
+				deadCode: false, unusedExports: false, deadline: dl, opts: nil,
+			})
+			if code != tt.want || !strings.Contains(stderr.String(), tt.output) {
+				t.Errorf("runValidate() = %d, stderr %q; want %d, %q", code, stderr.String(), tt.want, tt.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+var errTUIUsage = errors.New("usage: darna tui [-dir <path>] [-color <mode>] [-theme <name>]")
+
+// tuiHelp lists the commands of "darna tui".
+const tuiHelp = "<n>... toggles files, d <n> selects a file with its dependencies, " +
+	"s stages the selection (s! even if not atomic), q quits"
+
+// tuiAction is what a command asks the session to do next.
+type tuiAction int
+
+const (
+	tuiContinue tuiAction = iota // Show the files again and read the next command.
+	tuiStage                     // Stage the selection and end the session.
+	tuiQuit                      // End the session without touching the index.
+)
+
+// tuiModel is the state of "darna tui": the changed files, the changed files each of
+// them depends on, and the files selected for the next commit.
+type tuiModel struct {
+	files    []string            // Changed Go files, sorted.
+	deps     map[string][]string // Changed files each file transitively depends on.
+	selected map[string]bool     // Files selected for the next commit.
+	notice   string              // Feedback on the last command, shown once.
+}
+
+// runTUI implements "darna tui": an interactive session showing the changed files and
+// their dependencies, where files are toggled into the selection to stage and every
+// change of the selection is checked for atomicity.
+func runTUI(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	color := fs.String("color", colorAuto, "colorize output: auto, always, never")
+	themeName := fs.String("theme", "default", "output theme when colorized: default, color-blind, mono")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing tui flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errTUIUsage
+	}
+
+	th, err := selectTheme(*themeName, *color, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	deps, err := validator.ChangesetDeps(ctx, *workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("analyzing changeset: %w", err)
+	}
+
+	staged, err := git.GetStagedFiles(ctx, *workDir)
+	if err != nil {
+		return fmt.Errorf("listing staged files: %w", err)
+	}
+
+	m := newTUIModel(deps, staged)
+	if len(m.files) == 0 {
+		writeString(w, "No changed Go files\n")
+
+		return nil
+	}
+
+	if runTUILoop(os.Stdin, w, m, th) != tuiStage {
+		return nil
+	}
+
+	return stageSelection(ctx, w, *workDir, m)
+}
+
+// newTUIModel selects the changed files already staged.
+func newTUIModel(deps map[string][]string, staged []string) *tuiModel {
+	m := &tuiModel{files: nil, deps: deps, selected: make(map[string]bool), notice: ""}
+
+	for file := range deps {
+		m.files = append(m.files, file)
+	}
+
+	slices.Sort(m.files)
+
+	for _, file := range staged {
+		if _, ok := deps[file]; ok {
+			m.selected[file] = true
+		}
+	}
+
+	return m
+}
+
+// runTUILoop shows the model and applies the commands read from in until one ends
+// the session, or in is exhausted.
+func runTUILoop(in io.Reader, w io.Writer, m *tuiModel, th theme) tuiAction {
+	scanner := bufio.NewScanner(in)
+
+	for {
+		m.view(w, th)
+		writeString(w, "> ")
+
+		if !scanner.Scan() {
+			return tuiQuit
+		}
+
+		if action := m.update(scanner.Text()); action != tuiContinue {
+			return action
+		}
+	}
+}
+
+// update applies one command line to the model.
+func (m *tuiModel) update(line string) tuiAction {
+	fields := strings.Fields(line)
+	m.notice = ""
+
+	switch {
+	case len(fields) == 0:
+		return tuiContinue
+	case fields[0] == "q":
+		return tuiQuit
+	case fields[0] == "s!":
+		return tuiStage
+	case fields[0] == "s":
+		if len(m.missing()) > 0 {
+			m.notice = "Selection is not atomic, use s! to stage it anyway"
+
+			return tuiContinue
+		}
+
+		return tuiStage
+	case fields[0] == "d" && len(fields) == 2: //nolint:mnd // Command and file number.
+		if file, ok := m.file(fields[1]); ok {
+			m.selected[file] = true
+			for _, dep := range m.deps[file] {
+				m.selected[dep] = true
+			}
+		}
+
+		return tuiContinue
+	}
+
+	for _, field := range fields {
+		file, ok := m.file(field)
+
+		switch {
+		case !ok:
+		case m.selected[file]:
+			delete(m.selected, file)
+		default:
+			m.selected[file] = true
+		}
+	}
+
+	return tuiContinue
+}
+
+// file resolves a 1-based file number, setting a notice when it is invalid.
+func (m *tuiModel) file(number string) (string, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 || n > len(m.files) {
+		m.notice = "Unknown command or file number " + number + ": " + tuiHelp
+
+		return "", false
+	}
+
+	return m.files[n-1], true
+}
+
+// missing returns, for each selected file, the dependencies left out of the selection.
+func (m *tuiModel) missing() map[string][]string {
+	missing := make(map[string][]string)
+
+	for file := range m.selected {
+		for _, dep := range m.deps[file] {
+			if !m.selected[dep] {
+				missing[file] = append(missing[file], dep)
+			}
+		}
+	}
+
+	return missing
+}
+
+// view prints the files with their selection and dependencies, highlighting the
+// dependencies the selection misses, followed by the atomicity of the selection.
+func (m *tuiModel) view(w io.Writer, th theme) {
+	writeString(w, "\n"+th.dim(tuiHelp)+"\n\n")
+
+	for i, file := range m.files {
+		box := "[ ] "
+		if m.selected[file] {
+			box = "[x] "
+		}
+
+		line := "  " + box + strconv.Itoa(i+1) + ". " + file
+
+		if len(m.deps[file]) > 0 {
+			names := make([]string, 0, len(m.deps[file]))
+
+			for _, dep := range m.deps[file] {
+				switch {
+				case !m.selected[file]:
+					names = append(names, th.dim(dep))
+				case m.selected[dep]:
+					names = append(names, th.success(dep))
+				default:
+					names = append(names, th.failure(dep))
+				}
+			}
+
+			line += " → " + strings.Join(names, ", ")
+		}
+
+		writeString(w, line+"\n")
+	}
+
+	writeString(w, "\n")
+
+	missing := m.missing()
+
+	switch {
+	case len(missing) > 0:
+		for _, file := range slices.Sorted(maps.Keys(missing)) {
+			writeString(w, th.failure("Not atomic: "+file+" needs "+strings.Join(missing[file], ", "))+"\n")
+		}
+	case len(m.selected) > 0:
+		writeString(w, th.success("Selection is atomic")+"\n")
+	}
+
+	if m.notice != "" {
+		writeString(w, m.notice+"\n")
+	}
+}
+
+// stageSelection stages the selected files and unstages the other changed files, so the
+// index holds exactly the selection among the changed files.
+func stageSelection(ctx context.Context, w io.Writer, workDir string, m *tuiModel) error {
+	var toStage, toUnstage []string
+
+	for _, file := range m.files {
+		if m.selected[file] {
+			toStage = append(toStage, file)
+		} else {
+			toUnstage = append(toUnstage, file)
+		}
+	}
+
+	if len(toUnstage) > 0 {
+		err := git.UnstageFiles(ctx, workDir, toUnstage...)
+		if err != nil {
+			return fmt.Errorf("unstaging deselected files: %w", err)
+		}
+	}
+
+	if len(toStage) > 0 {
+		err := git.StageFiles(ctx, workDir, toStage...)
+		if err != nil {
+			return fmt.Errorf("staging selection: %w", err)
+		}
+	}
+
+	writeString(w, "Staged: "+strings.Join(toStage, " ")+"\n")
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTUILoop(t *testing.T) {
+	t.Parallel()
+
+	m := newTUIModel(map[string][]string{
+		"a.go": {"b.go", "c.go"},
+		"b.go": {"c.go"},
+		"c.go": nil,
+		"d.go": nil,
+	}, []string{"a.go", "README.md"})
+
+	var buf bytes.Buffer
+
+	// Staging a.go alone is refused, then its dependencies are added and d.go toggled twice.
+	action := runTUILoop(strings.NewReader("s\nd 1\n4 4\ns\n"), &buf, m, plainTheme)
+	if action != tuiStage {
+		t.Fatalf("runTUILoop() = %v, want tuiStage", action)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"  [x] 1. a.go → b.go, c.go\n",
+		"Not atomic: a.go needs b.go, c.go\n",
+		"Selection is not atomic, use s! to stage it anyway\n",
+		"Selection is atomic\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the session, got:\n%s", want, out)
+		}
+	}
+
+	if len(m.selected) != 3 || m.selected["d.go"] {
+		t.Errorf("Selection = %v, want a.go, b.go and c.go", m.selected)
+	}
+
+	if runTUILoop(strings.NewReader("9\n"), &buf, m, plainTheme) != tuiQuit {
+		t.Error("Expected the session to end when the input does")
+	}
+}
+
+func TestStageSelection(t *testing.T) {
+	t.Parallel()
+
+	dir := initChainRepo(t)
+	writeFile(t, filepath.Join(dir, "e.go"), "package main\n\nfunc E() {}\n")
+	runGit(t, dir, "add", "a.go")
+
+	m := newTUIModel(map[string][]string{"a.go": nil, "c.go": nil, "d.go": nil, "e.go": nil}, nil)
+	m.selected["c.go"], m.selected["d.go"] = true, true
+
+	var buf bytes.Buffer
+
+	err := stageSelection(t.Context(), &buf, dir, m)
+	if err != nil {
+		t.Fatalf("stageSelection: %v", err)
+	}
+
+	if got := stagedNames(t, dir); got != "c.go d.go" {
+		t.Errorf("Staged %q, want c.go d.go", got)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var errValidateRefUsage = errors.New("usage: darna validate-ref [-dir <git-dir>] <oldrev> <newrev>")
+
+// runValidateRef implements "darna validate-ref <oldrev> <newrev>" for server-side hooks.
+// It works without a working tree, reading all content from git objects.
+func runValidateRef(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("validate-ref", flag.ContinueOnError)
+	gitDir := fs.String("dir", ".", "git directory, bare or not (default: current directory)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing validate-ref flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 { //nolint:mnd // Old and new revision.
+		return errValidateRefUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	reports, err := validator.ValidateRefUpdate(ctx, *gitDir, fs.Arg(0), fs.Arg(1), opts...)
+	if err != nil {
+		return fmt.Errorf("validating ref update: %w", err)
+	}
+
+	if !printCommitReports(w, reports) {
+		return errNotAtomic
+	}
+
+	return nil
+}
+
+// printCommitReports prints the violations of non-atomic commits.
+// It returns true when every commit is atomic.
+func printCommitReports(w io.Writer, reports []validator.CommitReport) bool {
+	atomic := true
+
+	for _, report := range reports {
+		if len(report.Violations) == 0 {
+			continue
+		}
+
+		atomic = false
+
+		writeString(w, "Commit "+shortHash(report.Commit)+" is not atomic:\n")
+
+		for _, vv := range report.Violations {
+			writeString(w, "  "+commitViolation(vv)+"\n")
+		}
+	}
+
+	return atomic
+}
+
+// commitViolation describes a violation of a commit on a single line.
+func commitViolation(vv validator.Violation) string {
+	if vv.Reason != "" {
+		return vv.Location() + ": " + usageText(vv)
+	}
+
+	return vv.Location() + ": " + vv.StagedSymbol + " uses " + vv.MissingSymbol + " (" + vv.MissingFile + ")" +
+		chainSuffix(vv)
+}
+
+func shortHash(hash string) string {
+	const short = 12
+	if len(hash) > short {
+		return hash[:short]
+	}
+
+	return hash
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"dario.cat/darna/internal/validator"
+)
+
+func TestCommitViolation(t *testing.T) {
+	t.Parallel()
+
+	vv := validator.Violation{
+		StagedFile:    "a.go",
+		StagedSymbol:  "example.com/x.A",
+		StagedKind:    "",
+		MissingFile:   "b.go",
+		MissingSymbol: "example.com/x.B",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          42,
+		Column:        10,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}
+
+	if got, want := commitViolation(vv), "a.go:42:10: example.com/x.A uses example.com/x.B (b.go)"; got != want {
+		t.Errorf("commitViolation() = %q, want %q", got, want)
+	}
+
+	vv.StagedSymbol, vv.MissingSymbol = "Helper", ""
+	vv.Reason = "moved from b.go but its removal is not staged"
+
+	if got, want := commitViolation(vv), "a.go:42:10: Helper "+vv.Reason; got != want {
+		t.Errorf("commitViolation() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+
+	"dario.cat/darna/internal/validator"
+)
+
+var (
+	errVerifyUsage = errors.New("usage: darna verify [-dir <path>] [-tests]")
+
+	// errVerifyFailed is returned when the staged snapshot does not build or its tests fail.
+	errVerifyFailed = errors.New("staged snapshot failed verification")
+)
+
+// runVerify implements "darna verify": it proves the staged snapshot compiles on its own
+// and, with -tests, that "go test ./..." passes on a copy of the index, whatever the
+// state of the working tree.
+func runVerify(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	tests := fs.Bool("tests", false, "also run go test ./... on a copy of the staged files")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing verify flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errVerifyUsage
+	}
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	buildErrs, err := validator.CheckStagedBuild(ctx, *workDir, opts...)
+	if err != nil {
+		return fmt.Errorf("checking staged build: %w", err)
+	}
+
+	if len(buildErrs) > 0 {
+		writeString(w, "Staged snapshot does not build:\n")
+
+		for _, be := range buildErrs {
+			writeString(w, "  "+be.String()+"\n")
+		}
+
+		return errVerifyFailed
+	}
+
+	writeString(w, "Staged snapshot builds\n")
+
+	if !*tests {
+		return nil
+	}
+
+	return testStaged(ctx, w, *workDir, goBuildFlags(conf))
+}
+
+// testStaged runs "go test ./..." with buildFlags on a copy of the staged files of
+// workDir, streaming its output to w.
+func testStaged(ctx context.Context, w io.Writer, workDir string, buildFlags []string) error {
+	dir, cleanup, err := validator.CheckoutStaged(ctx, workDir)
+	if err != nil {
+		return fmt.Errorf("copying staged snapshot: %w", err)
+	}
+
+	defer cleanup()
+
+	cmd := exec.CommandContext(ctx, "go", slices.Concat([]string{"test"}, buildFlags, []string{"./..."})...)
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return errVerifyFailed
+	}
+
+	if err != nil {
+		return fmt.Errorf("running go test: %w", err)
+	}
+
+	writeString(w, "Staged tests pass\n")
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunVerifyTests(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/verify\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package verify\n\nfunc A() int { return 1 }\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package verify\n")
+	writeFile(t, filepath.Join(dir, "a_test.go"),
+		"package verify\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {\n\tif A() != 1 {\n\t\tt.Fatal(A())\n\t}\n}\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "code")
+
+	// Unstaged breakage is not part of the commit.
+	writeFile(t, filepath.Join(dir, "a.go"), "package verify\n\nfunc A() int { return 2 }\n")
+
+	var buf bytes.Buffer
+
+	err := runVerify(t.Context(), &buf, []string{"-dir", dir, "-tests"})
+	if err != nil {
+		t.Fatalf("runVerify: %v\n%s", err, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "Staged tests pass") {
+		t.Errorf("Expected passing staged tests, got:\n%s", buf.String())
+	}
+
+	runGit(t, dir, "add", "a.go")
+	buf.Reset()
+
+	err = runVerify(t.Context(), &buf, []string{"-dir", dir, "-tests"})
+	if !errors.Is(err, errVerifyFailed) {
+		t.Errorf("runVerify() = %v, want errVerifyFailed once the breakage is staged; output:\n%s", err, buf.String())
+	}
+}
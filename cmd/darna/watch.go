@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+var errWatchUsage = errors.New("usage: darna watch [-dir <path>] [-interval <duration>] [-json]")
+
+// defaultWatchInterval is how often darna watch looks for changes.
+const defaultWatchInterval = time.Second
+
+// watchStatus is the JSON form of a status line of darna watch.
+type watchStatus struct {
+	Time       time.Time             `json:"time"`
+	Atomic     bool                  `json:"atomic"`
+	Error      string                `json:"error,omitempty"`
+	Violations []validator.Violation `json:"violations"`
+}
+
+// fileState is what darna watch and darna daemon compare between snapshots to spot
+// changed files.
+type fileState struct {
+	status  git.FileStatus
+	modTime time.Time
+	size    int64
+}
+
+// watcher re-validates a working tree incrementally as its files change or are staged.
+type watcher struct {
+	workDir  string
+	opts     []validator.Option
+	jsonOut  bool
+	analysis *validator.Analysis  // Reused between validations, nil until the first one.
+	files    map[string]fileState // Changed files at the last poll, nil before it.
+}
+
+// runWatch implements "darna watch": it polls the git status of the working tree and
+// the files it lists, and re-validates whenever a file changes or is staged, printing
+// the atomicity status each time. Only the packages of the changed files are reloaded.
+func runWatch(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	workDir := fs.String("dir", ".", "working directory (default: $DARNA_DIR or current directory)")
+	interval := fs.Duration("interval", defaultWatchInterval, "how often to look for changes")
+	jsonOut := fs.Bool("json", false, "print each status as a single-line JSON object")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parsing watch flags: %w", err)
+	}
+
+	conf, _, err := applyConfig(ctx, fs, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *interval <= 0 {
+		return errWatchUsage
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	opts, err := configOptions(conf)
+	if err != nil {
+		return err
+	}
+
+	wt := &watcher{workDir: *workDir, opts: opts, jsonOut: *jsonOut, analysis: nil, files: nil}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		err = wt.poll(ctx, w)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll validates again, printing the status, when files changed since the last poll
+// or on the first one.
+func (wt *watcher) poll(ctx context.Context, w io.Writer) error {
+	files, err := snapshotFiles(ctx, wt.workDir)
+	if err != nil && wt.files == nil {
+		return err
+	}
+
+	if err != nil {
+		return wt.print(w, nil, err) // E.g. index.lock held by a concurrent git command.
+	}
+
+	changed := changedFiles(wt.files, files)
+	if wt.files != nil && len(changed) == 0 {
+		return nil
+	}
+
+	wt.files = files
+
+	violations, analysis, err := validator.ValidateIncremental(ctx, wt.workDir, changed, wt.analysis, wt.opts...)
+	if ctx.Err() != nil {
+		return nil //nolint:nilerr // Interrupted while validating.
+	}
+
+	// A failed validation starts afresh next time.
+	wt.analysis = analysis
+
+	return wt.print(w, violations, err)
+}
+
+// snapshotFiles returns the state of the files git reports as changed in the working
+// tree or the index of workDir. Files missing from it are as committed.
+func snapshotFiles(ctx context.Context, workDir string) (map[string]fileState, error) {
+	statuses, err := git.GetAllFileStatus(ctx, workDir)
+	if err != nil {
+		return nil, fmt.Errorf("watching: %w", err)
+	}
+
+	files := make(map[string]fileState, len(statuses))
+
+	for file, status := range statuses {
+		state := fileState{status: status, modTime: time.Time{}, size: 0}
+
+		if info, statErr := os.Stat(filepath.Join(workDir, file)); statErr == nil {
+			state.modTime, state.size = info.ModTime(), info.Size()
+		}
+
+		files[file] = state
+	}
+
+	return files, nil
+}
+
+// changedFiles returns the files whose state differs between the two snapshots,
+// including those only one of them lists.
+func changedFiles(prev, cur map[string]fileState) []string {
+	var changed []string
+
+	for file, state := range cur {
+		if old, ok := prev[file]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size ||
+			old.status != state.status {
+			changed = append(changed, file)
+		}
+	}
+
+	for file := range prev {
+		if _, ok := cur[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+
+	return changed
+}
+
+// print writes the status after a validation that returned violations and err.
+func (wt *watcher) print(w io.Writer, violations []validator.Violation, err error) error {
+	now := time.Now()
+
+	if wt.jsonOut {
+		status := watchStatus{
+			Time:       now,
+			Atomic:     err == nil && len(violations) == 0,
+			Error:      "",
+			Violations: append(make([]validator.Violation, 0, len(violations)), violations...),
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+
+		line, marshalErr := json.Marshal(status)
+		if marshalErr != nil {
+			return fmt.Errorf("encoding JSON: %w", marshalErr)
+		}
+
+		writeString(w, string(line)+"\n")
+
+		return nil
+	}
+
+	stamp := "[" + now.Format(time.TimeOnly) + "] "
+
+	switch {
+	case err != nil:
+		writeString(w, stamp+"error: "+strings.ReplaceAll(err.Error(), "\n", " ")+"\n")
+	case len(violations) == 0:
+		writeString(w, stamp+"atomic\n")
+	default:
+		writeString(w, stamp+"not atomic: "+strconv.Itoa(len(violations))+" violation(s)\n")
+
+		for _, vv := range violations {
+			writeString(w, "  "+commitViolation(vv)+"\n")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/watch\n\ngo 1.24\n")
+	runGit(t, dir, "add", "go.mod")
+	runGit(t, dir, "commit", "-m", "module")
+
+	wt := &watcher{workDir: dir, opts: nil, jsonOut: false, analysis: nil, files: nil}
+
+	var buf bytes.Buffer
+
+	poll := func() string {
+		t.Helper()
+
+		buf.Reset()
+
+		err := wt.poll(t.Context(), &buf)
+		if err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	if got := poll(); !strings.HasSuffix(got, "] atomic\n") {
+		t.Errorf("First poll printed %q, want an atomic status", got)
+	}
+
+	if got := poll(); got != "" {
+		t.Errorf("Poll without changes printed %q", got)
+	}
+
+	writeFile(t, filepath.Join(dir, "a.go"), "package main\n\nfunc A() int { return C() }\n")
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 1 }\n")
+
+	if got := poll(); !strings.HasSuffix(got, "] atomic\n") {
+		t.Errorf("Poll with nothing staged printed %q, want an atomic status", got)
+	}
+
+	runGit(t, dir, "add", "a.go")
+
+	if got := poll(); !strings.Contains(got, "not atomic: 1 violation(s)") || !strings.Contains(got, "(c.go)") {
+		t.Errorf("Poll after staging a.go printed %q, want its violation", got)
+	}
+
+	// Same size, later modification time.
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "c.go"), "package main\n\nfunc C() int { return 2 }\n")
+	runGit(t, dir, "add", "c.go")
+
+	wt.jsonOut = true
+
+	var status watchStatus
+
+	err := json.Unmarshal([]byte(poll()), &status)
+	if err != nil || !status.Atomic || len(status.Violations) != 0 {
+		t.Errorf("Poll after staging c.go = %+v, %v, want an atomic status", status, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,14 +16,15 @@ import (
 const DefaultTimeout = 30 * time.Second
 
 // DefaultPrompt is the built-in prompt for generating Conventional Commits messages.
-const DefaultPrompt = `Generate a single-line commit message for the following diff.
+// It describes the rules LintMessage enforces.
+var DefaultPrompt = `Generate a single-line commit message for the following diff.
 Follow the Conventional Commits format exactly:
 
 <type>[optional scope]: <description>
 
-Types: feat, fix, refactor, docs, test, chore, ci, perf, style, build
+Types: ` + strings.Join(commitTypes, ", ") + `
 Scope: optional, in parentheses if present
-Description: imperative mood, lowercase, no period, max 72 chars after prefix
+Description: imperative mood, lowercase, no period, max ` + strconv.Itoa(maxDescriptionLength) + ` chars after prefix
 
 Output ONLY the commit message line. No explanation, no quotes, no markdown.`
 
@@ -101,7 +103,7 @@ func (ag *cliAgent) Generate(ctx context.Context, diff, prompt string) (string,
 	timeoutCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
 	defer cancel()
 
-	fullPrompt := prompt + "\n\nDiff:\n" + diff
+	fullPrompt := BuildPrompt(prompt, diff)
 
 	//nolint:gosec // Agent name is validated in NewAgent; args built from user-provided prompt.
 	cmd := exec.CommandContext(timeoutCtx, ag.name, ag.args(fullPrompt)...)
@@ -141,6 +143,23 @@ func (ag *cliAgent) Generate(ctx context.Context, diff, prompt string) (string,
 	return msg, nil
 }
 
+// BuildPrompt assembles the full prompt sent to an agent: the instructions followed by the diff.
+func BuildPrompt(prompt, diff string) string {
+	return prompt + "\n\nDiff:\n" + diff
+}
+
+// DryRunAgent returns the full prompt an agent would receive instead of invoking one.
+type DryRunAgent struct{}
+
+// Generate returns the assembled prompt and diff.
+func (DryRunAgent) Generate(_ context.Context, diff, prompt string) (string, error) {
+	if diff == "" {
+		return "", ErrEmptyDiff
+	}
+
+	return BuildPrompt(prompt, diff), nil
+}
+
 // isNotFound checks if the error indicates the binary was not found.
 func isNotFound(err error) bool {
 	var execErr *exec.Error
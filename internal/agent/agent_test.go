@@ -94,6 +94,24 @@ func TestGenerateAgentNotFound(t *testing.T) {
 	}
 }
 
+func TestDryRunAgentGenerate(t *testing.T) {
+	t.Parallel()
+
+	got, err := agent.DryRunAgent{}.Generate(t.Context(), "+diff", "prompt")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	if want := agent.BuildPrompt("prompt", "+diff"); got != want {
+		t.Errorf("Generate() = %q, want %q", got, want)
+	}
+
+	_, err = agent.DryRunAgent{}.Generate(t.Context(), "", "prompt")
+	if !errors.Is(err, agent.ErrEmptyDiff) {
+		t.Errorf("Generate() with empty diff error = %v, want %v", err, agent.ErrEmptyDiff)
+	}
+}
+
 func TestDefaultPromptNotEmpty(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commitTypes are the Conventional Commits types of DefaultPrompt.
+var commitTypes = []string{"feat", "fix", "refactor", "docs", "test", "chore", "ci", "perf", "style", "build"}
+
+// maxDescriptionLength bounds the description of DefaultPrompt, in characters after the
+// type and scope prefix.
+const maxDescriptionLength = 72
+
+// scissors marks the start of the diff "git commit --verbose" appends to the message file.
+const scissors = "# ------------------------ >8 ------------------------"
+
+var (
+	// headerPattern matches "<type>[(scope)][!]: <description>".
+	headerPattern = regexp.MustCompile(`^(\w+)(\(([^()]*)\))?!?: (.*)$`)
+	scopePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]*$`)
+)
+
+// generatedPrefixes start the subjects git and its tools write themselves, which are
+// not linted.
+var generatedPrefixes = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+// commonVerbs are the verbs descriptions most often start with, used to spot their
+// third person forms ("adds", "fixes") that the imperative mood rule forbids.
+var commonVerbs = []string{
+	"add", "allow", "avoid", "bump", "change", "clean", "create", "delete", "disable", "document", "drop",
+	"enable", "ensure", "extract", "fix", "handle", "implement", "improve", "introduce", "make", "merge",
+	"move", "prevent", "refactor", "remove", "rename", "replace", "return", "revert", "set", "simplify",
+	"support", "test", "update", "upgrade", "use", "validate",
+}
+
+// notParticiples end in "ed" or "ing" while being fine in the imperative mood.
+var notParticiples = []string{"bring", "embed", "exceed", "feed", "need", "proceed", "seed", "shed", "speed", "string"}
+
+// LintMessage checks a commit message against the Conventional Commits rules of
+// DefaultPrompt, plus the blank line separating a body from the subject, and returns
+// the problems found. Comment lines and anything below the scissors line of
+// "git commit --verbose" are ignored, as git strips them. Merge, revert, fixup and
+// squash messages generated by git are accepted as they are.
+func LintMessage(msg string) []string {
+	lines := messageLines(msg)
+	if len(lines) == 0 {
+		return []string{"message is empty"}
+	}
+
+	subject := lines[0]
+
+	for _, prefix := range generatedPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return nil
+		}
+	}
+
+	var problems []string
+
+	if len(lines) > 1 && lines[1] != "" {
+		problems = append(problems, "subject must be followed by a blank line")
+	}
+
+	match := headerPattern.FindStringSubmatch(subject)
+	if match == nil {
+		return append(problems, "subject must follow \"<type>[optional scope]: <description>\"")
+	}
+
+	commitType, scoped, scope, description := match[1], match[2] != "", match[3], match[4]
+
+	if !slices.Contains(commitTypes, commitType) {
+		problems = append(problems, "type "+strconv.Quote(commitType)+" must be one of "+strings.Join(commitTypes, ", "))
+	}
+
+	if scoped && !scopePattern.MatchString(scope) {
+		problems = append(problems, "scope "+strconv.Quote(scope)+" must be a lowercase word")
+	}
+
+	return append(problems, lintDescription(description)...)
+}
+
+// lintDescription checks the description of a subject, after its prefix.
+func lintDescription(description string) []string {
+	if strings.TrimSpace(description) == "" {
+		return []string{"description is empty"}
+	}
+
+	var problems []string
+
+	if n := utf8.RuneCountInString(description); n > maxDescriptionLength {
+		problems = append(problems, "description is "+strconv.Itoa(n)+" characters long, max "+
+			strconv.Itoa(maxDescriptionLength))
+	}
+
+	if first, _ := utf8.DecodeRuneInString(description); unicode.IsUpper(first) {
+		problems = append(problems, "description must start in lowercase")
+	}
+
+	if strings.HasSuffix(description, ".") {
+		problems = append(problems, "description must not end with a period")
+	}
+
+	word := strings.ToLower(strings.Fields(description)[0])
+	if !isImperative(word) {
+		problems = append(problems, "description must use the imperative mood (\""+word+"\")")
+	}
+
+	return problems
+}
+
+// isImperative reports whether word may start a description in the imperative mood.
+// It rejects past tenses and participles ("added", "adding"), and the third person of
+// common verbs ("adds", "fixes"); anything else passes.
+func isImperative(word string) bool {
+	const minLen = 5 // Shorter words ending in "ed" or "ing" are rarely verb forms.
+
+	if len(word) >= minLen && (strings.HasSuffix(word, "ed") || strings.HasSuffix(word, "ing")) {
+		return slices.Contains(notParticiples, word)
+	}
+
+	if stem, ok := strings.CutSuffix(word, "s"); ok {
+		if slices.Contains(commonVerbs, stem) {
+			return false
+		}
+
+		if stem, ok = strings.CutSuffix(stem, "e"); ok && slices.Contains(commonVerbs, stem) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// messageLines returns the lines of msg as git keeps them: without comment lines,
+// nor anything after the scissors line, and with trailing spaces and lines trimmed.
+func messageLines(msg string) []string {
+	var lines []string
+
+	for line := range strings.SplitSeq(msg, "\n") {
+		if line == scissors {
+			break
+		}
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lines = append(lines, strings.TrimRightFunc(line, unicode.IsSpace))
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// Leading blank lines are stripped by git too.
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+
+	return lines
+}
@@ -0,0 +1,79 @@
+package agent_test
+
+import (
+	"strings"
+	"testing"
+
+	"dario.cat/darna/internal/agent"
+)
+
+func TestLintMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		msg  string
+		want []string // Substrings of the expected problems, in order.
+	}{
+		{name: "valid", msg: "feat: add commit message linting\n", want: nil},
+		{name: "scope and breaking", msg: "fix(cmd/darna)!: drop the legacy flag", want: nil},
+		{name: "body", msg: "docs: explain hooks\n\nLonger explanation.\n", want: nil},
+		{
+			name: "comments and scissors",
+			msg: "# Please enter the commit message\nchore: tidy up\n" +
+				"# ------------------------ >8 ------------------------\nDiff.",
+			want: nil,
+		},
+		{name: "merge", msg: "Merge branch 'main' into feature", want: nil},
+		{name: "fixup", msg: "fixup! feat: add commit message linting", want: nil},
+		{name: "empty", msg: "# Only a comment\n\n", want: []string{"message is empty"}},
+		{name: "no type", msg: "add commit message linting", want: []string{"subject must follow"}},
+		{name: "unknown type", msg: "feature: add linting", want: []string{"type \"feature\" must be one of"}},
+		{name: "bad scope", msg: "feat(CLI): add linting", want: []string{"scope \"CLI\""}},
+		{name: "empty scope", msg: "feat(): add linting", want: []string{"scope \"\""}},
+		{name: "no blank line", msg: "feat: add linting\nBody.", want: []string{"blank line"}},
+		{name: "uppercase", msg: "feat: Add linting", want: []string{"lowercase"}},
+		{name: "period", msg: "feat: add linting.", want: []string{"period"}},
+		{name: "past tense", msg: "fix: fixed the parser", want: []string{"imperative mood (\"fixed\")"}},
+		{name: "gerund", msg: "fix: handling of empty files", want: []string{"imperative mood (\"handling\")"}},
+		{name: "third person", msg: "fix: fixes the parser", want: []string{"imperative mood (\"fixes\")"}},
+		{name: "imperative exception", msg: "feat: embed the prompt", want: nil},
+		{
+			name: "too long",
+			msg:  "feat: " + strings.Repeat("a", 73),
+			want: []string{"description is 73 characters long, max 72"},
+		},
+		{
+			name: "several",
+			msg:  "Feat: Added linting.",
+			want: []string{"type \"Feat\"", "lowercase", "period", "imperative mood"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := agent.LintMessage(tt.msg)
+			if len(got) != len(tt.want) {
+				t.Fatalf("LintMessage(%q) = %q, want problems containing %q", tt.msg, got, tt.want)
+			}
+
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("LintMessage(%q)[%d] = %q, want it to contain %q", tt.msg, i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultPromptDescribesLintRules(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{"feat, fix, refactor, docs, test, chore, ci, perf, style, build", "max 72 chars"} {
+		if !strings.Contains(agent.DefaultPrompt, rule) {
+			t.Errorf("DefaultPrompt does not mention %q", rule)
+		}
+	}
+}
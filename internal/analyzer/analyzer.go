@@ -2,17 +2,31 @@
 package analyzer
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/token"
 	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
+	"golang.org/x/tools/go/gcexportdata"
 	"golang.org/x/tools/go/packages"
 )
 
 // ErrPackagesContainErrors is returned when loaded packages have errors.
 var ErrPackagesContainErrors = errors.New("packages contain errors")
 
+// IgnoreDirective suppresses the violations of the declaration it documents or, in a
+// file header, of the whole file. Text after the directive is a free-form reason.
+const IgnoreDirective = "//darna:ignore"
+
 // Symbol represents a symbol (function, type, variable, constant) in Go code.
 type Symbol struct {
 	ID      string         // "pkg/path.SymbolName".
@@ -23,19 +37,131 @@ type Symbol struct {
 	Pos     token.Position // Source position.
 }
 
-// LoadPackages loads Go packages with full type information.
-func LoadPackages(dir string, overlay map[string][]byte, patterns ...string) ([]*packages.Package, error) {
+// Build selects the files of the loaded packages, as the build flags and target platform
+// of the go command do. The zero value selects those of the default build for the host.
+type Build struct {
+	Tags   []string // Build tags satisfied, as given to -tags.
+	GOOS   string   // Target operating system, the host's if empty.
+	GOARCH string   // Target architecture, the host's if empty.
+}
+
+// Env returns the environment of the go command loading the packages of dir, seen
+// through overlay, for the platform of b, or nil to inherit that of the process. Outside
+// any module, as in legacy GOPATH layouts and ad-hoc script dirs, the go command runs in
+// GOPATH mode: packages below GOPATH/src get their import path, and the others one made
+// of their directory, as "_/abs/dir".
+func (b Build) Env(dir string, overlay map[string][]byte) []string {
+	var env []string
+
+	if b.GOOS != "" {
+		env = append(env, "GOOS="+b.GOOS)
+	}
+
+	if b.GOARCH != "" {
+		env = append(env, "GOARCH="+b.GOARCH)
+	}
+
+	if !inModule(dir, overlay) {
+		env = append(env, "GO111MODULE=off")
+	}
+
+	if env == nil {
+		return nil
+	}
+
+	return append(os.Environ(), env...)
+}
+
+// inModule reports whether dir, seen through overlay, belongs to a module or workspace:
+// whether it or a parent directory holds a go.mod or go.work file, or GO111MODULE or
+// GOWORK settle it.
+func inModule(dir string, overlay map[string][]byte) bool {
+	switch os.Getenv("GO111MODULE") {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+
+	if gowork := os.Getenv("GOWORK"); gowork != "" && gowork != "off" {
+		return true
+	}
+
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		for _, name := range []string{"go.mod", "go.work"} {
+			file := filepath.Join(dir, name)
+			if _, ok := overlay[file]; ok {
+				return true
+			}
+
+			if _, err := os.Stat(file); err == nil {
+				return true
+			}
+		}
+
+		if filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
+
+// Flags returns the flags of the go command selecting the files of b.
+func (b Build) Flags() []string {
+	if len(b.Tags) == 0 {
+		return nil
+	}
+
+	return []string{"-tags=" + strings.Join(b.Tags, ",")}
+}
+
+// LoadPackages loads Go packages with full type information, their dependencies
+// included.
+func LoadPackages(
+	ctx context.Context, dir string, overlay map[string][]byte, build Build, patterns ...string,
+) ([]*packages.Package, error) {
+	return load(ctx, dir, overlay, build, loadMode|packages.NeedDeps, patterns)
+}
+
+// LoadPackagesShallow loads the Go packages matching patterns with full type information,
+// and their dependencies with only the types they export: from compiler export data, or
+// type-checked without function bodies when files are overlaid, since export data only
+// reflects the disk. The Imports of the packages carry no syntax nor type information.
+func LoadPackagesShallow(
+	ctx context.Context, dir string, overlay map[string][]byte, build Build, patterns ...string,
+) ([]*packages.Package, error) {
+	mode := loadMode
+
+	// go/packages aborts the process on export data it cannot decode, as that of a Go
+	// toolchain newer than golang.org/x/tools, so such toolchains get a full load.
+	if len(overlay) == 0 && !exportDataReadable(ctx, dir) {
+		mode |= packages.NeedDeps
+	}
+
+	return load(ctx, dir, overlay, build, mode, patterns)
+}
+
+// loadMode is what the loaded packages carry.
+const loadMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedSyntax |
+	packages.NeedTypes |
+	packages.NeedTypesInfo |
+	packages.NeedImports |
+	packages.NeedEmbedFiles |
+	packages.NeedCompiledGoFiles |
+	packages.NeedModule
+
+func load(
+	ctx context.Context, dir string, overlay map[string][]byte, build Build, mode packages.LoadMode, patterns []string,
+) ([]*packages.Package, error) {
 	cfg := &packages.Config{ //nolint:exhaustruct // Optional fields intentionally omitted.
-		Mode: packages.NeedName |
-			packages.NeedFiles |
-			packages.NeedSyntax |
-			packages.NeedTypes |
-			packages.NeedTypesInfo |
-			packages.NeedImports |
-			packages.NeedDeps,
-		Dir:     dir,
-		Overlay: overlay,
-		Tests:   true,
+		Context:    ctx,
+		Mode:       mode,
+		Dir:        dir,
+		Env:        build.Env(dir, overlay),
+		BuildFlags: build.Flags(),
+		Overlay:    overlay,
+		Tests:      true,
 	}
 
 	pkgs, err := packages.Load(cfg, patterns...)
@@ -50,6 +176,49 @@ func LoadPackages(dir string, overlay map[string][]byte, patterns ...string) ([]
 	return pkgs, nil
 }
 
+// exportDataProbes caches exportDataReadable by directory.
+var exportDataProbes sync.Map
+
+// exportDataReadable reports whether the export data of the Go toolchain used in dir can
+// be decoded, trying that of the errors package. Probes cut short by ctx are not cached.
+func exportDataReadable(ctx context.Context, dir string) bool {
+	if readable, ok := exportDataProbes.Load(dir); ok {
+		return readable.(bool) //nolint:forcetypeassert // Only bools are stored.
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "list", "-export", "-f", "{{.Export}}", "errors")
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+
+	readable := err == nil && decodesExportData(strings.TrimSpace(string(out)), "errors")
+	if ctx.Err() == nil {
+		exportDataProbes.Store(dir, readable)
+	}
+
+	return readable
+}
+
+// decodesExportData reports whether the export data file at path, of the package with
+// the given path, can be decoded.
+func decodesExportData(path, pkgPath string) bool {
+	f, err := os.Open(path) //nolint:gosec // Path comes from go list.
+	if err != nil {
+		return false
+	}
+
+	defer func() { _ = f.Close() }()
+
+	r, err := gcexportdata.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return false
+	}
+
+	_, err = gcexportdata.Read(r, token.NewFileSet(), make(map[string]*types.Package), pkgPath)
+
+	return err == nil
+}
+
 // PrintErrors prints all errors from the given packages to stderr.
 // Call this only when the caller has decided errors must be surfaced.
 func PrintErrors(pkgs []*packages.Package) {
@@ -81,7 +250,7 @@ func CollectSymbols(pkg *packages.Package) (defined, used map[string]types.Objec
 			continue // Skip nil and non-package-level definitions.
 		}
 
-		id := symbolID(obj)
+		id := SymbolID(obj)
 		if id != "" {
 			defined[id] = obj
 		}
@@ -94,7 +263,7 @@ func CollectSymbols(pkg *packages.Package) (defined, used map[string]types.Objec
 		}
 
 		if obj.Pkg() != pkg.Types { // External reference.
-			id := symbolID(obj)
+			id := SymbolID(obj)
 			if id != "" {
 				used[id] = obj
 			}
@@ -104,15 +273,45 @@ func CollectSymbols(pkg *packages.Package) (defined, used map[string]types.Objec
 	return defined, used
 }
 
-// symbolID generates a unique identifier for a types.Object.
-func symbolID(obj types.Object) string {
+// SymbolID returns the identifier of a symbol: "pkg/path.Name" for package-level
+// objects and "pkg/path.Type.Method" for methods, so that methods of different types
+// sharing a name stay apart. It returns "" for built-ins, struct fields and the methods
+// of unnamed interfaces, which are no symbols.
+func SymbolID(obj types.Object) string {
 	if obj.Pkg() == nil {
 		return "" // Built-in, skip.
 	}
 
+	switch obj := obj.(type) {
+	case *types.Var:
+		if obj.IsField() {
+			return ""
+		}
+	case *types.Func:
+		if recv := obj.Signature().Recv(); recv != nil {
+			named := receiverNamed(recv.Type())
+			if named == nil {
+				return ""
+			}
+
+			return obj.Pkg().Path() + "." + named.Obj().Name() + "." + obj.Name()
+		}
+	}
+
 	return obj.Pkg().Path() + "." + obj.Name()
 }
 
+// receiverNamed returns the named type a method receiver of type typ belongs to, if any.
+func receiverNamed(typ types.Type) *types.Named {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	named, _ := typ.(*types.Named)
+
+	return named
+}
+
 // ObjectKind returns a string representation of the object kind.
 func ObjectKind(obj types.Object) string {
 	switch obj.(type) {
@@ -128,3 +327,103 @@ func ObjectKind(obj types.Object) string {
 		return "unknown"
 	}
 }
+
+// HasIgnoreDirective reports whether cg contains a line starting with IgnoreDirective.
+func HasIgnoreDirective(cg *ast.CommentGroup) bool {
+	if cg == nil {
+		return false
+	}
+
+	for _, c := range cg.List {
+		rest, ok := strings.CutPrefix(c.Text, IgnoreDirective)
+		if ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsIgnoredFile reports whether a comment before the package clause of file holds
+// IgnoreDirective.
+func IsIgnoredFile(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+
+		if HasIgnoreDirective(cg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EmbedDirective makes the compiler embed the files matching its patterns into the
+// variable it documents.
+const EmbedDirective = "//go:embed"
+
+// EmbedPatterns returns the patterns of the EmbedDirective lines in cg, unquoted, with
+// their "all:" prefix dropped. Malformed quoted patterns are skipped.
+func EmbedPatterns(cg *ast.CommentGroup) []string {
+	if cg == nil {
+		return nil
+	}
+
+	var patterns []string
+
+	for _, c := range cg.List {
+		rest, ok := strings.CutPrefix(c.Text, EmbedDirective)
+		if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+
+		for _, pattern := range splitEmbedPatterns(rest) {
+			patterns = append(patterns, strings.TrimPrefix(pattern, "all:"))
+		}
+	}
+
+	return patterns
+}
+
+// splitEmbedPatterns splits the arguments of an EmbedDirective on spaces, except
+// within Go string literals, which are unquoted.
+func splitEmbedPatterns(args string) []string {
+	var patterns []string
+
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		if args[0] != '"' && args[0] != '`' {
+			end := strings.IndexAny(args, " \t")
+			if end < 0 {
+				end = len(args)
+			}
+
+			patterns = append(patterns, args[:end])
+			args = args[end:]
+
+			continue
+		}
+
+		end := 1
+		for end < len(args) && args[end] != args[0] {
+			if args[0] == '"' && args[end] == '\\' {
+				end++
+			}
+
+			end++
+		}
+
+		if end >= len(args) {
+			break // Unterminated literal.
+		}
+
+		if pattern, err := strconv.Unquote(args[:end+1]); err == nil {
+			patterns = append(patterns, pattern)
+		}
+
+		args = args[end+1:]
+	}
+
+	return patterns
+}
@@ -1,9 +1,11 @@
 package analyzer_test
 
 import (
+	"go/ast"
 	"go/types"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"dario.cat/darna/internal/analyzer"
@@ -85,7 +87,7 @@ const Pi = 3.14
 	}
 
 	// Load the package.
-	pkgs, err := analyzer.LoadPackages(tmpDir, nil, ".")
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
 	if err != nil {
 		t.Fatalf("LoadPackages() error = %v", err)
 	}
@@ -123,3 +125,111 @@ const Pi = 3.14
 		t.Logf("Found %d external usages (this is fine for stdlib deps)", len(used))
 	}
 }
+
+func TestLoadPackagesShallow(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module testpkg\n\ngo 1.24\n",
+		"a/a.go": "package a\n\nimport \"testpkg/b\"\n\nfunc A() { b.B() }\n",
+		"b/b.go": "package b\n\nimport \"strings\"\n\nvar S = strings.ToUpper\n\nfunc B() { C() }\n\nfunc C() {}\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// An overlay keeps dependencies from being read from export data.
+	overlays := map[string]map[string][]byte{
+		"export data": nil,
+		"overlay": {
+			filepath.Join(tmpDir, "a", "a.go"): []byte("package a\n\nimport \"testpkg/b\"\n\nfunc A() { b.C() }\n"),
+		},
+	}
+
+	for name, overlay := range overlays {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pkgs, err := analyzer.LoadPackagesShallow(t.Context(), tmpDir, overlay, analyzer.Build{}, "./a")
+			if err != nil {
+				t.Fatalf("LoadPackagesShallow() error = %v", err)
+			}
+
+			if len(pkgs) != 1 || pkgs[0].TypesInfo == nil {
+				t.Fatalf("Expected package a with type information, got %v", pkgs)
+			}
+
+			want := "B"
+			if overlay != nil {
+				want = "C"
+			}
+
+			_, used := analyzer.CollectSymbols(pkgs[0])
+			if _, ok := used["testpkg/b."+want]; !ok {
+				t.Errorf("Expected a to use testpkg/b.%s, got %v", want, used)
+			}
+		})
+	}
+}
+
+func TestHasIgnoreDirective(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "//darna:ignore", want: true},
+		{text: "//darna:ignore Wired in the next commit.", want: true},
+		{text: "//darna:ignored", want: false},
+		{text: "// darna:ignore", want: false},
+	}
+
+	for _, tt := range tests {
+		cg := &ast.CommentGroup{List: []*ast.Comment{{Slash: 0, Text: tt.text}}}
+		if got := analyzer.HasIgnoreDirective(cg); got != tt.want {
+			t.Errorf("HasIgnoreDirective(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	if analyzer.HasIgnoreDirective(nil) {
+		t.Error("HasIgnoreDirective(nil) = true, want false")
+	}
+}
+
+func TestEmbedPatterns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "//go:embed templates/*.html", want: []string{"templates/*.html"}},
+		{text: "//go:embed a.txt all:static \"with space.txt\" `raw name`", want: []string{
+			"a.txt", "static", "with space.txt", "raw name",
+		}},
+		{text: "//go:embed \"unterminated", want: nil},
+		{text: "//go:embedded a.txt", want: nil},
+		{text: "// go:embed a.txt", want: nil},
+	}
+
+	for _, tt := range tests {
+		cg := &ast.CommentGroup{List: []*ast.Comment{{Slash: 0, Text: tt.text}}}
+		if got := analyzer.EmbedPatterns(cg); !slices.Equal(got, tt.want) {
+			t.Errorf("EmbedPatterns(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
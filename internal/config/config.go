@@ -0,0 +1,394 @@
+// Package config loads darna defaults from configuration files.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the repository config file, looked up at the repo root.
+const FileName = ".darna.toml"
+
+// globalFile is the path of the user config file below the user config directory.
+const globalFile = "darna/config.toml"
+
+// Severities of violations.
+const (
+	SeverityError   = "error"   // Violations fail the run.
+	SeverityWarning = "warning" // Violations are reported without failing the run.
+)
+
+var (
+	errUnknownKey      = errors.New("unknown key")
+	errInvalidValue    = errors.New("invalid value")
+	errInvalidSyntax   = errors.New("invalid syntax")
+	errUnsupportedToml = errors.New("unsupported TOML")
+
+	errUnterminatedArray = fmt.Errorf("%w: unterminated array", errInvalidSyntax)
+)
+
+// Config holds darna defaults. Empty fields are unset and leave the built-in
+// defaults in place.
+type Config struct {
+	Agent      string   // Agent used by --commit-msg=default.
+	PromptFile string   // Prompt file for --commit-msg, absolute once loaded.
+	Exclude    []string // Globs of files exempt from atomicity checks.
+	Include    []string // Globs of the staged files to validate, all if empty.
+	Format     string   // Output format of violations.
+	Severity   string   // SeverityError or SeverityWarning.
+	Color      string   // Color mode: auto, always or never.
+	Theme      string   // Output theme when colorized.
+	TestPolicy string   // How test files take part in validation: strict or lenient.
+	Pairs      []string // Paired-file rules, such as "*.proto -> *.pb.go".
+	Tags       []string // Build tags to load packages with.
+	Platforms  []string // GOOS/GOARCH combinations to analyze, such as "windows/amd64".
+
+	SkipGenerated *bool // Exempt generated files from atomicity checks, nil if unset.
+}
+
+// GlobalPath returns the path of the user config file, $XDG_CONFIG_HOME/darna/config.toml
+// or ~/.config/darna/config.toml, using getenv to read the environment. It returns an
+// empty string when neither variable is set.
+func GlobalPath(getenv func(string) string) string {
+	if dir := getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, globalFile)
+	}
+
+	if home := getenv("HOME"); home != "" {
+		return filepath.Join(home, ".config", globalFile)
+	}
+
+	return ""
+}
+
+// Resolve builds the effective config from, in increasing precedence, the user config
+// at globalPath, the repository config at repoPath and the DARNA_* variables returned
+// by getenv. Empty paths are skipped. Command line flags override the result.
+func Resolve(globalPath, repoPath string, getenv func(string) string) (Config, error) {
+	var cfg Config
+
+	for _, path := range []string{globalPath, repoPath} {
+		if path == "" {
+			continue
+		}
+
+		err := Load(path, &cfg)
+		if err != nil {
+			return cfg, err
+		}
+	}
+
+	ApplyEnv(&cfg, getenv)
+
+	return cfg, nil
+}
+
+// Load reads the TOML file at path into cfg, overriding only the keys the file sets.
+// A missing file leaves cfg untouched. Relative prompt files are resolved against
+// the directory of path.
+//
+// Only a flat subset of TOML is supported: strings, booleans, integers and arrays
+// of strings, without tables.
+func Load(path string, cfg *Config) error {
+	data, err := os.ReadFile(path) //nolint:gosec // Config paths are chosen by darna, not by input.
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	values, err := parse(string(data))
+	if err != nil {
+		return fmt.Errorf("%s:%w", path, err)
+	}
+
+	for _, kv := range values {
+		err = cfg.set(kv, filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, kv.line, err)
+		}
+	}
+
+	return nil
+}
+
+// Environment variables read by ApplyEnv.
+const (
+	EnvAgent      = "DARNA_AGENT"
+	EnvPromptFile = "DARNA_PROMPT_FILE"
+	EnvFormat     = "DARNA_FORMAT"
+)
+
+// ApplyEnv overrides cfg with the non-empty DARNA_* variables returned by getenv,
+// typically os.Getenv.
+func ApplyEnv(cfg *Config, getenv func(string) string) {
+	for name, field := range map[string]*string{
+		EnvAgent:      &cfg.Agent,
+		EnvPromptFile: &cfg.PromptFile,
+		EnvFormat:     &cfg.Format,
+	} {
+		if value := getenv(name); value != "" {
+			*field = value
+		}
+	}
+}
+
+func (c *Config) set(kv keyValue, dir string) error {
+	var err error
+
+	switch kv.key {
+	case "agent":
+		c.Agent, err = kv.str()
+	case "prompt_file":
+		c.PromptFile, err = kv.str()
+		if err == nil && c.PromptFile != "" && !filepath.IsAbs(c.PromptFile) {
+			c.PromptFile = filepath.Join(dir, c.PromptFile)
+		}
+	case "exclude":
+		c.Exclude, err = kv.strs()
+	case "include":
+		c.Include, err = kv.strs()
+	case "format":
+		c.Format, err = kv.str()
+	case "color":
+		c.Color, err = kv.str()
+	case "theme":
+		c.Theme, err = kv.str()
+	case "test_policy":
+		c.TestPolicy, err = kv.str()
+	case "pairs":
+		c.Pairs, err = kv.strs()
+	case "tags":
+		c.Tags, err = kv.strs()
+	case "platforms":
+		c.Platforms, err = kv.strs()
+	case "skip_generated":
+		var skip bool
+
+		skip, err = kv.boolean()
+		c.SkipGenerated = &skip
+	case "severity":
+		c.Severity, err = kv.str()
+		if err == nil && !slices.Contains([]string{SeverityError, SeverityWarning}, c.Severity) {
+			err = fmt.Errorf("%w for severity %q (want error or warning)", errInvalidValue, c.Severity)
+		}
+	default:
+		err = fmt.Errorf("%w %q", errUnknownKey, kv.key)
+	}
+
+	return err
+}
+
+// keyValue is a parsed assignment. value is a string, bool, int64 or []string.
+type keyValue struct {
+	key   string
+	value any
+	line  int
+}
+
+func (kv keyValue) str() (string, error) {
+	s, ok := kv.value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w for %s: want a string", errInvalidValue, kv.key)
+	}
+
+	return s, nil
+}
+
+func (kv keyValue) boolean() (bool, error) {
+	b, ok := kv.value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w for %s: want a boolean", errInvalidValue, kv.key)
+	}
+
+	return b, nil
+}
+
+func (kv keyValue) strs() ([]string, error) {
+	s, ok := kv.value.([]string)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s: want an array of strings", errInvalidValue, kv.key)
+	}
+
+	return s, nil
+}
+
+// parse returns the assignments of a TOML document in order. Arrays may span lines.
+func parse(doc string) ([]keyValue, error) {
+	var values []keyValue
+
+	lines := strings.Split(doc, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("%d: %w: tables are not supported", lineNo, errUnsupportedToml)
+		}
+
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: %w: want key = value", lineNo, errInvalidSyntax)
+		}
+
+		key = strings.TrimSpace(key)
+		rest = strings.TrimSpace(rest)
+
+		// Join the lines of a multi-line array.
+		for strings.HasPrefix(rest, "[") && !arrayClosed(rest) && i+1 < len(lines) {
+			i++
+			rest += "\n" + lines[i]
+		}
+
+		value, err := parseValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", lineNo, err)
+		}
+
+		values = append(values, keyValue{key: key, value: value, line: lineNo})
+	}
+
+	return values, nil
+}
+
+// arrayClosed reports whether s, which starts an array, contains its closing bracket.
+func arrayClosed(s string) bool {
+	_, _, err := parseArray(s)
+
+	return !errors.Is(err, errUnterminatedArray)
+}
+
+// parseValue parses a value followed by an optional comment.
+func parseValue(s string) (any, error) {
+	var (
+		value any
+		rest  string
+		err   error
+	)
+
+	switch {
+	case strings.HasPrefix(s, "["):
+		value, rest, err = parseArray(s)
+	case strings.HasPrefix(s, `"`), strings.HasPrefix(s, "'"):
+		value, rest, err = parseString(s)
+	default:
+		token, _, _ := strings.Cut(s, "#")
+		value, err = parseScalar(strings.TrimSpace(token))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return nil, fmt.Errorf("%w: unexpected %q after value", errInvalidSyntax, rest)
+	}
+
+	return value, nil
+}
+
+func parseScalar(token string) (any, error) {
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	n, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported value %q", errInvalidSyntax, token)
+	}
+
+	return n, nil
+}
+
+// parseString parses a basic ("...") or literal ('...') string at the start of s.
+func parseString(s string) (string, string, error) {
+	if s[0] == '\'' {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("%w: unterminated string", errInvalidSyntax)
+		}
+
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			str, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("%w: %w", errInvalidSyntax, err)
+			}
+
+			return str, s[i+1:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: unterminated string", errInvalidSyntax)
+}
+
+// parseArray parses an array of strings at the start of s, allowing comments and a
+// trailing comma.
+func parseArray(s string) ([]string, string, error) {
+	items := []string{}
+	rest := s[1:]
+	expectItem := true
+
+	for {
+		rest = skipSpaceAndComments(rest)
+
+		switch {
+		case rest == "":
+			return nil, "", errUnterminatedArray
+		case rest[0] == ']':
+			return items, rest[1:], nil
+		case rest[0] == ',' && !expectItem:
+			rest = rest[1:]
+			expectItem = true
+		case (rest[0] == '"' || rest[0] == '\'') && expectItem:
+			item, after, err := parseString(rest)
+			if err != nil {
+				return nil, "", err
+			}
+
+			items = append(items, item)
+			rest = after
+			expectItem = false
+		default:
+			return nil, "", fmt.Errorf("%w: arrays may only hold strings", errUnsupportedToml)
+		}
+	}
+}
+
+func skipSpaceAndComments(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if !strings.HasPrefix(s, "#") {
+			return s
+		}
+
+		_, after, found := strings.Cut(s, "\n")
+		if !found {
+			return ""
+		}
+
+		s = after
+	}
+}
@@ -0,0 +1,196 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dario.cat/darna/internal/config"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), config.FileName)
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfig(t, `# darna defaults
+agent = "codex"
+prompt_file = 'prompts/commit.txt' # Relative to the config.
+format = "json"
+severity = "warning"
+exclude = [
+  "vendor",   # Whole directory.
+  'gen/*.go',
+]
+include = ["internal"]
+test_policy = "lenient"
+skip_generated = true
+pairs = ["*.proto -> *.pb.go"]
+tags = ["integration", "wireinject"]
+platforms = ["linux/amd64", "windows/amd64"]
+`)
+
+	var conf config.Config
+
+	conf.Agent = "claude"
+
+	err := config.Load(path, &conf)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if conf.Agent != "codex" || conf.Format != "json" || conf.Severity != config.SeverityWarning {
+		t.Errorf("Unexpected config %+v", conf)
+	}
+
+	if want := filepath.Join(filepath.Dir(path), "prompts", "commit.txt"); conf.PromptFile != want {
+		t.Errorf("PromptFile = %q, want %q", conf.PromptFile, want)
+	}
+
+	if want := []string{"vendor", "gen/*.go"}; !slices.Equal(conf.Exclude, want) {
+		t.Errorf("Exclude = %v, want %v", conf.Exclude, want)
+	}
+
+	if want := []string{"internal"}; !slices.Equal(conf.Include, want) {
+		t.Errorf("Include = %v, want %v", conf.Include, want)
+	}
+
+	if conf.TestPolicy != "lenient" {
+		t.Errorf("TestPolicy = %q, want lenient", conf.TestPolicy)
+	}
+
+	if conf.SkipGenerated == nil || !*conf.SkipGenerated {
+		t.Errorf("SkipGenerated = %v, want true", conf.SkipGenerated)
+	}
+
+	if want := []string{"*.proto -> *.pb.go"}; !slices.Equal(conf.Pairs, want) {
+		t.Errorf("Pairs = %v, want %v", conf.Pairs, want)
+	}
+
+	if want := []string{"integration", "wireinject"}; !slices.Equal(conf.Tags, want) {
+		t.Errorf("Tags = %v, want %v", conf.Tags, want)
+	}
+
+	if want := []string{"linux/amd64", "windows/amd64"}; !slices.Equal(conf.Platforms, want) {
+		t.Errorf("Platforms = %v, want %v", conf.Platforms, want)
+	}
+}
+
+func TestLoadKeepsUnsetKeys(t *testing.T) {
+	t.Parallel()
+
+	var conf config.Config
+
+	conf.Agent, conf.Format = "claude", "sarif"
+
+	err := config.Load(writeConfig(t, "format = \"github\"\n"), &conf)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if conf.Agent != "claude" || conf.Format != "github" {
+		t.Errorf("Load() = %+v, want agent kept and format overridden", conf)
+	}
+
+	err = config.Load(filepath.Join(t.TempDir(), config.FileName), &conf)
+	if err != nil {
+		t.Errorf("Load() of a missing file: %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown key", content: "colour = \"red\"\n"},
+		{name: "wrong type", content: "agent = true\n"},
+		{name: "not a boolean", content: "skip_generated = \"yes\"\n"},
+		{name: "bad severity", content: "severity = \"fatal\"\n"},
+		{name: "table", content: "[validate]\nformat = \"json\"\n"},
+		{name: "unterminated array", content: "exclude = [\"vendor\"\n"},
+		{name: "unterminated string", content: "agent = \"codex\n"},
+		{name: "trailing garbage", content: "agent = \"codex\" extra\n"},
+		{name: "missing value", content: "agent\n"},
+	}
+
+	for _, tt := range tests {
+		var conf config.Config
+
+		err := config.Load(writeConfig(t, tt.content), &conf)
+		if err == nil {
+			t.Errorf("%s: Load() succeeded with %+v, want an error", tt.name, conf)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{config.EnvAgent: "mistral", config.EnvFormat: "sarif"}
+	var conf config.Config
+
+	conf.Agent, conf.PromptFile, conf.Format = "codex", "prompt.txt", "json"
+
+	config.ApplyEnv(&conf, func(name string) string { return env[name] })
+
+	if conf.Agent != "mistral" || conf.Format != "sarif" || conf.PromptFile != "prompt.txt" {
+		t.Errorf("ApplyEnv() = %+v, want agent and format from the environment only", conf)
+	}
+}
+
+func TestGlobalPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{env: map[string]string{"XDG_CONFIG_HOME": "/xdg", "HOME": "/home/u"}, want: "/xdg/darna/config.toml"},
+		{env: map[string]string{"HOME": "/home/u"}, want: "/home/u/.config/darna/config.toml"},
+		{env: map[string]string{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := config.GlobalPath(func(name string) string { return tt.env[name] }); got != filepath.FromSlash(tt.want) {
+			t.Errorf("GlobalPath(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	t.Parallel()
+
+	global := writeConfig(t, "agent = \"claude\"\ncolor = \"never\"\nformat = \"json\"\ntheme = \"mono\"\n")
+	repo := writeConfig(t, "format = \"sarif\"\ntheme = \"color-blind\"\n")
+	env := map[string]string{config.EnvFormat: "github"}
+
+	conf, err := config.Resolve(global, repo, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	// Global only: agent and color; repo over global: theme; environment over both: format.
+	if conf.Agent != "claude" || conf.Color != "never" || conf.Theme != "color-blind" || conf.Format != "github" {
+		t.Errorf("Resolve() = %+v", conf)
+	}
+
+	_, err = config.Resolve(writeConfig(t, "bogus = 1\n"), repo, func(string) string { return "" })
+	if err == nil {
+		t.Error("Expected an error from an invalid global config")
+	}
+}
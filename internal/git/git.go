@@ -4,11 +4,18 @@ package git
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+// ErrUnexpectedOutput is returned when git output cannot be parsed.
+var ErrUnexpectedOutput = errors.New("unexpected git output")
+
 // GetStagedFiles returns the list of staged files in the specified directory.
 // Only includes files that are added, copied, modified, or renamed (not deleted).
 func GetStagedFiles(ctx context.Context, dir string) ([]string, error) {
@@ -63,6 +70,168 @@ func GetAllFileStatus(ctx context.Context, dir string) (map[string]FileStatus, e
 		return nil, fmt.Errorf("getting file status: %w", err)
 	}
 
+	return parsePorcelain(output), nil
+}
+
+// GetFileStatus is like GetAllFileStatus but bounds how deep untracked directories are scanned.
+// Untracked files nested in at most untrackedDepth directory levels are listed individually;
+// deeper untracked directories are reported collapsed as "dir/" entries. A depth of zero
+// lists every untracked file.
+func GetFileStatus(ctx context.Context, dir string, untrackedDepth int) (map[string]FileStatus, error) {
+	if untrackedDepth <= 0 {
+		return GetAllFileStatus(ctx, dir)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"status", "--porcelain", "-z", "--untracked-files=normal")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	status := parsePorcelain(output)
+
+	untracked, err := listUntracked(ctx, dir, untrackedDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range untracked {
+		status[file] = FileStatus{Staging: '?', Worktree: '?'}
+	}
+
+	return status, nil
+}
+
+// listUntracked lists untracked, non-ignored files at most depth directory levels deep.
+// Git reports untracked directories collapsed, without entering them; only those within
+// the bound are then read, level by level, so nothing past it is ever walked.
+func listUntracked(ctx context.Context, dir string, depth int) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"ls-files", "--others", "--exclude-standard", "--directory", "-z")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+
+	var files, nested []string
+
+	for _, entry := range splitNUL(output) {
+		switch {
+		case strings.Count(entry, "/") > depth:
+			// A file, or a directory whose files all are, past the bound.
+		case strings.HasSuffix(entry, "/"):
+			found, readErr := readUntracked(dir, entry, depth)
+			if readErr != nil {
+				return nil, readErr
+			}
+
+			nested = append(nested, found...)
+		default:
+			files = append(files, entry)
+		}
+	}
+
+	if len(nested) == 0 {
+		return files, nil
+	}
+
+	// Git did not look inside the directories, so their ignore rules are still to apply.
+	ignored, err := checkIgnored(ctx, dir, nested)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range nested {
+		if !ignored[file] {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// readUntracked lists the files below the untracked directory prefix, a slash-terminated
+// path relative to dir, that are at most depth directory levels deep. A nested repository
+// is listed as the directory itself, as git does.
+func readUntracked(dir, prefix string, depth int) ([]string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(prefix))
+
+	_, err := os.Lstat(filepath.Join(path, ".git"))
+	if err == nil {
+		return []string{prefix}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+
+	var files []string
+
+	for _, entry := range entries {
+		name := prefix + entry.Name()
+
+		if !entry.IsDir() {
+			files = append(files, name)
+
+			continue
+		}
+
+		if strings.Count(name, "/") >= depth {
+			continue
+		}
+
+		found, readErr := readUntracked(dir, name+"/", depth)
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		files = append(files, found...)
+	}
+
+	return files, nil
+}
+
+// checkIgnored reports which of the given paths, relative to dir, git ignores.
+func checkIgnored(ctx context.Context, dir string, paths []string) (map[string]bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"check-ignore", "--stdin", "-z")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+
+	output, err := cmd.Output()
+
+	// Exit status 1 means that none of the paths is ignored.
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() != 1) {
+		return nil, fmt.Errorf("checking ignored files: %w", err)
+	}
+
+	ignored := make(map[string]bool)
+	for _, path := range splitNUL(output) {
+		ignored[path] = true
+	}
+
+	return ignored, nil
+}
+
+// splitNUL splits NUL-separated git output (-z) into non-empty entries.
+func splitNUL(output []byte) []string {
+	var entries []string
+
+	for entry := range bytes.SplitSeq(output, []byte{0}) {
+		if len(entry) > 0 {
+			entries = append(entries, string(entry))
+		}
+	}
+
+	return entries
+}
+
+// parsePorcelain parses NUL-separated git status --porcelain output.
+func parsePorcelain(output []byte) map[string]FileStatus {
 	status := make(map[string]FileStatus)
 
 	entries := bytes.SplitSeq(output, []byte{0})
@@ -75,7 +244,52 @@ func GetAllFileStatus(ctx context.Context, dir string) (map[string]FileStatus, e
 		}
 	}
 
-	return status, nil
+	return status
+}
+
+// StageFiles adds the given paths, relative to dir, to the index.
+func StageFiles(ctx context.Context, dir string, paths ...string) error {
+	args := append([]string{"-C", dir, "add", "--"}, paths...)
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // Paths come from validation results.
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("staging files: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// UnstageFiles removes the staged changes of the given paths, relative to dir, from
+// the index, keeping the working tree untouched.
+func UnstageFiles(ctx context.Context, dir string, paths ...string) error {
+	args := append([]string{"-C", dir, "reset", "-q", "--"}, paths...)
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // Paths come from validation results.
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unstaging files: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// Commit records the given paths, relative to dir, in a new commit with message. Like
+// git commit with pathspecs, it commits their working tree content, staged or not, and
+// updates the index to match; changes staged for other paths stay in the index.
+func Commit(ctx context.Context, dir, message string, paths ...string) error {
+	args := append([]string{"-C", dir, "commit", "-q", "-m", message, "--"}, paths...)
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // Paths come from a commit plan.
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("committing: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
 }
 
 // GetStagedContent reads the staged content of a file from the git index in the specified directory.
@@ -92,11 +306,303 @@ func GetStagedContent(ctx context.Context, dir, path string) ([]byte, error) {
 	return output, nil
 }
 
-// GetStagedDiff returns the unified diff of staged changes in the specified directory.
-// This represents what would be committed (git diff --cached).
-func GetStagedDiff(ctx context.Context, dir string) (string, error) {
+// GetFileAtRevision reads the content of a file at the given revision (e.g. "HEAD") in the specified directory.
+func GetFileAtRevision(ctx context.Context, dir, rev, path string) ([]byte, error) {
+	//nolint:gosec // Revision and path come from caller-controlled config.
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "show", rev+":"+path)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("getting content of %s at %s: %w", path, rev, err)
+	}
+
+	return output, nil
+}
+
+// GetBranch returns the name of the checked out branch, or "HEAD" when detached.
+func GetBranch(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"rev-parse", "--abbrev-ref", "HEAD")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting branch: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetTopLevel returns the absolute path of the root of the working tree containing dir.
+func GetTopLevel(ctx context.Context, dir string) (string, error) {
 	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
-		"diff", "--cached")
+		"rev-parse", "--show-toplevel")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting top level: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetHooksDir returns the absolute path of the hooks directory of the repository at dir,
+// honouring core.hooksPath.
+func GetHooksDir(ctx context.Context, dir string) (string, error) {
+	return GetGitPath(ctx, dir, "hooks")
+}
+
+// GetGitPath returns the absolute path of name inside the git directory of the
+// repository at dir, as "git rev-parse --git-path" resolves it.
+func GetGitPath(ctx context.Context, dir, name string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"rev-parse", "--path-format=absolute", "--git-path", name)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting git path %s: %w", name, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetRemoteURL returns the configured URL of the named remote.
+func GetRemoteURL(ctx context.Context, dir, remote string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // Remote comes from caller-controlled config.
+		"remote", "get-url", remote)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting URL of remote %s: %w", remote, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// InitBare creates a bare repository at dir, or leaves the one there untouched.
+func InitBare(ctx context.Context, dir string) error {
+	cmd := exec.CommandContext(ctx, "git", "init", "-q", "--bare", dir) //nolint:gosec // dir comes from the caller.
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("initializing %s: %w (%s)", dir, err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// Fetch fetches refspecs from the repository at remoteURL into dir. A non-empty
+// authHeader is sent as the Authorization header of HTTP requests; it is passed through
+// the environment so that it neither shows in the process list nor lands in the config.
+func Fetch(ctx context.Context, dir, remoteURL, authHeader string, refspecs ...string) error {
+	args := append([]string{"-C", dir, "fetch", "-q", "--no-tags", "--", remoteURL}, refspecs...)
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // URL and refspecs come from the caller.
+
+	if authHeader != "" {
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: "+authHeader,
+		)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("fetching: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// ZeroRev is the all-zero object name git hooks use for a ref that does not exist.
+const ZeroRev = "0000000000000000000000000000000000000000"
+
+// RevList returns the commits reachable from newRev but not from oldRev, oldest first.
+// When oldRev is ZeroRev (a new ref), commits already reachable from any existing ref are excluded.
+func RevList(ctx context.Context, dir, oldRev, newRev string) ([]string, error) {
+	args := []string{"-C", dir, "rev-list", "--reverse", "--topo-order"}
+	if oldRev == ZeroRev {
+		args = append(args, newRev, "--not", "--all")
+	} else {
+		args = append(args, oldRev+".."+newRev)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // Revisions come from caller-controlled config.
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing revisions: %w", err)
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+// ResolveCommit returns the full hash of the commit rev names.
+func ResolveCommit(ctx context.Context, dir, rev string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // Revision comes from caller-controlled config.
+		"rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", rev, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetCommitSubject returns the first line of the message of commit.
+func GetCommitSubject(ctx context.Context, dir, commit string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // Commit comes from caller-controlled config.
+		"log", "-1", "--format=%s", commit)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting subject of %s: %w", commit, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetCommitFiles returns the files changed by a commit relative to its first parent.
+// Root commits list all their files.
+func GetCommitFiles(ctx context.Context, dir, commit string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // Commit comes from caller-controlled config.
+		"diff-tree", "--root", "--no-commit-id", "-r", "--name-only", "-z", commit)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("getting files of %s: %w", commit, err)
+	}
+
+	return splitNUL(output), nil
+}
+
+// GetChangedFiles returns the files that differ between two revisions. An empty toRev
+// compares fromRev with the working tree, untracked files aside.
+func GetChangedFiles(ctx context.Context, dir, fromRev, toRev string) ([]string, error) {
+	args := []string{"-C", dir, "diff", "--name-only", "-z", fromRev}
+	if toRev != "" {
+		args = append(args, toRev)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // Revisions come from caller-controlled config.
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("getting changes between %s and %s: %w", fromRev, toRev, err)
+	}
+
+	return splitNUL(output), nil
+}
+
+// GetTreeFiles lists every file in the tree of a revision.
+func GetTreeFiles(ctx context.Context, dir, rev string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // Revision comes from caller-controlled config.
+		"ls-tree", "-r", "--name-only", "-z", rev)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing tree of %s: %w", rev, err)
+	}
+
+	return splitNUL(output), nil
+}
+
+// GetMergedTags lists the tags reachable from a revision.
+func GetMergedTags(ctx context.Context, dir, rev string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // Revision comes from caller-controlled config.
+		"tag", "--list", "--merged", rev)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w", rev, err)
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+// GetIndexFiles lists every file in the index. Their content is read with
+// GetFilesAtRevision and an empty revision.
+func GetIndexFiles(ctx context.Context, dir string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"ls-files", "-z")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing index files: %w", err)
+	}
+
+	return splitNUL(output), nil
+}
+
+// CheckoutIndex writes every file of the index below dest, at the same paths relative
+// to the repository root, without touching the working tree.
+func CheckoutIndex(ctx context.Context, dir, dest string) error {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir and dest come from the caller.
+		"checkout-index", "--all", "--force", "--prefix="+filepath.Clean(dest)+string(filepath.Separator))
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("checking out index: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// GetFilesAtRevision reads the content of several files at a revision with a single
+// git cat-file process. Paths missing from the revision are omitted from the result.
+func GetFilesAtRevision(ctx context.Context, dir, rev string, paths []string) (map[string][]byte, error) {
+	var input bytes.Buffer
+	for _, path := range paths {
+		input.WriteString(rev + ":" + path + "\n")
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, //nolint:gosec // dir comes from caller-controlled config.
+		"cat-file", "--batch")
+	cmd.Stdin = &input
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading files at %s: %w", rev, err)
+	}
+
+	contents := make(map[string][]byte, len(paths))
+
+	for _, path := range paths {
+		header, rest, found := bytes.Cut(output, []byte{'\n'})
+		if !found {
+			return nil, fmt.Errorf("%w: truncated cat-file output for %s", ErrUnexpectedOutput, path)
+		}
+
+		fields := strings.Fields(string(header))
+		if len(fields) != 3 { //nolint:mnd // "<object> <type> <size>"; missing objects have two fields.
+			output = rest
+
+			continue
+		}
+
+		size, convErr := strconv.Atoi(fields[2])
+		if convErr != nil || len(rest) < size+1 {
+			return nil, fmt.Errorf("%w: bad cat-file header %q", ErrUnexpectedOutput, header)
+		}
+
+		contents[path] = rest[:size]
+		output = rest[size+1:] // Skip the content and its trailing newline.
+	}
+
+	return contents, nil
+}
+
+// GetStagedDiff returns the unified diff of staged changes in the specified directory.
+// This represents what would be committed (git diff --cached). When paths are given,
+// the diff is limited to those paths.
+func GetStagedDiff(ctx context.Context, dir string, paths ...string) (string, error) {
+	args := []string{"-C", dir, "diff", "--cached"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // dir and paths come from caller-controlled config.
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -106,6 +612,67 @@ func GetStagedDiff(ctx context.Context, dir string) (string, error) {
 	return string(output), nil
 }
 
+// GetStagedHunks returns the diff of staged changes without context lines
+// (git diff --cached -U0), so each hunk only covers changed lines. When paths are
+// given, the diff is limited to those paths.
+func GetStagedHunks(ctx context.Context, dir string, paths ...string) (string, error) {
+	args := []string{"-C", dir, "diff", "--cached", "-U0", "--no-color", "--no-ext-diff"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // dir and paths come from caller-controlled config.
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting staged hunks: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// GetUnstagedDiff returns the diff of unstaged changes of tracked files without context
+// lines (git diff -U0), so each hunk only covers changed lines. When paths are given,
+// the diff is limited to those paths.
+func GetUnstagedDiff(ctx context.Context, dir string, paths ...string) (string, error) {
+	args := []string{"-C", dir, "diff", "-U0", "--no-color", "--no-ext-diff"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // dir and paths come from caller-controlled config.
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting unstaged diff: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// GetDiff returns the diff between two revisions without context lines (git diff -U0),
+// so each hunk only covers changed lines. An empty toRev compares fromRev with the
+// working tree. When paths are given, the diff is limited to those paths.
+func GetDiff(ctx context.Context, dir, fromRev, toRev string, paths ...string) (string, error) {
+	args := []string{"-C", dir, "diff", "-U0", "--no-color", "--no-ext-diff", fromRev}
+	if toRev != "" {
+		args = append(args, toRev)
+	}
+
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // Revisions and paths come from the caller.
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getting diff between %s and %s: %w", fromRev, toRev, err)
+	}
+
+	return string(output), nil
+}
+
 // FilterGoFiles filters a list of files to only include .go files.
 func FilterGoFiles(files []string) []string {
 	var goFiles []string
@@ -135,6 +135,138 @@ func TestGetStagedDiffEmpty(t *testing.T) {
 	}
 }
 
+func TestGetFileStatusUntrackedDepth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+
+	writeTestFile(t, filepath.Join(dir, "hello.txt"), "hello\n")
+	runGit(t, dir, "add", "hello.txt")
+	runGit(t, dir, "commit", "-m", "initial")
+
+	// Untracked files: one at the root, one shallow, one deep inside a vendor-like tree.
+	deep := filepath.Join(dir, "node_modules", "pkg", "lib", "internal")
+
+	err := os.MkdirAll(deep, 0o750)
+	if err != nil {
+		t.Fatalf("creating %s: %v", deep, err)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "root.go"), "package main\n")
+	writeTestFile(t, filepath.Join(dir, "node_modules", "top.go"), "package top\n")
+	writeTestFile(t, filepath.Join(deep, "stray.go"), "package stray\n")
+
+	status, err := git.GetFileStatus(context.Background(), dir, 1)
+	if err != nil {
+		t.Fatalf("GetFileStatus: %v", err)
+	}
+
+	for _, want := range []string{"root.go", "node_modules/top.go", "node_modules/"} {
+		if _, ok := status[want]; !ok {
+			t.Errorf("GetFileStatus missing %q: %v", want, status)
+		}
+	}
+
+	if _, ok := status["node_modules/pkg/lib/internal/stray.go"]; ok {
+		t.Errorf("GetFileStatus listed a file beyond the depth bound: %v", status)
+	}
+
+	all, err := git.GetFileStatus(context.Background(), dir, 0)
+	if err != nil {
+		t.Fatalf("GetFileStatus: %v", err)
+	}
+
+	if _, ok := all["node_modules/pkg/lib/internal/stray.go"]; !ok {
+		t.Errorf("GetFileStatus with unlimited depth missed the deep file: %v", all)
+	}
+}
+
+func TestGetFileStatusUntrackedDepthSkipsDeepTree(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() == 0 {
+		t.Skip("root reads unreadable directories")
+	}
+
+	dir := t.TempDir()
+
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+
+	writeTestFile(t, filepath.Join(dir, ".gitignore"), "*.log\n")
+	runGit(t, dir, "add", ".gitignore")
+	runGit(t, dir, "commit", "-m", "initial")
+
+	// Reading the unreadable tree past the bound would fail the listing.
+	deep := filepath.Join(dir, "node_modules", "pkg", "lib")
+
+	err := os.MkdirAll(deep, 0o750)
+	if err != nil {
+		t.Fatalf("creating %s: %v", deep, err)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "node_modules", "top.go"), "package top\n")
+	writeTestFile(t, filepath.Join(dir, "node_modules", "debug.log"), "ignored\n")
+	writeTestFile(t, filepath.Join(deep, "stray.go"), "package stray\n")
+
+	err = os.Chmod(deep, 0)
+	if err != nil {
+		t.Fatalf("making %s unreadable: %v", deep, err)
+	}
+
+	t.Cleanup(func() { _ = os.Chmod(deep, 0o750) }) //nolint:gosec // Lets TempDir remove the tree.
+
+	status, err := git.GetFileStatus(context.Background(), dir, 1)
+	if err != nil {
+		t.Fatalf("GetFileStatus walked past the depth bound: %v", err)
+	}
+
+	if _, ok := status["node_modules/top.go"]; !ok {
+		t.Errorf("GetFileStatus missing %q: %v", "node_modules/top.go", status)
+	}
+
+	if _, ok := status["node_modules/debug.log"]; ok {
+		t.Errorf("GetFileStatus listed an ignored file: %v", status)
+	}
+}
+
+func TestGetFilesAtRevision(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "alpha\n")
+	writeTestFile(t, filepath.Join(dir, "b.txt"), "")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "initial")
+
+	contents, err := git.GetFilesAtRevision(context.Background(), dir, "HEAD", []string{"a.txt", "missing.txt", "b.txt"})
+	if err != nil {
+		t.Fatalf("GetFilesAtRevision: %v", err)
+	}
+
+	if string(contents["a.txt"]) != "alpha\n" {
+		t.Errorf("a.txt = %q, want %q", contents["a.txt"], "alpha\n")
+	}
+
+	if content, ok := contents["b.txt"]; !ok || len(content) != 0 {
+		t.Errorf("b.txt = %q (present %v), want empty and present", content, ok)
+	}
+
+	if _, ok := contents["missing.txt"]; ok {
+		t.Error("missing.txt should not be returned")
+	}
+}
+
 func runGit(t *testing.T, dir string, args ...string) {
 	t.Helper()
 
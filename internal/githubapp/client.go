@@ -0,0 +1,228 @@
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultAPIURL is the REST API of github.com. GitHub Enterprise Server instances serve
+// theirs at https://<host>/api/v3.
+const DefaultAPIURL = "https://api.github.com"
+
+// jwtLifetime is how long the app JWTs are valid for; GitHub accepts at most 10 minutes.
+const jwtLifetime = 9 * time.Minute
+
+// jwtClockSkew backdates the JWTs, as GitHub recommends, in case its clock is behind.
+const jwtClockSkew = time.Minute
+
+var (
+	// ErrPrivateKey is returned for PEM data holding no RSA private key.
+	ErrPrivateKey = errors.New("no RSA private key found")
+
+	errAPIStatus = errors.New("unexpected GitHub API response status")
+)
+
+// ParsePrivateKey parses the PEM encoded private key of a GitHub App, as downloaded
+// from its settings (PKCS #1) or converted to PKCS #8.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrPrivateKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPrivateKey, err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrPrivateKey
+	}
+
+	return rsaKey, nil
+}
+
+// client calls the GitHub REST API as a GitHub App.
+type client struct {
+	http   *http.Client
+	apiURL string
+	appID  int64
+	key    *rsa.PrivateKey
+	now    func() time.Time
+}
+
+// appJWT returns a JWT authenticating as the app itself, signed with RS256.
+func (c *client) appJWT() (string, error) {
+	now := c.now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT: %w", err)
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(c.appID, 10),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing JWT: %w", err)
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// installationToken returns a token acting on the repositories of an installation.
+func (c *client) installationToken(ctx context.Context, installationID int64) (string, error) {
+	jwt, err := c.appJWT()
+	if err != nil {
+		return "", err
+	}
+
+	var reply struct {
+		Token string `json:"token"`
+	}
+
+	path := "/app/installations/" + strconv.FormatInt(installationID, 10) + "/access_tokens"
+
+	err = c.do(ctx, http.MethodPost, path, jwt, nil, &reply)
+	if err != nil {
+		return "", fmt.Errorf("getting installation token: %w", err)
+	}
+
+	return reply.Token, nil
+}
+
+// checkRun is the subset of the check run fields darna sets.
+type checkRun struct {
+	Name       string          `json:"name,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"` //nolint:tagliatelle // GitHub API field.
+	Status     string          `json:"status,omitempty"`
+	Conclusion string          `json:"conclusion,omitempty"`
+	Output     *checkRunOutput `json:"output,omitempty"`
+}
+
+type checkRunOutput struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Annotations []annotation `json:"annotations,omitempty"`
+}
+
+type annotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`             //nolint:tagliatelle // GitHub API field.
+	EndLine         int    `json:"end_line"`               //nolint:tagliatelle // GitHub API field.
+	StartColumn     int    `json:"start_column,omitempty"` //nolint:tagliatelle // GitHub API field.
+	EndColumn       int    `json:"end_column,omitempty"`   //nolint:tagliatelle // GitHub API field.
+	AnnotationLevel string `json:"annotation_level"`       //nolint:tagliatelle // GitHub API field.
+	Title           string `json:"title"`
+	Message         string `json:"message"`
+}
+
+// createCheckRun creates a check run in repo, "owner/name", and returns its ID.
+func (c *client) createCheckRun(ctx context.Context, token, repo string, run checkRun) (int64, error) {
+	var reply struct {
+		ID int64 `json:"id"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/repos/"+repo+"/check-runs", token, run, &reply)
+	if err != nil {
+		return 0, fmt.Errorf("creating check run: %w", err)
+	}
+
+	return reply.ID, nil
+}
+
+// updateCheckRun updates a check run of repo. Annotations add to those already sent.
+func (c *client) updateCheckRun(ctx context.Context, token, repo string, id int64, run checkRun) error {
+	path := "/repos/" + repo + "/check-runs/" + strconv.FormatInt(id, 10)
+
+	err := c.do(ctx, http.MethodPatch, path, token, run, nil)
+	if err != nil {
+		return fmt.Errorf("updating check run: %w", err)
+	}
+
+	return nil
+}
+
+// do sends a request authenticated with the bearer token, encoding body and decoding
+// the response into out when they are not nil.
+func (c *client) do(ctx context.Context, method, path, token string, body, out any) error {
+	var reqBody io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling %s %s: %w", method, path, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return fmt.Errorf("%w: %s %s: %d", errAPIStatus, method, path, resp.StatusCode)
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+	}
+
+	return nil
+}
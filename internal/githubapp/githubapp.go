@@ -0,0 +1,381 @@
+// Package githubapp implements a GitHub App that checks the atomicity of every commit
+// of pull requests: it receives pull_request webhooks, fetches the pull request into a
+// bare cache repository, audits its commits and reports them as a check run with an
+// annotation per violation.
+package githubapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/validator"
+)
+
+// CheckName is the name of the check runs the app creates.
+const CheckName = "darna"
+
+// maxPayloadSize is the largest webhook payload GitHub delivers.
+const maxPayloadSize = 25 << 20
+
+// checkTimeout bounds how long checking a pull request may take.
+const checkTimeout = 10 * time.Minute
+
+// maxAnnotations is how many annotations GitHub accepts per check run request.
+const maxAnnotations = 50
+
+// checkedActions are the pull_request actions that bring commits to check.
+var checkedActions = []string{"opened", "reopened", "synchronize"}
+
+var errMissingInstallation = errors.New("webhook has no installation; deliveries must come from a GitHub App")
+
+// Config configures a Server.
+type Config struct {
+	AppID         int64           // ID of the GitHub App.
+	PrivateKey    *rsa.PrivateKey // Private key of the app, see ParsePrivateKey.
+	WebhookSecret []byte          // Secret the webhook deliveries are signed with.
+	CacheDir      string          // Where the bare repositories pull requests are fetched into live.
+
+	APIURL     string             // REST API URL, DefaultAPIURL when empty.
+	HTTPClient *http.Client       // Client for the REST API, http.DefaultClient when nil.
+	ErrorLog   *log.Logger        // Logger of failed checks, the standard logger when nil.
+	Options    []validator.Option // Options every audit is run with.
+}
+
+// Server is an http.Handler receiving the webhook deliveries of the app. Pull requests
+// are checked in the background, once the delivery is acknowledged.
+type Server struct {
+	cfg    Config
+	client *client
+	log    *log.Logger
+
+	mu    sync.Mutex
+	repos map[string]*sync.Mutex // Serializes fetches into each cache repository.
+
+	wg sync.WaitGroup // Pending checks.
+}
+
+// New returns a Server for the app cfg describes.
+func New(cfg Config) *Server {
+	apiURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	logger := cfg.ErrorLog
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &Server{
+		cfg:    cfg,
+		client: &client{http: httpClient, apiURL: apiURL, appID: cfg.AppID, key: cfg.PrivateKey, now: time.Now},
+		log:    logger,
+		mu:     sync.Mutex{},
+		repos:  make(map[string]*sync.Mutex),
+		wg:     sync.WaitGroup{},
+	}
+}
+
+// Wait blocks until the checks started so far are done.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// pullRequestEvent is the subset of the pull_request webhook payload the app reads.
+type pullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"base"`
+	} `json:"pull_request"` //nolint:tagliatelle // GitHub webhook field.
+	Repository struct {
+		FullName string `json:"full_name"` //nolint:tagliatelle // GitHub webhook field.
+		CloneURL string `json:"clone_url"` //nolint:tagliatelle // GitHub webhook field.
+	} `json:"repository"`
+	Installation *struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
+// ServeHTTP verifies and acknowledges a webhook delivery, starting a check for pull
+// requests that were opened, reopened or pushed to. Other events are ignored.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "reading payload", http.StatusBadRequest)
+
+		return
+	}
+
+	if !validSignature(s.cfg.WebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+
+		return
+	}
+
+	if r.Header.Get("X-GitHub-Event") != "pull_request" {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	var event pullRequestEvent
+
+	err = json.Unmarshal(body, &event)
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+
+		return
+	}
+
+	if !slices.Contains(checkedActions, event.Action) {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	if event.Installation == nil {
+		http.Error(w, errMissingInstallation.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), checkTimeout)
+		defer cancel()
+
+		err := s.checkPullRequest(ctx, event)
+		if err != nil {
+			s.log.Printf("darna: checking %s#%d: %v", event.Repository.FullName, event.Number, err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature reports whether header holds the HMAC-SHA256 of body keyed with
+// secret, as "sha256=<hex>".
+func validSignature(secret, body []byte, header string) bool {
+	got, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+
+	sig, err := hex.DecodeString(got)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// checkPullRequest audits the commits of the pull request of event and reports them as
+// a check run on its head commit. Once the check run exists, failures conclude it.
+func (s *Server) checkPullRequest(ctx context.Context, event pullRequestEvent) error {
+	repo, head := event.Repository.FullName, event.PullRequest.Head.SHA
+
+	token, err := s.client.installationToken(ctx, event.Installation.ID)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.client.createCheckRun(ctx, token, repo, checkRun{
+		Name: CheckName, HeadSHA: head, Status: "in_progress", Conclusion: "", Output: nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	gitDir, err := s.fetch(ctx, token, event)
+	if err == nil {
+		var reports []validator.CommitReport
+
+		reports, err = validator.ValidateRefUpdate(ctx, gitDir, event.PullRequest.Base.SHA, head, s.cfg.Options...)
+		if err == nil {
+			return s.report(ctx, token, repo, id, gitDir, reports)
+		}
+	}
+
+	output := &checkRunOutput{Title: "darna could not check this pull request", Summary: err.Error(), Annotations: nil}
+
+	updateErr := s.client.updateCheckRun(ctx, token, repo, id, checkRun{
+		Name: "", HeadSHA: "", Status: "completed", Conclusion: "failure", Output: output,
+	})
+
+	return errors.Join(err, updateErr)
+}
+
+// fetch brings the base branch and the head of the pull request of event into the
+// cache repository of its repository, and returns the path of the latter.
+func (s *Server) fetch(ctx context.Context, token string, event pullRequestEvent) (string, error) {
+	repo := event.Repository.FullName
+	gitDir := filepath.Join(s.cfg.CacheDir, filepath.FromSlash(repo)+".git")
+
+	s.mu.Lock()
+
+	mu, ok := s.repos[repo]
+	if !ok {
+		mu = &sync.Mutex{}
+		s.repos[repo] = mu
+	}
+
+	s.mu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(gitDir), 0o750) //nolint:mnd // Private to the server.
+	if err != nil {
+		return "", fmt.Errorf("creating cache dir: %w", err)
+	}
+
+	err = git.InitBare(ctx, gitDir)
+	if err != nil {
+		return "", fmt.Errorf("creating cache repository: %w", err)
+	}
+
+	// Pull requests from forks are fetched from the base repository too, which has their
+	// head under refs/pull.
+	pull := "refs/pull/" + strconv.Itoa(event.Number) + "/head"
+	base := "refs/heads/" + event.PullRequest.Base.Ref
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token))
+
+	err = git.Fetch(ctx, gitDir, event.Repository.CloneURL, auth, "+"+pull+":"+pull, "+"+base+":"+base)
+	if err != nil {
+		return "", fmt.Errorf("fetching pull request: %w", err)
+	}
+
+	return gitDir, nil
+}
+
+// report concludes the check run with the audit reports: a summary listing every commit
+// and an annotation per violation, sent in batches as GitHub limits them per request.
+func (s *Server) report(
+	ctx context.Context, token, repo string, id int64, gitDir string, reports []validator.CommitReport,
+) error {
+	var (
+		summary     strings.Builder
+		annotations []annotation
+		failing     int
+	)
+
+	for _, cr := range reports {
+		subject, _ := git.GetCommitSubject(ctx, gitDir, cr.Commit)
+
+		mark := "✅"
+		if len(cr.Violations) > 0 {
+			mark = "❌"
+			failing++
+		}
+
+		summary.WriteString("- " + mark + " `" + shortHash(cr.Commit) + "` " + subject + "\n")
+
+		for _, vv := range cr.Violations {
+			summary.WriteString("  - " + violationMessage(vv) + "\n")
+
+			annotations = append(annotations, newAnnotation(cr.Commit, vv))
+		}
+	}
+
+	run := checkRun{Name: "", HeadSHA: "", Status: "completed", Conclusion: "success", Output: nil}
+
+	title := "All " + strconv.Itoa(len(reports)) + " commit(s) are atomic"
+	if failing > 0 {
+		run.Conclusion = "failure"
+		title = strconv.Itoa(failing) + " of " + strconv.Itoa(len(reports)) + " commit(s) not atomic"
+	}
+
+	for {
+		batch := annotations[:min(len(annotations), maxAnnotations)]
+		annotations = annotations[len(batch):]
+
+		run.Output = &checkRunOutput{Title: title, Summary: summary.String(), Annotations: batch}
+
+		err := s.client.updateCheckRun(ctx, token, repo, id, run)
+		if err != nil || len(annotations) == 0 {
+			return err
+		}
+	}
+}
+
+// newAnnotation returns the annotation of a violation of commit, at the usage when its
+// position is known and on the first line of the file otherwise.
+func newAnnotation(commit string, vv validator.Violation) annotation {
+	a := annotation{
+		Path:            filepath.ToSlash(vv.StagedFile),
+		StartLine:       max(vv.Line, 1),
+		EndLine:         max(vv.Line, 1),
+		StartColumn:     0,
+		EndColumn:       0,
+		AnnotationLevel: "failure",
+		Title:           "Commit " + shortHash(commit) + " is not atomic",
+		Message:         violationMessage(vv),
+	}
+
+	if vv.Line > 0 && vv.Column > 0 {
+		a.StartColumn, a.EndColumn = vv.Column, vv.Column
+	}
+
+	return a
+}
+
+// violationMessage describes a violation of a commit in one line.
+func violationMessage(vv validator.Violation) string {
+	if vv.Reason != "" {
+		return vv.StagedSymbol + " " + vv.Reason
+	}
+
+	return vv.StagedSymbol + " uses " + vv.MissingSymbol + " from " + vv.MissingFile +
+		", which only gets there in a later commit"
+}
+
+func shortHash(hash string) string {
+	const short = 12
+	if len(hash) > short {
+		return hash[:short]
+	}
+
+	return hash
+}
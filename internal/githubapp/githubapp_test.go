@@ -0,0 +1,293 @@
+package githubapp_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"dario.cat/darna/internal/githubapp"
+)
+
+const (
+	testSecret         = "webhook-secret"
+	testToken          = "installation-token"
+	testInstallationID = "7"
+)
+
+// fakeGitHub serves the REST API calls of the app, recording the check run requests.
+type fakeGitHub struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu   sync.Mutex
+	runs []map[string]any // Bodies of the check run requests, in order.
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/app/installations/"+testInstallationID+"/access_tokens":
+		if !f.validJWT(auth) {
+			http.Error(w, "bad JWT", http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"token":"` + testToken + `"}`))
+	case auth != testToken:
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/octo/repo/check-runs",
+		r.Method == http.MethodPatch && r.URL.Path == "/repos/octo/repo/check-runs/42":
+		var body map[string]any
+
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			f.t.Errorf("Decoding check run: %v", err)
+		}
+
+		f.mu.Lock()
+		f.runs = append(f.runs, body)
+		f.mu.Unlock()
+
+		_, _ = w.Write([]byte(`{"id":42}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validJWT reports whether jwt is signed by the app key.
+func (f *fakeGitHub) validJWT(jwt string) bool {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	return rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], sig) == nil
+}
+
+func TestServer_PullRequest(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	origin, base, head := setupPullRequest(t)
+
+	api := &fakeGitHub{t: t, key: &key.PublicKey, mu: sync.Mutex{}, runs: nil}
+	apiServer := httptest.NewServer(api)
+	t.Cleanup(apiServer.Close)
+
+	var logs bytes.Buffer
+
+	srv := githubapp.New(githubapp.Config{
+		AppID:         1,
+		PrivateKey:    key,
+		WebhookSecret: []byte(testSecret),
+		CacheDir:      t.TempDir(),
+		APIURL:        apiServer.URL,
+		HTTPClient:    apiServer.Client(),
+		ErrorLog:      log.New(&logs, "", 0),
+		Options:       nil,
+	})
+
+	payload := `{"action":"synchronize","number":1,` +
+		`"pull_request":{"head":{"sha":"` + head + `"},"base":{"ref":"main","sha":"` + base + `"}},` +
+		`"repository":{"full_name":"octo/repo","clone_url":"` + origin + `"},` +
+		`"installation":{"id":` + testInstallationID + `}}`
+
+	if code := deliver(t, srv, "pull_request", payload, testSecret); code != http.StatusAccepted {
+		t.Fatalf("Delivery answered %d, want %d", code, http.StatusAccepted)
+	}
+
+	srv.Wait()
+
+	if logs.Len() > 0 {
+		t.Fatalf("Check failed: %s", logs.String())
+	}
+
+	if len(api.runs) != 2 || api.runs[0]["head_sha"] != head || api.runs[0]["status"] != "in_progress" {
+		t.Fatalf("Check run requests = %v, want a creation on the head and an update", api.runs)
+	}
+
+	if api.runs[1]["conclusion"] != "failure" {
+		t.Errorf("Conclusion = %v, want failure", api.runs[1]["conclusion"])
+	}
+
+	output, _ := api.runs[1]["output"].(map[string]any)
+	annotations, _ := output["annotations"].([]any)
+
+	if output["title"] != "1 of 2 commit(s) not atomic" || len(annotations) != 1 {
+		t.Fatalf("Output = %v, want one failing commit and its annotation", output)
+	}
+
+	annotation, _ := annotations[0].(map[string]any)
+	if annotation["path"] != "main.go" || !strings.Contains(annotation["message"].(string), "newthing.go") {
+		t.Errorf("Annotation = %v, want one on main.go naming newthing.go", annotation)
+	}
+}
+
+func TestServer_Deliveries(t *testing.T) {
+	t.Parallel()
+
+	srv := githubapp.New(githubapp.Config{ //nolint:exhaustruct // No check is started.
+		AppID:         1,
+		WebhookSecret: []byte(testSecret),
+	})
+
+	tests := []struct {
+		name   string
+		event  string
+		secret string
+		body   string
+		want   int
+	}{
+		{"bad signature", "pull_request", "other", `{"action":"opened"}`, http.StatusUnauthorized},
+		{"other event", "push", testSecret, `{}`, http.StatusNoContent},
+		{"ignored action", "pull_request", testSecret, `{"action":"labeled"}`, http.StatusNoContent},
+		{"no installation", "pull_request", testSecret, `{"action":"opened"}`, http.StatusBadRequest},
+		{"invalid payload", "pull_request", testSecret, `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := deliver(t, srv, tt.event, tt.body, tt.secret); got != tt.want {
+				t.Errorf("Delivery answered %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	for typ, der := range map[string][]byte{"RSA PRIVATE KEY": x509.MarshalPKCS1PrivateKey(key), "PRIVATE KEY": pkcs8} {
+		parsed, err := githubapp.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: typ, Headers: nil, Bytes: der}))
+		if err != nil || !parsed.Equal(key) {
+			t.Errorf("ParsePrivateKey(%s) = %v, want the key", typ, err)
+		}
+	}
+
+	if _, err = githubapp.ParsePrivateKey([]byte("not a key")); !errors.Is(err, githubapp.ErrPrivateKey) {
+		t.Errorf("ParsePrivateKey(garbage) = %v, want ErrPrivateKey", err)
+	}
+}
+
+// deliver sends a webhook delivery signed with secret and returns the response status.
+func deliver(t *testing.T, srv http.Handler, event, body, secret string) int {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+// setupPullRequest creates a bare origin repository whose main branch has a Go module,
+// and whose refs/pull/1/head adds two commits, the first not atomic. It returns the
+// origin path and the base and head commits.
+func setupPullRequest(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	work := t.TempDir()
+
+	runGit(t, work, "init", "-b", "main")
+	runGit(t, work, "config", "user.email", "test@example.com")
+	runGit(t, work, "config", "user.name", "Test User")
+	runGit(t, work, "config", "commit.gpgsign", "false")
+
+	writeFile(t, filepath.Join(work, "go.mod"), "module example.com/app\n\ngo 1.24\n")
+	writeFile(t, filepath.Join(work, "main.go"), "package main\n\nfunc main() {}\n")
+	runGit(t, work, "add", ".")
+	runGit(t, work, "commit", "-m", "Initial commit")
+
+	base := gitOutput(t, work, "rev-parse", "HEAD")
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	runGit(t, work, "clone", "-q", "--bare", work, origin)
+
+	writeFile(t, filepath.Join(work, "main.go"), "package main\n\nfunc main() { NewThing() }\n")
+	runGit(t, work, "commit", "-am", "Use NewThing")
+	writeFile(t, filepath.Join(work, "newthing.go"), "package main\n\nfunc NewThing() {}\n")
+	runGit(t, work, "add", "newthing.go")
+	runGit(t, work, "commit", "-m", "Add NewThing")
+
+	head := gitOutput(t, work, "rev-parse", "HEAD")
+	runGit(t, work, "push", "-q", origin, "HEAD:refs/pull/1/head")
+
+	return origin, base, head
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	gitOutput(t, dir, args...)
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.CommandContext(t.Context(), "git", args...) //nolint:gosec // Test helper for git commands.
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\nOutput: %s", args, err, output)
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Writing %s: %v", path, err)
+	}
+}
@@ -0,0 +1,130 @@
+package graph
+
+import (
+	"cmp"
+	"go/token"
+	"slices"
+)
+
+// Fragment is everything a DependencyGraph records from the code of one package path,
+// its test variants included, in a form that can be encoded, saved and added to
+// another graph without loading the package again.
+type Fragment struct {
+	Symbols      []Symbol                             `json:"symbols"`       // Defined by the package.
+	Callers      []string                             `json:"callers"`       // IDs with usages recorded from it.
+	Edges        map[string][]string                  `json:"edges"`         // Caller -> dependencies.
+	Decoupled    map[string][]string                  `json:"decoupled"`     // Caller -> decoupled dependencies.
+	UsePos       map[string]map[string]token.Position `json:"use_pos"`       // Caller -> dependency -> first use.
+	IgnoredSyms  []string                             `json:"ignored_syms"`  // Declarations with an ignore directive.
+	IgnoredFiles []string                             `json:"ignored_files"` // Files with an ignore directive.
+}
+
+// Fragment returns what g recorded from the package with the given path.
+func (g *DependencyGraph) Fragment(pkgPath string) Fragment {
+	f := Fragment{
+		Symbols:      nil,
+		Callers:      nil,
+		Edges:        make(map[string][]string),
+		Decoupled:    make(map[string][]string),
+		UsePos:       make(map[string]map[string]token.Position),
+		IgnoredSyms:  nil,
+		IgnoredFiles: nil,
+	}
+
+	for _, sym := range g.Symbols {
+		if sym.Package == pkgPath {
+			f.Symbols = append(f.Symbols, *sym)
+		}
+	}
+
+	slices.SortFunc(f.Symbols, func(a, b Symbol) int { return cmp.Compare(a.ID, b.ID) })
+
+	for caller := range g.pkgCallers[pkgPath] {
+		f.Callers = append(f.Callers, g.ids[caller])
+	}
+
+	slices.Sort(f.Callers)
+
+	for _, callerID := range f.Callers {
+		caller := g.index[callerID]
+		n := g.nodes[caller]
+
+		for _, e := range n.out {
+			if g.recordedFrom(pkgPath, caller, e.to) {
+				f.Edges[callerID] = append(f.Edges[callerID], g.ids[e.to])
+			}
+		}
+
+		slices.Sort(f.Edges[callerID])
+
+		if len(n.decoupled) > 0 {
+			f.Decoupled[callerID] = slices.Sorted(g.Decoupled(callerID))
+		}
+
+		for _, e := range n.out {
+			if e.pos.file == 0 || !g.recordedFrom(pkgPath, caller, e.to) {
+				continue
+			}
+
+			if f.UsePos[callerID] == nil {
+				f.UsePos[callerID] = make(map[string]token.Position)
+			}
+
+			f.UsePos[callerID][g.ids[e.to]] = g.position(e.pos)
+		}
+	}
+
+	for id, path := range g.ignoredSyms {
+		if path == pkgPath {
+			f.IgnoredSyms = append(f.IgnoredSyms, id)
+		}
+	}
+
+	for file, path := range g.ignoredFiles {
+		if path == pkgPath {
+			f.IgnoredFiles = append(f.IgnoredFiles, file)
+		}
+	}
+
+	slices.Sort(f.IgnoredSyms)
+	slices.Sort(f.IgnoredFiles)
+
+	return f
+}
+
+// AddFragment adds to g a fragment of the package with the given path, as if the
+// package had been analyzed with AnalyzePackage. The fragment must come from a graph
+// with the same decoupling functions and SetImplementations setting.
+func (g *DependencyGraph) AddFragment(pkgPath string, f Fragment) {
+	for _, sym := range f.Symbols {
+		if _, exists := g.Symbols[sym.ID]; !exists {
+			g.FileSyms[sym.File] = append(g.FileSyms[sym.File], sym.ID)
+		}
+
+		g.Symbols[sym.ID] = &sym
+	}
+
+	for _, callerID := range f.Callers {
+		g.addCaller(pkgPath, g.intern(callerID))
+	}
+
+	for callerID, deps := range f.Edges {
+		for _, depID := range deps {
+			g.addUse(g.intern(callerID), g.intern(depID), f.UsePos[callerID][depID])
+		}
+	}
+
+	for callerID, deps := range f.Decoupled {
+		for _, depID := range deps {
+			g.addDecoupled(g.intern(callerID), g.intern(depID))
+		}
+	}
+
+	for _, id := range f.IgnoredSyms {
+		g.ignoredSyms[id] = pkgPath
+	}
+
+	for _, file := range f.IgnoredFiles {
+		g.ignoredFiles[file] = pkgPath
+	}
+}
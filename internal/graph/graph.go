@@ -2,9 +2,22 @@
 package graph
 
 import (
+	"cmp"
+	"context"
+	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"iter"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 
 	"dario.cat/darna/internal/analyzer"
 	"golang.org/x/tools/go/packages"
@@ -12,44 +25,271 @@ import (
 
 // Symbol represents a symbol in the dependency graph.
 type Symbol struct {
-	ID      string         // "pkg/path.SymbolName".
-	Name    string         // Symbol name.
+	ID      string         // "pkg/path.SymbolName" or "pkg/path.Type.Method".
+	Name    string         // Symbol name, "Type.Method" for methods.
 	Package string         // Package path.
-	Kind    string         // "func", "type", "var", "const".
+	Kind    string         // "func", "type", "var", "const", "import", "file".
 	File    string         // Defining file path.
 	Pos     token.Position // Source position.
 }
 
-// DependencyGraph represents the dependency relationships between symbols.
+// DependencyGraph represents the dependency relationships between symbols. Symbol IDs
+// are interned into indices and the edges of each symbol kept in sorted slices of them,
+// so that repositories with hundreds of thousands of symbols yield compact graphs that
+// put little pressure on the garbage collector.
 type DependencyGraph struct {
-	Symbols  map[string]*Symbol             // ID -> Symbol.
-	FileSyms map[string][]string            // File -> defined symbol IDs.
-	OutEdges map[string]map[string]struct{} // Symbol -> symbols it depends on.
-	InEdges  map[string]map[string]struct{} // Symbol -> symbols that depend on it.
+	Symbols  map[string]*Symbol  // ID -> Symbol.
+	FileSyms map[string][]string // File -> defined symbol IDs.
+
+	index     map[string]symIndex // Symbol ID -> interned index.
+	ids       []string            // Interned index -> symbol ID.
+	nodes     []node              // Interned index -> edges of the symbol.
+	files     map[string]int32    // File of a use -> interned index.
+	fileNames []string            // Interned index -> file, "" at 0 for unknown positions.
+
+	decouplingFuncs map[string]bool                  // Names or IDs of decoupling functions.
+	implementations bool                             // Link types and the interfaces they implement.
+	progress        func(done, total int)            // Called as AnalyzePackages analyzes packages.
+	pkgCallers      map[string]map[symIndex]struct{} // Package path -> symbols with usages recorded from it.
+	ignoredSyms     map[string]string                // Symbol ID -> package path, for //darna:ignore declarations.
+	ignoredFiles    map[string]string                // File -> package path, for //darna:ignore file headers.
+}
+
+// symIndex is an interned symbol ID. Indices are never reused, even once the symbol
+// and its edges are removed, so that they stay valid across updates.
+type symIndex int32
+
+// node holds the edges of a symbol, each slice sorted by index.
+type node struct {
+	out       []edge     // Symbols it depends on.
+	in        []symIndex // Symbols that depend on it.
+	decoupled []symIndex // Symbols reached only through decoupling calls.
+}
+
+// edge is a dependency on a symbol, with the position of its first use.
+type edge struct {
+	to  symIndex
+	pos usePos
+}
+
+// usePos is a token.Position with an interned file. The zero value is unknown.
+type usePos struct {
+	file, offset, line, column int32
 }
 
 // NewDependencyGraph creates a new empty dependency graph.
 func NewDependencyGraph() *DependencyGraph {
 	return &DependencyGraph{
-		Symbols:  make(map[string]*Symbol),
-		FileSyms: make(map[string][]string),
-		OutEdges: make(map[string]map[string]struct{}),
-		InEdges:  make(map[string]map[string]struct{}),
+		Symbols:         make(map[string]*Symbol),
+		FileSyms:        make(map[string][]string),
+		index:           make(map[string]symIndex),
+		ids:             nil,
+		nodes:           nil,
+		files:           make(map[string]int32),
+		fileNames:       []string{""},
+		decouplingFuncs: make(map[string]bool),
+		implementations: false,
+		progress:        nil,
+		pkgCallers:      make(map[string]map[symIndex]struct{}),
+		ignoredSyms:     make(map[string]string),
+		ignoredFiles:    make(map[string]string),
+	}
+}
+
+// SetDecouplingFuncs declares functions, by name ("register") or symbol ID
+// ("example.com/plugins.Register"), as decoupling points. Usages inside a call to a
+// decoupling function, including the function itself, are recorded in Decoupled
+// instead of OutEdges/InEdges, so transitive traversals do not pass through them.
+// It must be called before AnalyzePackage.
+func (g *DependencyGraph) SetDecouplingFuncs(names []string) {
+	for _, name := range names {
+		g.decouplingFuncs[name] = true
 	}
 }
 
+// SetImplementations makes AnalyzePackage link the types of a package and the
+// non-empty interfaces they implement, declared in the package or in the packages of
+// its module it imports, with edges both ways: the type depends on the interface, and
+// the interface on the type. Changing the method set of either side thus needs the
+// other. Types implementing an interface of a package they do not import are not
+// linked. It must be called before AnalyzePackage.
+func (g *DependencyGraph) SetImplementations(on bool) {
+	g.implementations = on
+}
+
+// SetProgress makes AnalyzePackages call fn each time it finishes analyzing one of its
+// total packages, with the number done so far. Calls come from its goroutines, one at a
+// time. A nil fn reports nothing.
+func (g *DependencyGraph) SetProgress(fn func(done, total int)) {
+	g.progress = fn
+}
+
 // AddDependency adds a dependency edge from one symbol to another.
 func (g *DependencyGraph) AddDependency(from, to string) {
-	if g.OutEdges[from] == nil {
-		g.OutEdges[from] = make(map[string]struct{})
+	g.addEdge(g.intern(from), g.intern(to))
+}
+
+// OutEdges returns the symbols id depends on.
+func (g *DependencyGraph) OutEdges(id string) iter.Seq[string] {
+	return g.symbols(id, func(n node) iter.Seq[symIndex] { return n.deps() })
+}
+
+// InEdges returns the symbols that depend on id.
+func (g *DependencyGraph) InEdges(id string) iter.Seq[string] {
+	return g.symbols(id, func(n node) iter.Seq[symIndex] { return slices.Values(n.in) })
+}
+
+// Decoupled returns the symbols id reaches only through decoupling calls.
+func (g *DependencyGraph) Decoupled(id string) iter.Seq[string] {
+	return g.symbols(id, func(n node) iter.Seq[symIndex] { return slices.Values(n.decoupled) })
+}
+
+// UsePos returns the position of the first use of to by from, or the zero position
+// when none was recorded.
+func (g *DependencyGraph) UsePos(from, to string) token.Position {
+	i, ok := g.index[from]
+	j, found := g.index[to]
+
+	if !ok || !found {
+		return token.Position{}
 	}
 
-	if g.InEdges[to] == nil {
-		g.InEdges[to] = make(map[string]struct{})
+	out := g.nodes[i].out
+	if k, found := slices.BinarySearchFunc(out, j, compareEdge); found {
+		return g.position(out[k].pos)
 	}
 
-	g.OutEdges[from][to] = struct{}{}
-	g.InEdges[to][from] = struct{}{}
+	return token.Position{}
+}
+
+// symbols returns the IDs of the symbols edges lists from the node of id.
+func (g *DependencyGraph) symbols(id string, edges func(n node) iter.Seq[symIndex]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		i, ok := g.index[id]
+		if !ok {
+			return
+		}
+
+		for j := range edges(g.nodes[i]) {
+			if !yield(g.ids[j]) {
+				return
+			}
+		}
+	}
+}
+
+// deps returns the symbols n depends on.
+func (n node) deps() iter.Seq[symIndex] {
+	return func(yield func(symIndex) bool) {
+		for _, e := range n.out {
+			if !yield(e.to) {
+				return
+			}
+		}
+	}
+}
+
+// intern returns the index of id, allocating one on first sight.
+func (g *DependencyGraph) intern(id string) symIndex {
+	if i, ok := g.index[id]; ok {
+		return i
+	}
+
+	i := symIndex(len(g.ids)) //nolint:gosec // Far fewer symbols than 2^31.
+	g.index[id] = i
+	g.ids = append(g.ids, id)
+	g.nodes = append(g.nodes, node{})
+
+	return i
+}
+
+// addEdge adds the edge from -> to if missing, and returns it.
+func (g *DependencyGraph) addEdge(from, to symIndex) *edge {
+	i, found := slices.BinarySearchFunc(g.nodes[from].out, to, compareEdge)
+	if !found {
+		g.nodes[from].out = slices.Insert(g.nodes[from].out, i, edge{to: to, pos: usePos{}})
+		g.nodes[to].in = insertIndex(g.nodes[to].in, from)
+	}
+
+	return &g.nodes[from].out[i]
+}
+
+// addUse adds the edge from -> to, used at pos, keeping the earliest use per file.
+func (g *DependencyGraph) addUse(from, to symIndex, pos token.Position) {
+	use := g.compactPos(pos)
+
+	e := g.addEdge(from, to)
+	if e.pos.file == 0 || use.file == e.pos.file && use.offset < e.pos.offset {
+		e.pos = use
+	}
+}
+
+func (g *DependencyGraph) addDecoupled(from, to symIndex) {
+	g.nodes[from].decoupled = insertIndex(g.nodes[from].decoupled, to)
+}
+
+// addCaller records that usages of caller were recorded from the package with the
+// given path.
+func (g *DependencyGraph) addCaller(pkgPath string, caller symIndex) {
+	if g.pkgCallers[pkgPath] == nil {
+		g.pkgCallers[pkgPath] = make(map[symIndex]struct{})
+	}
+
+	g.pkgCallers[pkgPath][caller] = struct{}{}
+}
+
+// compactPos returns the compact form of pos, interning its file.
+func (g *DependencyGraph) compactPos(pos token.Position) usePos {
+	if pos.Filename == "" {
+		return usePos{}
+	}
+
+	file, ok := g.files[pos.Filename]
+	if !ok {
+		file = int32(len(g.fileNames)) //nolint:gosec // Far fewer files than 2^31.
+		g.files[pos.Filename] = file
+		g.fileNames = append(g.fileNames, pos.Filename)
+	}
+
+	//nolint:gosec // Source files are far smaller than 2 GiB.
+	return usePos{file: file, offset: int32(pos.Offset), line: int32(pos.Line), column: int32(pos.Column)}
+}
+
+// position returns the token.Position of use.
+func (g *DependencyGraph) position(use usePos) token.Position {
+	if use.file == 0 {
+		return token.Position{}
+	}
+
+	return token.Position{
+		Filename: g.fileNames[use.file],
+		Offset:   int(use.offset),
+		Line:     int(use.line),
+		Column:   int(use.column),
+	}
+}
+
+// compareEdge orders edges by the index of the symbol they lead to.
+func compareEdge(e edge, to symIndex) int {
+	return cmp.Compare(e.to, to)
+}
+
+// insertIndex inserts i into the sorted indices, unless already there.
+func insertIndex(indices []symIndex, i symIndex) []symIndex {
+	if k, found := slices.BinarySearch(indices, i); !found {
+		return slices.Insert(indices, k, i)
+	}
+
+	return indices
+}
+
+// deleteIndex removes i from the sorted indices, if there.
+func deleteIndex(indices []symIndex, i symIndex) []symIndex {
+	if k, found := slices.BinarySearch(indices, i); found {
+		return slices.Delete(indices, k, k+1)
+	}
+
+	return indices
 }
 
 // AnalyzePackage analyzes a package and adds its symbols and dependencies to the graph.
@@ -61,54 +301,317 @@ func (g *DependencyGraph) AnalyzePackage(pkg *packages.Package) {
 
 	g.registerDefinitions(pkg)
 	g.trackUsages(pkg)
+	g.trackEmbeds(pkg)
+	g.trackCompanions(pkg)
+	g.recordIgnores(pkg)
+
+	if g.implementations {
+		g.trackImplementations(pkg)
+	}
+}
+
+// AnalyzePackages analyzes pkgs like AnalyzePackage, each into a partial graph of its
+// own on up to GOMAXPROCS goroutines. The partial graphs are merged in the order of
+// pkgs, so the result is the same as analyzing them one after the other. Once ctx is
+// done, the packages left are skipped and nothing is added to g.
+func (g *DependencyGraph) AnalyzePackages(ctx context.Context, pkgs ...*packages.Package) error {
+	partials := make([]*DependencyGraph, len(pkgs))
+	work := make(chan int)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex // Serializes progress calls.
+		done int
+	)
+
+	for range min(runtime.GOMAXPROCS(0), len(pkgs)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range work {
+				if ctx.Err() != nil {
+					continue // Drain the work left.
+				}
+
+				partial := NewDependencyGraph()
+				partial.decouplingFuncs = g.decouplingFuncs // Only read while analyzing.
+				partial.implementations = g.implementations
+				partial.AnalyzePackage(pkgs[i])
+				partials[i] = partial
+
+				if g.progress != nil {
+					mu.Lock()
+					done++
+					g.progress(done, len(pkgs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range pkgs {
+		work <- i
+	}
+
+	close(work)
+	wg.Wait()
+
+	err := ctx.Err()
+	if err != nil {
+		return fmt.Errorf("analyzing packages: %w", err)
+	}
+
+	for _, partial := range partials {
+		g.merge(partial)
+	}
+
+	return nil
+}
+
+// merge adds everything recorded in other to g, as if the packages other analyzed were
+// analyzed by g after those it already holds.
+func (g *DependencyGraph) merge(other *DependencyGraph) {
+	for file, ids := range other.FileSyms {
+		for _, id := range ids {
+			if _, exists := g.Symbols[id]; !exists {
+				g.FileSyms[file] = append(g.FileSyms[file], id)
+			}
+
+			g.Symbols[id] = other.Symbols[id]
+		}
+	}
+
+	for i, n := range other.nodes {
+		from := g.intern(other.ids[i])
+
+		for _, e := range n.out {
+			g.addUse(from, g.intern(other.ids[e.to]), other.position(e.pos))
+		}
+
+		for _, to := range n.decoupled {
+			g.addDecoupled(from, g.intern(other.ids[to]))
+		}
+	}
+
+	for pkgPath, callers := range other.pkgCallers {
+		for caller := range callers {
+			g.addCaller(pkgPath, g.intern(other.ids[caller]))
+		}
+	}
+
+	maps.Copy(g.ignoredSyms, other.ignoredSyms)
+	maps.Copy(g.ignoredFiles, other.ignoredFiles)
+}
+
+// IsIgnoredSymbol reports whether the declaration of the symbol carries an
+// analyzer.IgnoreDirective.
+func (g *DependencyGraph) IsIgnoredSymbol(id string) bool {
+	_, ok := g.ignoredSyms[id]
+
+	return ok
+}
+
+// IsIgnoredFile reports whether the header of file carries an analyzer.IgnoreDirective.
+func (g *DependencyGraph) IsIgnoredFile(file string) bool {
+	_, ok := g.ignoredFiles[file]
+
+	return ok
+}
+
+// UpdatePackage replaces everything previously recorded for the paths of pkgs with a
+// fresh analysis of pkgs. All variants sharing a path (e.g. a package and its test
+// variant) must be passed together. Edges from other packages into pkgs are kept, and
+// so are the links of their interfaces to the types of other packages: update those
+// packages too when the interfaces change. On error, ctx was done and the paths of pkgs
+// are left out of g.
+func (g *DependencyGraph) UpdatePackage(ctx context.Context, pkgs ...*packages.Package) error {
+	for _, pkg := range pkgs {
+		g.RemovePackage(pkg.PkgPath)
+	}
+
+	return g.AnalyzePackages(ctx, pkgs...)
+}
+
+// RemovePackage drops the symbols defined in the package with the given path and
+// the edges recorded from its code.
+func (g *DependencyGraph) RemovePackage(pkgPath string) {
+	for caller := range g.pkgCallers[pkgPath] {
+		g.nodes[caller].out = slices.DeleteFunc(g.nodes[caller].out, func(e edge) bool {
+			if !g.recordedFrom(pkgPath, caller, e.to) {
+				return false
+			}
+
+			g.nodes[e.to].in = deleteIndex(g.nodes[e.to].in, caller)
+
+			return true
+		})
+
+		if sym := g.Symbols[g.ids[caller]]; sym == nil || sym.Package == pkgPath {
+			g.nodes[caller].decoupled = nil
+		}
+	}
+
+	delete(g.pkgCallers, pkgPath)
+
+	maps.DeleteFunc(g.ignoredSyms, func(_, path string) bool { return path == pkgPath })
+	maps.DeleteFunc(g.ignoredFiles, func(_, path string) bool { return path == pkgPath })
+
+	for id, sym := range g.Symbols {
+		if sym.Package == pkgPath {
+			delete(g.FileSyms, sym.File)
+			delete(g.Symbols, id)
+		}
+	}
+}
+
+// recordedFrom reports whether the edge from -> to, from one of the callers of the
+// package with the given path, was recorded from the code of the package. The edges
+// trackImplementations adds between interfaces and types of different packages are
+// recorded from the package of the type: interfaces of other packages only hold edges
+// recorded from it to its types, and its interfaces hold edges to the types of other
+// packages recorded from those.
+func (g *DependencyGraph) recordedFrom(pkgPath string, from, to symIndex) bool {
+	fromSym, toSym := g.Symbols[g.ids[from]], g.Symbols[g.ids[to]]
+	if fromSym != nil && fromSym.Package != pkgPath {
+		return toSym != nil && toSym.Package == pkgPath
+	}
+
+	if toSym == nil || toSym.Package == pkgPath {
+		return true
+	}
+
+	_, linked := g.pkgCallers[toSym.Package][from]
+
+	return !linked
 }
 
 // TransitiveDeps returns all symbols that the given symbol transitively depends on.
 func (g *DependencyGraph) TransitiveDeps(startID string) []string {
-	visited := make(map[string]bool)
+	return g.depthFirst(startID, func(n node) iter.Seq[symIndex] { return n.deps() })
+}
 
-	var result []string
+// PathTree holds shortest dependency paths from one symbol to everything it
+// transitively depends on.
+type PathTree struct {
+	start  string
+	parent map[string]string // Symbol -> its predecessor on a shortest path from start.
+}
 
-	var dfs func(id string)
+// ShortestPaths finds, breadth-first, a shortest path from startID to every symbol it
+// transitively depends on. Among equally short paths, earlier uses win, so the result
+// does not depend on map iteration order.
+func (g *DependencyGraph) ShortestPaths(startID string) PathTree {
+	return g.ShortestPathsWithin(startID, 0)
+}
 
-	dfs = func(id string) {
-		if visited[id] {
-			return
-		}
+// ShortestPathsWithin is like ShortestPaths, but stops at symbols maxDepth edges away
+// from startID: a maxDepth of 1 only reaches direct dependencies. Zero means unlimited.
+func (g *DependencyGraph) ShortestPathsWithin(startID string, maxDepth int) PathTree {
+	tree := PathTree{start: startID, parent: make(map[string]string)}
 
-		visited[id] = true
+	start, ok := g.index[startID]
+	if !ok {
+		return tree
+	}
 
-		result = append(result, id)
-		for depID := range g.OutEdges[id] {
-			dfs(depID)
+	depth := map[symIndex]int{start: 0}
+	queue := []symIndex{start}
+
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		if maxDepth > 0 && depth[i] >= maxDepth {
+			continue
+		}
+
+		for _, dep := range g.sortedDeps(i) {
+			if _, seen := depth[dep]; !seen {
+				tree.parent[g.ids[dep]] = g.ids[i]
+				depth[dep] = depth[i] + 1
+				queue = append(queue, dep)
+			}
 		}
 	}
-	dfs(startID)
 
-	return result
+	return tree
+}
+
+// Reached returns the symbols the tree has a path to, excluding its start.
+func (t PathTree) Reached() iter.Seq[string] {
+	return maps.Keys(t.parent)
+}
+
+// To returns the symbols on the path from the tree's start to targetID, both included,
+// or nil when targetID is not reachable.
+func (t PathTree) To(targetID string) []string {
+	if targetID == t.start {
+		return []string{t.start}
+	}
+
+	if _, ok := t.parent[targetID]; !ok {
+		return nil
+	}
+
+	path := []string{targetID}
+	for id := targetID; id != t.start; {
+		id = t.parent[id]
+		path = append(path, id)
+	}
+
+	slices.Reverse(path)
+
+	return path
+}
+
+// sortedDeps returns the direct dependencies of i ordered by first use, then ID.
+func (g *DependencyGraph) sortedDeps(i symIndex) []symIndex {
+	out := slices.Clone(g.nodes[i].out)
+	slices.SortFunc(out, func(a, b edge) int {
+		if diff := cmp.Compare(a.pos.offset, b.pos.offset); diff != 0 {
+			return diff
+		}
+
+		return strings.Compare(g.ids[a.to], g.ids[b.to])
+	})
+
+	return slices.Collect(node{out: out, in: nil, decoupled: nil}.deps())
 }
 
 // TransitiveDependents returns all symbols that transitively depend on the given symbol.
 func (g *DependencyGraph) TransitiveDependents(targetID string) []string {
-	visited := make(map[string]bool)
+	return g.depthFirst(targetID, func(n node) iter.Seq[symIndex] { return slices.Values(n.in) }) // Reverse edges.
+}
+
+// depthFirst returns startID and the symbols reached from it following edges, in
+// depth-first preorder.
+func (g *DependencyGraph) depthFirst(startID string, edges func(n node) iter.Seq[symIndex]) []string {
+	start, ok := g.index[startID]
+	if !ok {
+		return []string{startID}
+	}
+
+	visited := make(map[symIndex]bool)
 
 	var result []string
 
-	var dfs func(id string)
+	var dfs func(i symIndex)
 
-	dfs = func(id string) {
-		if visited[id] {
+	dfs = func(i symIndex) {
+		if visited[i] {
 			return
 		}
 
-		visited[id] = true
+		visited[i] = true
 
-		result = append(result, id)
-		for depID := range g.InEdges[id] { // Follow reverse edges.
-			dfs(depID)
+		result = append(result, g.ids[i])
+		for j := range edges(g.nodes[i]) {
+			dfs(j)
 		}
 	}
-	dfs(targetID)
+	dfs(start)
 
 	return result
 }
@@ -120,7 +623,7 @@ func (g *DependencyGraph) registerDefinitions(pkg *packages.Package) {
 		}
 
 		sym := &Symbol{
-			ID:      symbolID(obj),
+			ID:      analyzer.SymbolID(obj),
 			Name:    obj.Name(),
 			Package: obj.Pkg().Path(),
 			Kind:    analyzer.ObjectKind(obj),
@@ -133,6 +636,71 @@ func (g *DependencyGraph) registerDefinitions(pkg *packages.Package) {
 
 		g.Symbols[sym.ID] = sym
 	}
+
+	g.registerMethods(pkg)
+	g.registerBlankImports(pkg)
+}
+
+// registerMethods registers the methods declared in pkg as symbols named after their
+// receiver type, as in "Server.Handle", and its init functions as a single "init"
+// symbol. They are no package-level objects, but their declarations depend on other
+// symbols all the same.
+func (g *DependencyGraph) registerMethods(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil && fn.Name.Name != "init" {
+				continue
+			}
+
+			obj, id := pkg.TypesInfo.Defs[fn.Name], callerSymbolID(pkg, fn)
+			if obj == nil || id == "" {
+				continue
+			}
+
+			sym := &Symbol{
+				ID:      id,
+				Name:    strings.TrimPrefix(id, pkg.PkgPath+"."),
+				Package: pkg.PkgPath,
+				Kind:    analyzer.ObjectKind(obj),
+				File:    pkg.Fset.Position(obj.Pos()).Filename,
+				Pos:     pkg.Fset.Position(obj.Pos()),
+			}
+			if _, exists := g.Symbols[sym.ID]; !exists {
+				g.FileSyms[sym.File] = append(g.FileSyms[sym.File], sym.ID)
+			}
+
+			g.Symbols[sym.ID] = sym
+		}
+	}
+}
+
+// registerBlankImports registers the blank imports of pkg, as in `_ "image/png"`, as
+// symbols of kind "import". Nothing in the importing file uses the imported package,
+// which is only imported for the side effects of its initialization.
+func (g *DependencyGraph) registerBlankImports(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		for _, spec := range file.Imports {
+			id := blankImportID(pkg, spec)
+			if id == "" {
+				continue
+			}
+
+			sym := &Symbol{
+				ID:      id,
+				Name:    strings.TrimPrefix(id, pkg.PkgPath+"."),
+				Package: pkg.PkgPath,
+				Kind:    "import",
+				File:    pkg.Fset.Position(spec.Pos()).Filename,
+				Pos:     pkg.Fset.Position(spec.Path.Pos()),
+			}
+			if _, exists := g.Symbols[sym.ID]; !exists {
+				g.FileSyms[sym.File] = append(g.FileSyms[sym.File], sym.ID)
+			}
+
+			g.Symbols[sym.ID] = sym
+		}
+	}
 }
 
 func (g *DependencyGraph) trackUsages(pkg *packages.Package) {
@@ -148,6 +716,8 @@ func (g *DependencyGraph) trackUsages(pkg *packages.Package) {
 				g.trackTypeSpecUsages(pkg, decl)
 			case *ast.ValueSpec:
 				g.trackValueSpecUsages(pkg, decl)
+			case *ast.ImportSpec:
+				g.trackBlankImportUsages(pkg, decl)
 			}
 
 			return true
@@ -155,46 +725,389 @@ func (g *DependencyGraph) trackUsages(pkg *packages.Package) {
 	}
 }
 
+// trackImplementations links the types declared in pkg and the interfaces they
+// implement, as described in SetImplementations.
+func (g *DependencyGraph) trackImplementations(pkg *packages.Package) {
+	ifaces := interfaces(pkg.Types)
+
+	for _, imp := range pkg.Imports {
+		if imp.Types != nil && imp.Module != nil && pkg.Module != nil && imp.Module.Path == pkg.Module.Path {
+			ifaces = append(ifaces, interfaces(imp.Types)...)
+		}
+	}
+
+	if len(ifaces) == 0 {
+		return
+	}
+
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() || types.IsInterface(obj.Type()) || isGeneric(obj) {
+			continue
+		}
+
+		typ := g.intern(analyzer.SymbolID(obj))
+		pos := pkg.Fset.Position(obj.Pos())
+
+		for _, iface := range ifaces {
+			it := iface.Type().Underlying().(*types.Interface) //nolint:forcetypeassert // Checked by interfaces.
+			if !types.Implements(obj.Type(), it) && !types.Implements(types.NewPointer(obj.Type()), it) {
+				continue
+			}
+
+			// The type's package records both edges, so that they go away with it and stay
+			// when the interface's package is updated.
+			in := g.intern(analyzer.SymbolID(iface))
+			g.addCaller(pkg.PkgPath, typ)
+			g.addCaller(pkg.PkgPath, in)
+			g.addUse(typ, in, pos)
+			g.addUse(in, typ, token.Position{})
+		}
+	}
+}
+
+// interfaces returns the non-generic interfaces with methods declared at the package
+// level of pkg.
+func interfaces(pkg *types.Package) []*types.TypeName {
+	var ifaces []*types.TypeName
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() || isGeneric(obj) {
+			continue
+		}
+
+		if it, ok := obj.Type().Underlying().(*types.Interface); ok && it.NumMethods() > 0 && it.IsMethodSet() {
+			ifaces = append(ifaces, obj)
+		}
+	}
+
+	return ifaces
+}
+
+// isGeneric reports whether obj declares a type with type parameters.
+func isGeneric(obj *types.TypeName) bool {
+	named, ok := obj.Type().(*types.Named)
+
+	return ok && named.TypeParams().Len() > 0
+}
+
+// trackEmbeds registers the files of pkg.EmbedFiles as symbols of kind "file", with IDs
+// and names holding their path relative to the package directory, as in
+// "pkg/path/templates/index.html", and records the variables embedding them with
+// analyzer.EmbedDirective as their dependents.
+func (g *DependencyGraph) trackEmbeds(pkg *packages.Package) {
+	if len(pkg.EmbedFiles) == 0 {
+		return
+	}
+
+	for _, file := range pkg.Syntax {
+		dir := filepath.Dir(pkg.Fset.Position(file.Package).Filename)
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok || len(vs.Names) != 1 {
+					continue
+				}
+
+				patterns := analyzer.EmbedPatterns(vs.Doc)
+				if !gen.Lparen.IsValid() {
+					patterns = append(patterns, analyzer.EmbedPatterns(gen.Doc)...)
+				}
+
+				g.trackEmbedUsages(pkg, dir, vs.Names[0], patterns)
+			}
+		}
+	}
+}
+
+// trackEmbedUsages records the files of pkg.EmbedFiles matching patterns, relative to
+// dir, as dependencies of the variable name declares.
+func (g *DependencyGraph) trackEmbedUsages(pkg *packages.Package, dir string, name *ast.Ident, patterns []string) {
+	obj := pkg.TypesInfo.Defs[name]
+	if len(patterns) == 0 || obj == nil || obj.Parent() != pkg.Types.Scope() {
+		return
+	}
+
+	caller := g.intern(analyzer.SymbolID(obj))
+	g.addCaller(pkg.PkgPath, caller)
+
+	for _, file := range pkg.EmbedFiles {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || !matchesEmbed(patterns, filepath.ToSlash(rel)) {
+			continue
+		}
+
+		g.addUse(caller, g.intern(g.registerFile(pkg, file, rel)), pkg.Fset.Position(name.Pos()))
+	}
+}
+
+// registerFile registers a file of pkg other than a Go one, at rel from the package
+// directory, as a symbol of kind "file", and returns its ID.
+func (g *DependencyGraph) registerFile(pkg *packages.Package, file, rel string) string {
+	sym := &Symbol{
+		ID:      pkg.PkgPath + "/" + filepath.ToSlash(rel),
+		Name:    filepath.ToSlash(rel),
+		Package: pkg.PkgPath,
+		Kind:    "file",
+		File:    file,
+		Pos:     token.Position{Filename: file, Offset: 0, Line: 0, Column: 0},
+	}
+	if _, exists := g.Symbols[sym.ID]; !exists {
+		g.FileSyms[sym.File] = append(g.FileSyms[sym.File], sym.ID)
+	}
+
+	g.Symbols[sym.ID] = sym
+
+	return sym.ID
+}
+
+// trackCompanions records the companion files of pkg.OtherFiles that Go declarations
+// depend on: functions declared without a body depend on the assembly files defining
+// them, and the declarations of files importing "C" depend on every C, C++ or assembly
+// source of the package, since their cgo preamble may include or call into any of them.
+func (g *DependencyGraph) trackCompanions(pkg *packages.Package) {
+	if len(pkg.OtherFiles) == 0 {
+		return
+	}
+
+	// Files importing "C" are only compiled once rewritten by cgo.
+	if len(pkg.CompiledGoFiles) > 0 {
+		for _, file := range pkg.GoFiles {
+			if !slices.Contains(pkg.CompiledGoFiles, file) {
+				g.trackCgoUsages(pkg, file)
+			}
+		}
+	}
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body == nil {
+				g.trackAsmUsages(pkg, fn)
+			}
+		}
+	}
+}
+
+// trackCgoUsages records the sources of pkg.OtherFiles as dependencies of the symbols
+// declared in file, a Go file importing "C".
+func (g *DependencyGraph) trackCgoUsages(pkg *packages.Package, file string) {
+	ids := slices.Clone(g.FileSyms[file])
+
+	for _, other := range pkg.OtherFiles {
+		if filepath.Ext(other) == ".syso" {
+			continue // Prebuilt objects only get linked.
+		}
+
+		otherID := g.registerFile(pkg, other, filepath.Base(other))
+
+		for _, id := range ids {
+			caller := g.intern(id)
+			g.addCaller(pkg.PkgPath, caller)
+			g.addUse(caller, g.intern(otherID), g.Symbols[id].Pos)
+		}
+	}
+}
+
+// trackAsmUsages records the assembly files of pkg defining fn, a function declared
+// without a body, as its dependencies. Files that cannot be read are assumed to define it.
+func (g *DependencyGraph) trackAsmUsages(pkg *packages.Package, fn *ast.FuncDecl) {
+	callerID := callerSymbolID(pkg, fn)
+	if callerID == "" {
+		return
+	}
+
+	for _, other := range pkg.OtherFiles {
+		if ext := filepath.Ext(other); ext != ".s" && ext != ".S" {
+			continue
+		}
+
+		content, err := os.ReadFile(other) //nolint:gosec // Path comes from the package loader.
+		if err == nil && !definesAsmFunc(content, fn.Name.Name) {
+			continue
+		}
+
+		caller := g.intern(callerID)
+		g.addCaller(pkg.PkgPath, caller)
+		g.addUse(caller, g.intern(g.registerFile(pkg, other, filepath.Base(other))), pkg.Fset.Position(fn.Name.Pos()))
+	}
+}
+
+// definesAsmFunc reports whether the assembly source content has a TEXT directive for
+// the function name of its package, as in "TEXT ·name(SB)" or "TEXT ·name<ABIInternal>(SB)".
+func definesAsmFunc(content []byte, name string) bool {
+	for line := range strings.Lines(string(content)) {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "TEXT")
+		if !ok {
+			continue
+		}
+
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, "·"+name+"(") || strings.HasPrefix(rest, "·"+name+"<") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesEmbed reports whether the slash-separated path rel, or one of the directories
+// holding it, matches one of patterns.
+func matchesEmbed(patterns []string, rel string) bool {
+	for candidate := rel; candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		for _, pattern := range patterns {
+			if ok, err := path.Match(pattern, candidate); err == nil && ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// recordIgnores records the files and declarations of pkg marked with
+// analyzer.IgnoreDirective. A directive on a grouped declaration covers all its specs.
+func (g *DependencyGraph) recordIgnores(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		if analyzer.IsIgnoredFile(file) {
+			g.ignoredFiles[pkg.Fset.Position(file.Package).Filename] = pkg.PkgPath
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if id := callerSymbolID(pkg, decl); id != "" && analyzer.HasIgnoreDirective(decl.Doc) {
+					g.ignoredSyms[id] = pkg.PkgPath
+				}
+			case *ast.GenDecl:
+				g.recordSpecIgnores(pkg, decl)
+			}
+		}
+	}
+}
+
+// recordSpecIgnores records the specs of decl marked with analyzer.IgnoreDirective,
+// in their own comments or in the documentation of decl.
+func (g *DependencyGraph) recordSpecIgnores(pkg *packages.Package, decl *ast.GenDecl) {
+	all := analyzer.HasIgnoreDirective(decl.Doc)
+
+	for _, spec := range decl.Specs {
+		var (
+			names    []*ast.Ident
+			doc, com *ast.CommentGroup
+		)
+
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			names, doc, com = []*ast.Ident{spec.Name}, spec.Doc, spec.Comment
+		case *ast.ValueSpec:
+			names, doc, com = spec.Names, spec.Doc, spec.Comment
+		default:
+			continue
+		}
+
+		if !all && !analyzer.HasIgnoreDirective(doc) && !analyzer.HasIgnoreDirective(com) {
+			continue
+		}
+
+		for _, name := range names {
+			if obj := pkg.TypesInfo.Defs[name]; obj != nil {
+				if id := analyzer.SymbolID(obj); id != "" {
+					g.ignoredSyms[id] = pkg.PkgPath
+				}
+			}
+		}
+	}
+}
+
+// trackTypeSpecUsages records the package-level declarations a type declaration uses:
+// the types of struct fields, embedded types, interface method signatures and embedded
+// interfaces, the target of an alias, and the constraints of type parameters. Types
+// declared in function bodies are covered by the usages of their function.
 func (g *DependencyGraph) trackTypeSpecUsages(pkg *packages.Package, ts *ast.TypeSpec) {
 	obj := pkg.TypesInfo.Defs[ts.Name]
-	if obj == nil {
+	if obj == nil || obj.Parent() != pkg.Types.Scope() {
 		return
 	}
 
-	callerID := symbolID(obj)
+	callerID := analyzer.SymbolID(obj)
 	if callerID == "" {
 		return
 	}
 
-	ast.Inspect(ts.Type, func(inner ast.Node) bool {
-		g.recordUsage(pkg, callerID, inner)
+	// Type parameter constraints, including union and approximation elements
+	// such as [T int | ~float64 | Celsius], reference the named types in their type set.
+	if ts.TypeParams != nil {
+		g.recordUsages(pkg, callerID, ts.TypeParams)
+	}
 
-		return true
-	})
+	g.recordUsages(pkg, callerID, ts.Type)
 }
 
-func (g *DependencyGraph) trackValueSpecUsages(pkg *packages.Package, vs *ast.ValueSpec) {
-	callerIDs := collectValueSpecCallerIDs(pkg, vs)
-	if len(callerIDs) == 0 {
+// trackBlankImportUsages records what a blank import depends on: the initialization of
+// the imported package, that is its package-level variables and init functions.
+func (g *DependencyGraph) trackBlankImportUsages(pkg *packages.Package, spec *ast.ImportSpec) {
+	callerID := blankImportID(pkg, spec)
+	if callerID == "" {
 		return
 	}
 
-	inspect := func(node ast.Node) {
-		ast.Inspect(node, func(inner ast.Node) bool {
-			for _, callerID := range callerIDs {
-				g.recordUsage(pkg, callerID, inner)
-			}
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return
+	}
 
-			return true
-		})
+	imported := pkg.Imports[path]
+	if imported == nil || imported.Types == nil {
+		return
 	}
 
-	if vs.Type != nil {
-		inspect(vs.Type)
+	caller := g.intern(callerID)
+	g.addCaller(pkg.PkgPath, caller)
+
+	pos := pkg.Fset.Position(spec.Path.Pos())
+	g.addUse(caller, g.intern(imported.PkgPath+".init"), pos)
+
+	scope := imported.Types.Scope()
+	for _, name := range scope.Names() {
+		if obj, ok := scope.Lookup(name).(*types.Var); ok {
+			g.addUse(caller, g.intern(analyzer.SymbolID(obj)), pos)
+		}
 	}
+}
 
-	for _, val := range vs.Values {
-		inspect(val)
+// blankImportID returns the ID of the symbol standing for spec, as in
+// `pkg/path._ "image/png"`, or "" when spec is no blank import.
+func blankImportID(pkg *packages.Package, spec *ast.ImportSpec) string {
+	if spec.Name == nil || spec.Name.Name != "_" {
+		return ""
+	}
+
+	return pkg.PkgPath + "._ " + spec.Path.Value
+}
+
+func (g *DependencyGraph) trackValueSpecUsages(pkg *packages.Package, vs *ast.ValueSpec) {
+	callerIDs := collectValueSpecCallerIDs(pkg, vs)
+	if len(callerIDs) == 0 {
+		return
+	}
+
+	for _, callerID := range callerIDs {
+		if vs.Type != nil {
+			g.recordUsages(pkg, callerID, vs.Type)
+		}
+
+		for _, val := range vs.Values {
+			g.recordUsages(pkg, callerID, val)
+		}
 	}
 }
 
@@ -207,7 +1120,7 @@ func collectValueSpecCallerIDs(pkg *packages.Package, vs *ast.ValueSpec) []strin
 			continue
 		}
 
-		callerID := symbolID(obj)
+		callerID := analyzer.SymbolID(obj)
 		if callerID != "" {
 			ids = append(ids, callerID)
 		}
@@ -219,71 +1132,97 @@ func collectValueSpecCallerIDs(pkg *packages.Package, vs *ast.ValueSpec) []strin
 func (g *DependencyGraph) trackFuncBodyUsages(
 	pkg *packages.Package, callerID string, fn *ast.FuncDecl,
 ) {
-	// Track usages in function signature (parameter and return types).
-	ast.Inspect(fn.Type, func(inner ast.Node) bool {
-		g.recordUsage(pkg, callerID, inner)
+	// Track usages in function signature (receiver, parameter and return types).
+	if fn.Recv != nil {
+		g.recordUsages(pkg, callerID, fn.Recv)
+	}
 
-		return true
-	})
+	g.recordUsages(pkg, callerID, fn.Type)
 
 	if fn.Body == nil {
 		return
 	}
 
-	ast.Inspect(fn.Body, func(inner ast.Node) bool {
-		g.recordUsage(pkg, callerID, inner)
+	g.recordUsages(pkg, callerID, fn.Body)
+}
+
+// recordUsages records every usage found in node as a dependency of callerID.
+// Subtrees that are calls to decoupling functions are recorded as decoupled instead.
+func (g *DependencyGraph) recordUsages(pkg *packages.Package, callerID string, node ast.Node) {
+	caller := g.intern(callerID)
+	g.addCaller(pkg.PkgPath, caller)
+
+	ast.Inspect(node, func(inner ast.Node) bool {
+		if call, ok := inner.(*ast.CallExpr); ok && g.isDecouplingCall(pkg, call) {
+			ast.Inspect(call, func(arg ast.Node) bool {
+				if calleeID := usedSymbolID(pkg, arg); calleeID != "" {
+					g.addDecoupled(caller, g.intern(calleeID))
+				}
+
+				return true
+			})
+
+			return false
+		}
+
+		if calleeID := usedSymbolID(pkg, inner); calleeID != "" {
+			g.addUse(caller, g.intern(calleeID), pkg.Fset.Position(inner.Pos()))
+		}
 
 		return true
 	})
 }
 
-func (g *DependencyGraph) recordUsage(
-	pkg *packages.Package, callerID string, inner ast.Node,
-) {
-	switch node := inner.(type) {
-	case *ast.Ident:
-		if obj := pkg.TypesInfo.Uses[node]; obj != nil {
-			if calleeID := symbolID(obj); calleeID != "" {
-				g.AddDependency(callerID, calleeID)
-			}
-		}
-	case *ast.SelectorExpr:
-		if obj := pkg.TypesInfo.Uses[node.Sel]; obj != nil {
-			if calleeID := symbolID(obj); calleeID != "" {
-				g.AddDependency(callerID, calleeID)
-			}
-		}
+// isDecouplingCall reports whether call invokes a declared decoupling function.
+func (g *DependencyGraph) isDecouplingCall(pkg *packages.Package, call *ast.CallExpr) bool {
+	if len(g.decouplingFuncs) == 0 {
+		return false
 	}
-}
 
-func callerSymbolID(pkg *packages.Package, fn *ast.FuncDecl) string {
-	if fn.Recv == nil {
-		return pkg.PkgPath + "." + fn.Name.Name
+	fun := call.Fun
+	if index, ok := fun.(*ast.IndexExpr); ok { // Explicit generic instantiation.
+		fun = index.X
 	}
 
-	if len(fn.Recv.List) == 0 {
-		return ""
+	calleeID := usedSymbolID(pkg, fun)
+	if calleeID == "" {
+		return false
 	}
 
-	recvType := fn.Recv.List[0].Type
+	return g.decouplingFuncs[calleeID] || g.decouplingFuncs[calleeID[strings.LastIndex(calleeID, ".")+1:]]
+}
+
+// usedSymbolID returns the ID of the symbol an identifier or selector refers to, if any.
+// Objects local to a file or a function, such as imported package names, type
+// parameters and local variables, are no symbols, even when a package-level
+// declaration shares their name.
+func usedSymbolID(pkg *packages.Package, node ast.Node) string {
+	var ident *ast.Ident
 
-	// Handle pointer receivers.
-	if star, ok := recvType.(*ast.StarExpr); ok {
-		recvType = star.X
+	switch nn := node.(type) {
+	case *ast.Ident:
+		ident = nn
+	case *ast.SelectorExpr:
+		ident = nn.Sel
+	default:
+		return ""
 	}
 
-	if ident, ok := recvType.(*ast.Ident); ok {
-		return pkg.PkgPath + "." + ident.Name + "." + fn.Name.Name
+	obj := pkg.TypesInfo.Uses[ident]
+	if obj == nil || obj.Pkg() != nil && obj.Parent() != nil && obj.Parent() != obj.Pkg().Scope() {
+		return ""
 	}
 
-	return ""
+	return analyzer.SymbolID(obj)
 }
 
-// symbolID generates a unique identifier for a types.Object.
-func symbolID(obj types.Object) string {
-	if obj.Pkg() == nil {
-		return "" // Built-in, skip.
+// callerSymbolID returns the ID of the function or method fn declares, or "" when it
+// declares no symbol, as for methods of invalid receivers.
+func callerSymbolID(pkg *packages.Package, fn *ast.FuncDecl) string {
+	obj := pkg.TypesInfo.Defs[fn.Name]
+	if obj == nil {
+		return ""
 	}
 
-	return obj.Pkg().Path() + "." + obj.Name()
+	return analyzer.SymbolID(obj)
 }
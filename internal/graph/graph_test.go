@@ -1,10 +1,18 @@
 package graph_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
+	"golang.org/x/tools/go/packages"
+
 	"dario.cat/darna/internal/analyzer"
 	"dario.cat/darna/internal/graph"
 )
@@ -20,14 +28,6 @@ func TestNewDependencyGraph(t *testing.T) {
 	if g.FileSyms == nil {
 		t.Error("FileSyms map should not be nil")
 	}
-
-	if g.OutEdges == nil {
-		t.Error("OutEdges map should not be nil")
-	}
-
-	if g.InEdges == nil {
-		t.Error("InEdges map should not be nil")
-	}
 }
 
 func TestAddDependency(t *testing.T) {
@@ -39,11 +39,11 @@ func TestAddDependency(t *testing.T) {
 
 	g.AddDependency(from, to)
 
-	if _, ok := g.OutEdges[from][to]; !ok {
+	if !slices.Contains(slices.Collect(g.OutEdges(from)), to) {
 		t.Errorf("Expected outgoing edge from %s to %s", from, to)
 	}
 
-	if _, ok := g.InEdges[to][from]; !ok {
+	if !slices.Contains(slices.Collect(g.InEdges(to)), from) {
 		t.Errorf("Expected incoming edge to %s from %s", to, from)
 	}
 }
@@ -76,6 +76,57 @@ func TestTransitiveDeps(t *testing.T) {
 	}
 }
 
+func TestShortestPaths(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewDependencyGraph()
+
+	// A reaches D both directly through C and through B -> C.
+	g.AddDependency("pkg.A", "pkg.B")
+	g.AddDependency("pkg.B", "pkg.C")
+	g.AddDependency("pkg.A", "pkg.C")
+	g.AddDependency("pkg.C", "pkg.D")
+
+	paths := g.ShortestPaths("pkg.A")
+
+	if got, want := paths.To("pkg.D"), []string{"pkg.A", "pkg.C", "pkg.D"}; !slices.Equal(got, want) {
+		t.Errorf("To(pkg.D) = %v, want %v", got, want)
+	}
+
+	if got := paths.To("pkg.A"); !slices.Equal(got, []string{"pkg.A"}) {
+		t.Errorf("To(pkg.A) = %v, want only the start", got)
+	}
+
+	if got := paths.To("pkg.Unknown"); got != nil {
+		t.Errorf("To(pkg.Unknown) = %v, want nil", got)
+	}
+}
+
+func TestShortestPathsWithin(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewDependencyGraph()
+
+	g.AddDependency("pkg.A", "pkg.B")
+	g.AddDependency("pkg.B", "pkg.C")
+	g.AddDependency("pkg.C", "pkg.D")
+
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{maxDepth: 1, want: []string{"pkg.B"}},
+		{maxDepth: 2, want: []string{"pkg.B", "pkg.C"}},
+		{maxDepth: 0, want: []string{"pkg.B", "pkg.C", "pkg.D"}},
+	}
+
+	for _, tt := range tests {
+		if got := slices.Sorted(g.ShortestPathsWithin("pkg.A", tt.maxDepth).Reached()); !slices.Equal(got, tt.want) {
+			t.Errorf("ShortestPathsWithin(pkg.A, %d).Reached() = %v, want %v", tt.maxDepth, got, tt.want)
+		}
+	}
+}
+
 func TestTransitiveDependents(t *testing.T) {
 	t.Parallel()
 
@@ -142,7 +193,7 @@ const Pi = 3.14
 	}
 
 	// Load the package.
-	pkgs, err := analyzer.LoadPackages(tmpDir, nil, ".")
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
 	if err != nil {
 		t.Fatalf("LoadPackages() error = %v", err)
 	}
@@ -173,7 +224,670 @@ const Pi = 3.14
 	}
 
 	// Check that Foo -> Bar dependency exists.
-	if _, ok := g.OutEdges[fooID][barID]; !ok {
+	if !slices.Contains(slices.Collect(g.OutEdges(fooID)), barID) {
 		t.Errorf("Expected Foo to depend on Bar")
 	}
 }
+
+func TestAnalyzePackageTypeDeclarations(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	content := `package testpkg
+
+import "io"
+
+type Base struct{}
+
+type Field struct{}
+
+type Param struct{}
+
+type Result struct{}
+
+type Target struct{}
+
+type T struct{}
+
+type S struct {
+	Base
+	*io.PipeReader
+	F Field
+	M map[string][]*Target
+}
+
+type I interface {
+	io.Reader
+	Do(p Param) Result
+}
+
+type A = Target
+
+type G[T any, N ~int | Field] struct{ v T }
+
+func local() {
+	type Param struct{ r Result }
+}
+`
+
+	err := os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testpkg\n\ngo 1.24\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+	g.AnalyzePackage(pkgs[0])
+
+	// Neither the io import nor the type parameter T are symbols.
+	want := map[string][]string{
+		"testpkg.S":     {"io.PipeReader", "testpkg.Base", "testpkg.Field", "testpkg.Target"},
+		"testpkg.I":     {"io.Reader", "testpkg.Param", "testpkg.Result"},
+		"testpkg.A":     {"testpkg.Target"},
+		"testpkg.G":     {"testpkg.Field"},
+		"testpkg.local": {"testpkg.Result"},
+		"testpkg.Param": nil,
+	}
+
+	for id, deps := range want {
+		if got := slices.Sorted(g.OutEdges(id)); !slices.Equal(got, deps) {
+			t.Errorf("OutEdges(%s) = %v, want %v", id, got, deps)
+		}
+	}
+}
+
+func TestAnalyzePackageFuncSignatures(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	content := `package testpkg
+
+type Param struct{}
+
+type Result struct{}
+
+type Box[T any] struct{ v T }
+
+type Server struct{}
+
+func Handle(p *Param) (Result, error) { return Result{}, nil }
+
+func (s *Server) Serve(p []Param) {}
+
+func (b Box[T]) Get() (v T) { return }
+`
+
+	err := os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testpkg\n\ngo 1.24\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+	g.AnalyzePackage(pkgs[0])
+
+	want := map[string][]string{
+		"testpkg.Handle":       {"testpkg.Param", "testpkg.Result"},
+		"testpkg.Server.Serve": {"testpkg.Param", "testpkg.Server"},
+		"testpkg.Box.Get":      {"testpkg.Box"},
+	}
+
+	for id, deps := range want {
+		if got := slices.Sorted(g.OutEdges(id)); !slices.Equal(got, deps) {
+			t.Errorf("OutEdges(%s) = %v, want %v", id, got, deps)
+		}
+
+		if sym := g.Symbols[id]; sym == nil || sym.Name != id[len("testpkg."):] {
+			t.Errorf("Symbols[%s] = %+v, want a symbol named %s", id, sym, id[len("testpkg."):])
+		}
+	}
+}
+
+func TestAnalyzePackageMethodIDs(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	content := `package testpkg
+
+type Set struct{ n int }
+
+func (s *Set) Add(v int) { s.n += v }
+
+type List[T any] struct{}
+
+func (l List[T]) Add(v T) {}
+
+type Adder interface{ Add(v int) }
+
+var n int
+
+func Add(v int) {}
+
+func Use(s *Set, l List[string], a Adder) {
+	s.Add(1)
+	l.Add("x")
+	a.Add(2)
+	s.n = 3
+}
+`
+
+	err := os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testpkg\n\ngo 1.24\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+	g.AnalyzePackage(pkgs[0])
+
+	// Neither the package function Add nor the variable n are reached through the
+	// methods and the field sharing their names.
+	want := []string{
+		"testpkg.Adder", "testpkg.Adder.Add", "testpkg.List", "testpkg.List.Add", "testpkg.Set", "testpkg.Set.Add",
+	}
+	if got := slices.Sorted(g.OutEdges("testpkg.Use")); !slices.Equal(got, want) {
+		t.Errorf("OutEdges(testpkg.Use) = %v, want %v", got, want)
+	}
+
+	for _, id := range []string{"testpkg.Set.Add", "testpkg.List.Add", "testpkg.Add"} {
+		if _, ok := g.Symbols[id]; !ok {
+			t.Errorf("Symbols[%s] missing", id)
+		}
+	}
+}
+
+func TestAnalyzePackageDecouplingFuncs(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	content := `package testpkg
+
+type Plugin struct{}
+
+func register(p *Plugin) {}
+
+func Load() {
+	register(&Plugin{})
+}
+`
+
+	err := os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testpkg\n\ngo 1.24\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+	g.SetDecouplingFuncs([]string{"register"})
+	g.AnalyzePackage(pkgs[0])
+
+	for _, dep := range []string{"testpkg.register", "testpkg.Plugin"} {
+		if slices.Contains(slices.Collect(g.OutEdges("testpkg.Load")), dep) {
+			t.Errorf("Expected Load -> %s to be decoupled, found a regular edge", dep)
+		}
+
+		if !slices.Contains(slices.Collect(g.Decoupled("testpkg.Load")), dep) {
+			t.Errorf("Expected decoupled edge Load -> %s", dep)
+		}
+	}
+}
+
+func TestFragmentRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	content := `package testpkg
+
+type Plugin struct{}
+
+func register(p *Plugin) {}
+
+func Load() {
+	register(&Plugin{})
+	Helper()
+}
+
+//darna:ignore
+func Helper() {}
+`
+
+	err := os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testpkg\n\ngo 1.24\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, ".")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	want := graph.NewDependencyGraph()
+	want.SetDecouplingFuncs([]string{"register"})
+	want.AnalyzePackage(pkgs[0])
+
+	data, err := json.Marshal(want.Fragment("testpkg"))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var fragment graph.Fragment
+
+	err = json.Unmarshal(data, &fragment)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	got := graph.NewDependencyGraph()
+	got.AddFragment("testpkg", fragment)
+
+	if !reflect.DeepEqual(got.Symbols, want.Symbols) || !reflect.DeepEqual(got.Fragment("testpkg"), fragment) {
+		t.Errorf("Graph from fragment differs from the analyzed one")
+	}
+
+	if !slices.Equal(slices.Collect(got.InEdges("testpkg.Helper")), []string{"testpkg.Load"}) {
+		t.Errorf("InEdges(Helper) = %v, want [testpkg.Load]", slices.Collect(got.InEdges("testpkg.Helper")))
+	}
+
+	if !got.IsIgnoredSymbol("testpkg.Helper") {
+		t.Errorf("Expected Helper to stay ignored")
+	}
+
+	got.RemovePackage("testpkg")
+
+	if len(got.Symbols) != 0 || slices.Collect(got.InEdges("testpkg.Helper")) != nil ||
+		got.IsIgnoredSymbol("testpkg.Helper") {
+		t.Errorf("Expected RemovePackage to drop everything the fragment added")
+	}
+}
+
+func TestAnalyzePackages(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod":        "module testpkg\n\ngo 1.24\n",
+		"a/a.go":        "package a\n\nimport \"testpkg/b\"\n\nfunc A() { b.B() }\n",
+		"a/a_test.go":   "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) { A() }\n",
+		"b/b.go":        "package b\n\nfunc B() { C() }\n\nfunc C() {}\n",
+		"c/c.go":        "package c\n\nimport \"testpkg/a\"\n\nvar V = a.A\n",
+		"c/ignored.go":  "//darna:ignore\npackage c\n\nfunc D() {}\n",
+		"b/b_x_test.go": "package b_test\n\nimport \"testpkg/b\"\n\nvar _ = b.B\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	want := graph.NewDependencyGraph()
+	for _, pkg := range pkgs {
+		want.AnalyzePackage(pkg)
+	}
+
+	got := graph.NewDependencyGraph()
+	err = got.AnalyzePackages(t.Context(), pkgs...)
+	if err != nil {
+		t.Fatalf("AnalyzePackages() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got.Symbols, want.Symbols) {
+		t.Errorf("Parallel analysis differs from the serial one")
+	}
+
+	for _, pkg := range pkgs {
+		if !reflect.DeepEqual(got.Fragment(pkg.PkgPath), want.Fragment(pkg.PkgPath)) {
+			t.Errorf("Parallel analysis of %s differs from the serial one", pkg.PkgPath)
+		}
+	}
+
+	for file, ids := range want.FileSyms {
+		gotIDs := slices.Sorted(slices.Values(got.FileSyms[file]))
+		if !slices.Equal(gotIDs, slices.Sorted(slices.Values(ids))) {
+			t.Errorf("FileSyms[%s] = %v, want %v", file, got.FileSyms[file], ids)
+		}
+	}
+
+	if !got.IsIgnoredFile(filepath.Join(tmpDir, "c", "ignored.go")) {
+		t.Errorf("Expected ignored.go to stay ignored")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	cancelled := graph.NewDependencyGraph()
+
+	err = cancelled.AnalyzePackages(ctx, pkgs...)
+	if !errors.Is(err, context.Canceled) || len(cancelled.Symbols) != 0 {
+		t.Errorf("AnalyzePackages() with a done context = %v with %d symbols, want %v and none",
+			err, len(cancelled.Symbols), context.Canceled)
+	}
+}
+
+func TestAnalyzePackagesImports(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod":       "module testpkg\n\ngo 1.24\n",
+		"a/a.go":       "package a\n\nvar X = 1\n\ntype T struct{}\n\nfunc F() {}\n",
+		"a/init.go":    "package a\n\nfunc init() { F() }\n",
+		"b/dot.go":     "package b\n\nimport . \"testpkg/a\"\n\nfunc G(t T) int { F(); return X }\n",
+		"b/blank.go":   "package b\n\nimport _ \"testpkg/a\"\n",
+		"b/nothing.go": "package b\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+
+	err = g.AnalyzePackages(t.Context(), pkgs...)
+	if err != nil {
+		t.Fatalf("AnalyzePackages() error = %v", err)
+	}
+
+	blankID := `testpkg/b._ "testpkg/a"`
+
+	want := map[string][]string{
+		"testpkg/b.G":    {"testpkg/a.F", "testpkg/a.T", "testpkg/a.X"},
+		blankID:          {"testpkg/a.X", "testpkg/a.init"},
+		"testpkg/a.init": {"testpkg/a.F"},
+	}
+
+	for id, deps := range want {
+		if got := slices.Sorted(g.OutEdges(id)); !slices.Equal(got, deps) {
+			t.Errorf("OutEdges(%s) = %v, want %v", id, got, deps)
+		}
+	}
+
+	if got := g.FileSyms[filepath.Join(tmpDir, "b", "blank.go")]; !slices.Equal(got, []string{blankID}) {
+		t.Errorf("FileSyms[b/blank.go] = %v, want the blank import", got)
+	}
+
+	if sym := g.Symbols["testpkg/a.init"]; sym == nil || sym.File != filepath.Join(tmpDir, "a", "init.go") {
+		t.Errorf("Symbols[testpkg/a.init] = %+v, want the init function of a/init.go", sym)
+	}
+}
+
+func TestAnalyzePackagesCompanionFiles(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module testpkg\n\ngo 1.24\n",
+		"cg/cg.go": "package cg\n\n// #include \"add.h\"\nimport \"C\"\n\n" +
+			"func Add(a, b int) int { return int(C.add(C.int(a), C.int(b))) }\n",
+		"cg/add.h": "int add(int a, int b);\n",
+		"cg/add.c": "#include \"add.h\"\n\nint add(int a, int b) { return a + b; }\n",
+		"asm/asm.go": "package asm\n\n//go:noescape\nfunc Sum(xs []int) int\n\n" +
+			"func Twice(xs []int) int { return 2 * Sum(xs) }\n",
+		"asm/sum.s":    "#include \"textflag.h\"\n\nTEXT ·Sum(SB), NOSPLIT, $0-32\n\tRET\n",
+		"asm/unused.s": "#include \"textflag.h\"\n\nTEXT ·Other(SB), NOSPLIT, $0-0\n\tRET\n",
+		"asm/stub.go":  "package asm\n\nfunc Other()\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+
+	err = g.AnalyzePackages(t.Context(), pkgs...)
+	if err != nil {
+		t.Fatalf("AnalyzePackages() error = %v", err)
+	}
+
+	// Cgo rewrites C.add into uses of symbols it generates, which are kept out.
+	want := map[string][]string{
+		"testpkg/cg.Add":    {"testpkg/cg/add.c", "testpkg/cg/add.h"},
+		"testpkg/asm.Sum":   {"testpkg/asm/sum.s"},
+		"testpkg/asm.Other": {"testpkg/asm/unused.s"},
+		"testpkg/asm.Twice": {"testpkg/asm.Sum"},
+	}
+
+	for id, deps := range want {
+		got := slices.DeleteFunc(slices.Sorted(g.OutEdges(id)), func(dep string) bool {
+			return strings.HasPrefix(dep, "testpkg/cg._C")
+		})
+		if !slices.Equal(got, deps) {
+			t.Errorf("OutEdges(%s) = %v, want %v", id, got, deps)
+		}
+	}
+
+	sym := g.Symbols["testpkg/asm/sum.s"]
+	if sym == nil || sym.Kind != "file" || sym.File != filepath.Join(tmpDir, "asm", "sum.s") {
+		t.Errorf("Symbols[testpkg/asm/sum.s] = %+v, want the assembly file", sym)
+	}
+}
+
+func TestAnalyzePackagesImplementations(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module testpkg\n\ngo 1.24\n",
+		"a/a.go": "package a\n\ntype Shape interface{ Area() float64 }\n\ntype Any interface{}\n",
+		"b/b.go": "package b\n\nimport \"testpkg/a\"\n\nvar _ a.Shape\n\n" +
+			"type Square struct{ s float64 }\n\nfunc (q *Square) Area() float64 { return q.s * q.s }\n\n" +
+			"type Named interface{ Name() string }\n\ntype Cat struct{}\n\nfunc (Cat) Name() string { return \"cat\" }\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	g := graph.NewDependencyGraph()
+	g.SetImplementations(true)
+
+	err = g.AnalyzePackages(t.Context(), pkgs...)
+	if err != nil {
+		t.Fatalf("AnalyzePackages() error = %v", err)
+	}
+
+	// The empty interface Any is implemented by every type and linked to none.
+	want := map[string][]string{
+		"testpkg/a.Shape":  {"testpkg/b.Square"},
+		"testpkg/b.Square": {"testpkg/a.Shape"},
+		"testpkg/b.Named":  {"testpkg/b.Cat"},
+		"testpkg/b.Cat":    {"testpkg/b.Named"},
+	}
+
+	for id, deps := range want {
+		if got := slices.Sorted(g.OutEdges(id)); !slices.Equal(got, deps) {
+			t.Errorf("OutEdges(%s) = %v, want %v", id, got, deps)
+		}
+	}
+
+	// Removing b drops the edges it recorded, and only those.
+	g.AddDependency("testpkg/a.Shape", "testpkg/a.Any")
+	g.RemovePackage("testpkg/b")
+
+	if got := slices.Collect(g.OutEdges("testpkg/a.Shape")); !slices.Equal(got, []string{"testpkg/a.Any"}) {
+		t.Errorf("OutEdges(testpkg/a.Shape) = %v after removing b, want [testpkg/a.Any]", got)
+	}
+}
+
+func TestUpdatePackageKeepsImplementations(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	// Shape uses Unit, so updating a clears the edges recorded from its code.
+	files := map[string]string{
+		"go.mod": "module testpkg\n\ngo 1.24\n",
+		"a/a.go": "package a\n\ntype Unit float64\n\ntype Shape interface{ Area() Unit }\n",
+		"b/b.go": "package b\n\nimport \"testpkg/a\"\n\nvar _ a.Shape\n\n" +
+			"type Square struct{ s a.Unit }\n\nfunc (q *Square) Area() a.Unit { return q.s * q.s }\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyze := func() (*graph.DependencyGraph, []*packages.Package) {
+		t.Helper()
+
+		pkgs, err := analyzer.LoadPackages(t.Context(), tmpDir, nil, analyzer.Build{}, "./...")
+		if err != nil {
+			t.Fatalf("LoadPackages() error = %v", err)
+		}
+
+		g := graph.NewDependencyGraph()
+		g.SetImplementations(true)
+
+		err = g.AnalyzePackages(t.Context(), pkgs...)
+		if err != nil {
+			t.Fatalf("AnalyzePackages() error = %v", err)
+		}
+
+		return g, pkgs
+	}
+
+	got, _ := analyze()
+
+	content := files["a/a.go"] + "\nfunc Zero() Unit { return 0 }\n"
+
+	err := os.WriteFile(filepath.Join(tmpDir, "a", "a.go"), []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write a/a.go: %v", err)
+	}
+
+	want, pkgs := analyze()
+
+	pkgs = slices.DeleteFunc(pkgs, func(pkg *packages.Package) bool { return pkg.PkgPath != "testpkg/a" })
+
+	err = got.UpdatePackage(t.Context(), pkgs...)
+	if err != nil {
+		t.Fatalf("UpdatePackage() error = %v", err)
+	}
+
+	for _, pkgPath := range []string{"testpkg/a", "testpkg/b"} {
+		if !reflect.DeepEqual(got.Fragment(pkgPath), want.Fragment(pkgPath)) {
+			t.Errorf("Fragment(%s) = %+v after updating a, want %+v", pkgPath, got.Fragment(pkgPath), want.Fragment(pkgPath))
+		}
+	}
+
+	if edges := slices.Collect(got.OutEdges("testpkg/a.Shape")); !slices.Contains(edges, "testpkg/b.Square") {
+		t.Errorf("OutEdges(testpkg/a.Shape) = %v after updating a, want testpkg/b.Square among them", edges)
+	}
+}
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"cmp"
+	"slices"
+)
+
+// FilePair aggregates the violations between one staged file and one missing file.
+type FilePair struct {
+	StagedFile  string      // File being committed.
+	MissingFile string      // File with unstaged changes that's needed.
+	Violations  []Violation // Distinct violations of the pair, earliest usage first.
+}
+
+// AggregateViolations deduplicates violations and groups them per (staged file,
+// missing file) pair. Pairs are sorted by missing file, then staged file.
+func AggregateViolations(violations []Violation) []FilePair {
+	type pairKey struct{ staged, missing string }
+
+	type violationKey struct {
+		pair                                pairKey
+		stagedSymbol, missingSymbol, reason string
+	}
+
+	seen := make(map[violationKey]bool, len(violations))
+	byPair := make(map[pairKey]*FilePair)
+
+	for _, v := range violations {
+		pk := pairKey{staged: v.StagedFile, missing: v.MissingFile}
+
+		vk := violationKey{pair: pk, stagedSymbol: v.StagedSymbol, missingSymbol: v.MissingSymbol, reason: v.Reason}
+		if seen[vk] {
+			continue
+		}
+
+		seen[vk] = true
+
+		if byPair[pk] == nil {
+			byPair[pk] = &FilePair{StagedFile: v.StagedFile, MissingFile: v.MissingFile, Violations: nil}
+		}
+
+		byPair[pk].Violations = append(byPair[pk].Violations, v)
+	}
+
+	pairs := make([]FilePair, 0, len(byPair))
+	for _, pair := range byPair {
+		slices.SortStableFunc(pair.Violations, compareUsage)
+		pairs = append(pairs, *pair)
+	}
+
+	slices.SortFunc(pairs, func(a, b FilePair) int {
+		return cmp.Or(cmp.Compare(a.MissingFile, b.MissingFile), cmp.Compare(a.StagedFile, b.StagedFile))
+	})
+
+	return pairs
+}
+
+// compareUsage orders violations by usage position, unknown positions last.
+func compareUsage(a, b Violation) int {
+	if (a.Line == 0) != (b.Line == 0) {
+		if a.Line == 0 {
+			return 1
+		}
+
+		return -1
+	}
+
+	return cmp.Or(
+		cmp.Compare(a.Line, b.Line),
+		cmp.Compare(a.Column, b.Column),
+		cmp.Compare(a.StagedSymbol, b.StagedSymbol),
+		cmp.Compare(a.MissingSymbol, b.MissingSymbol),
+	)
+}
@@ -0,0 +1,493 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+)
+
+// APIChange is a change of the exported API of a package, between HEAD and the staged
+// snapshot.
+type APIChange struct {
+	Package string `json:"package"`          // Package path.
+	Symbol  string `json:"symbol,omitempty"` // Name, "Type.Method" or "Type.Field"; empty for the package.
+	Message string `json:"message"`          // What changed, e.g. "removed".
+}
+
+// String formats the change as "symbol: message", or only the message for the package.
+func (c APIChange) String() string {
+	if c.Symbol == "" {
+		return c.Message
+	}
+
+	return c.Symbol + ": " + c.Message
+}
+
+// CheckAPI compares the exported API of the packages of workDir at HEAD with the one
+// the staged snapshot would commit, and reports removals and incompatible changes,
+// sorted by package and symbol: changed signatures, types, constant values, struct
+// fields, method sets and methods added to implementable interfaces. Additions are
+// compatible and left out, as are commands, internal packages and tests.
+func CheckAPI(ctx context.Context, workDir string, opts ...Option) ([]APIChange, error) {
+	incompatible, _, err := diffAPI(ctx, workDir, newOptions(opts))
+
+	return incompatible, err
+}
+
+// diffAPI compares the exported API of the packages of workDir at HEAD with the one of
+// the staged snapshot, and returns its incompatible changes and its additions, each
+// sorted by package and symbol.
+func diffAPI(ctx context.Context, workDir string, cfg options) ([]APIChange, []APIChange, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	root, cleanup, err := snapshotDir()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer cleanup()
+
+	headFiles, err := git.GetTreeFiles(ctx, absWorkDir, "HEAD")
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing files: %w", err)
+	}
+
+	head, err := git.GetFilesAtRevision(ctx, absWorkDir, "HEAD", filterModuleFiles(headFiles))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading HEAD files: %w", err)
+	}
+
+	headOverlay := make(map[string][]byte, len(head))
+	for path, content := range head {
+		headOverlay[filepath.Join(root, path)] = content
+	}
+
+	oldAPI, err := loadAPI(ctx, root, headOverlay, cfg.build())
+	if err != nil {
+		return nil, nil, fmt.Errorf("HEAD: %w", err)
+	}
+
+	stagedOverlay, err := indexOverlay(ctx, absWorkDir, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newAPI, err := loadAPI(ctx, root, stagedOverlay, cfg.build())
+	if err != nil {
+		return nil, nil, fmt.Errorf("staged snapshot: %w", err)
+	}
+
+	var incompatible, compatible []APIChange
+
+	for path, oldPkg := range oldAPI {
+		newPkg, ok := newAPI[path]
+		if !ok {
+			incompatible = append(incompatible, APIChange{Package: path, Symbol: "", Message: "package removed"})
+
+			continue
+		}
+
+		c := compareAPI(oldPkg, newPkg)
+		incompatible = append(incompatible, c.changes...)
+		compatible = append(compatible, c.additions...)
+	}
+
+	for path := range newAPI {
+		if oldAPI[path] == nil {
+			compatible = append(compatible, APIChange{Package: path, Symbol: "", Message: "package added"})
+		}
+	}
+
+	return sortAPIChanges(incompatible), sortAPIChanges(compatible), nil
+}
+
+// sortAPIChanges sorts changes by package, symbol and message, and returns them, never nil.
+func sortAPIChanges(changes []APIChange) []APIChange {
+	slices.SortFunc(changes, func(a, b APIChange) int {
+		return cmp.Or(cmp.Compare(a.Package, b.Package), cmp.Compare(a.Symbol, b.Symbol),
+			cmp.Compare(a.Message, b.Message))
+	})
+
+	if changes == nil {
+		return []APIChange{}
+	}
+
+	return changes
+}
+
+// loadAPI type-checks the packages below root, seen through overlay and selected by
+// build, and returns the public ones by path: neither commands, internal packages nor test variants. Errors in
+// function bodies, as uses of the API the snapshot removes, leave declarations intact
+// and are ignored.
+func loadAPI(
+	ctx context.Context, root string, overlay map[string][]byte, build analyzer.Build,
+) (map[string]*types.Package, error) {
+	pkgs, err := analyzer.LoadPackagesShallow(ctx, root, overlay, build, "./...")
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	api := make(map[string]*types.Package, len(pkgs))
+
+	for _, pkg := range pkgs {
+		if pkg.ID != pkg.PkgPath || pkg.Name == "main" || isInternalPackage(pkg.PkgPath) {
+			continue
+		}
+
+		api[pkg.PkgPath] = pkg.Types
+	}
+
+	return api, nil
+}
+
+// isInternalPackage reports whether path has an "internal" element, which only its
+// parent tree may import.
+func isInternalPackage(path string) bool {
+	return slices.Contains(strings.Split(path, "/"), "internal")
+}
+
+// compareAPI compares the exported package-level objects of oldPkg and newPkg.
+func compareAPI(oldPkg, newPkg *types.Package) *apiComparison {
+	path := oldPkg.Path()
+
+	// types.RelativeTo compares packages, not paths, and each version has its own.
+	qf := func(pkg *types.Package) string {
+		if pkg.Path() == path {
+			return ""
+		}
+
+		return pkg.Path()
+	}
+
+	c := &apiComparison{path: path, qf: qf, changes: nil, additions: nil}
+
+	for _, name := range oldPkg.Scope().Names() {
+		oldObj := oldPkg.Scope().Lookup(name)
+		if !oldObj.Exported() {
+			continue
+		}
+
+		newObj := newPkg.Scope().Lookup(name)
+		if newObj == nil {
+			c.report(name, "removed")
+
+			continue
+		}
+
+		c.compareObjects(name, oldObj, newObj)
+	}
+
+	for _, name := range newPkg.Scope().Names() {
+		if newPkg.Scope().Lookup(name).Exported() && oldPkg.Scope().Lookup(name) == nil {
+			c.add(name, "added")
+		}
+	}
+
+	return c
+}
+
+// apiComparison collects the incompatible changes and the additions of one package.
+// Types of both versions are printed relative to the package, whose path is the same.
+type apiComparison struct {
+	path      string
+	qf        types.Qualifier
+	changes   []APIChange
+	additions []APIChange
+}
+
+func (c *apiComparison) report(symbol, message string) {
+	c.changes = append(c.changes, APIChange{Package: c.path, Symbol: symbol, Message: message})
+}
+
+func (c *apiComparison) add(symbol, message string) {
+	c.additions = append(c.additions, APIChange{Package: c.path, Symbol: symbol, Message: message})
+}
+
+// compareObjects compares two versions of the package-level object name.
+func (c *apiComparison) compareObjects(name string, oldObj, newObj types.Object) {
+	if objectKind(oldObj) != objectKind(newObj) {
+		c.report(name, "changed from a "+objectKind(oldObj)+" to a "+objectKind(newObj))
+
+		return
+	}
+
+	switch oldObj := oldObj.(type) {
+	case *types.Func:
+		newFunc, _ := newObj.(*types.Func)
+		c.compareStrings(name, "signature", c.funcString(oldObj), c.funcString(newFunc))
+	case *types.Var:
+		c.compareStrings(name, "type", c.typeString(oldObj.Type()), c.typeString(newObj.Type()))
+	case *types.Const:
+		c.compareStrings(name, "type", c.typeString(oldObj.Type()), c.typeString(newObj.Type()))
+		newConst, _ := newObj.(*types.Const)
+		c.compareStrings(name, "value", oldObj.Val().ExactString(), newConst.Val().ExactString())
+	case *types.TypeName:
+		newType, _ := newObj.(*types.TypeName)
+		c.compareTypes(name, oldObj, newType)
+	}
+}
+
+// compareStrings reports what of symbol changed when old and new differ.
+func (c *apiComparison) compareStrings(symbol, what, oldStr, newStr string) {
+	if oldStr != newStr {
+		c.report(symbol, what+" changed from "+oldStr+" to "+newStr)
+	}
+}
+
+// compareTypes compares two versions of the type name: its definition, the exported
+// fields of structs, the methods of interfaces and the method sets of other types.
+func (c *apiComparison) compareTypes(name string, oldObj, newObj *types.TypeName) {
+	oldNamed, oldOK := oldObj.Type().(*types.Named)
+	newNamed, newOK := newObj.Type().(*types.Named)
+
+	if oldObj.IsAlias() || newObj.IsAlias() || !oldOK || !newOK {
+		c.compareStrings(name, "type", c.typeString(oldObj.Type()), c.typeString(newObj.Type()))
+
+		return
+	}
+
+	oldParams, newParams := c.typeParamsString(oldNamed.TypeParams()), c.typeParamsString(newNamed.TypeParams())
+	if oldParams != newParams {
+		c.report(name, "type parameters changed from "+cmp.Or(oldParams, "none")+" to "+cmp.Or(newParams, "none"))
+	}
+
+	switch oldUnder := oldNamed.Underlying().(type) {
+	case *types.Struct:
+		newUnder, ok := newNamed.Underlying().(*types.Struct)
+		if !ok {
+			c.compareStrings(name, "definition", "a struct", c.typeString(newNamed.Underlying()))
+
+			return
+		}
+
+		c.compareFields(name, oldUnder, newUnder)
+	case *types.Interface:
+		newUnder, ok := newNamed.Underlying().(*types.Interface)
+		if !ok {
+			c.compareStrings(name, "definition", "an interface", c.typeString(newNamed.Underlying()))
+
+			return
+		}
+
+		c.compareInterfaces(name, oldUnder, newUnder)
+
+		return
+	default:
+		c.compareStrings(name, "definition", c.typeString(oldUnder), c.typeString(newNamed.Underlying()))
+	}
+
+	c.compareMethodSets(name, oldNamed, newNamed)
+}
+
+// compareFields reports the exported fields of oldStruct removed or retyped in newStruct,
+// and those newStruct adds.
+func (c *apiComparison) compareFields(name string, oldStruct, newStruct *types.Struct) {
+	oldFields := make(map[string]*types.Var, oldStruct.NumFields())
+	for field := range oldStruct.Fields() {
+		oldFields[field.Name()] = field
+	}
+
+	newFields := make(map[string]*types.Var, newStruct.NumFields())
+	for field := range newStruct.Fields() {
+		newFields[field.Name()] = field
+
+		if oldField := oldFields[field.Name()]; field.Exported() && (oldField == nil || !oldField.Exported()) {
+			c.add(name+"."+field.Name(), "field added")
+		}
+	}
+
+	for field := range oldStruct.Fields() {
+		if !field.Exported() {
+			continue
+		}
+
+		newField, ok := newFields[field.Name()]
+		if !ok || !newField.Exported() {
+			c.report(name+"."+field.Name(), "field removed")
+
+			continue
+		}
+
+		c.compareStrings(name+"."+field.Name(), "field type",
+			c.typeString(field.Type()), c.typeString(newField.Type()))
+	}
+}
+
+// compareInterfaces reports the methods of oldIface removed or changed in newIface,
+// and the methods newIface adds: incompatible unless unexported methods already prevent
+// other packages from implementing it.
+func (c *apiComparison) compareInterfaces(name string, oldIface, newIface *types.Interface) {
+	sealed := false
+
+	for method := range oldIface.Methods() {
+		if !method.Exported() {
+			sealed = true
+
+			continue
+		}
+
+		newMethod := lookupMethod(newIface, method.Name())
+		if newMethod == nil {
+			c.report(name+"."+method.Name(), "removed")
+
+			continue
+		}
+
+		c.compareStrings(name+"."+method.Name(), "signature", c.funcString(method), c.funcString(newMethod))
+	}
+
+	for method := range newIface.Methods() {
+		switch {
+		case !method.Exported() || lookupMethod(oldIface, method.Name()) != nil:
+		case sealed:
+			c.add(name+"."+method.Name(), "added")
+		default:
+			c.report(name+"."+method.Name(), "added to the interface, breaking its implementations")
+		}
+	}
+}
+
+// lookupMethod returns the method of iface named name, or nil.
+func lookupMethod(iface *types.Interface, name string) *types.Func {
+	for method := range iface.Methods() {
+		if method.Name() == name {
+			return method
+		}
+	}
+
+	return nil
+}
+
+// compareMethodSets reports the exported methods of oldNamed, promoted ones included,
+// that newNamed removes, changes or only keeps for pointers, and those newNamed adds.
+func (c *apiComparison) compareMethodSets(name string, oldNamed, newNamed *types.Named) {
+	oldPtrSet := types.NewMethodSet(types.NewPointer(oldNamed))
+	newPtrSet := types.NewMethodSet(types.NewPointer(newNamed))
+	oldValueSet := types.NewMethodSet(oldNamed)
+	newValueSet := types.NewMethodSet(newNamed)
+
+	for sel := range oldPtrSet.Methods() {
+		method := sel.Obj()
+		if !method.Exported() {
+			continue
+		}
+
+		symbol := name + "." + method.Name()
+
+		newSel := newPtrSet.Lookup(method.Pkg(), method.Name())
+		if newSel == nil {
+			c.report(symbol, "removed")
+
+			continue
+		}
+
+		oldFunc, _ := method.(*types.Func)
+		newFunc, _ := newSel.Obj().(*types.Func)
+		c.compareStrings(symbol, "signature", c.funcString(oldFunc), c.funcString(newFunc))
+
+		inValueSet := oldValueSet.Lookup(method.Pkg(), method.Name()) != nil
+		if inValueSet && newValueSet.Lookup(method.Pkg(), method.Name()) == nil {
+			c.report(symbol, "now has a pointer receiver")
+		}
+	}
+
+	for sel := range newPtrSet.Methods() {
+		method := sel.Obj()
+		if method.Exported() && oldPtrSet.Lookup(method.Pkg(), method.Name()) == nil {
+			c.add(name+"."+method.Name(), "added")
+		}
+	}
+}
+
+// funcString formats the signature of fn without parameter names, which callers do not
+// depend on: "[T any] func(int, ...string) (T, error)".
+func (c *apiComparison) funcString(fn *types.Func) string {
+	sig, _ := fn.Type().(*types.Signature)
+
+	var b strings.Builder
+
+	b.WriteString(c.typeParamsString(sig.TypeParams()))
+
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+
+	b.WriteString("func(")
+
+	for i := range sig.Params().Len() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		paramType := sig.Params().At(i).Type()
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			b.WriteString("...")
+
+			paramType = paramType.(*types.Slice).Elem() //nolint:forcetypeassert // Variadic parameters are slices.
+		}
+
+		b.WriteString(c.typeString(paramType))
+	}
+
+	b.WriteString(")")
+
+	switch results := sig.Results(); results.Len() {
+	case 0:
+	case 1:
+		b.WriteString(" " + c.typeString(results.At(0).Type()))
+	default:
+		resultTypes := make([]string, results.Len())
+		for i := range results.Len() {
+			resultTypes[i] = c.typeString(results.At(i).Type())
+		}
+
+		b.WriteString(" (" + strings.Join(resultTypes, ", ") + ")")
+	}
+
+	return b.String()
+}
+
+// typeParamsString formats type parameters as "[K comparable, V any]", or "" for none.
+func (c *apiComparison) typeParamsString(params *types.TypeParamList) string {
+	if params.Len() == 0 {
+		return ""
+	}
+
+	parts := make([]string, params.Len())
+	for i := range params.Len() {
+		parts[i] = params.At(i).Obj().Name() + " " + c.typeString(params.At(i).Constraint())
+	}
+
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (c *apiComparison) typeString(t types.Type) string {
+	return types.TypeString(t, c.qf)
+}
+
+// objectKind names the kind of a package-level object.
+func objectKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Func:
+		return "func"
+	case *types.Var:
+		return "var"
+	case *types.Const:
+		return "const"
+	case *types.TypeName:
+		return "type"
+	default:
+		return "declaration"
+	}
+}
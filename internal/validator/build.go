@@ -0,0 +1,233 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+)
+
+// BuildError is a compile error of the staged snapshot.
+type BuildError struct {
+	File    string `json:"file,omitempty"`   // Path relative to the work dir; empty when unknown.
+	Line    int    `json:"line,omitempty"`   // 1-based line, 0 when unknown.
+	Column  int    `json:"column,omitempty"` // 1-based column, 0 when unknown.
+	Message string `json:"message"`
+}
+
+// String returns the error as "file:line:column: message", omitting unknown parts.
+func (e BuildError) String() string {
+	loc := e.File
+	if e.Line > 0 {
+		loc += ":" + strconv.Itoa(e.Line)
+	}
+
+	if e.Column > 0 {
+		loc += ":" + strconv.Itoa(e.Column)
+	}
+
+	if loc == "" {
+		return e.Message
+	}
+
+	return loc + ": " + e.Message
+}
+
+// CheckStagedBuild type-checks the commit the index would produce: every package,
+// tests included, loaded from the staged content of all tracked files. Unstaged
+// changes and untracked files are left out entirely, so a clean result proves the
+// commit compiles on its own instead of relying on the dependency graph.
+func CheckStagedBuild(ctx context.Context, workDir string, opts ...Option) ([]BuildError, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	root, cleanup, err := snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+
+	defer cleanup()
+
+	overlay, err := indexOverlay(ctx, absWorkDir, root)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, err := analyzer.LoadPackages(ctx, root, overlay, newOptions(opts).build(), "./...")
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	return buildErrors(pkgs, root), nil
+}
+
+// indexOverlay returns the staged content of the Go files and module metadata of
+// absWorkDir, keyed by their path below root.
+func indexOverlay(ctx context.Context, absWorkDir, root string) (map[string][]byte, error) {
+	files, err := git.GetIndexFiles(ctx, absWorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+
+	// An empty revision reads the index.
+	staged, err := git.GetFilesAtRevision(ctx, absWorkDir, "", filterModuleFiles(files))
+	if err != nil {
+		return nil, fmt.Errorf("reading staged files: %w", err)
+	}
+
+	overlay := make(map[string][]byte, len(staged))
+	for path, content := range staged {
+		overlay[filepath.Join(root, path)] = content
+	}
+
+	return overlay, nil
+}
+
+// CheckoutStaged writes the staged content of every tracked file to a temporary
+// directory, leaving unstaged changes and untracked files behind, so that tools can run
+// on exactly what would be committed. It returns the directory matching workDir in that
+// copy, and the function removing the copy.
+func CheckoutStaged(ctx context.Context, workDir string) (string, func(), error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	top, err := git.GetTopLevel(ctx, absWorkDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("finding repository root: %w", err)
+	}
+
+	// git reports the root with symlinks resolved.
+	resolved, err := filepath.EvalSymlinks(absWorkDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	rel, err := filepath.Rel(top, resolved)
+	if err != nil {
+		return "", nil, fmt.Errorf("locating work dir: %w", err)
+	}
+
+	root, cleanup, err := snapshotDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = git.CheckoutIndex(ctx, top, root)
+	if err != nil {
+		cleanup()
+
+		return "", nil, fmt.Errorf("copying staged files: %w", err)
+	}
+
+	return filepath.Join(root, rel), cleanup, nil
+}
+
+// snapshotDir creates an empty directory to anchor an overlay of files read from git
+// objects, and returns it with the function removing it.
+func snapshotDir() (string, func(), error) {
+	root, err := os.MkdirTemp("", "darna-snapshot-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating snapshot dir: %w", err)
+	}
+
+	cleanup := func() { _ = os.RemoveAll(root) }
+
+	// Resolve symlinked temp dirs (e.g. /var on macOS) so loader paths match.
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		cleanup()
+
+		return "", nil, fmt.Errorf("resolving snapshot dir: %w", err)
+	}
+
+	return resolved, cleanup, nil
+}
+
+// buildErrors collects the errors of the packages, relative to root and deduplicated
+// across test variants, sorted by position.
+func buildErrors(pkgs []*packages.Package, root string) []BuildError {
+	seen := make(map[BuildError]bool)
+
+	var errs []BuildError
+
+	for _, pkg := range pkgs {
+		for _, pkgErr := range pkg.Errors {
+			be := newBuildError(pkgErr, root)
+			if !seen[be] {
+				seen[be] = true
+				errs = append(errs, be)
+			}
+		}
+	}
+
+	slices.SortFunc(errs, func(a, b BuildError) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+
+		if a.Column != b.Column {
+			return a.Column - b.Column
+		}
+
+		return strings.Compare(a.Message, b.Message)
+	})
+
+	return errs
+}
+
+// newBuildError converts a package error, positioned as "file:line:column" with
+// optional line and column, to a BuildError.
+func newBuildError(pkgErr packages.Error, root string) BuildError {
+	be := BuildError{File: "", Line: 0, Column: 0, Message: pkgErr.Msg}
+
+	if pkgErr.Pos == "" || pkgErr.Pos == "-" {
+		return be
+	}
+
+	parts := strings.Split(pkgErr.Pos, ":")
+
+	// Trailing numeric parts are the line and column; the rest is the file.
+	var nums []int
+
+	for len(parts) > 1 && len(nums) < 2 { //nolint:mnd // Line and column.
+		n, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			break
+		}
+
+		nums = append([]int{n}, nums...)
+		parts = parts[:len(parts)-1]
+	}
+
+	be.File = strings.Join(parts, ":")
+	if rel, err := filepath.Rel(root, be.File); err == nil && !strings.HasPrefix(rel, "..") {
+		be.File = rel
+	}
+
+	if len(nums) > 0 {
+		be.Line = nums[0]
+	}
+
+	if len(nums) > 1 {
+		be.Column = nums[1]
+	}
+
+	return be
+}
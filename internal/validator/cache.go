@@ -0,0 +1,429 @@
+package validator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/graph"
+)
+
+// cacheFormat versions the entries of the analysis cache. It changes whenever
+// graph.Fragment, or what the analysis records in it, does.
+const cacheFormat = "darna-graph-8"
+
+// errMissedPackage is returned when loading the packages missing from the cache does
+// not bring all of them.
+var errMissedPackage = errors.New("package missing from the cache was not loaded")
+
+// loadGraph loads every package below dir, seen through overlay, and builds their
+// dependency graph. With WithCacheDir, the packages whose cached graph fragment is still
+// valid are only listed: they are returned without syntax nor type information.
+func loadGraph(
+	ctx context.Context, dir string, overlay map[string][]byte, o options,
+) ([]*packages.Package, *graph.DependencyGraph, error) {
+	if o.cacheDir != "" {
+		pkgs, dg, err := loadCachedGraph(ctx, dir, overlay, o)
+		if err == nil || errors.Is(err, analyzer.ErrPackagesContainErrors) {
+			return pkgs, dg, err
+		}
+
+		// An unusable cache only costs time: everything is loaded as without one.
+	}
+
+	o.report(Progress{Stage: StageLoading, Done: 0, Total: 0})
+
+	pkgs, err := analyzer.LoadPackagesShallow(ctx, dir, overlay, o.build(), "./...")
+
+	o.report(Progress{Stage: StageLoaded, Done: 0, Total: len(pkgs)})
+
+	dg, graphErr := buildGraph(ctx, pkgs, o)
+	if graphErr != nil {
+		return nil, nil, graphErr
+	}
+
+	return pkgs, dg, err //nolint:wrapcheck // Callers wrap loader errors.
+}
+
+// loadCachedGraph implements loadGraph with a cache dir. Packages are cached by path,
+// test variants included, so that a path is either entirely loaded or entirely cached.
+func loadCachedGraph(
+	ctx context.Context, dir string, overlay map[string][]byte, o options,
+) ([]*packages.Package, *graph.DependencyGraph, error) {
+	o.report(Progress{Stage: StageLoading, Done: 0, Total: 0})
+
+	listed, err := packages.Load(&packages.Config{ //nolint:exhaustruct // Optional fields intentionally omitted.
+		Context: ctx,
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+			packages.NeedModule | packages.NeedEmbedFiles,
+		Dir:        dir,
+		Env:        o.build().Env(dir, overlay),
+		BuildFlags: o.build().Flags(),
+		Overlay:    overlay,
+		Tests:      true,
+	}, "./...")
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing packages: %w", err)
+	}
+
+	salt, err := cacheSalt(ctx, dir, overlay, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := &packageKeys{salt: salt, overlay: overlay, keys: make(map[string]string)}
+
+	byPath := make(map[string][]*packages.Package)
+	for _, pkg := range listed {
+		byPath[pkg.PkgPath] = append(byPath[pkg.PkgPath], pkg)
+	}
+
+	dg := graph.NewDependencyGraph()
+	dg.SetDecouplingFuncs(o.decouplingFuncs)
+	dg.SetImplementations(o.implements)
+
+	var (
+		result   []*packages.Package
+		patterns []string
+	)
+
+	missed := make(map[string]string) // Package path -> cache entry, empty if it cannot be cached.
+
+	for _, pkgPath := range slices.Sorted(maps.Keys(byPath)) {
+		entry := keys.entry(o.cacheDir, byPath[pkgPath])
+		if fragment, ok := readFragment(entry); ok {
+			dg.AddFragment(pkgPath, fragment)
+			result = append(result, byPath[pkgPath]...)
+
+			continue
+		}
+
+		pattern := packagePattern(dir, byPath, pkgPath)
+		if pattern == "" {
+			return nil, nil, fmt.Errorf("%w: %s", errMissedPackage, pkgPath)
+		}
+
+		missed[pkgPath] = entry
+
+		if !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	if len(missed) > 0 {
+		loaded, err := analyzer.LoadPackagesShallow(ctx, dir, overlay, o.build(), patterns...)
+		if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+			return nil, nil, err //nolint:wrapcheck // Callers wrap loader errors.
+		}
+
+		o.report(Progress{Stage: StageLoaded, Done: 0, Total: len(result) + len(loaded)})
+
+		// Other packages sharing the directories of those missed were loaded too, but
+		// their fragments are already in the graph.
+		loaded = slices.DeleteFunc(loaded, func(pkg *packages.Package) bool {
+			_, ok := missed[pkg.PkgPath]
+
+			return !ok
+		})
+
+		stop := o.reportAnalysis(dg)
+		err = storeFragments(ctx, dg, loaded, missed)
+
+		stop()
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for pkgPath := range missed {
+			if !slices.ContainsFunc(loaded, func(pkg *packages.Package) bool { return pkg.PkgPath == pkgPath }) {
+				return nil, nil, fmt.Errorf("%w: %s", errMissedPackage, pkgPath)
+			}
+		}
+
+		result = append(result, loaded...)
+	}
+
+	if len(missed) == 0 {
+		o.report(Progress{Stage: StageLoaded, Done: 0, Total: len(result)})
+	}
+
+	if countErrors(result) > 0 {
+		return result, dg, analyzer.ErrPackagesContainErrors
+	}
+
+	return result, dg, nil
+}
+
+// storeFragments analyzes the loaded packages into dg and caches the fragments of their
+// paths at the entries of missed. Paths with errors are not cached, since their
+// fragments are incomplete. It only fails once ctx is done.
+func storeFragments(
+	ctx context.Context, dg *graph.DependencyGraph, loaded []*packages.Package, missed map[string]string,
+) error {
+	err := dg.AnalyzePackages(ctx, loaded...)
+	if err != nil {
+		return err //nolint:wrapcheck // Already describes the analysis.
+	}
+
+	failed := make(map[string]bool)
+
+	for _, pkg := range loaded {
+		if len(pkg.Errors) > 0 || pkg.TypesInfo == nil {
+			failed[pkg.PkgPath] = true
+		}
+	}
+
+	for pkgPath, entry := range missed {
+		if entry != "" && !failed[pkgPath] {
+			writeFragment(entry, dg.Fragment(pkgPath))
+		}
+	}
+
+	return nil
+}
+
+// packagePattern returns the pattern loading the package with the given path, the
+// directory of its files below dir. Test mains, whose files live in the build cache,
+// are loaded with the package they test.
+func packagePattern(dir string, byPath map[string][]*packages.Package, pkgPath string) string {
+	for _, pkg := range byPath[pkgPath] {
+		for _, file := range pkg.GoFiles {
+			if rel, err := filepath.Rel(dir, filepath.Dir(file)); err == nil && filepath.IsLocal(rel) {
+				return "./" + filepath.ToSlash(rel)
+			}
+		}
+	}
+
+	if tested, ok := strings.CutSuffix(pkgPath, ".test"); ok && byPath[tested] != nil {
+		return packagePattern(dir, byPath, tested)
+	}
+
+	return ""
+}
+
+// cacheSalt returns what every cache key depends on besides the packages: the cache
+// format, the toolchains darna was built with and analyzes with, the module files and
+// the options changing the graph.
+func cacheSalt(ctx context.Context, dir string, overlay map[string][]byte, o options) (string, error) {
+	vars := []string{"GOVERSION", "GOROOT", "GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED", "GOEXPERIMENT", "GOMOD", "GOWORK"}
+
+	cmd := exec.CommandContext(ctx, "go", append([]string{"env"}, vars...)...) //nolint:gosec // Fixed arguments.
+	cmd.Dir = dir
+	cmd.Env = o.build().Env(dir, overlay)
+
+	env, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("reading go env: %w", err)
+	}
+
+	h := sha256.New()
+	writeFields(h, cacheFormat, runtime.Version(), string(env), strings.Join(o.decouplingFuncs, ","),
+		strconv.FormatBool(o.implements), strings.Join(o.tags, ","))
+
+	values := strings.Split(string(env), "\n")
+	for i, name := range vars {
+		// Outside a module or a workspace, the path is empty or /dev/null.
+		if name != "GOMOD" && name != "GOWORK" || i >= len(values) || !filepath.IsAbs(values[i]) {
+			continue
+		}
+
+		file := values[i]
+		sum := strings.TrimSuffix(file, ".mod") + ".sum" // go.sum, or go.work.sum.
+
+		for _, path := range []string{file, sum, filepath.Join(filepath.Dir(file), "vendor", "modules.txt")} {
+			content, _ := readSource(overlay, path) // Missing files weigh as empty.
+			writeFields(h, path, string(content))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// packageKeys computes the cache keys of packages: hashes of their files, or of their
+// module version when they come from the module cache, and of the keys of their imports.
+type packageKeys struct {
+	salt    string
+	overlay map[string][]byte
+	keys    map[string]string // Package ID -> key, empty if the package cannot be cached.
+}
+
+// entry returns the path of the cache entry of a package path from its variants, or ""
+// if any of them cannot be cached.
+func (k *packageKeys) entry(cacheDir string, variants []*packages.Package) string {
+	ids := make([]string, 0, len(variants))
+
+	for _, pkg := range variants {
+		key := k.key(pkg)
+		if key == "" {
+			return ""
+		}
+
+		ids = append(ids, pkg.ID+"="+key)
+	}
+
+	slices.Sort(ids)
+
+	h := sha256.New()
+	writeFields(h, ids...)
+	key := hex.EncodeToString(h.Sum(nil))
+
+	return filepath.Join(cacheDir, "graph", key[:2], key+".json")
+}
+
+// key returns the key of pkg, or "" if it cannot be cached.
+func (k *packageKeys) key(pkg *packages.Package) string {
+	if key, ok := k.keys[pkg.ID]; ok {
+		return key
+	}
+
+	k.keys[pkg.ID] = "" // Import cycles, only found in broken code, are not cached.
+
+	h := sha256.New()
+	writeFields(h, k.salt, pkg.ID, pkg.Name)
+
+	if version := moduleVersion(pkg.Module); version != "" {
+		writeFields(h, version)
+	} else {
+		for _, file := range slices.Concat(pkg.GoFiles, pkg.OtherFiles) {
+			content, err := readSource(k.overlay, file)
+			if err != nil {
+				return ""
+			}
+
+			sum := sha256.Sum256(content)
+			writeFields(h, file, string(sum[:]))
+		}
+
+		// Only which files are embedded is recorded, not their content.
+		writeFields(h, pkg.EmbedFiles...)
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(pkg.Imports)) {
+		key := k.key(pkg.Imports[path])
+		if key == "" {
+			return ""
+		}
+
+		writeFields(h, path, key)
+	}
+
+	key := hex.EncodeToString(h.Sum(nil))
+	k.keys[pkg.ID] = key
+
+	return key
+}
+
+// moduleVersion returns the version identifying the files of the packages of module,
+// or "" if they may change under the same version: in the main modules, modules
+// replaced by a directory and the standard library, which is keyed by file instead.
+func moduleVersion(module *packages.Module) string {
+	switch {
+	case module == nil:
+		return ""
+	case module.Replace != nil && module.Replace.Version == "":
+		return ""
+	case module.Replace != nil:
+		return module.Path + "@" + module.Version + "=>" + module.Replace.Path + "@" + module.Replace.Version
+	case module.Version == "":
+		return ""
+	default:
+		return module.Path + "@" + module.Version
+	}
+}
+
+// writeFields writes fields to h, each terminated so that they cannot run together.
+func writeFields(h hash.Hash, fields ...string) {
+	for _, field := range fields {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+}
+
+// readSource returns the content of file as the loader sees it, from overlay or disk.
+func readSource(overlay map[string][]byte, file string) ([]byte, error) {
+	if content, ok := overlay[file]; ok {
+		return content, nil
+	}
+
+	content, err := os.ReadFile(file) //nolint:gosec // Path comes from the package loader.
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+
+	return content, nil
+}
+
+// readFragment reads the cache entry at path, if any.
+func readFragment(path string) (graph.Fragment, bool) {
+	var fragment graph.Fragment
+
+	if path == "" {
+		return fragment, false
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // Path is derived from the cache dir.
+	if err != nil {
+		return fragment, false
+	}
+
+	return fragment, json.Unmarshal(data, &fragment) == nil
+}
+
+// writeFragment stores fragment at path, through a rename so that concurrent runs never
+// read a partial entry. Failures are ignored: the fragment is computed again next time.
+func writeFragment(path string, fragment graph.Fragment) {
+	data, err := json.Marshal(fragment)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o750) //nolint:mnd // Private to the user.
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// countErrors returns the number of errors across pkgs.
+func countErrors(pkgs []*packages.Package) int {
+	var n int
+
+	for _, pkg := range pkgs {
+		n += len(pkg.Errors)
+	}
+
+	return n
+}
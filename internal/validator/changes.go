@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// unchangedSymbols returns the IDs of the symbols defined in files with unstaged
+// modifications whose declarations the unstaged diff does not touch, in dg loaded from
+// root. Staged code can use them as committed. Files whose diff cannot be read, or that do not parse, are
+// assumed fully changed.
+func unchangedSymbols(
+	absWorkDir, root string,
+	versions fileVersions,
+	statuses map[string]git.FileStatus,
+	dg *graph.DependencyGraph,
+) map[string]bool {
+	unchanged := make(map[string]bool)
+
+	for rel, status := range statuses {
+		// Only files left out of the index entirely have their committed content here.
+		if status.Staging != ' ' || status.Worktree != 'M' {
+			continue
+		}
+
+		diff, err := versions.diff(rel)
+		if err != nil {
+			continue
+		}
+
+		// The graph holds the index content, and the working tree one may have lost
+		// declarations, so both sides of the diff are mapped to declarations.
+		staged, err := versions.staged(rel)
+		if err != nil {
+			continue
+		}
+
+		worktree, err := versions.worktree(rel)
+		if err != nil {
+			continue
+		}
+
+		file := filepath.Join(absWorkDir, rel)
+		hunks := parseHunks(rel, diff)
+
+		changed := changedNames(declSpans(file, staged), hunks, declSpan.overlapsOld)
+		current := changedNames(declSpans(file, worktree), hunks, declSpan.overlaps)
+
+		if changed == nil || current == nil {
+			continue
+		}
+
+		maps.Copy(changed, current)
+
+		for _, id := range dg.FileSyms[filepath.Join(root, rel)] {
+			sym := dg.Symbols[id]
+			if sym == nil {
+				continue
+			}
+
+			if !changed[declName(sym)] {
+				unchanged[id] = true
+			}
+		}
+	}
+
+	return unchanged
+}
+
+// changedNames returns the names declared by the spans that hunks touch according to
+// overlaps, or nil when the spans are nil because their file does not parse.
+func changedNames(spans []declSpan, hunks []Hunk, overlaps func(declSpan, Hunk) bool) map[string]bool {
+	if spans == nil {
+		return nil
+	}
+
+	changed := make(map[string]bool)
+
+	for _, span := range spans {
+		if slices.ContainsFunc(hunks, func(h Hunk) bool { return overlaps(span, h) }) {
+			for _, name := range span.names {
+				changed[name] = true
+			}
+		}
+	}
+
+	return changed
+}
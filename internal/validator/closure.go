@@ -0,0 +1,130 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// FixClosure returns the minimal set of files that must be staged, in addition to the
+// staged ones, for the commit to be atomic. Unlike the missing files of individual
+// violations, it accounts for what the newly staged files need in turn: staging a file
+// commits its working tree version, whose own dependencies may have unstaged changes.
+// Paths are relative to workDir and sorted.
+func FixClosure(ctx context.Context, workDir string, opts ...Option) ([]string, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	staged, stagedSet, notStagedSet := categorizeFiles(absWorkDir, statuses)
+
+	versions := workTreeVersions(ctx, absWorkDir)
+	unpaired := slices.Concat(findUnpairedModuleFiles(versions, statuses), findUnpairedFiles(statuses, cfg.pairs))
+
+	stagedGo := git.FilterGoFiles(staged)
+	if len(stagedGo) == 0 {
+		var files []string
+
+		for _, v := range unpaired {
+			if !slices.Contains(files, v.MissingFile) {
+				files = append(files, v.MissingFile)
+			}
+		}
+
+		return files, nil
+	}
+
+	// Files that get staged are committed as they are in the working tree, so only
+	// already staged files keep their index content.
+	overlay := buildOverlay(ctx, absWorkDir, statuses)
+	for file, status := range statuses {
+		if status.Staging == ' ' {
+			delete(overlay, filepath.Join(absWorkDir, file))
+		}
+	}
+
+	pkgs, dg, err := loadGraph(ctx, absWorkDir, overlay, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	if cfg.ignoreMain {
+		stagedGo = excludeFiles(stagedGo, mainPackageFiles(pkgs))
+	}
+
+	closure := make(map[string]bool)
+
+	// Moved symbols, new module requirements, unpaired module files and paired files
+	// name their missing file directly.
+	fileViolations := findIncompleteMoves(versions, statuses)
+	fileViolations = append(fileViolations, findUnstagedRequirements(absWorkDir, versions, statuses)...)
+	fileViolations = append(fileViolations, unpaired...)
+
+	for _, v := range fileViolations {
+		closure[filepath.Join(absWorkDir, v.MissingFile)] = true
+	}
+
+	solveClosure(dg, stagedGo, stagedSet, notStagedSet, closure)
+
+	files := make([]string, 0, len(closure))
+	for file := range closure {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	return convertToRelativePaths(files, absWorkDir), nil
+}
+
+// solveClosure adds to closure every not staged file reachable from the staged files,
+// following the dependencies of each file as soon as it joins the closure.
+func solveClosure(
+	dg *graph.DependencyGraph, stagedGo []string, stagedSet, notStagedSet, closure map[string]bool,
+) {
+	queue := append([]string(nil), stagedGo...)
+	for file := range closure {
+		queue = append(queue, file)
+	}
+
+	visited := make(map[string]bool, len(queue))
+
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+
+		if visited[file] {
+			continue
+		}
+
+		visited[file] = true
+
+		for _, symID := range dg.FileSyms[file] {
+			for _, depID := range dg.TransitiveDeps(symID) {
+				depSym := dg.Symbols[depID]
+				if depSym == nil || stagedSet[depSym.File] || closure[depSym.File] {
+					continue
+				}
+
+				if isNotStaged(depSym.File, notStagedSet) {
+					closure[depSym.File] = true
+					queue = append(queue, depSym.File)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package validator
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/graph"
+)
+
+// growCluster extends the committable set with the candidates cohesive with it, until
+// none is left. A candidate can only join once every changeset file it depends on is in
+// the set, so the set stays atomic. It is cohesive when it uses symbols of the set, or
+// lives in the package of a set file and shares a used symbol with it.
+func growCluster(
+	dg *graph.DependencyGraph, set, candidates []string, changesetFiles map[string]bool,
+) []string {
+	inSet := make(map[string]bool, len(set))
+	for _, file := range set {
+		inSet[file] = true
+	}
+
+	for grown := true; grown; {
+		grown = false
+
+		for _, file := range candidates {
+			if inSet[file] || !dependsOnlyOn(dg, file, inSet, changesetFiles) || !cohesive(dg, file, set) {
+				continue
+			}
+
+			inSet[file] = true
+			set = append(set, file)
+			grown = true
+		}
+	}
+
+	return set
+}
+
+// dependsOnlyOn reports whether every changeset file that file depends on is in set.
+func dependsOnlyOn(dg *graph.DependencyGraph, file string, set, changesetFiles map[string]bool) bool {
+	for dep := range changesetDeps(dg, file, changesetFiles) {
+		if !set[dep] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addTests extends the committable set with the candidate test files of its files:
+// foo_test.go when foo.go is in the set, and the test files using symbols of the set.
+// As in growCluster, a test file only joins when the set holds every changeset file it
+// depends on.
+func addTests(dg *graph.DependencyGraph, set, candidates []string, changesetFiles map[string]bool) []string {
+	inSet := make(map[string]bool, len(set))
+	for _, file := range set {
+		inSet[file] = true
+	}
+
+	for _, file := range candidates {
+		if inSet[file] || !isTestFile(file) || !dependsOnlyOn(dg, file, inSet, changesetFiles) {
+			continue
+		}
+
+		if inSet[strings.TrimSuffix(file, "_test.go")+".go"] || usesFiles(dg, file, inSet) {
+			set = append(set, file)
+		}
+	}
+
+	return set
+}
+
+// usesFiles reports whether the symbols of file use symbols defined in files.
+func usesFiles(dg *graph.DependencyGraph, file string, files map[string]bool) bool {
+	for id := range usedSymbols(dg, file) {
+		if sym := dg.Symbols[id]; sym != nil && files[sym.File] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cohesive reports whether file uses symbols of set, or shares a used symbol with a
+// file of set in the same package.
+func cohesive(dg *graph.DependencyGraph, file string, set []string) bool {
+	used := usedSymbols(dg, file)
+
+	for _, other := range set {
+		if usesFiles(dg, file, map[string]bool{other: true}) {
+			return true
+		}
+
+		if filepath.Dir(other) != filepath.Dir(file) {
+			continue
+		}
+
+		for id := range usedSymbols(dg, other) {
+			if used[id] && dg.Symbols[id] != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// usedSymbols returns the symbols directly used by the symbols defined in file.
+func usedSymbols(dg *graph.DependencyGraph, file string) map[string]bool {
+	used := make(map[string]bool)
+
+	for _, symID := range dg.FileSyms[file] {
+		for depID := range dg.OutEdges(symID) {
+			if !slices.Contains(dg.FileSyms[file], depID) {
+				used[depID] = true
+			}
+		}
+	}
+
+	return used
+}
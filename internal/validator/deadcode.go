@@ -0,0 +1,254 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// Advisory is a finding about the staged changes that does not make the commit
+// non-atomic, such as code they leave unused. Advisories never fail a run.
+type Advisory struct {
+	File    string `json:"file"`    // File defining Symbol, relative to the work dir.
+	Line    int    `json:"line"`    // Line of the declaration of Symbol.
+	Symbol  string `json:"symbol"`  // Symbol ID.
+	Kind    string `json:"kind"`    // "func", "type", "var" or "const".
+	Message string `json:"message"` // What the staged changes do to Symbol.
+}
+
+// String formats the advisory as "file:line: symbol message".
+func (a Advisory) String() string {
+	return a.File + ":" + strconv.Itoa(a.Line) + ": " + a.Symbol + " " + a.Message
+}
+
+// DeadCode reports the symbols whose last use the staged changes remove: used in the
+// committed code, but no longer referenced anywhere in the staged snapshot. Methods,
+// which interfaces may call, and entry points such as main, init and tests are never
+// reported.
+//
+// The staged snapshot is analyzed as the validation does, with tracked files at their
+// staged content, and compared with the same tree whose staged files are at HEAD.
+func DeadCode(ctx context.Context, workDir string, opts ...Option) ([]Advisory, error) {
+	snap, err := loadStagedSnapshots(ctx, workDir, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return findDeadCode(snap.staged, snap.head, snap.stagedSet, snap.absWorkDir), nil
+}
+
+// UnusedExports reports the exported functions, types, variables and constants that
+// the staged changes add but that no staged or committed code uses, such as an API whose
+// only user was left unstaged or untracked. Methods and tests are never reported.
+func UnusedExports(ctx context.Context, workDir string, opts ...Option) ([]Advisory, error) {
+	snap, err := loadStagedSnapshots(ctx, workDir, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	advisories := []Advisory{}
+
+	for file := range snap.stagedSet {
+		for _, id := range snap.staged.FileSyms[file] {
+			sym := snap.staged.Symbols[id]
+			isNew := snap.added[file] || snap.head.Symbols[id] == nil
+			if sym == nil || !isNew || !isExportedSymbol(sym) ||
+				!mayBeDead(sym, snap.absWorkDir) || snap.isUsedByCommittable(id) {
+				continue
+			}
+
+			advisories = append(advisories, Advisory{
+				File:    convertToRelativePaths([]string{sym.File}, snap.absWorkDir)[0],
+				Line:    sym.Pos.Line,
+				Symbol:  id,
+				Kind:    sym.Kind,
+				Message: "is new but unused: no staged or committed code references it",
+			})
+		}
+	}
+
+	sortAdvisories(advisories)
+
+	return advisories, nil
+}
+
+// stagedSnapshots holds the dependency graphs of the staged snapshot and of the same
+// tree with its staged files at HEAD.
+type stagedSnapshots struct {
+	absWorkDir string
+	statuses   map[string]git.FileStatus
+	stagedSet  map[string]bool // Absolute paths of the staged Go files.
+	added      map[string]bool // Absolute paths of the staged Go files missing from HEAD.
+	staged     *graph.DependencyGraph
+	head       *graph.DependencyGraph
+}
+
+// loadStagedSnapshots analyzes the staged snapshot as the validation does, with tracked
+// files at their staged content, and the same tree whose staged files are at HEAD. When
+// no Go file is staged, nothing is analyzed and both graphs are nil.
+func loadStagedSnapshots(ctx context.Context, workDir string, cfg options) (*stagedSnapshots, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	staged := git.FilterGoFiles(stagedFiles(statuses))
+	if len(staged) == 0 {
+		return &stagedSnapshots{
+			absWorkDir: absWorkDir, statuses: statuses, stagedSet: nil, added: nil, staged: nil, head: nil,
+		}, nil
+	}
+
+	overlay := buildOverlay(ctx, absWorkDir, statuses)
+
+	_, dg, err := loadGraph(ctx, absWorkDir, overlay, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	headOverlay := maps.Clone(overlay)
+	stagedSet := make(map[string]bool, len(staged))
+	added := make(map[string]bool)
+
+	for _, file := range staged {
+		absPath := filepath.Join(absWorkDir, file)
+		stagedSet[absPath] = true
+
+		head, err := git.GetFileAtRevision(ctx, absWorkDir, "HEAD", file)
+		if err != nil {
+			added[absPath] = true // Left as is: the HEAD graph still has its symbols.
+
+			continue
+		}
+
+		headOverlay[absPath] = head
+	}
+
+	_, headGraph, err := loadGraph(ctx, absWorkDir, headOverlay, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages at HEAD: %w", err)
+	}
+
+	return &stagedSnapshots{
+		absWorkDir: absWorkDir,
+		statuses:   statuses,
+		stagedSet:  stagedSet,
+		added:      added,
+		staged:     dg,
+		head:       headGraph,
+	}, nil
+}
+
+// isUsedByCommittable reports whether a symbol other than id itself, defined in a
+// tracked file, uses id in the staged snapshot. Untracked files are not part of the
+// commit, nor of any before it.
+func (s *stagedSnapshots) isUsedByCommittable(id string) bool {
+	for userID := range s.staged.InEdges(id) {
+		user := s.staged.Symbols[userID]
+		if userID == id || user == nil {
+			continue
+		}
+
+		file := convertToRelativePaths([]string{user.File}, s.absWorkDir)[0]
+		if fileState(file, s.statuses) != StateUntracked {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findDeadCode returns the advisories of the symbols that the symbols of the staged
+// files use in headGraph but that nothing uses in dg. Only staged files differ between
+// both graphs, so the last uses were removed from them.
+func findDeadCode(dg, headGraph *graph.DependencyGraph, stagedSet map[string]bool, absWorkDir string) []Advisory {
+	removedFrom := make(map[string]map[string]bool)
+
+	for file := range stagedSet {
+		for _, userID := range headGraph.FileSyms[file] {
+			for depID := range headGraph.OutEdges(userID) {
+				sym := dg.Symbols[depID]
+				if sym == nil || depID == userID || !mayBeDead(sym, absWorkDir) || isUsed(dg, depID) {
+					continue
+				}
+
+				if removedFrom[depID] == nil {
+					removedFrom[depID] = make(map[string]bool)
+				}
+
+				removedFrom[depID][convertToRelativePaths([]string{file}, absWorkDir)[0]] = true
+			}
+		}
+	}
+
+	advisories := make([]Advisory, 0, len(removedFrom))
+
+	for id, files := range removedFrom {
+		sym := dg.Symbols[id]
+		advisories = append(advisories, Advisory{
+			File:   convertToRelativePaths([]string{sym.File}, absWorkDir)[0],
+			Line:   sym.Pos.Line,
+			Symbol: id,
+			Kind:   sym.Kind,
+			Message: "is no longer used: the staged changes remove its last use, from " +
+				strings.Join(slices.Sorted(maps.Keys(files)), ", "),
+		})
+	}
+
+	sortAdvisories(advisories)
+
+	return advisories
+}
+
+// mayBeDead reports whether sym, defined inside absWorkDir, is a declaration that
+// should be used by other code: not a method, entry point or test.
+func mayBeDead(sym *graph.Symbol, absWorkDir string) bool {
+	switch sym.Kind {
+	case "func", "type", "var", "const":
+	default:
+		return false
+	}
+
+	if strings.Contains(sym.Name, ".") || sym.Name == "main" || sym.Name == "init" || sym.Name == "_" {
+		return false
+	}
+
+	if strings.HasSuffix(sym.File, "_test.go") && (isTestFunc(sym.Name) || strings.HasPrefix(sym.Name, "Benchmark")) {
+		return false
+	}
+
+	return isWithin(sym.File, absWorkDir)
+}
+
+// isUsed reports whether a symbol other than id itself uses id.
+func isUsed(dg *graph.DependencyGraph, id string) bool {
+	for userID := range dg.InEdges(id) {
+		if userID != id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortAdvisories sorts advisories by file, line and symbol.
+func sortAdvisories(advisories []Advisory) {
+	slices.SortFunc(advisories, func(a, b Advisory) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Symbol, b.Symbol))
+	})
+}
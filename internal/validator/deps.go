@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// Deps returns the symbols that target, a Go file or a symbol (full ID or bare name),
+// transitively depends on, with the git state of their files. For a file, the
+// dependencies of all its symbols are returned, except the symbols of the file itself.
+// The working tree is analyzed as is and symbols outside workDir are left out. Symbols
+// are sorted by file, line and ID.
+func Deps(ctx context.Context, workDir, target string, opts ...Option) ([]GraphNode, error) {
+	return querySymbols(ctx, workDir, target, opts, (*graph.DependencyGraph).TransitiveDeps)
+}
+
+// Rdeps is the reverse of Deps: it returns the symbols that transitively depend on
+// target, a Go file or a symbol (full ID or bare name), with the git state of their
+// files. For a file, the symbols of the file itself are left out.
+func Rdeps(ctx context.Context, workDir, target string, opts ...Option) ([]GraphNode, error) {
+	return querySymbols(ctx, workDir, target, opts, (*graph.DependencyGraph).TransitiveDependents)
+}
+
+// querySymbols resolves target and collects the symbols traverse reaches from it.
+func querySymbols(
+	ctx context.Context, workDir, target string, opts []Option,
+	traverse func(dg *graph.DependencyGraph, id string) []string,
+) ([]GraphNode, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	_, dg, err := loadGraph(ctx, absWorkDir, nil, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	roots, err := resolveTarget(dg, filepath.Join(absWorkDir, target), target)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		seen[root] = true
+	}
+
+	nodes := []GraphNode{}
+
+	for _, root := range roots {
+		for _, id := range traverse(dg, root) {
+			sym := dg.Symbols[id]
+			if seen[id] || sym == nil || !isWithin(sym.File, absWorkDir) {
+				continue
+			}
+
+			seen[id] = true
+
+			nodes = append(nodes, newGraphNode(sym, absWorkDir, statuses))
+		}
+	}
+
+	sortGraphNodes(nodes)
+
+	return nodes, nil
+}
@@ -0,0 +1,230 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// GraphScope selects which part of the dependency graph ExportGraph exports.
+type GraphScope string
+
+// Scopes accepted by ExportGraph.
+const (
+	// ScopeChanged exports the symbols of changed files and the symbols on the
+	// shortest dependency paths between them.
+	ScopeChanged GraphScope = "changed"
+	// ScopeAll exports every symbol defined in the work dir.
+	ScopeAll GraphScope = "all"
+)
+
+// FileState is the git state of the file defining a symbol.
+type FileState string
+
+// States of GraphNode.Status.
+const (
+	StateCommitted FileState = "committed" // No changes.
+	StateStaged    FileState = "staged"    // Every change is staged.
+	StateUnstaged  FileState = "unstaged"  // No change is staged.
+	StatePartial   FileState = "partial"   // Some changes are staged, others are not.
+	StateUntracked FileState = "untracked" // Not tracked by git.
+)
+
+// GraphVersion is the version of the Graph schema. Fields may be added within a
+// version; renaming or removing one bumps it.
+const GraphVersion = 1
+
+// Graph is the exported symbol dependency graph, in a stable order: nodes by file,
+// line and ID, edges by their endpoints.
+type Graph struct {
+	Version int         `json:"version"` // GraphVersion.
+	Nodes   []GraphNode `json:"nodes"`
+	Edges   []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a symbol of the exported graph.
+type GraphNode struct {
+	ID      string    `json:"id"`      // Symbol ID, as in "pkg/path.Type.Method".
+	Name    string    `json:"name"`    // Symbol name, "Type.Method" for methods.
+	Kind    string    `json:"kind"`    // "func", "type", "var", "const", "import", "file".
+	Package string    `json:"package"` // Package path.
+	File    string    `json:"file"`    // Defining file, relative to the work dir.
+	Line    int       `json:"line"`    // Line of the declaration, 0 for files.
+	Status  FileState `json:"status"`  // Git state of File.
+}
+
+// GraphEdge records that the symbol From depends on the symbol To.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+var errUnknownScope = errors.New("unknown graph scope")
+
+// ExportGraph exports the symbol dependency graph of the working tree, as is, with
+// the git state of every symbol's file. Symbols outside workDir are left out.
+func ExportGraph(ctx context.Context, workDir string, scope GraphScope, opts ...Option) (*Graph, error) {
+	if scope != ScopeChanged && scope != ScopeAll {
+		return nil, fmt.Errorf("%w: %q", errUnknownScope, scope)
+	}
+
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	_, dg, err := loadGraph(ctx, absWorkDir, nil, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	var nodes map[string]bool
+	if scope == ScopeAll {
+		nodes = workDirSymbols(dg, absWorkDir)
+	} else {
+		nodes = changedSymbols(dg, getChangeset(absWorkDir, statuses))
+	}
+
+	return buildExport(dg, nodes, absWorkDir, statuses), nil
+}
+
+// workDirSymbols returns the IDs of the symbols defined in files inside absWorkDir.
+func workDirSymbols(dg *graph.DependencyGraph, absWorkDir string) map[string]bool {
+	nodes := make(map[string]bool)
+
+	for file, syms := range dg.FileSyms {
+		if !isWithin(file, absWorkDir) {
+			continue
+		}
+
+		for _, symID := range syms {
+			nodes[symID] = true
+		}
+	}
+
+	return nodes
+}
+
+// changedSymbols returns the IDs of the symbols defined in the changed files, plus the
+// symbols on a shortest path from one of them to another, so that the export shows why
+// changed files depend on each other even through committed code.
+func changedSymbols(dg *graph.DependencyGraph, changed []string) map[string]bool {
+	nodes := make(map[string]bool)
+
+	for _, file := range changed {
+		for _, symID := range dg.FileSyms[file] {
+			nodes[symID] = true
+		}
+	}
+
+	changedIDs := make([]string, 0, len(nodes))
+	for symID := range nodes {
+		changedIDs = append(changedIDs, symID)
+	}
+
+	for _, symID := range changedIDs {
+		tree := dg.ShortestPaths(symID)
+
+		for _, targetID := range changedIDs {
+			for _, pathID := range tree.To(targetID) {
+				nodes[pathID] = true
+			}
+		}
+	}
+
+	return nodes
+}
+
+// buildExport converts the given symbols, and the edges between them, to a Graph.
+func buildExport(
+	dg *graph.DependencyGraph, nodes map[string]bool, absWorkDir string, statuses map[string]git.FileStatus,
+) *Graph {
+	export := &Graph{Version: GraphVersion, Nodes: make([]GraphNode, 0, len(nodes)), Edges: []GraphEdge{}}
+
+	for symID := range nodes {
+		sym := dg.Symbols[symID]
+		if sym == nil {
+			continue
+		}
+
+		export.Nodes = append(export.Nodes, newGraphNode(sym, absWorkDir, statuses))
+
+		for depID := range dg.OutEdges(symID) {
+			if depID != symID && nodes[depID] && dg.Symbols[depID] != nil {
+				export.Edges = append(export.Edges, GraphEdge{From: symID, To: depID})
+			}
+		}
+	}
+
+	sortGraphNodes(export.Nodes)
+	slices.SortFunc(export.Edges, func(a, b GraphEdge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
+	})
+
+	return export
+}
+
+// newGraphNode describes sym, with the git state of its file.
+func newGraphNode(sym *graph.Symbol, absWorkDir string, statuses map[string]git.FileStatus) GraphNode {
+	file := convertToRelativePaths([]string{sym.File}, absWorkDir)[0]
+
+	return GraphNode{
+		ID:      sym.ID,
+		Name:    sym.Name,
+		Kind:    sym.Kind,
+		Package: sym.Package,
+		File:    file,
+		Line:    sym.Pos.Line,
+		Status:  fileState(file, statuses),
+	}
+}
+
+// sortGraphNodes sorts nodes by file, line and ID.
+func sortGraphNodes(nodes []GraphNode) {
+	slices.SortFunc(nodes, func(a, b GraphNode) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+// fileState maps the git status of file, relative to the work dir, to a FileState.
+// Untracked directories reported collapsed cover every file below them.
+func fileState(file string, statuses map[string]git.FileStatus) FileState {
+	status, ok := statuses[file]
+	if !ok {
+		for path, dirStatus := range statuses {
+			if dirStatus.Staging == '?' && strings.HasSuffix(path, "/") && strings.HasPrefix(file, path) {
+				return StateUntracked
+			}
+		}
+
+		return StateCommitted
+	}
+
+	switch {
+	case status.Staging == '?':
+		return StateUntracked
+	case status.Staging != ' ' && status.Worktree != ' ':
+		return StatePartial
+	case status.Staging != ' ':
+		return StateStaged
+	case status.Worktree != ' ':
+		return StateUnstaged
+	default:
+		return StateCommitted
+	}
+}
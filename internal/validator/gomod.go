@@ -0,0 +1,308 @@
+package validator
+
+import (
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"dario.cat/darna/internal/git"
+)
+
+// findUnstagedRequirements reports staged Go files importing a module that only the
+// working tree go.mod requires. Committing them without go.mod leaves a commit that
+// does not build.
+func findUnstagedRequirements(
+	absWorkDir string, versions fileVersions, statuses map[string]git.FileStatus,
+) []Violation {
+	// Module directory (absolute) -> module paths required in the working tree only.
+	newRequires := make(map[string][]string)
+
+	for file, status := range statuses {
+		if filepath.Base(file) != "go.mod" || status.Worktree == ' ' || status.Staging == '?' {
+			continue
+		}
+
+		if mods := unstagedRequires(versions, file); len(mods) > 0 {
+			newRequires[filepath.Dir(filepath.Join(absWorkDir, file))] = mods
+		}
+	}
+
+	if len(newRequires) == 0 {
+		return nil
+	}
+
+	var violations []Violation
+
+	for _, file := range sortedStagedGoFiles(statuses) {
+		absFile := filepath.Join(absWorkDir, file)
+
+		modDir := moduleDir(absFile, absWorkDir)
+
+		mods := newRequires[modDir]
+		if len(mods) == 0 {
+			continue
+		}
+
+		content, err := versions.staged(file)
+		if err != nil {
+			continue
+		}
+
+		for _, imp := range fileImports(absFile, content) {
+			mod := requiringModule(imp.path, mods)
+			if mod == "" {
+				continue
+			}
+
+			violations = append(violations, Violation{
+				StagedFile:    file,
+				StagedSymbol:  imp.path,
+				StagedKind:    "",
+				MissingFile:   convertToRelativePaths([]string{filepath.Join(modDir, "go.mod")}, absWorkDir)[0],
+				MissingSymbol: mod,
+				MissingKind:   "",
+				MissingLine:   0,
+				MissingColumn: 0,
+				Reason:        "is a new import that requires staging go.mod (" + mod + ")",
+				Line:          imp.pos.Line,
+				Column:        imp.pos.Column,
+				Chain:         nil,
+				Direct:        true,
+				Fingerprint:   "",
+			}.withFingerprint())
+		}
+	}
+
+	return violations
+}
+
+// findUnpairedModuleFiles reports the go.mod and go.sum files staged without their
+// counterpart: a go.mod whose staged dependency changes leave the go.sum changes
+// unstaged, or a go.sum staged while the go.mod dependency changes it records are not.
+// Either way, the commit records checksums that do not match its requirements.
+func findUnpairedModuleFiles(versions fileVersions, statuses map[string]git.FileStatus) []Violation {
+	modDirs := make(map[string]bool)
+
+	for file := range statuses {
+		if base := filepath.Base(file); base == "go.mod" || base == "go.sum" {
+			modDirs[filepath.Dir(file)] = true
+		}
+	}
+
+	var violations []Violation
+
+	for _, dir := range slices.Sorted(maps.Keys(modDirs)) {
+		modFile, sumFile := filepath.Join(dir, "go.mod"), filepath.Join(dir, "go.sum")
+		modStatus, modOK := statuses[modFile]
+		sumStatus, sumOK := statuses[sumFile]
+
+		staged, err := versions.staged(modFile)
+		if err != nil {
+			staged = nil // Not in the index.
+		}
+
+		var stagedFile, missingFile, reason string
+
+		switch {
+		case modOK && isStagedStatus(modStatus) && sumOK && sumStatus.Worktree != ' ':
+			head, err := versions.head(modFile)
+			if err != nil {
+				head = nil // Added by the staged changes.
+			}
+
+			if !sameDependencies(head, staged, modFile) {
+				stagedFile, missingFile = modFile, sumFile
+				reason = "has dependency changes whose go.sum checksums are not staged"
+			}
+		case sumOK && isStagedStatus(sumStatus) && modOK && modStatus.Worktree != ' ':
+			worktree, err := versions.worktree(modFile)
+			if err == nil && !sameDependencies(staged, worktree, modFile) {
+				stagedFile, missingFile = sumFile, modFile
+				reason = "has go.sum checksums for go.mod dependency changes that are not staged"
+			}
+		}
+
+		if stagedFile == "" {
+			continue
+		}
+
+		violations = append(violations, Violation{
+			StagedFile:    stagedFile,
+			StagedSymbol:  modulePath(staged, modFile),
+			StagedKind:    "",
+			MissingFile:   missingFile,
+			MissingSymbol: filepath.Base(missingFile),
+			MissingKind:   "",
+			MissingLine:   0,
+			MissingColumn: 0,
+			Reason:        reason,
+			Line:          0,
+			Column:        0,
+			Chain:         nil,
+			Direct:        true,
+			Fingerprint:   "",
+		}.withFingerprint())
+	}
+
+	return violations
+}
+
+// isStagedStatus reports whether status has staged changes.
+func isStagedStatus(status git.FileStatus) bool {
+	return status.Staging != ' ' && status.Staging != '?'
+}
+
+// sameDependencies reports whether two versions of the go.mod file, nil when missing,
+// require, replace and exclude the same module versions.
+func sameDependencies(a, b []byte, file string) bool {
+	depsA, depsB := moduleDependencies(a, file), moduleDependencies(b, file)
+
+	return slices.Equal(depsA, depsB)
+}
+
+// moduleDependencies lists the require, replace and exclude directives of a go.mod
+// file, sorted. Files that are missing or do not parse have none.
+func moduleDependencies(content []byte, file string) []string {
+	mf, err := modfile.ParseLax(file, content, nil)
+	if content == nil || err != nil {
+		return nil
+	}
+
+	var deps []string
+
+	for _, req := range mf.Require {
+		deps = append(deps, "require "+req.Mod.String())
+	}
+
+	for _, rep := range mf.Replace {
+		deps = append(deps, "replace "+rep.Old.String()+" => "+rep.New.String())
+	}
+
+	for _, exc := range mf.Exclude {
+		deps = append(deps, "exclude "+exc.Mod.String())
+	}
+
+	slices.Sort(deps)
+
+	return deps
+}
+
+// modulePath returns the module path declared by a go.mod file, or its name when it is
+// missing or declares none.
+func modulePath(content []byte, file string) string {
+	mf, err := modfile.ParseLax(file, content, nil)
+	if content == nil || err != nil || mf.Module == nil {
+		return file
+	}
+
+	return mf.Module.Mod.Path
+}
+
+// unstagedRequires returns the module paths the working tree go.mod requires but the
+// staged one does not.
+func unstagedRequires(versions fileVersions, file string) []string {
+	staged, err := versions.staged(file)
+	if err != nil {
+		return nil
+	}
+
+	worktree, err := versions.worktree(file)
+	if err != nil {
+		return nil
+	}
+
+	stagedMod, err := modfile.ParseLax(file, staged, nil)
+	if err != nil {
+		return nil
+	}
+
+	worktreeMod, err := modfile.ParseLax(file, worktree, nil)
+	if err != nil {
+		return nil
+	}
+
+	required := make(map[string]bool, len(stagedMod.Require))
+	for _, req := range stagedMod.Require {
+		required[req.Mod.Path] = true
+	}
+
+	var mods []string
+
+	for _, req := range worktreeMod.Require {
+		if !required[req.Mod.Path] {
+			mods = append(mods, req.Mod.Path)
+		}
+	}
+
+	return mods
+}
+
+// sortedStagedGoFiles returns the staged Go files in statuses, relative and sorted.
+func sortedStagedGoFiles(statuses map[string]git.FileStatus) []string {
+	files := git.FilterGoFiles(stagedFiles(statuses))
+	sort.Strings(files)
+
+	return files
+}
+
+// moduleDir returns the directory of the go.mod closest to file, stopping at absWorkDir.
+func moduleDir(file, absWorkDir string) string {
+	dir := filepath.Dir(file)
+
+	for isWithin(dir, absWorkDir) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil || dir == absWorkDir {
+			return dir
+		}
+
+		dir = filepath.Dir(dir)
+	}
+
+	return absWorkDir
+}
+
+// importRef is an import path and where it appears.
+type importRef struct {
+	path string
+	pos  token.Position
+}
+
+// fileImports returns the imports of a Go source file.
+func fileImports(path string, content []byte) []importRef {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, path, content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	imports := make([]importRef, 0, len(f.Imports))
+
+	for _, spec := range f.Imports {
+		if imp, unquoteErr := strconv.Unquote(spec.Path.Value); unquoteErr == nil {
+			imports = append(imports, importRef{path: imp, pos: fset.Position(spec.Path.Pos())})
+		}
+	}
+
+	return imports
+}
+
+// requiringModule returns the longest module path in mods providing the package imp.
+func requiringModule(imp string, mods []string) string {
+	var best string
+
+	for _, mod := range mods {
+		if (imp == mod || strings.HasPrefix(imp, mod+"/")) && len(mod) > len(best) {
+			best = mod
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,422 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+var (
+	// ErrUnknownTarget is returned by SuggestHunks, Deps and Rdeps when no file or
+	// symbol matches the target.
+	ErrUnknownTarget = errors.New("no Go file or symbol matches the target")
+
+	// ErrAmbiguousTarget is returned by SuggestHunks, Deps and Rdeps when the target
+	// names several symbols.
+	ErrAmbiguousTarget = errors.New("target names several symbols, use the full symbol ID")
+)
+
+// Hunk is a part of the unstaged changes of a file that can be staged on its own.
+type Hunk struct {
+	File      string `json:"file"`                // Path relative to the work dir.
+	StartLine int    `json:"startLine"`           // First changed line in the working tree file.
+	EndLine   int    `json:"endLine"`             // Last changed line; StartLine for pure deletions.
+	Untracked bool   `json:"untracked,omitempty"` // The whole file is new.
+
+	header   string // Diff header of File, shared by its hunks.
+	body     string // The hunk, from its @@ line on.
+	oldStart int    // First changed line in the index, like StartLine.
+	oldEnd   int    // Last changed line in the index, like EndLine.
+}
+
+// Range returns the hunk location as "file:start-end", or "file:line" for one line.
+func (h Hunk) Range() string {
+	if h.StartLine == h.EndLine {
+		return h.File + ":" + strconv.Itoa(h.StartLine)
+	}
+
+	return h.File + ":" + strconv.Itoa(h.StartLine) + "-" + strconv.Itoa(h.EndLine)
+}
+
+// FormatPatch returns hunks as a patch that "git apply --cached --unidiff-zero" stages.
+func FormatPatch(hunks []Hunk) string {
+	var b strings.Builder
+
+	for i, h := range hunks {
+		if i == 0 || hunks[i-1].File != h.File {
+			b.WriteString(h.header)
+		}
+
+		b.WriteString(h.body)
+	}
+
+	return b.String()
+}
+
+// SuggestHunks returns the unstaged hunks to stage so that target, a Go file or a
+// symbol (full ID or bare name), can be committed atomically: the changes of target
+// itself and of every declaration it transitively depends on. The import hunks of the
+// files involved are included as well, since the selected code may need them.
+// Untracked files needed in full are returned as a single hunk. Hunks are sorted by
+// file, then line.
+func SuggestHunks(ctx context.Context, workDir, target string, opts ...Option) ([]Hunk, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	// The working tree holds the code the hunks come from.
+	_, dg, err := loadGraph(ctx, absWorkDir, nil, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	needed, err := neededDecls(dg, filepath.Join(absWorkDir, target), target)
+	if err != nil {
+		return nil, err
+	}
+
+	var hunks []Hunk
+
+	for _, file := range slices.Sorted(maps.Keys(needed)) {
+		rel := convertToRelativePaths([]string{file}, absWorkDir)[0]
+
+		status, ok := statuses[rel]
+		if !ok || status.Worktree == ' ' {
+			continue // Nothing left to stage.
+		}
+
+		fileHunks, err := fileHunks(ctx, absWorkDir, rel, status)
+		if err != nil {
+			return nil, err
+		}
+
+		hunks = append(hunks, selectHunks(fileHunks, file, needed[file])...)
+	}
+
+	return hunks, nil
+}
+
+// neededDecls maps the files holding the declarations target depends on, itself
+// included, to the names of those declarations; a nil set stands for the whole file.
+func neededDecls(dg *graph.DependencyGraph, absTarget, target string) (map[string]map[string]bool, error) {
+	roots, err := resolveTarget(dg, absTarget, target)
+	if err != nil {
+		return nil, err
+	}
+
+	needed := make(map[string]map[string]bool)
+	if _, ok := dg.FileSyms[absTarget]; ok {
+		needed[absTarget] = nil
+	}
+
+	for _, root := range roots {
+		for _, id := range dg.TransitiveDeps(root) {
+			sym := dg.Symbols[id]
+			if sym == nil {
+				continue // External dependency.
+			}
+
+			names, ok := needed[sym.File]
+			switch {
+			case ok && names == nil:
+				// Whole file already needed.
+			case ok:
+				names[declName(sym)] = true
+			default:
+				needed[sym.File] = map[string]bool{declName(sym): true}
+			}
+		}
+	}
+
+	return needed, nil
+}
+
+// resolveTarget returns the symbols target stands for: those defined in the file
+// absTarget if it is one, or else the single symbol whose ID is target or ends in it.
+func resolveTarget(dg *graph.DependencyGraph, absTarget, target string) ([]string, error) {
+	if syms, ok := dg.FileSyms[absTarget]; ok {
+		return syms, nil
+	}
+
+	var roots []string
+
+	for id := range dg.Symbols {
+		if id == target || strings.HasSuffix(id, "."+target) {
+			roots = append(roots, id)
+		}
+	}
+
+	switch {
+	case len(roots) == 0:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTarget, target)
+	case len(roots) > 1:
+		slices.Sort(roots)
+
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguousTarget, strings.Join(roots, ", "))
+	}
+
+	return roots, nil
+}
+
+// fileHunks returns the unstaged hunks of rel, or the whole file when untracked.
+func fileHunks(ctx context.Context, absWorkDir, rel string, status git.FileStatus) ([]Hunk, error) {
+	if status.Staging == '?' {
+		content, err := os.ReadFile(filepath.Join(absWorkDir, rel))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", rel, err)
+		}
+
+		return []Hunk{newFileHunk(rel, string(content))}, nil
+	}
+
+	diff, err := git.GetUnstagedDiff(ctx, absWorkDir, rel)
+	if err != nil {
+		return nil, fmt.Errorf("diffing %s: %w", rel, err)
+	}
+
+	return parseHunks(rel, diff), nil
+}
+
+// newFileHunk returns an untracked file as a hunk creating it.
+func newFileHunk(rel, content string) Hunk {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var body strings.Builder
+
+	body.WriteString("@@ -0,0 +1," + strconv.Itoa(len(lines)) + " @@\n")
+
+	for _, line := range lines {
+		body.WriteString("+" + line)
+	}
+
+	if !strings.HasSuffix(content, "\n") && content != "" {
+		body.WriteString("\n\\ No newline at end of file\n")
+	}
+
+	slashed := filepath.ToSlash(rel)
+
+	return Hunk{
+		File:      rel,
+		StartLine: 1,
+		EndLine:   max(len(lines), 1),
+		Untracked: true,
+		header: "diff --git a/" + slashed + " b/" + slashed + "\nnew file mode 100644\n" +
+			"--- /dev/null\n+++ b/" + slashed + "\n",
+		body:     body.String(),
+		oldStart: 0,
+		oldEnd:   0,
+	}
+}
+
+// parseHunks splits the zero-context diff of a single file into hunks.
+func parseHunks(rel, diff string) []Hunk {
+	headerEnd := strings.Index(diff, "\n@@ ")
+	if headerEnd < 0 {
+		return nil
+	}
+
+	header := diff[:headerEnd+1]
+
+	var hunks []Hunk
+
+	for chunk := range strings.SplitSeq(diff[headerEnd+1:], "\n@@ ") {
+		body := chunk
+		if !strings.HasPrefix(body, "@@ ") {
+			body = "@@ " + body
+		}
+
+		if !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+
+		fields := strings.Fields(body)
+		oldStart, oldCount := hunkRange(fields, 1)
+		start, count := hunkRange(fields, 2) //nolint:mnd // "@@", old range and new range.
+		hunks = append(hunks, Hunk{
+			File:      rel,
+			StartLine: start,
+			EndLine:   start + max(count, 1) - 1,
+			Untracked: false,
+			header:    header,
+			body:      body,
+			oldStart:  oldStart,
+			oldEnd:    oldStart + max(oldCount, 1) - 1,
+		})
+	}
+
+	return hunks
+}
+
+// hunkRange parses the "-start,count" or "+start,count" range at index i of the fields
+// of a hunk header.
+//
+//nolint:nonamedreturns // Named returns document the two numbers.
+func hunkRange(fields []string, i int) (start, count int) {
+	if len(fields) <= i {
+		return 0, 0
+	}
+
+	startText, countText, found := strings.Cut(strings.TrimLeft(fields[i], "-+"), ",")
+
+	start, _ = strconv.Atoi(startText)
+	count = 1
+
+	if found {
+		count, _ = strconv.Atoi(countText)
+	}
+
+	return start, count
+}
+
+// selectHunks returns the hunks overlapping the imports or the named declarations of
+// file, or all of them when names is nil.
+func selectHunks(hunks []Hunk, file string, names map[string]bool) []Hunk {
+	if names == nil || len(hunks) == 1 && hunks[0].Untracked {
+		return hunks
+	}
+
+	ranges := declRanges(file, names)
+
+	return slices.DeleteFunc(hunks, func(h Hunk) bool {
+		return !slices.ContainsFunc(ranges, func(s declSpan) bool { return s.overlaps(h) })
+	})
+}
+
+// declSpan is the line range of a top-level declaration, doc comment included, with
+// the package-level names it declares. Methods count as declarations of their receiver
+// type; import declarations declare no names.
+type declSpan struct {
+	names      []string
+	start, end int
+	imports    bool
+}
+
+// declSpans returns the top-level declarations of file, read from src unless nil, or
+// nil when it does not parse.
+func declSpans(file string, src []byte) []declSpan {
+	fset := token.NewFileSet()
+
+	// A nil slice in the interface would be parsed as empty content.
+	var source any
+	if src != nil {
+		source = src
+	}
+
+	parsed, err := parser.ParseFile(fset, file, source, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	spans := make([]declSpan, 0, len(parsed.Decls))
+
+	for _, decl := range parsed.Decls {
+		var (
+			doc   *ast.CommentGroup
+			names []string
+		)
+
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+
+			if d.Recv == nil {
+				names = []string{d.Name.Name}
+			} else {
+				names = []string{receiverName(d.Recv)}
+			}
+		case *ast.GenDecl:
+			doc = d.Doc
+
+			for _, spec := range d.Specs {
+				names = append(names, specNames(spec)...)
+			}
+		}
+
+		start := decl.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+
+		gen, ok := decl.(*ast.GenDecl)
+		spans = append(spans, declSpan{
+			names:   names,
+			start:   fset.Position(start).Line,
+			end:     fset.Position(decl.End()).Line,
+			imports: ok && gen.Tok == token.IMPORT,
+		})
+	}
+
+	return spans
+}
+
+// overlaps reports whether the span shares a line with h in the working tree.
+func (s declSpan) overlaps(h Hunk) bool {
+	return h.StartLine <= s.end && h.EndLine >= s.start
+}
+
+// overlapsOld reports whether the span shares a line with h in the index.
+func (s declSpan) overlapsOld(h Hunk) bool {
+	return h.oldStart <= s.end && h.oldEnd >= s.start
+}
+
+// declRanges returns the declarations of file that import packages or define names.
+func declRanges(file string, names map[string]bool) []declSpan {
+	return slices.DeleteFunc(declSpans(file, nil), func(s declSpan) bool {
+		return !s.imports && !slices.ContainsFunc(s.names, func(name string) bool { return names[name] })
+	})
+}
+
+// declName returns the name of the package-level declaration defining sym: its own,
+// or its receiver type's for methods.
+func declName(sym *graph.Symbol) string {
+	name, _, _ := strings.Cut(sym.Name, ".")
+
+	return name
+}
+
+// receiverName returns the base type name of a method receiver.
+func receiverName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+
+	expr := recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// ImpactReport estimates the blast radius of changing a file: the symbols in
+// other files that transitively depend on the symbols it defines.
+type ImpactReport struct {
+	File    string       // Analyzed file, relative to the work dir.
+	Symbols int          // Number of dependent symbols outside File.
+	Files   []FileImpact // Affected files, most affected first.
+}
+
+// FileImpact counts the dependent symbols defined in one affected file.
+type FileImpact struct {
+	File    string // Affected file, relative to the work dir.
+	Symbols int    // Number of dependent symbols in File.
+}
+
+// Impact computes which symbols and files transitively depend on file.
+// The working tree is analyzed as is; file may be absolute or relative to workDir.
+func Impact(ctx context.Context, workDir, file string, opts ...Option) (*ImpactReport, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	absFile := file
+	if !filepath.IsAbs(absFile) {
+		absFile = filepath.Join(absWorkDir, file)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, fmt.Errorf("computing impact: %w", err)
+	}
+
+	_, dg, err := loadGraph(ctx, absWorkDir, nil, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	return computeImpact(dg, absFile, absWorkDir), nil
+}
+
+func computeImpact(dg *graph.DependencyGraph, absFile, absWorkDir string) *ImpactReport {
+	dependents := make(map[string]bool)
+
+	for _, symID := range dg.FileSyms[absFile] {
+		for _, depID := range dg.TransitiveDependents(symID) {
+			depSym := dg.Symbols[depID]
+			if depSym != nil && depSym.File != absFile && isWithin(depSym.File, absWorkDir) {
+				dependents[depID] = true
+			}
+		}
+	}
+
+	perFile := make(map[string]int)
+	for symID := range dependents {
+		perFile[dg.Symbols[symID].File]++
+	}
+
+	report := &ImpactReport{
+		File:    convertToRelativePaths([]string{absFile}, absWorkDir)[0],
+		Symbols: len(dependents),
+		Files:   make([]FileImpact, 0, len(perFile)),
+	}
+
+	for file, count := range perFile {
+		report.Files = append(report.Files, FileImpact{
+			File:    convertToRelativePaths([]string{file}, absWorkDir)[0],
+			Symbols: count,
+		})
+	}
+
+	slices.SortFunc(report.Files, func(a, b FileImpact) int {
+		if diff := b.Symbols - a.Symbols; diff != 0 {
+			return diff
+		}
+
+		return cmp.Compare(a.File, b.File)
+	})
+
+	return report
+}
+
+// isWithin reports whether path is inside dir. Generated files such as test
+// mains live in the build cache and are not part of the repository.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// StagedImpactReport lists what the staged changes transitively affect: the symbols
+// whose declarations they touch and every symbol depending on those.
+type StagedImpactReport struct {
+	Changed  []string `json:"changed"`  // IDs of the symbols the staged changes touch.
+	Files    []string `json:"files"`    // Files of affected symbols, relative to the work dir.
+	Packages []string `json:"packages"` // Packages of affected symbols.
+	Exported []string `json:"exported"` // IDs of the affected exported symbols.
+}
+
+// StagedImpact computes which files, packages and exported symbols the staged changes
+// transitively affect. A symbol is touched when the staged diff overlaps its
+// declaration; every symbol of a staged file that does not parse, or is new, is. The
+// working tree is analyzed as is and symbols outside workDir are left out.
+func StagedImpact(ctx context.Context, workDir string, opts ...Option) (*StagedImpactReport, error) {
+	absWorkDir, changed, affected, err := affectedByStaged(ctx, workDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	files, packages, exported := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+
+	for _, sym := range affected {
+		files[convertToRelativePaths([]string{sym.File}, absWorkDir)[0]] = true
+		packages[sym.Package] = true
+
+		if isExportedSymbol(sym) {
+			exported[sym.ID] = true
+		}
+	}
+
+	return &StagedImpactReport{
+		Changed:  changed,
+		Files:    slices.Sorted(maps.Keys(files)),
+		Packages: slices.Sorted(maps.Keys(packages)),
+		Exported: slices.Sorted(maps.Keys(exported)),
+	}, nil
+}
+
+// affectedByStaged analyzes the working tree and returns the absolute work dir, the
+// sorted IDs of the symbols the staged changes touch, and the symbols inside the work
+// dir that are touched or transitively depend on touched ones.
+func affectedByStaged(ctx context.Context, workDir string, opts []Option) (string, []string, []*graph.Symbol, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	_, dg, err := loadGraph(ctx, absWorkDir, nil, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return "", nil, nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	changed, err := stagedSymbols(ctx, absWorkDir, statuses, dg)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	seen := make(map[string]bool, len(changed))
+
+	var affected []*graph.Symbol
+
+	for _, symID := range changed {
+		for _, id := range append(dg.TransitiveDependents(symID), symID) {
+			sym := dg.Symbols[id]
+			if seen[id] || sym == nil || !isWithin(sym.File, absWorkDir) {
+				continue
+			}
+
+			seen[id] = true
+
+			affected = append(affected, sym)
+		}
+	}
+
+	return absWorkDir, changed, affected, nil
+}
+
+// stagedSymbols returns the sorted IDs of the symbols of staged Go files whose
+// declarations the staged diff touches.
+func stagedSymbols(
+	ctx context.Context, absWorkDir string, statuses map[string]git.FileStatus, dg *graph.DependencyGraph,
+) ([]string, error) {
+	changed := []string{}
+
+	for rel, status := range statuses {
+		if status.Staging == ' ' || status.Staging == '?' || status.Staging == 'D' || !strings.HasSuffix(rel, ".go") {
+			continue
+		}
+
+		file := filepath.Join(absWorkDir, rel)
+
+		var names map[string]bool
+
+		if status.Staging != 'A' {
+			diff, err := git.GetStagedHunks(ctx, absWorkDir, rel)
+			if err != nil {
+				return nil, fmt.Errorf("reading staged changes of %s: %w", rel, err)
+			}
+
+			content, err := git.GetStagedContent(ctx, absWorkDir, rel)
+			if err != nil {
+				return nil, fmt.Errorf("reading staged changes of %s: %w", rel, err)
+			}
+
+			names = changedNames(declSpans(file, content), parseHunks(rel, diff), declSpan.overlaps)
+		}
+
+		for _, symID := range dg.FileSyms[file] {
+			if sym := dg.Symbols[symID]; sym != nil && (names == nil || names[declName(sym)]) {
+				changed = append(changed, symID)
+			}
+		}
+	}
+
+	slices.Sort(changed)
+
+	return changed, nil
+}
+
+// isExportedSymbol reports whether sym is a declaration other packages can use: an
+// exported function, type, variable or constant, or an exported method of an exported
+// type.
+func isExportedSymbol(sym *graph.Symbol) bool {
+	switch sym.Kind {
+	case "func", "type", "var", "const":
+	default:
+		return false
+	}
+
+	for part := range strings.SplitSeq(sym.Name, ".") {
+		if !token.IsExported(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ImpactedTest is a test function depending on symbols the staged changes touch.
+type ImpactedTest struct {
+	Package string `json:"package"` // Package pattern for go test, as in "./internal/x" or ".".
+	Name    string `json:"name"`    // Test, example or fuzz function name.
+	File    string `json:"file"`    // Defining file, relative to the work dir.
+}
+
+// ImpactedTests returns the tests, examples and fuzz targets that are touched by the
+// staged changes or transitively depend on symbols they touch, sorted by package and
+// name. Benchmarks are left out since go test -run does not select them.
+func ImpactedTests(ctx context.Context, workDir string, opts ...Option) ([]ImpactedTest, error) {
+	absWorkDir, _, affected, err := affectedByStaged(ctx, workDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tests := []ImpactedTest{}
+
+	for _, sym := range affected {
+		if sym.Kind != "func" || !strings.HasSuffix(sym.File, "_test.go") || !isTestFunc(sym.Name) {
+			continue
+		}
+
+		file := convertToRelativePaths([]string{sym.File}, absWorkDir)[0]
+
+		pkg := filepath.ToSlash(filepath.Dir(file))
+		if pkg != "." {
+			pkg = "./" + pkg
+		}
+
+		tests = append(tests, ImpactedTest{
+			Package: pkg,
+			Name:    sym.Name,
+			File:    file,
+		})
+	}
+
+	slices.SortFunc(tests, func(a, b ImpactedTest) int {
+		return cmp.Or(cmp.Compare(a.Package, b.Package), cmp.Compare(a.Name, b.Name))
+	})
+
+	return tests, nil
+}
+
+// isTestFunc reports whether name is the name of a test, example or fuzz function,
+// following the rules of go test: the prefix is followed by nothing or by a character
+// that is not a lowercase letter. Methods are never tests.
+func isTestFunc(name string) bool {
+	if strings.Contains(name, ".") {
+		return false
+	}
+
+	for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+
+		if rest == "" {
+			return true
+		}
+
+		r, _ := utf8.DecodeRuneInString(rest)
+
+		return !unicode.IsLower(r)
+	}
+
+	return false
+}
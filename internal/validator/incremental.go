@@ -0,0 +1,340 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/types"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/tools/go/packages"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// Analysis caches the loaded packages and dependency graph of a working tree so
+// ValidateIncremental can update them without reloading every package.
+type Analysis struct {
+	workDir string                 // Absolute working directory.
+	cfg     options                // Options the analysis was built with.
+	pkgs    []*packages.Package    // Loaded packages, including test variants.
+	graph   *graph.DependencyGraph // Dependency graph of pkgs.
+	modules []string               // Module dirs of the work dir, as loadedModules returns them.
+}
+
+// ValidateIncremental validates the staged changes like ValidateAtomicCommit, reusing
+// prev and only reloading the packages that contain changedFiles (paths absolute or
+// relative to workDir). Files whose content or staging state changed since prev must
+// be listed. A nil prev, or one built for another directory, triggers a full analysis.
+//
+// Packages importing a reloaded package are analyzed again when the symbols it defines
+// change, since their uses may no longer resolve, and, with WithImplementations, when
+// its interfaces change, since their types may no longer implement them. Otherwise
+// they are not type-checked again, so errors a change introduces in them only surface
+// on the next full analysis.
+// Changes to go.mod, go.sum or go.work trigger a full analysis. Staged files of the
+// modules nested in workDir are validated without reusing prev, one module at a time.
+func ValidateIncremental(
+	ctx context.Context, workDir string, changedFiles []string, prev *Analysis, opts ...Option,
+) ([]Violation, *Analysis, error) {
+	analysis, statuses, err := refreshAnalysis(ctx, workDir, changedFiles, prev, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The analysis loads the root module only: the staged files of nested modules are
+	// validated as ValidateAtomicCommit does, a module at a time.
+	var violations []Violation
+
+	modules := owningModules(analysis.modules, statuses, analysis.cfg)
+	if slices.Contains(modules, ".") {
+		violations, err = checkStaged(ctx, analysis.workDir, analysis.workDir, workTreeVersions(ctx, analysis.workDir),
+			statuses, analysis.pkgs, analysis.graph, analysis.cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	nested := slices.DeleteFunc(modules, func(module string) bool { return module == "." })
+
+	var overlay map[string][]byte
+	if len(nested) > 0 {
+		overlay = buildOverlay(ctx, analysis.workDir, statuses)
+	}
+
+	for _, module := range nested {
+		found, err := validateModule(ctx, analysis.workDir, analysis.workDir, overlay, statuses, module, analysis.cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		violations = unionViolations(violations, found)
+	}
+
+	return sortViolations(violations), analysis, nil
+}
+
+// FindCommittableSetIncremental is FindCommittableSet reusing prev the way
+// ValidateIncremental does, so both can share one Analysis.
+func FindCommittableSetIncremental(
+	ctx context.Context, workDir string, changedFiles []string, prev *Analysis, includeDependants bool,
+	opts ...Option,
+) ([]string, *Analysis, error) {
+	analysis, statuses, err := refreshAnalysis(ctx, workDir, changedFiles, prev, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := git.FilterGoFiles(getCandidates(analysis.workDir, statuses))
+	if analysis.cfg.ignoreMain {
+		candidates = excludeFiles(candidates, mainPackageFiles(analysis.pkgs))
+	}
+
+	if len(candidates) == 0 {
+		return nil, analysis, nil
+	}
+
+	files := findCommittableSet(analysis.graph, candidates, statuses, analysis.workDir, includeDependants, analysis.cfg)
+
+	return files, analysis, nil
+}
+
+// refreshAnalysis brings prev up to date with changedFiles, or builds a new analysis
+// when prev cannot be reused, and returns it with the current file statuses.
+func refreshAnalysis(
+	ctx context.Context, workDir string, changedFiles []string, prev *Analysis, opts []Option,
+) (*Analysis, map[string]git.FileStatus, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	cfg := newOptions(opts)
+	if prev != nil && prev.workDir == absWorkDir {
+		cfg = prev.cfg
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	overlay := buildOverlay(ctx, absWorkDir, statuses)
+
+	analysis := prev
+	if analysis == nil || analysis.workDir != absWorkDir || slices.ContainsFunc(changedFiles, isModuleFile) {
+		analysis, err = newAnalysis(ctx, absWorkDir, overlay, cfg)
+	} else {
+		err = analysis.update(ctx, changedFiles, overlay)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return analysis, statuses, nil
+}
+
+func newAnalysis(ctx context.Context, absWorkDir string, overlay map[string][]byte, cfg options) (*Analysis, error) {
+	pkgs, dg, err := loadGraph(ctx, absWorkDir, overlay, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	modules, err := loadedModules(absWorkDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Analysis{workDir: absWorkDir, cfg: cfg, pkgs: pkgs, graph: dg, modules: modules}, nil
+}
+
+// isModuleFile reports whether file describes the module or workspace, whose changes
+// may affect every package.
+func isModuleFile(file string) bool {
+	return slices.Contains([]string{"go.mod", "go.sum", "go.work", "go.work.sum"}, filepath.Base(file))
+}
+
+// update reloads the packages in the directories of changedFiles and replaces them, and
+// their part of the graph, in a. The packages importing those whose symbols changed, or
+// whose interfaces did with WithImplementations, are reloaded next.
+func (a *Analysis) update(ctx context.Context, changedFiles []string, overlay map[string][]byte) error {
+	dirs := make(map[string]bool)
+
+	for _, file := range changedFiles {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(a.workDir, file)
+		}
+
+		if dir := filepath.Dir(file); isWithin(dir, a.workDir) {
+			dirs[dir] = true
+		}
+	}
+
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	paths := make(map[string]bool)
+
+	for _, pkg := range a.pkgs {
+		if inDirs(pkg, dirs) {
+			paths[pkg.PkgPath] = true
+		}
+	}
+
+	before, ifacesBefore := packageSymbols(a.graph, paths), packageInterfaces(a.pkgs, paths)
+
+	reloaded, err := a.reload(ctx, dirs, overlay)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range reloaded {
+		paths[pkg.PkgPath] = true
+	}
+
+	after, ifacesAfter := packageSymbols(a.graph, paths), packageInterfaces(a.pkgs, paths)
+	importers := make(map[string]bool)
+
+	for _, pkg := range a.pkgs {
+		if len(pkg.GoFiles) == 0 || inDirs(pkg, dirs) {
+			continue
+		}
+
+		for _, imp := range pkg.Imports {
+			// Their types are linked to the interfaces they implement again.
+			relink := a.cfg.implements && !slices.Equal(ifacesBefore[imp.PkgPath], ifacesAfter[imp.PkgPath])
+
+			if paths[imp.PkgPath] && (relink || !slices.Equal(before[imp.PkgPath], after[imp.PkgPath])) {
+				importers[filepath.Dir(pkg.GoFiles[0])] = true
+			}
+		}
+	}
+
+	// Test mains, whose files live in the build cache, are reloaded with the package
+	// they test.
+	maps.DeleteFunc(importers, func(dir string, _ bool) bool { return !isWithin(dir, a.workDir) })
+
+	if len(importers) == 0 {
+		return nil
+	}
+
+	_, err = a.reload(ctx, importers, overlay)
+
+	return err
+}
+
+// reload loads the packages in dirs again and replaces them, and their part of the
+// graph, in a. It returns the reloaded packages.
+func (a *Analysis) reload(
+	ctx context.Context, dirs map[string]bool, overlay map[string][]byte,
+) ([]*packages.Package, error) {
+	patterns := make([]string, 0, len(dirs))
+
+	for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+		rel, err := filepath.Rel(a.workDir, dir)
+		switch {
+		case err != nil:
+			continue
+		case rel == ".":
+			patterns = append(patterns, ".")
+		default:
+			patterns = append(patterns, "./"+filepath.ToSlash(rel))
+		}
+	}
+
+	reloaded, err := analyzer.LoadPackagesShallow(ctx, a.workDir, overlay, a.cfg.build(), patterns...)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	reloadedIDs := make(map[string]bool, len(reloaded))
+	reloadedPaths := make(map[string]bool, len(reloaded))
+
+	for _, pkg := range reloaded {
+		reloadedIDs[pkg.ID] = true
+		reloadedPaths[pkg.PkgPath] = true
+	}
+
+	kept := make([]*packages.Package, 0, len(a.pkgs))
+
+	for _, pkg := range a.pkgs {
+		if !reloadedIDs[pkg.ID] && !inDirs(pkg, dirs) {
+			kept = append(kept, pkg)
+
+			continue
+		}
+
+		// Packages that no longer exist, e.g. after deleting their last file.
+		if !reloadedPaths[pkg.PkgPath] {
+			a.graph.RemovePackage(pkg.PkgPath)
+		}
+	}
+
+	a.pkgs = append(kept, reloaded...)
+
+	err = a.graph.UpdatePackage(ctx, reloaded...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already describes the analysis.
+	}
+
+	return reloaded, nil
+}
+
+// packageSymbols returns the sorted IDs of the symbols dg records for each of paths.
+func packageSymbols(dg *graph.DependencyGraph, paths map[string]bool) map[string][]string {
+	symbols := make(map[string][]string, len(paths))
+
+	for id, sym := range dg.Symbols {
+		if paths[sym.Package] {
+			symbols[sym.Package] = append(symbols[sym.Package], id)
+		}
+	}
+
+	for _, ids := range symbols {
+		slices.Sort(ids)
+	}
+
+	return symbols
+}
+
+// packageInterfaces returns the sorted interfaces declared at the package level of
+// each of paths among pkgs, with their method sets, as in "Shape interface{Area() float64}".
+func packageInterfaces(pkgs []*packages.Package, paths map[string]bool) map[string][]string {
+	ifaces := make(map[string][]string, len(paths))
+
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || !paths[pkg.PkgPath] {
+			continue
+		}
+
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			if obj, ok := scope.Lookup(name).(*types.TypeName); ok && types.IsInterface(obj.Type()) {
+				ifaces[pkg.PkgPath] = append(ifaces[pkg.PkgPath], name+" "+types.TypeString(obj.Type().Underlying(), nil))
+			}
+		}
+	}
+
+	for _, names := range ifaces {
+		slices.Sort(names)
+	}
+
+	return ifaces
+}
+
+// inDirs reports whether any source file of pkg is in one of dirs.
+func inDirs(pkg *packages.Package, dirs map[string]bool) bool {
+	for _, file := range pkg.GoFiles {
+		if dirs[filepath.Dir(file)] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,135 @@
+package validator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/git"
+)
+
+// moduleDirs returns the directories of absWorkDir holding a go.mod, slash-separated and
+// relative to it, "." for absWorkDir itself. Like the go command, it skips vendor and
+// testdata directories, and those whose name starts with "." or "_".
+func moduleDirs(absWorkDir string) ([]string, error) {
+	var dirs []string
+
+	err := filepath.WalkDir(absWorkDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if d.IsDir() && file != absWorkDir &&
+			(name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() && name == "go.mod" {
+			rel, err := filepath.Rel(absWorkDir, filepath.Dir(file))
+			if err != nil {
+				return err //nolint:wrapcheck // Wrapped below.
+			}
+
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding modules: %w", err)
+	}
+
+	return dirs, nil
+}
+
+// owningModule returns the directory among dirs, as moduleDirs returns them, of the
+// module containing file, relative to the work dir: the deepest one holding it. It
+// returns "" when no module contains file.
+func owningModule(dirs []string, file string) string {
+	owner := ""
+
+	for _, dir := range dirs {
+		if (dir == "." || strings.HasPrefix(file, dir+"/")) && (owner == "" || len(dir) > len(owner)) {
+			owner = dir
+		}
+	}
+
+	return owner
+}
+
+// stagedModules returns the directories of the modules owning the staged Go files that
+// cfg validates, relative to absWorkDir, sorted. Work dirs that are a single module at
+// their root, that hold no module at all, or whose go.work already loads every module
+// from the checkout, are loaded as a whole: "." alone.
+func stagedModules(absWorkDir string, statuses map[string]git.FileStatus, cfg options) ([]string, error) {
+	dirs, err := loadedModules(absWorkDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return owningModules(dirs, statuses, cfg), nil
+}
+
+// loadedModules returns the directories of the modules of absWorkDir, as moduleDirs
+// returns them, or "." alone when it is loaded as a whole, as described in
+// stagedModules.
+func loadedModules(absWorkDir string, cfg options) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(absWorkDir, "go.work")); err == nil && !cfg.fromIndex {
+		return []string{"."}, nil
+	}
+
+	dirs, err := moduleDirs(absWorkDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dirs) == 0 {
+		return []string{"."}, nil
+	}
+
+	return dirs, nil
+}
+
+// owningModules returns the directories among dirs, as loadedModules returns them, of
+// the modules owning the staged Go files that cfg validates, sorted, or "." alone when
+// there are none.
+func owningModules(dirs []string, statuses map[string]git.FileStatus, cfg options) []string {
+	var modules []string
+
+	for _, file := range git.FilterGoFiles(stagedFiles(statuses)) {
+		file = filepath.ToSlash(file)
+		if !cfg.validated(file) {
+			continue
+		}
+
+		if module := owningModule(dirs, file); module != "" && !slices.Contains(modules, module) {
+			modules = append(modules, module)
+		}
+	}
+
+	if len(modules) == 0 {
+		return []string{"."}
+	}
+
+	slices.Sort(modules)
+
+	return modules
+}
+
+// moduleRel returns file, relative to the work dir and slash-separated, relative to the
+// directory of module instead, and whether module contains it. An empty module is the
+// work dir itself.
+func moduleRel(module, file string) (string, bool) {
+	if module == "" || module == "." {
+		return file, true
+	}
+
+	rel, ok := strings.CutPrefix(file, module+"/")
+
+	return path.Clean(rel), ok
+}
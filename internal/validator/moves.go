@@ -0,0 +1,190 @@
+package validator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"dario.cat/darna/internal/git"
+)
+
+// declLocations maps a package-scoped declaration key to the files defining it.
+type declLocations map[string][]string
+
+// findIncompleteMoves reports symbols that moved between two files of the same package
+// where only one half of the move is staged. It compares where each top-level
+// declaration lives in the head, staged and worktree versions:
+//   - New location staged but old definition not removed: the commit has a duplicate.
+//   - Old definition removal staged but new location not: the commit loses the symbol.
+func findIncompleteMoves(versions fileVersions, statuses map[string]git.FileStatus) []Violation {
+	head := make(declLocations)
+	staged := make(declLocations)
+	worktree := make(declLocations)
+
+	for file, status := range statuses {
+		if !strings.HasSuffix(file, ".go") || (status.Staging == ' ' && status.Worktree == ' ') {
+			continue
+		}
+
+		if content, err := versions.head(file); err == nil {
+			head.add(file, content)
+		}
+
+		if content, err := versions.staged(file); err == nil {
+			staged.add(file, content)
+		}
+
+		if content, err := versions.worktree(file); err == nil {
+			worktree.add(file, content)
+		}
+	}
+
+	var violations []Violation
+
+	for _, key := range sortedKeys(head) {
+		oldFile := head[key][0]
+
+		newFiles := worktree[key]
+		if len(newFiles) != 1 || newFiles[0] == oldFile {
+			continue // Not moved, removed, or ambiguous.
+		}
+
+		newFile := newFiles[0]
+		symbol := key[strings.LastIndex(key, ":")+1:]
+		inOld := slices.Contains(staged[key], oldFile)
+		inNew := slices.Contains(staged[key], newFile)
+
+		switch {
+		case inOld && inNew:
+			violations = append(violations, Violation{
+				StagedFile:    newFile,
+				StagedSymbol:  symbol,
+				StagedKind:    "",
+				MissingFile:   oldFile,
+				MissingSymbol: symbol,
+				MissingKind:   "",
+				MissingLine:   0,
+				MissingColumn: 0,
+				Reason:        "moved from " + oldFile + " but its removal is not staged",
+				Line:          0,
+				Column:        0,
+				Chain:         nil,
+				Direct:        true,
+				Fingerprint:   "",
+			}.withFingerprint())
+		case !inOld && !inNew:
+			violations = append(violations, Violation{
+				StagedFile:    oldFile,
+				StagedSymbol:  symbol,
+				StagedKind:    "",
+				MissingFile:   newFile,
+				MissingSymbol: symbol,
+				MissingKind:   "",
+				MissingLine:   0,
+				MissingColumn: 0,
+				Reason:        "moved to " + newFile + " which is not staged",
+				Line:          0,
+				Column:        0,
+				Chain:         nil,
+				Direct:        true,
+				Fingerprint:   "",
+			}.withFingerprint())
+		}
+	}
+
+	return violations
+}
+
+// add records the top-level declarations of a Go source file.
+// Unparsable content is ignored; the package loader reports it.
+func (dl declLocations) add(file string, content []byte) {
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, content, parser.SkipObjectResolution)
+	if err != nil {
+		return
+	}
+
+	prefix := filepath.Dir(file) + ":" + parsed.Name.Name + "."
+
+	for _, name := range declNames(parsed) {
+		dl[prefix+name] = append(dl[prefix+name], file)
+	}
+}
+
+// declNames lists the names of top-level declarations, qualifying methods with their receiver type.
+func declNames(file *ast.File) []string {
+	var names []string
+
+	for _, decl := range file.Decls {
+		switch dd := decl.(type) {
+		case *ast.FuncDecl:
+			if dd.Recv == nil && dd.Name.Name == "init" {
+				continue // Multiple init functions per package are allowed.
+			}
+
+			names = append(names, funcDeclName(dd))
+		case *ast.GenDecl:
+			for _, spec := range dd.Specs {
+				names = append(names, specNames(spec)...)
+			}
+		}
+	}
+
+	return names
+}
+
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	recvType := fn.Recv.List[0].Type
+	if star, ok := recvType.(*ast.StarExpr); ok {
+		recvType = star.X
+	}
+
+	// Generic receivers name their type parameters: Box[T] or Pair[K, V].
+	switch generic := recvType.(type) {
+	case *ast.IndexExpr:
+		recvType = generic.X
+	case *ast.IndexListExpr:
+		recvType = generic.X
+	}
+
+	if ident, ok := recvType.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+
+	return fn.Name.Name
+}
+
+func specNames(spec ast.Spec) []string {
+	var names []string
+
+	switch ss := spec.(type) {
+	case *ast.TypeSpec:
+		names = append(names, ss.Name.Name)
+	case *ast.ValueSpec:
+		for _, name := range ss.Names {
+			if name.Name != "_" {
+				names = append(names, name.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+func sortedKeys(dl declLocations) []string {
+	keys := make([]string, 0, len(dl))
+	for key := range dl {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,398 @@
+package validator
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/graph"
+)
+
+// Option configures validation and committable-set selection.
+type Option func(*options)
+
+// TestPolicy controls how _test.go files take part in validation.
+type TestPolicy string
+
+// Test policies accepted by WithTestPolicy.
+const (
+	// TestPolicyStrict checks test files like any other file.
+	TestPolicyStrict TestPolicy = "strict"
+	// TestPolicyLenient never blocks staged production code on unstaged test files,
+	// while staged tests still require the production code they use.
+	TestPolicyLenient TestPolicy = "lenient"
+)
+
+// Progress describes how far the analysis behind a call has come, as reported to the
+// function given to WithProgress.
+type Progress struct {
+	Stage ProgressStage
+	Done  int // Packages analyzed so far, with StageAnalyzing.
+	Total int // Packages loaded, with StageLoaded, or to analyze, with StageAnalyzing.
+}
+
+// ProgressStage is a step of the analysis reported through WithProgress.
+type ProgressStage string
+
+// Progress stages, in the order a load goes through them. A call may load more than
+// once, each time starting over from StageLoading.
+const (
+	// StageLoading starts loading packages, which reports nothing until done.
+	StageLoading ProgressStage = "loading"
+	// StageLoaded ends loading, with the number of packages loaded.
+	StageLoaded ProgressStage = "loaded"
+	// StageAnalyzing reports each package whose dependency graph was built, until Done
+	// reaches Total. Packages served by the cache are neither analyzed nor reported.
+	StageAnalyzing ProgressStage = "analyzing"
+)
+
+// options holds the settings applied by Option values.
+type options struct {
+	untrackedDepth int  // Maximum untracked directory depth to scan (0 = unlimited).
+	ignoreMain     bool // Exclude files of package main from analysis.
+	skipGenerated  bool // Exclude generated files from analysis.
+	cluster        bool // Grow committable sets with cohesive files.
+	withTests      bool // Add the test files of committable sets.
+	changedOnly    bool // Only report missing symbols the unstaged diff changes.
+	fromIndex      bool // Load packages from a snapshot of the index instead of the checkout.
+	implements     bool // Link types and the interfaces they implement.
+	maxCommits     int  // Upper bound on the number of planned commits (0 = unlimited).
+	maxDepth       int  // Dependency edges followed when computing violations (0 = unlimited).
+	maxFiles       int  // Upper bound on the size of committable sets (0 = unlimited).
+
+	decouplingFuncs []string   // Functions whose calls do not create dependencies.
+	exclude         []string   // Globs of files exempt from atomicity checks.
+	include         []string   // Globs of the staged files to validate, all if empty.
+	files           []string   // Staged files to validate, slash-separated; all if empty.
+	pairs           []PairRule // Files committed together, such as generated code and its source.
+	tags            []string   // Build tags selecting the files of the loaded packages.
+	platforms       []Platform // Platforms the packages are loaded for, the host if empty.
+
+	testPolicy TestPolicy // How test files take part in validation, strict if empty.
+	cacheDir   string     // Where graph fragments are cached, nowhere if empty.
+	module     string     // Dir of the module loaded, relative to the work dir; all of it if empty.
+
+	progress func(Progress) // Receives progress reports, if set.
+}
+
+// WithUntrackedDepth bounds how many directory levels of untracked trees are scanned.
+// Untracked files deeper than depth are still treated as not staged, but are never
+// listed individually or offered as committable candidates. Zero means unlimited.
+func WithUntrackedDepth(depth int) Option {
+	return func(o *options) {
+		o.untrackedDepth = depth
+	}
+}
+
+// WithMaxDepth bounds how many dependency edges are followed from a staged symbol when
+// computing violations: 1 only reports direct uses, 2 adds one level of transitive
+// dependencies, and so on. Zero means unlimited.
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		o.maxDepth = depth
+	}
+}
+
+// WithChangedSymbolsOnly narrows violations to the symbols the unstaged diff actually
+// changes. By default any use of a symbol from a file with unstaged modifications is
+// a violation; with this option, symbols whose declarations are untouched by the
+// unstaged hunks are treated as committed.
+func WithChangedSymbolsOnly() Option {
+	return func(o *options) {
+		o.changedOnly = true
+	}
+}
+
+// WithIndexSnapshot makes ValidateAtomicCommit load packages from a temporary snapshot
+// built from git blobs, every tracked file at its staged content, instead of from the
+// checkout. Only the content of untracked Go files is read from the working tree, since
+// staged code may depend on them. Files git ignores, a go.work around the checkout, and
+// unstaged churn that breaks the build no longer affect the analysis.
+func WithIndexSnapshot() Option {
+	return func(o *options) {
+		o.fromIndex = true
+	}
+}
+
+// WithIgnoreMain exempts files belonging to package main from atomicity checks.
+// They are neither validated when staged nor offered as committable candidates.
+func WithIgnoreMain() Option {
+	return func(o *options) {
+		o.ignoreMain = true
+	}
+}
+
+// WithSkipGenerated exempts generated files, recognized by the standard
+// "// Code generated ... DO NOT EDIT." header, from atomicity checks. They are neither
+// validated when staged nor reported as missing.
+func WithSkipGenerated() Option {
+	return func(o *options) {
+		o.skipGenerated = true
+	}
+}
+
+// WithClustering makes FindCommittableSet propose multi-file commits: the committable
+// set grows with the candidates cohesive with it, those using its symbols or sharing
+// used symbols with its files of the same package, as long as the set stays atomic.
+func WithClustering() Option {
+	return func(o *options) {
+		o.cluster = true
+	}
+}
+
+// WithTests makes FindCommittableSet ship the set with its tests: foo_test.go joins
+// when foo.go is selected, and so does any test file using symbols of the set, as long
+// as the set holds every changeset file it depends on.
+func WithTests() Option {
+	return func(o *options) {
+		o.withTests = true
+	}
+}
+
+// WithMaxFiles caps the committable sets of FindCommittableSet at n files. The files
+// left out of a larger set are offered by later calls, once the first ones are
+// committed. Zero means unlimited.
+func WithMaxFiles(n int) Option {
+	return func(o *options) {
+		o.maxFiles = n
+	}
+}
+
+// WithMaxCommits asks CommitPlan for at most n commits by merging commits of the same
+// dependency layer. Commits of different layers are never merged, so the plan may still
+// exceed n. Zero means one commit per file.
+func WithMaxCommits(n int) Option {
+	return func(o *options) {
+		o.maxCommits = n
+	}
+}
+
+// WithDecouplingFuncs declares functions, by name or full symbol ID, as decoupling
+// points. Dependencies that only flow through a call to one of them, such as a plugin
+// passed to register(&MyPlugin{}), are not treated as violations.
+func WithDecouplingFuncs(names ...string) Option {
+	return func(o *options) {
+		o.decouplingFuncs = append(o.decouplingFuncs, names...)
+	}
+}
+
+// WithImplementations links types to the interfaces they implement, both ways, so that
+// staging a change to the method set of an interface without its unstaged
+// implementations is a violation, and so is staging an implementation without its
+// unstaged interface. Interfaces are only linked to the types of the packages of the
+// module importing them, or declaring them.
+func WithImplementations() Option {
+	return func(o *options) {
+		o.implements = true
+	}
+}
+
+// WithExclude exempts files matching any of globs from atomicity checks: they are not
+// validated when staged, and violations requiring them are dropped. Globs use
+// path.Match syntax against slash-separated paths relative to the work dir; a glob
+// matching a directory covers every file below it.
+func WithExclude(globs ...string) Option {
+	return func(o *options) {
+		o.exclude = append(o.exclude, globs...)
+	}
+}
+
+// WithInclude restricts validation to the staged files matching any of globs, with the
+// syntax of WithExclude. Files outside them can still be reported as missing.
+func WithInclude(globs ...string) Option {
+	return func(o *options) {
+		o.include = append(o.include, globs...)
+	}
+}
+
+// WithPairedFiles makes staging a file matched by any of rules, without the unstaged
+// changes of the file it pairs with, a violation, and likewise the other way around:
+// with "*.proto -> *.pb.go", api.proto and api.pb.go are committed together.
+func WithPairedFiles(rules ...PairRule) Option {
+	return func(o *options) {
+		o.pairs = append(o.pairs, rules...)
+	}
+}
+
+// WithBuildTags loads packages with the given build tags satisfied, as "go build -tags"
+// does, so that files guarded by them, such as integration tests or wire injectors,
+// take part in the analysis instead of being ignored.
+func WithBuildTags(tags ...string) Option {
+	return func(o *options) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithPlatforms makes ValidateAtomicCommit load and check the packages once per
+// platform, and report the violations found under any of them, so that files only built
+// for some platforms, such as foo_windows.go, are validated too. Other calls analyze the
+// first platform only.
+func WithPlatforms(platforms ...Platform) Option {
+	return func(o *options) {
+		o.platforms = append(o.platforms, platforms...)
+	}
+}
+
+// WithFiles restricts validation to the given staged files, relative to the work dir,
+// as the pre-commit framework passes them. Only their packages, and the packages of the
+// module those import, are loaded, which is faster on large modules. Other staged files
+// are left alone, and so is code of other packages using the given files.
+func WithFiles(files ...string) Option {
+	return func(o *options) {
+		for _, file := range files {
+			o.files = append(o.files, path.Clean(filepath.ToSlash(file)))
+		}
+	}
+}
+
+// WithTestPolicy sets how _test.go files take part in validation.
+func WithTestPolicy(policy TestPolicy) Option {
+	return func(o *options) {
+		o.testPolicy = policy
+	}
+}
+
+// WithCacheDir keeps the graph fragments of analyzed packages under dir, keyed by the
+// content of their files, the packages they import, go.mod and the Go toolchain, so
+// that later runs only type-check the packages that changed since. The cache is only
+// used when loading whole modules from the checkout, so not under WithFiles nor
+// WithIndexSnapshot.
+func WithCacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
+// WithProgress makes long-running calls report how far their analysis has come to fn,
+// for instance to show progress on a terminal. fn is called one report at a time, but
+// possibly from other goroutines.
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// report sends p to the function given to WithProgress, if any.
+func (o options) report(p Progress) {
+	if o.progress != nil {
+		o.progress(p)
+	}
+}
+
+// reportAnalysis makes dg report the packages it analyzes, if o has a progress
+// function. The returned function stops it, for graphs that outlive the call.
+func (o options) reportAnalysis(dg *graph.DependencyGraph) func() {
+	if o.progress == nil {
+		return func() {}
+	}
+
+	dg.SetProgress(func(done, total int) {
+		o.report(Progress{Stage: StageAnalyzing, Done: done, Total: total})
+	})
+
+	return func() { dg.SetProgress(nil) }
+}
+
+// build returns the build the packages are loaded with, for the first platform given
+// to WithPlatforms if any.
+func (o options) build() analyzer.Build {
+	var platform Platform
+	if len(o.platforms) > 0 {
+		platform = o.platforms[0]
+	}
+
+	return analyzer.Build{Tags: o.tags, GOOS: platform.GOOS, GOARCH: platform.GOARCH}
+}
+
+// validated reports whether the staged file at rel, relative to the work dir, is
+// subject to validation.
+func (o options) validated(rel string) bool {
+	return !matchGlobs(o.exclude, rel) && (len(o.include) == 0 || matchGlobs(o.include, rel)) &&
+		(len(o.files) == 0 || slices.Contains(o.files, filepath.ToSlash(rel)))
+}
+
+// patterns returns the package patterns to load, relative to the module loaded: the
+// directories of the files given to WithFiles it contains, or the whole module.
+func (o options) patterns() []string {
+	if len(o.files) == 0 {
+		return []string{"./..."}
+	}
+
+	var patterns []string
+
+	for _, file := range o.files {
+		rel, ok := moduleRel(o.module, file)
+
+		pattern := "./" + path.Dir(rel)
+		if ok && !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// filterViolations removes the violations of staged files not subject to validation,
+// those requiring an excluded file and, under TestPolicyLenient, those of production
+// code requiring a test file.
+func (o options) filterViolations(violations []Violation) []Violation {
+	return slices.DeleteFunc(violations, func(v Violation) bool {
+		if o.testPolicy == TestPolicyLenient && !isTestFile(v.StagedFile) && isTestFile(v.MissingFile) {
+			return true
+		}
+
+		return !o.validated(v.StagedFile) || matchGlobs(o.exclude, v.MissingFile)
+	})
+}
+
+// isTestFile reports whether path is a Go test file.
+func isTestFile(path string) bool {
+	return strings.HasSuffix(path, "_test.go")
+}
+
+// matchGlobs reports whether rel, or a directory containing it, matches any of globs.
+func matchGlobs(globs []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+
+	for _, glob := range globs {
+		for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+			if ok, _ := path.Match(strings.TrimSuffix(glob, "/"), p); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func newOptions(opts []Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// buildGraph builds the dependency graph of the loaded packages according to o. It only
+// fails once ctx is done.
+func buildGraph(ctx context.Context, pkgs []*packages.Package, o options) (*graph.DependencyGraph, error) {
+	dg := graph.NewDependencyGraph()
+	dg.SetDecouplingFuncs(o.decouplingFuncs)
+	dg.SetImplementations(o.implements)
+
+	defer o.reportAnalysis(dg)()
+
+	err := dg.AnalyzePackages(ctx, pkgs...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already describes the analysis.
+	}
+
+	return dg, nil
+}
@@ -0,0 +1,135 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/git"
+)
+
+// ErrInvalidPairRule is returned by ParsePairRule for rules not of the form "a -> b".
+var ErrInvalidPairRule = errors.New("invalid paired-file rule")
+
+// PairRule pairs the files matching From with the file To names, such as generated code
+// with its source, so that they are committed together. Each side is a slash-separated
+// pattern with at most one "*", which To replaces with what it matched in From. Patterns
+// without a slash match file names, and pair files of the same directory; others match
+// paths relative to the work dir.
+type PairRule struct {
+	From string
+	To   string
+}
+
+// String formats the rule as "from -> to".
+func (r PairRule) String() string {
+	return r.From + " -> " + r.To
+}
+
+// ParsePairRule parses a rule of the form "from -> to", such as "*.proto -> *.pb.go".
+// To may only use "*" when From does.
+func ParsePairRule(rule string) (PairRule, error) {
+	from, to, ok := strings.Cut(rule, "->")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+
+	switch {
+	case !ok || from == "" || to == "":
+		return PairRule{}, fmt.Errorf("%w %q: want \"from -> to\"", ErrInvalidPairRule, rule)
+	case strings.Count(from, "*") > 1 || strings.Count(to, "*") > 1:
+		return PairRule{}, fmt.Errorf("%w %q: at most one * per side", ErrInvalidPairRule, rule)
+	case strings.Contains(to, "*") && !strings.Contains(from, "*"):
+		return PairRule{}, fmt.Errorf("%w %q: * in %q matches nothing in %q", ErrInvalidPairRule, rule, to, from)
+	case strings.Contains(from, "/") != strings.Contains(to, "/"):
+		return PairRule{}, fmt.Errorf("%w %q: both sides must be file names or paths", ErrInvalidPairRule, rule)
+	}
+
+	return PairRule{From: from, To: to}, nil
+}
+
+// companion returns the file rel, relative to the work dir and slash-separated, is
+// paired with by the rule, and whether rel matches From at all.
+func (r PairRule) companion(rel string) (string, bool) {
+	dir, name := "", rel
+	if !strings.Contains(r.From, "/") {
+		dir, name = path.Split(rel)
+	}
+
+	prefix, suffix, wildcard := strings.Cut(r.From, "*")
+	if !wildcard {
+		return dir + r.To, name == r.From
+	}
+
+	if len(name) < len(prefix)+len(suffix) || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+
+	stem := name[len(prefix) : len(name)-len(suffix)]
+	if strings.Contains(stem, "/") {
+		return "", false
+	}
+
+	return dir + strings.Replace(r.To, "*", stem, 1), true
+}
+
+// findUnpairedFiles reports the files staged while the file rules pair them with, in
+// either direction, has unstaged changes: regenerated code left behind its source, or
+// the other way around.
+func findUnpairedFiles(statuses map[string]git.FileStatus, rules []PairRule) []Violation {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	type pair struct{ staged, missing string }
+
+	seen := make(map[pair]bool)
+
+	var violations []Violation
+
+	for _, file := range slices.Sorted(maps.Keys(statuses)) {
+		for _, rule := range rules {
+			other, ok := rule.companion(file)
+			if !ok || other == file {
+				continue
+			}
+
+			if _, ok := statuses[other]; !ok {
+				continue // Unchanged.
+			}
+
+			for _, p := range []pair{{file, other}, {other, file}} {
+				stagedStatus, missingStatus := statuses[p.staged], statuses[p.missing]
+				if seen[p] || !isStagedStatus(stagedStatus) || missingStatus.Worktree == ' ' {
+					continue
+				}
+
+				seen[p] = true
+				violations = append(violations, newPairViolation(p.staged, p.missing, rule))
+			}
+		}
+	}
+
+	return violations
+}
+
+// newPairViolation reports that staged, paired with missing by rule, needs it staged.
+func newPairViolation(staged, missing string, rule PairRule) Violation {
+	return Violation{
+		StagedFile:    staged,
+		StagedSymbol:  path.Base(staged),
+		StagedKind:    "",
+		MissingFile:   missing,
+		MissingSymbol: path.Base(missing),
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "is paired with " + missing + " (" + rule.String() + "), whose changes are not staged",
+		Line:          0,
+		Column:        0,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}.withFingerprint()
+}
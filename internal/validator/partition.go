@@ -0,0 +1,287 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// Partition splits the whole changeset (staged, unstaged and untracked files) into
+// atomic commit groups. Each group is a weakly connected component of the file
+// dependency subgraph induced by the changeset, so it only depends on its own files
+// and committed code and can be committed independently of the other groups. Files
+// inside a group are ordered dependencies first.
+//
+// Files in a dependency cycle, and files depending on them, cannot be placed in any
+// group and are returned as blocked. All paths are relative to workDir.
+//
+//nolint:nonamedreturns // Named returns document the two file lists.
+func Partition(ctx context.Context, workDir string, opts ...Option) (groups [][]string, blocked []string, err error) {
+	absWorkDir, deps, err := loadChangesetDeps(ctx, workDir, newOptions(opts))
+	if err != nil || len(deps) == 0 {
+		return nil, nil, err
+	}
+
+	absGroups, absBlocked := partitionFiles(deps)
+
+	for _, group := range absGroups {
+		groups = append(groups, convertToRelativePaths(group, absWorkDir))
+	}
+
+	if len(absBlocked) > 0 {
+		blocked = convertToRelativePaths(absBlocked, absWorkDir)
+	}
+
+	return groups, blocked, nil
+}
+
+// ChangesetDeps returns, for every changed Go file of the working tree (staged,
+// unstaged or untracked), the other changed files it transitively depends on, sorted.
+// A set of changed files can be committed atomically when it contains the
+// dependencies of each of its files. Paths are relative to workDir.
+func ChangesetDeps(ctx context.Context, workDir string, opts ...Option) (map[string][]string, error) {
+	absWorkDir, deps, err := loadChangesetDeps(ctx, workDir, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]string, len(deps))
+	for file, fileDeps := range deps {
+		rel := convertToRelativePaths([]string{file}, absWorkDir)[0]
+		result[rel] = sortFilesCopy(convertToRelativePaths(slices.Collect(maps.Keys(fileDeps)), absWorkDir))
+	}
+
+	return result, nil
+}
+
+// loadChangesetDeps analyzes the working tree and returns, for every changed Go file,
+// the changeset files it transitively depends on. Paths are absolute.
+func loadChangesetDeps(
+	ctx context.Context, workDir string, cfg options,
+) (string, map[string]map[string]bool, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	changed := git.FilterGoFiles(getChangeset(absWorkDir, statuses))
+	if len(changed) == 0 {
+		return absWorkDir, nil, nil
+	}
+
+	// The whole working tree will eventually be committed, so no overlay is needed.
+	pkgs, dg, err := loadGraph(ctx, absWorkDir, nil, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return "", nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	if cfg.ignoreMain {
+		changed = excludeFiles(changed, mainPackageFiles(pkgs))
+	}
+
+	changeset := make(map[string]bool, len(changed))
+	for _, file := range changed {
+		changeset[file] = true
+	}
+
+	direct := make(map[string]map[string]bool, len(changed))
+	for _, file := range changed {
+		direct[file] = changesetDeps(dg, file, changeset)
+	}
+
+	return absWorkDir, closeFileDeps(direct), nil
+}
+
+// getChangeset returns absolute paths of every file with staged, unstaged or untracked changes.
+func getChangeset(absWorkDir string, statuses map[string]git.FileStatus) []string {
+	var changed []string
+
+	for file, status := range statuses {
+		if status.Staging == ' ' && status.Worktree == ' ' {
+			continue
+		}
+
+		absPath, err := filepath.Abs(filepath.Join(absWorkDir, file))
+		if err != nil {
+			continue
+		}
+
+		changed = append(changed, absPath)
+	}
+
+	return changed
+}
+
+// partitionFiles groups changeset files into connected components of their dependency
+// subgraph, excluding cyclic files and their dependants, which are returned as blocked.
+//
+//nolint:nonamedreturns // Named returns clarify same-type return values.
+func partitionFiles(deps map[string]map[string]bool) (groups [][]string, blocked []string) {
+	blockedSet := findBlockedFiles(deps)
+
+	// Union files with their dependencies to find weakly connected components.
+	parent := make(map[string]string)
+
+	var find func(file string) string
+
+	find = func(file string) string {
+		if parent[file] == file {
+			return file
+		}
+
+		parent[file] = find(parent[file])
+
+		return parent[file]
+	}
+
+	for file := range deps {
+		if !blockedSet[file] {
+			parent[file] = file
+		}
+	}
+
+	for file := range parent {
+		for dep := range deps[file] {
+			parent[find(file)] = find(dep)
+		}
+	}
+
+	components := make(map[string][]string)
+	for file := range parent {
+		root := find(file)
+		components[root] = append(components[root], file)
+	}
+
+	for _, group := range components {
+		sortDependenciesFirst(group, deps)
+		groups = append(groups, group)
+	}
+
+	slices.SortFunc(groups, func(a, b []string) int {
+		return cmp.Compare(slices.Min(a), slices.Min(b))
+	})
+
+	for file := range blockedSet {
+		blocked = append(blocked, file)
+	}
+
+	return groups, sortFilesCopy(blocked)
+}
+
+// changesetDeps returns the changeset files that the symbols of file transitively depend
+// on, excluding itself. Files are committed whole, so a file also needs what the other
+// symbols of its dependencies use: closeFileDeps takes that into account.
+func changesetDeps(dg *graph.DependencyGraph, file string, changeset map[string]bool) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, symID := range dg.FileSyms[file] {
+		for _, depID := range dg.TransitiveDeps(symID) {
+			depSym := dg.Symbols[depID]
+			if depSym == nil || depSym.File == file {
+				continue
+			}
+
+			if changeset[depSym.File] {
+				result[depSym.File] = true
+			}
+		}
+	}
+
+	return result
+}
+
+// closeFileDeps returns the transitive closure of the file dependency graph direct: for
+// every file, the other files it reaches.
+func closeFileDeps(direct map[string]map[string]bool) map[string]map[string]bool {
+	closed := make(map[string]map[string]bool, len(direct))
+
+	for file := range direct {
+		reached := make(map[string]bool)
+		stack := slices.Collect(maps.Keys(direct[file]))
+
+		for len(stack) > 0 {
+			dep := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if reached[dep] {
+				continue
+			}
+
+			reached[dep] = true
+
+			for next := range direct[dep] {
+				if !reached[next] {
+					stack = append(stack, next)
+				}
+			}
+		}
+
+		delete(reached, file)
+		closed[file] = reached
+	}
+
+	return closed
+}
+
+// findBlockedFiles returns files that are part of a dependency cycle or depend on one.
+// Since deps is transitively closed, a file is cyclic if one of its dependencies
+// depends back on it.
+func findBlockedFiles(deps map[string]map[string]bool) map[string]bool {
+	cyclic := make(map[string]bool)
+
+	for file, fileDeps := range deps {
+		for dep := range fileDeps {
+			if deps[dep][file] {
+				cyclic[file] = true
+
+				break
+			}
+		}
+	}
+
+	blocked := make(map[string]bool, len(cyclic))
+
+	for file, fileDeps := range deps {
+		if cyclic[file] {
+			blocked[file] = true
+
+			continue
+		}
+
+		for dep := range fileDeps {
+			if cyclic[dep] {
+				blocked[file] = true
+
+				break
+			}
+		}
+	}
+
+	return blocked
+}
+
+// sortDependenciesFirst orders files so that dependencies precede their dependants.
+// With transitively closed, acyclic deps, a dependency always has strictly fewer
+// dependencies than its dependant, so ordering by dependency count is topological.
+func sortDependenciesFirst(files []string, deps map[string]map[string]bool) {
+	slices.SortFunc(files, func(a, b string) int {
+		if diff := len(deps[a]) - len(deps[b]); diff != 0 {
+			return diff
+		}
+
+		return cmp.Compare(a, b)
+	})
+}
@@ -0,0 +1,232 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+)
+
+// Plan is an ordered sequence of atomic commits covering the working tree changes.
+type Plan struct {
+	Commits [][]string `json:"commits"` // Files of each commit, in commit order, relative to the work dir.
+}
+
+// CommitPlan splits the working tree changes into atomic commits, ordered so that
+// every commit only depends on files committed before it.
+//
+// By default each file gets its own commit, except for the files of a dependency cycle,
+// which can only be committed together and share one. With WithMaxCommits, commits of the same
+// dependency layer, which never depend on each other, are merged until the plan fits the
+// limit or every layer is a single commit. Commits of different layers are never merged,
+// even when they are independent, so the plan may stay above the limit.
+func CommitPlan(ctx context.Context, workDir string, opts ...Option) (*Plan, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, deps, err := loadChangesetDeps(ctx, workDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Commits: nil}
+
+	for _, commit := range planCommits(deps, cfg.maxCommits) {
+		plan.Commits = append(plan.Commits, convertToRelativePaths(commit, absWorkDir))
+	}
+
+	return plan, nil
+}
+
+// planCommits layers the file graph deps with layerFiles and turns each component of a
+// layer into a commit. Components in the same layer never depend on each other, so any of
+// them can share a commit; merging the smallest commits of a layer first keeps commit
+// sizes balanced.
+func planCommits(deps map[string]map[string]bool, maxCommits int) [][]string {
+	layers := layerFiles(deps)
+
+	count := 0
+	for _, layer := range layers {
+		count += len(layer)
+	}
+
+	for ; maxCommits > 0 && count > maxCommits; count-- {
+		if !mergeSmallestCommits(layers) {
+			break
+		}
+	}
+
+	var commits [][]string
+
+	for _, layer := range layers {
+		for _, commit := range layer {
+			slices.Sort(commit)
+		}
+
+		slices.SortFunc(layer, func(a, b []string) int {
+			return cmp.Compare(a[0], b[0])
+		})
+
+		commits = append(commits, layer...)
+	}
+
+	return commits
+}
+
+// layerFiles condenses the file graph deps into its strongly connected components and
+// layers them dependencies first with Kahn's algorithm, each component in a commit of its
+// own. The files of a dependency cycle form a single component, so the condensed graph
+// has no cycles and every file is layered.
+func layerFiles(deps map[string]map[string]bool) [][][]string {
+	components := stronglyConnected(deps)
+
+	componentOf := make(map[string]int, len(deps))
+	for i, component := range components {
+		for _, file := range component {
+			componentOf[file] = i
+		}
+	}
+
+	// pending counts the components each one depends on; dependants is the reverse edge.
+	pending := make([]int, len(components))
+	dependants := make([][]int, len(components))
+
+	for i, component := range components {
+		seen := map[int]bool{i: true}
+
+		for _, file := range component {
+			for dep := range deps[file] {
+				if j, ok := componentOf[dep]; ok && !seen[j] {
+					seen[j] = true
+					pending[i]++
+					dependants[j] = append(dependants[j], i)
+				}
+			}
+		}
+	}
+
+	var (
+		layers   [][][]string
+		frontier []int
+	)
+
+	for i := range components {
+		if pending[i] == 0 {
+			frontier = append(frontier, i)
+		}
+	}
+
+	for len(frontier) > 0 {
+		var (
+			layer [][]string
+			next  []int
+		)
+
+		for _, i := range frontier {
+			layer = append(layer, slices.Clone(components[i]))
+
+			for _, j := range dependants[i] {
+				if pending[j]--; pending[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+
+		layers = append(layers, layer)
+		frontier = next
+	}
+
+	return layers
+}
+
+// stronglyConnected returns the strongly connected components of the file graph deps
+// with Tarjan's algorithm, each sorted. Files are visited in order, so the result is
+// deterministic.
+func stronglyConnected(deps map[string]map[string]bool) [][]string {
+	var (
+		components [][]string
+		stack      []string
+		visit      func(file string)
+	)
+
+	index := make(map[string]int, len(deps))
+	lowLink := make(map[string]int, len(deps))
+	onStack := make(map[string]bool, len(deps))
+
+	visit = func(file string) {
+		index[file] = len(index)
+		lowLink[file] = index[file]
+		stack = append(stack, file)
+		onStack[file] = true
+
+		for _, dep := range slices.Sorted(maps.Keys(deps[file])) {
+			if _, ok := deps[dep]; !ok {
+				continue // Not a changeset file.
+			}
+
+			if _, visited := index[dep]; !visited {
+				visit(dep)
+				lowLink[file] = min(lowLink[file], lowLink[dep])
+			} else if onStack[dep] {
+				lowLink[file] = min(lowLink[file], index[dep])
+			}
+		}
+
+		if lowLink[file] != index[file] {
+			return
+		}
+
+		var component []string
+
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+
+			component = append(component, top)
+			if top == file {
+				break
+			}
+		}
+
+		slices.Sort(component)
+		components = append(components, component)
+	}
+
+	for _, file := range slices.Sorted(maps.Keys(deps)) {
+		if _, visited := index[file]; !visited {
+			visit(file)
+		}
+	}
+
+	return components
+}
+
+// mergeSmallestCommits merges the two smallest commits of the layer where they are
+// smallest combined. It reports false when no layer has two commits left.
+func mergeSmallestCommits(layers [][][]string) bool {
+	best, bestSize := -1, 0
+
+	for i, layer := range layers {
+		if len(layer) < 2 { //nolint:mnd // A merge needs two commits.
+			continue
+		}
+
+		slices.SortStableFunc(layer, func(a, b []string) int {
+			return len(a) - len(b)
+		})
+
+		if size := len(layer[0]) + len(layer[1]); best < 0 || size < bestSize {
+			best, bestSize = i, size
+		}
+	}
+
+	if best < 0 {
+		return false
+	}
+
+	layer := layers[best]
+	merged := append(slices.Clone(layer[0]), layer[1]...)
+	layers[best] = append([][]string{merged}, layer[2:]...)
+
+	return true
+}
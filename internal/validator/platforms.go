@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidPlatform is returned by ParsePlatform for platforms not of the form "os/arch".
+var ErrInvalidPlatform = errors.New("invalid platform")
+
+// Platform is a GOOS/GOARCH combination packages are loaded for.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+// String formats the platform as "goos/goarch".
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// ParsePlatform parses a platform of the form "goos/goarch", such as "windows/amd64", as
+// "go tool dist list" prints them.
+func ParsePlatform(platform string) (Platform, error) {
+	goos, goarch, ok := strings.Cut(strings.TrimSpace(platform), "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return Platform{}, fmt.Errorf("%w %q: want \"goos/goarch\"", ErrInvalidPlatform, platform)
+	}
+
+	return Platform{GOOS: goos, GOARCH: goarch}, nil
+}
+
+// perPlatform returns the options of each platform given to WithPlatforms, or o alone
+// when there are not several to analyze.
+func (o options) perPlatform() []options {
+	if len(o.platforms) <= 1 {
+		return []options{o}
+	}
+
+	cfgs := make([]options, 0, len(o.platforms))
+
+	for _, platform := range o.platforms {
+		cfg := o
+		cfg.platforms = []Platform{platform}
+		cfgs = append(cfgs, cfg)
+	}
+
+	return cfgs
+}
+
+// unionViolations appends to violations those of more it does not hold yet, as told by
+// their fingerprints. A nil violations is more itself.
+func unionViolations(violations, more []Violation) []Violation {
+	if violations == nil {
+		return more
+	}
+
+	for _, v := range more {
+		if !slices.ContainsFunc(violations, func(w Violation) bool { return w.Fingerprint == v.Fingerprint }) {
+			violations = append(violations, v)
+		}
+	}
+
+	return violations
+}
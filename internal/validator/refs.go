@@ -0,0 +1,239 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+)
+
+// CommitReport lists the atomicity violations found in a single commit.
+type CommitReport struct {
+	Commit     string      `json:"commit"`     // Full commit hash.
+	Violations []Violation `json:"violations"` // Empty when the commit is atomic.
+}
+
+// ValidateRefUpdate validates every commit introduced by moving a ref from oldRev
+// to newRev, reading all content from git objects so it works in bare repositories
+// (e.g. from pre-receive or update hooks).
+//
+// Each commit is checked the way a staged change is checked against a working tree:
+// the commit's files play the role of the staged files and newRev plays the role of
+// the working tree. A commit is not atomic when its code depends on files that only
+// reach their final state in a later commit of the update.
+func ValidateRefUpdate(
+	ctx context.Context, gitDir, oldRev, newRev string, opts ...Option,
+) ([]CommitReport, error) {
+	cfg := newOptions(opts)
+
+	if newRev == git.ZeroRev {
+		return nil, nil // Ref deletion, nothing to validate.
+	}
+
+	commits, err := git.RevList(ctx, gitDir, oldRev, newRev)
+	if err != nil {
+		return nil, fmt.Errorf("listing commits: %w", err)
+	}
+
+	tipFiles, err := git.GetTreeFiles(ctx, gitDir, newRev)
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+
+	tip, err := git.GetFilesAtRevision(ctx, gitDir, newRev, filterModuleFiles(tipFiles))
+	if err != nil {
+		return nil, fmt.Errorf("reading files: %w", err)
+	}
+
+	reports := make([]CommitReport, 0, len(commits))
+
+	for _, commit := range commits {
+		violations, commitErr := validateCommit(ctx, gitDir, commit, newRev, tip, nil, cfg)
+		if commitErr != nil {
+			return nil, fmt.Errorf("validating %s: %w", commit, commitErr)
+		}
+
+		reports = append(reports, CommitReport{Commit: commit, Violations: violations})
+	}
+
+	return reports, nil
+}
+
+// ValidateCommit validates an existing commit, named by rev, against the working tree:
+// the commit must not depend on changes that only landed in later commits or are still
+// uncommitted, untracked files included. Run on HEAD, it tells whether the last commit
+// stands on its own before amending it or during review.
+func ValidateCommit(ctx context.Context, workDir, rev string, opts ...Option) (CommitReport, error) {
+	cfg := newOptions(opts)
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return CommitReport{}, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	commit, err := git.ResolveCommit(ctx, absWorkDir, rev)
+	if err != nil {
+		return CommitReport{}, fmt.Errorf("resolving commit: %w", err)
+	}
+
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
+	if err != nil {
+		return CommitReport{}, fmt.Errorf("getting file status: %w", err)
+	}
+
+	tracked, err := git.GetIndexFiles(ctx, absWorkDir)
+	if err != nil {
+		return CommitReport{}, fmt.Errorf("listing files: %w", err)
+	}
+
+	var untracked []string
+
+	for file, status := range statuses {
+		if status.Staging == '?' {
+			untracked = append(untracked, file)
+		}
+	}
+
+	// The working tree is the final state; files deleted from it are left out.
+	tip := make(map[string][]byte)
+
+	for _, file := range filterModuleFiles(slices.Concat(tracked, untracked)) {
+		content, readErr := os.ReadFile(filepath.Join(absWorkDir, file)) //nolint:gosec // Path comes from git.
+		if readErr == nil {
+			tip[file] = content
+		}
+	}
+
+	violations, err := validateCommit(ctx, absWorkDir, commit, "", tip, untracked, cfg)
+	if err != nil {
+		return CommitReport{}, fmt.Errorf("validating %s: %w", commit, err)
+	}
+
+	return CommitReport{Commit: commit, Violations: violations}, nil
+}
+
+// validateCommit checks a commit against the final state tip: toRev, or the working tree
+// when toRev is empty, where untracked files count as changed too. The commit plays the
+// staged changes, its parent HEAD and tip the working tree, so that the checks of
+// ValidateAtomicCommit apply.
+func validateCommit(
+	ctx context.Context,
+	gitDir, commit, toRev string,
+	tip map[string][]byte,
+	untracked []string,
+	cfg options,
+) ([]Violation, error) {
+	// Snapshot directories are new every time, so caching them would only fill the cache.
+	cfg.cacheDir = ""
+
+	commitFiles, err := git.GetCommitFiles(ctx, gitDir, commit)
+	if err != nil {
+		return nil, fmt.Errorf("getting commit files: %w", err)
+	}
+
+	laterFiles, err := git.GetChangedFiles(ctx, gitDir, commit, toRev)
+	if err != nil {
+		return nil, fmt.Errorf("getting later changes: %w", err)
+	}
+
+	statuses := commitStatuses(commitFiles, slices.Concat(laterFiles, untracked))
+	changed := filterModuleFiles(slices.Collect(maps.Keys(statuses)))
+
+	// The parent of a root commit does not exist, so none of its files are read.
+	parent, err := git.GetFilesAtRevision(ctx, gitDir, commit+"^", changed)
+	if err != nil {
+		return nil, fmt.Errorf("reading parent files: %w", err)
+	}
+
+	committed, err := git.GetFilesAtRevision(ctx, gitDir, commit, changed)
+	if err != nil {
+		return nil, fmt.Errorf("reading commit files: %w", err)
+	}
+
+	// The package loader only needs a directory to anchor the overlay; every file
+	// it reads comes from the versions.
+	root, cleanup, err := snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+
+	defer cleanup()
+
+	versions := commitVersions(ctx, gitDir, commit, toRev, root, parent, committed, tip)
+
+	if len(git.FilterGoFiles(commitFiles)) == 0 {
+		return checkStaged(ctx, root, root, versions, statuses, nil, nil, cfg)
+	}
+
+	overlay := maps.Clone(versions.overlay)
+
+	for _, path := range commitFiles {
+		absPath := filepath.Join(root, path)
+
+		// Files the commit deleted must not be resurrected from the tip.
+		delete(overlay, absPath)
+
+		if content, ok := committed[path]; ok {
+			overlay[absPath] = content
+		}
+	}
+
+	cfg.report(Progress{Stage: StageLoading, Done: 0, Total: 0})
+
+	pkgs, err := analyzer.LoadPackagesShallow(ctx, root, overlay, cfg.build(), "./...")
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	cfg.report(Progress{Stage: StageLoaded, Done: 0, Total: len(pkgs)})
+
+	dg, err := buildGraph(ctx, pkgs, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkStaged(ctx, root, root, versions, statuses, pkgs, dg, cfg)
+}
+
+// commitStatuses describes the files of a commit and those changed after it as git
+// status would with the commit staged and the final state checked out.
+func commitStatuses(commitFiles, laterFiles []string) map[string]git.FileStatus {
+	statuses := make(map[string]git.FileStatus, len(commitFiles)+len(laterFiles))
+
+	for _, file := range commitFiles {
+		statuses[file] = git.FileStatus{Staging: 'M', Worktree: ' '}
+	}
+
+	for _, file := range laterFiles {
+		status, ok := statuses[file]
+		if !ok {
+			status.Staging = ' '
+		}
+
+		status.Worktree = 'M'
+		statuses[file] = status
+	}
+
+	return statuses
+}
+
+// filterModuleFiles keeps the files the package loader needs: Go sources and module metadata.
+func filterModuleFiles(files []string) []string {
+	var result []string
+
+	for _, file := range files {
+		base := filepath.Base(file)
+		if strings.HasSuffix(file, ".go") || base == "go.mod" || base == "go.sum" {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
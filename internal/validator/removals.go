@@ -0,0 +1,130 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dario.cat/darna/internal/analyzer"
+	"dario.cat/darna/internal/git"
+	"dario.cat/darna/internal/graph"
+)
+
+// findRemovedInUse reports symbols that the staged changes delete or rename while code
+// outside the staged files, as it stands in the working tree, still uses them. Such a
+// commit breaks the code it leaves untouched, which the staged snapshot cannot show:
+// the symbol is gone from dg, and so are the edges to it.
+//
+// Removals are spotted syntactically, and only then are the packages loaded again with
+// the staged files at HEAD, which restores the removed symbols and their users.
+func findRemovedInUse(
+	ctx context.Context,
+	absWorkDir string,
+	versions fileVersions,
+	statuses map[string]git.FileStatus,
+	dg *graph.DependencyGraph,
+	cfg options,
+) []Violation {
+	overlay := maps.Clone(versions.overlay)
+	if overlay == nil {
+		overlay = make(map[string][]byte)
+	}
+
+	stagedSet := make(map[string]bool)
+	removing := false
+
+	for file, status := range statuses {
+		if !strings.HasSuffix(file, ".go") || status.Staging == ' ' || status.Staging == '?' {
+			continue
+		}
+
+		absPath := filepath.Join(absWorkDir, file)
+		stagedSet[absPath] = true
+
+		head, err := versions.head(file)
+		if err != nil {
+			continue // Added by the staged changes.
+		}
+
+		overlay[absPath] = head
+
+		staged, err := versions.staged(file)
+		if err != nil || dropsDecls(file, head, staged) {
+			removing = true // Deleted, or lost a declaration.
+		}
+	}
+
+	if !removing {
+		return nil
+	}
+
+	_, headGraph, err := loadGraph(ctx, absWorkDir, overlay, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil
+	}
+
+	var violations []Violation
+
+	for _, file := range slices.Sorted(maps.Keys(stagedSet)) {
+		for _, id := range headGraph.FileSyms[file] {
+			if _, ok := dg.Symbols[id]; ok {
+				continue // Still defined, possibly moved to another file.
+			}
+
+			for _, userID := range slices.Sorted(headGraph.InEdges(id)) {
+				user := headGraph.Symbols[userID]
+				if user == nil || stagedSet[user.File] {
+					continue // Staged users fail to build, which the loader reports.
+				}
+
+				violations = append(violations, removedViolation(headGraph, file, id, user, absWorkDir))
+			}
+		}
+	}
+
+	return violations
+}
+
+// dropsDecls reports whether staged lacks a top-level declaration that head has.
+func dropsDecls(file string, head, staged []byte) bool {
+	before := make(declLocations)
+	before.add(file, head)
+
+	after := make(declLocations)
+	after.add(file, staged)
+
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removedViolation reports that the symbol id, removed from the staged file, is still
+// used by user.
+func removedViolation(dg *graph.DependencyGraph, file, id string, user *graph.Symbol, absWorkDir string) Violation {
+	rel := convertToRelativePaths([]string{file, user.File}, absWorkDir)
+	usePos := dg.UsePos(user.ID, id)
+
+	return Violation{
+		StagedFile:    rel[0],
+		StagedSymbol:  id,
+		StagedKind:    dg.Symbols[id].Kind,
+		MissingFile:   rel[1],
+		MissingSymbol: user.ID,
+		MissingKind:   user.Kind,
+		MissingLine:   usePos.Line,
+		MissingColumn: usePos.Column,
+		Reason:        "is removed by the staged changes but " + rel[1] + " still uses it",
+		Line:          0,
+		Column:        0,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}.withFingerprint()
+}
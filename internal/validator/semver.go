@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"dario.cat/darna/internal/git"
+)
+
+// Version bumps recommended by SuggestSemver.
+const (
+	BumpMajor = "major" // The staged changes break the exported API.
+	BumpMinor = "minor" // They only add to it.
+	BumpPatch = "patch" // They leave it as is.
+)
+
+// SemverReport is the version bump the staged changes call for, and why.
+type SemverReport struct {
+	Bump         string      `json:"bump"`              // BumpMajor, BumpMinor or BumpPatch.
+	Current      string      `json:"current,omitempty"` // Highest semantic version tag reachable from HEAD.
+	Next         string      `json:"next,omitempty"`    // Current bumped; empty without Current.
+	Incompatible []APIChange `json:"incompatible"`      // Changes calling for a major bump.
+	Compatible   []APIChange `json:"compatible"`        // Additions calling for a minor bump.
+}
+
+// SuggestSemver recommends a semantic version bump from the changes the staged snapshot
+// makes to the exported API, as CheckAPI finds them: major for incompatible changes,
+// minor for additions, patch otherwise. Next follows the Go module convention that v0
+// makes no compatibility promise: breaking a v0 API only bumps its minor version.
+func SuggestSemver(ctx context.Context, workDir string, opts ...Option) (SemverReport, error) {
+	incompatible, compatible, err := diffAPI(ctx, workDir, newOptions(opts))
+	if err != nil {
+		return SemverReport{}, err
+	}
+
+	report := SemverReport{
+		Bump:         BumpPatch,
+		Current:      "",
+		Next:         "",
+		Incompatible: incompatible,
+		Compatible:   compatible,
+	}
+
+	switch {
+	case len(incompatible) > 0:
+		report.Bump = BumpMajor
+	case len(compatible) > 0:
+		report.Bump = BumpMinor
+	}
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return SemverReport{}, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	tags, err := git.GetMergedTags(ctx, absWorkDir, "HEAD")
+	if err != nil {
+		return SemverReport{}, fmt.Errorf("listing tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if semver.IsValid(tag) && semver.Prerelease(tag) == "" &&
+			(report.Current == "" || semver.Compare(tag, report.Current) > 0) {
+			report.Current = tag
+		}
+	}
+
+	if report.Current != "" {
+		report.Next = nextVersion(report.Current, report.Bump)
+	}
+
+	return report, nil
+}
+
+// nextVersion bumps current, a valid semantic version without pre-release, dropping
+// its build metadata.
+func nextVersion(current, bump string) string {
+	parts := strings.SplitN(strings.TrimPrefix(semver.Canonical(current), "v"), ".", 3) //nolint:mnd // X.Y.Z.
+
+	major, _ := strconv.Atoi(parts[0])
+	minor, _ := strconv.Atoi(parts[1])
+	patch, _ := strconv.Atoi(parts[2])
+
+	if bump == BumpMajor && major == 0 {
+		bump = BumpMinor
+	}
+
+	switch bump {
+	case BumpMajor:
+		major, minor, patch = major+1, 0, 0
+	case BumpMinor:
+		minor, patch = minor+1, 0
+	default:
+		patch++
+	}
+
+	return fmt.Sprintf("v%d.%d.%d", major, minor, patch)
+}
@@ -0,0 +1,7 @@
+// Package dep is a local module used as a new dependency of the test project.
+package dep
+
+// Greeting returns a greeting.
+func Greeting() string {
+	return "hello"
+}
@@ -0,0 +1,15 @@
+package main
+
+// Sum adds up values whose type is in the union constraint, which includes Celsius from units.go.
+func Sum[T int | ~float32 | Celsius](values ...T) T {
+	var total T
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Reading holds a measurement whose type is in the union constraint, which includes Celsius from units.go.
+type Reading[T int | Celsius] struct {
+	Value T
+}
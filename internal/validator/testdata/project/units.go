@@ -0,0 +1,4 @@
+package main
+
+// Celsius is a temperature in degrees Celsius.
+type Celsius float64
@@ -2,10 +2,19 @@
 package validator
 
 import (
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -15,17 +24,88 @@ import (
 	"dario.cat/darna/internal/graph"
 )
 
-// Violation represents a violation of the atomic commit rule.
+// Violation represents a violation of the atomic commit rule. It carries everything
+// formatters report, so they never need the dependency graph.
 type Violation struct {
-	StagedFile    string // File being committed.
-	StagedSymbol  string // Symbol defined in staged file.
-	MissingFile   string // File with unstaged changes that's needed.
-	MissingSymbol string // Symbol from missing file that's used.
+	// File being committed and the symbol defined there, with its kind if known.
+	StagedFile   string `json:"stagedFile"           yaml:"stagedFile"`
+	StagedSymbol string `json:"stagedSymbol"         yaml:"stagedSymbol"`
+	StagedKind   string `json:"stagedKind,omitempty" yaml:"stagedKind,omitempty"`
+
+	// File with unstaged changes that's needed and the symbol used from it, with its
+	// kind and definition position if known.
+	MissingFile   string `json:"missingFile"             yaml:"missingFile"`
+	MissingSymbol string `json:"missingSymbol"           yaml:"missingSymbol"`
+	MissingKind   string `json:"missingKind,omitempty"   yaml:"missingKind,omitempty"`
+	MissingLine   int    `json:"missingLine,omitempty"   yaml:"missingLine,omitempty"`
+	MissingColumn int    `json:"missingColumn,omitempty" yaml:"missingColumn,omitempty"`
+
+	// Why the missing file is needed, if not a plain usage.
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	// Position in StagedFile of the usage leading to MissingSymbol, 0 if unknown.
+	Line   int `json:"line,omitempty"   yaml:"line,omitempty"`
+	Column int `json:"column,omitempty" yaml:"column,omitempty"`
+
+	// Symbols from StagedSymbol to MissingSymbol, both included, if known.
+	Chain []string `json:"chain,omitempty" yaml:"chain,omitempty"`
+
+	// Whether StagedSymbol uses MissingSymbol itself rather than through other symbols.
+	Direct bool `json:"direct" yaml:"direct"`
+
+	// Identifies the violation across runs, unaffected by line shifts.
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// Location returns the usage site as "file:line:column", or the staged file alone
+// when the position is unknown.
+func (v Violation) Location() string {
+	switch {
+	case v.Line == 0:
+		return v.StagedFile
+	case v.Column == 0:
+		return v.StagedFile + ":" + strconv.Itoa(v.Line)
+	default:
+		return v.StagedFile + ":" + strconv.Itoa(v.Line) + ":" + strconv.Itoa(v.Column)
+	}
+}
+
+// withFingerprint returns v with its Fingerprint derived from the files, symbols and
+// reason of v. Positions are left out, so unrelated edits do not change it.
+func (v Violation) withFingerprint() Violation {
+	sum := sha256.Sum256([]byte(strings.Join(
+		[]string{v.StagedFile, v.StagedSymbol, v.MissingFile, v.MissingSymbol, v.Reason}, "\x00")))
+	v.Fingerprint = hex.EncodeToString(sum[:fingerprintSize])
+
+	return v
+}
+
+// fingerprintSize is how many bytes of the hash a fingerprint keeps.
+const fingerprintSize = 8
+
+// sortViolations sorts violations by staged file, position, staged symbol and missing
+// file, so that every run reports them in the same order, and returns them.
+func sortViolations(violations []Violation) []Violation {
+	slices.SortFunc(violations, func(a, b Violation) int {
+		return cmp.Or(
+			cmp.Compare(a.StagedFile, b.StagedFile),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+			cmp.Compare(a.StagedSymbol, b.StagedSymbol),
+			cmp.Compare(a.MissingFile, b.MissingFile),
+			cmp.Compare(a.MissingSymbol, b.MissingSymbol),
+			cmp.Compare(a.Reason, b.Reason),
+		)
+	})
+
+	return violations
 }
 
 // ValidateAtomicCommit validates that staged files form an atomic commit.
 // Returns violations if staged code depends on unstaged changes.
-func ValidateAtomicCommit(ctx context.Context, workDir string) ([]Violation, error) {
+func ValidateAtomicCommit(ctx context.Context, workDir string, opts ...Option) ([]Violation, error) {
+	cfg := newOptions(opts)
+
 	// Convert workDir to absolute path for proper relative path calculations.
 	absWorkDir, err := filepath.Abs(workDir)
 	if err != nil {
@@ -33,48 +113,387 @@ func ValidateAtomicCommit(ctx context.Context, workDir string) ([]Violation, err
 	}
 
 	// 1. Get file statuses from git.
-	statuses, err := git.GetAllFileStatus(ctx, absWorkDir)
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
 	if err != nil {
 		return nil, fmt.Errorf("getting file status: %w", err)
 	}
 
-	// Categorize files and convert to absolute paths.
-	staged, stagedSet, notStagedSet := categorizeFiles(absWorkDir, statuses)
-
-	// Filter to .go files.
-	stagedGo := git.FilterGoFiles(staged)
-	if len(stagedGo) == 0 {
-		return nil, nil // Nothing to validate.
+	// Without staged .go files, only module metadata needs validating.
+	if len(git.FilterGoFiles(stagedFiles(statuses))) == 0 {
+		return checkStaged(ctx, absWorkDir, absWorkDir, workTreeVersions(ctx, absWorkDir), statuses, nil, nil, cfg)
 	}
 
 	// Build overlay for partially-staged files (MM status) so the package
 	// loader sees the staged content instead of the working tree version.
+	root := absWorkDir
 	overlay := buildOverlay(ctx, absWorkDir, statuses)
 
-	// 2. Load all packages in the repo.
-	pkgs, err := analyzer.LoadPackages(absWorkDir, overlay, "./...")
+	if cfg.fromIndex {
+		var cleanup func()
+
+		root, overlay, cleanup, err = indexSnapshot(ctx, absWorkDir, statuses)
+		if err != nil {
+			return nil, err
+		}
+
+		defer cleanup()
+	}
+
+	// 2. Load all packages in the repo, or those of the files to validate, and build
+	// their dependency graph. Index snapshots live in a new directory every time, so
+	// caching them would only fill the cache.
+	if cfg.fromIndex {
+		cfg.cacheDir = ""
+	}
+
+	// Work dirs holding several modules, without a go.work tying them together, are
+	// loaded one module at a time: those owning staged files.
+	modules, err := stagedModules(absWorkDir, statuses, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Under WithPlatforms, the packages of every platform are loaded and checked in
+	// turn too, and the violations found under any load reported once.
+	var violations []Violation
+
+	for _, platformCfg := range cfg.perPlatform() {
+		for _, module := range modules {
+			found, err := validateModule(ctx, absWorkDir, root, overlay, statuses, module, platformCfg)
+			if err != nil {
+				return nil, err
+			}
+
+			violations = unionViolations(violations, found)
+		}
+	}
+
+	return sortViolations(violations), nil
+}
+
+// validateModule loads the packages of module, a directory of the work dir as returned
+// by stagedModules, and checks the staged files against them.
+func validateModule(
+	ctx context.Context, absWorkDir, root string, overlay map[string][]byte,
+	statuses map[string]git.FileStatus, module string, cfg options,
+) ([]Violation, error) {
+	cfg.module = module
+	dir := filepath.Join(root, filepath.FromSlash(module))
+
+	// Index snapshots only hold the directories of modules in the overlay.
+	if cfg.fromIndex {
+		err := os.MkdirAll(dir, 0o750)
+		if err != nil {
+			return nil, fmt.Errorf("creating module dir: %w", err)
+		}
+	}
+
+	pkgs, dg, err := loadValidated(ctx, dir, overlay, cfg)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+
+	// 3. For each staged file, check dependencies.
+	return checkStaged(ctx, absWorkDir, root, workTreeVersions(ctx, absWorkDir), statuses, pkgs, dg, cfg)
+}
+
+// loadValidated loads the packages ValidateAtomicCommit checks, all of them or those of
+// the files given to WithFiles and the local packages they import, and their graph.
+func loadValidated(
+	ctx context.Context, root string, overlay map[string][]byte, cfg options,
+) ([]*packages.Package, *graph.DependencyGraph, error) {
+	if len(cfg.files) == 0 {
+		return loadGraph(ctx, root, overlay, cfg)
+	}
+
+	cfg.report(Progress{Stage: StageLoading, Done: 0, Total: 0})
+
+	pkgs, err := analyzer.LoadPackages(ctx, root, overlay, cfg.build(), cfg.patterns()...)
+	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		return nil, nil, err //nolint:wrapcheck // Callers wrap loader errors.
+	}
+
+	pkgs = withLocalImports(pkgs, root)
+
+	cfg.report(Progress{Stage: StageLoaded, Done: 0, Total: len(pkgs)})
+
+	dg, graphErr := buildGraph(ctx, pkgs, cfg)
+	if graphErr != nil {
+		return nil, nil, graphErr
+	}
+
+	return pkgs, dg, err //nolint:wrapcheck // Callers wrap loader errors.
+}
+
+// indexSnapshot returns an empty snapshot directory and the overlay populating it for
+// WithIndexSnapshot: every tracked file at its staged content, plus the untracked Go
+// files staged code may depend on. The checkout itself is never loaded.
+//
+//nolint:nonamedreturns // Named returns tell the snapshot parts apart.
+func indexSnapshot(
+	ctx context.Context, absWorkDir string, statuses map[string]git.FileStatus,
+) (root string, overlay map[string][]byte, cleanup func(), err error) {
+	root, cleanup, err = snapshotDir()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	overlay, err = indexOverlay(ctx, absWorkDir, root)
+	if err != nil {
+		cleanup()
+
+		return "", nil, nil, err
+	}
+
+	for file, status := range statuses {
+		if status.Staging != '?' || !strings.HasSuffix(file, ".go") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(absWorkDir, file)) //nolint:gosec // Path comes from git status output.
+		if err != nil {
+			cleanup()
+
+			return "", nil, nil, fmt.Errorf("reading untracked %s: %w", file, err)
+		}
+
+		overlay[filepath.Join(root, file)] = content
+	}
+
+	return root, overlay, cleanup, nil
+}
+
+// ValidatedFiles returns the staged Go files of workDir that ValidateAtomicCommit checks
+// under opts, relative to workDir and sorted. Files are selected by path only, so those
+// exempted by their package, as with WithIgnoreMain or WithSkipGenerated, are listed.
+func ValidatedFiles(ctx context.Context, workDir string, opts ...Option) ([]string, error) {
+	cfg := newOptions(opts)
+
+	statuses, err := git.GetFileStatus(ctx, workDir, cfg.untrackedDepth)
 	if err != nil {
-		if !errors.Is(err, analyzer.ErrPackagesContainErrors) {
-			return nil, fmt.Errorf("loading packages: %w", err)
+		return nil, fmt.Errorf("getting file status: %w", err)
+	}
+
+	files := slices.DeleteFunc(git.FilterGoFiles(stagedFiles(statuses)), func(file string) bool {
+		return !cfg.validated(file)
+	})
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// withLocalImports returns pkgs followed by the packages below root they import,
+// directly or not, so that the graph covers the symbols staged code can reach when
+// only some packages were loaded.
+func withLocalImports(pkgs []*packages.Package, root string) []*packages.Package {
+	result := slices.Clone(pkgs)
+	roots := make(map[*packages.Package]bool, len(pkgs))
+
+	for _, pkg := range pkgs {
+		roots[pkg] = true
+	}
+
+	packages.Visit(pkgs, func(pkg *packages.Package) bool {
+		if roots[pkg] {
+			return true
+		}
+
+		// Packages outside the module, and thus everything they import, are not analyzed.
+		if len(pkg.GoFiles) == 0 || !isWithin(pkg.GoFiles[0], root) {
+			return false
+		}
+
+		result = append(result, pkg)
+
+		return true
+	}, nil)
+
+	return result
+}
+
+// stagedFiles returns the paths in statuses with index changes.
+func stagedFiles(statuses map[string]git.FileStatus) []string {
+	var files []string
+
+	for file, status := range statuses {
+		if status.Staging != ' ' && status.Staging != '?' {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// checkStaged reports the violations of the staged files in statuses against the
+// loaded packages and their dependency graph. Files are relative to absWorkDir and read
+// through versions, while root is the directory the packages were loaded from:
+// absWorkDir itself, or a snapshot of it.
+func checkStaged(
+	ctx context.Context,
+	absWorkDir, root string,
+	versions fileVersions,
+	statuses map[string]git.FileStatus,
+	pkgs []*packages.Package,
+	dg *graph.DependencyGraph,
+	cfg options,
+) ([]Violation, error) {
+	// Categorize files and convert to absolute paths.
+	staged, stagedSet, notStagedSet := categorizeFiles(root, statuses)
+
+	// go.mod and go.sum are committed together, whatever Go files are staged, and so
+	// are the files paired by WithPairedFiles.
+	unpaired := slices.Concat(findUnpairedModuleFiles(versions, statuses), findUnpairedFiles(statuses, cfg.pairs))
+
+	stagedGo := git.FilterGoFiles(staged)
+	if len(stagedGo) == 0 {
+		return sortViolations(cfg.filterViolations(unpaired)), nil
+	}
+
+	// Symbols moved between files with only half of the move staged leave the
+	// staged snapshot with a duplicate or missing definition.
+	// Likewise, imports of modules only required by an unstaged go.mod do not build,
+	// and neither does code left untouched that uses symbols the staged changes remove,
+	// nor a go.mod committed without the go.sum checksums of its dependencies.
+	moves := findIncompleteMoves(versions, statuses)
+	moves = append(moves, findUnstagedRequirements(absWorkDir, versions, statuses)...)
+	moves = append(moves, unpaired...)
+
+	// A removal that is half of a move is already reported as such.
+	removedInUse := findRemovedInUse(ctx, absWorkDir, versions, statuses, dg, cfg)
+	removed := slices.DeleteFunc(removedInUse, func(r Violation) bool {
+		return slices.ContainsFunc(moves, func(m Violation) bool {
+			return m.StagedFile == r.StagedFile && strings.HasSuffix(r.StagedSymbol, "."+m.StagedSymbol)
+		})
+	})
+	moves = append(moves, removed...)
+
+	// Package errors only matter when they originate from a staged file — errors
+	// confined to unstaged or untracked files can be ignored. An incomplete move or
+	// a missing requirement explains such errors better than the loader does.
+	if versions.strict && hasErrorsInStagedFiles(pkgs, stagedSet) {
+		if moves = cfg.filterViolations(dropSuppressed(dg, moves, root)); len(moves) > 0 {
+			return sortViolations(moves), nil
 		}
 
-		// Package errors exist. Only fail if any error is in a staged file —
-		// errors confined to unstaged or untracked files can be ignored.
-		if hasErrorsInStagedFiles(pkgs, stagedSet) {
-			analyzer.PrintErrors(pkgs)
+		analyzer.PrintErrors(pkgs)
+
+		return nil, fmt.Errorf("loading packages: %w", analyzer.ErrPackagesContainErrors)
+	}
+
+	if cfg.ignoreMain {
+		mainFiles := mainPackageFiles(pkgs)
+		stagedGo = excludeFiles(stagedGo, mainFiles)
+		moves = excludeViolations(moves, mainFiles, root)
+	}
+
+	// Generated files are neither validated nor reported as missing, since they cannot
+	// meaningfully be split from their generators.
+	var generated map[string]bool
+
+	if cfg.skipGenerated {
+		generated = generatedFiles(pkgs)
+		stagedGo = excludeFiles(stagedGo, generated)
+		moves = excludeViolations(moves, generated, root)
+	}
+
+	// Excluded staged files are not traversed at all; the graph keeps their symbols so
+	// that chains through them still reach the missing files.
+	stagedGo = slices.DeleteFunc(stagedGo, func(file string) bool {
+		rel, err := filepath.Rel(root, file)
+
+		return err == nil && !cfg.validated(rel)
+	})
+
+	violations := slices.Concat(moves, findViolations(dg, stagedGo, stagedSet, notStagedSet, root, cfg.maxDepth))
+	violations = slices.DeleteFunc(violations, func(v Violation) bool {
+		return generated[filepath.Join(root, v.MissingFile)]
+	})
+
+	if cfg.changedOnly {
+		unchanged := unchangedSymbols(absWorkDir, root, versions, statuses, dg)
+		violations = slices.DeleteFunc(violations, func(v Violation) bool {
+			return unchanged[v.MissingSymbol]
+		})
+	}
+
+	return sortViolations(cfg.filterViolations(dropSuppressed(dg, violations, root))), nil
+}
+
+// mainPackageFiles returns the absolute paths of files belonging to package main.
+func mainPackageFiles(pkgs []*packages.Package) map[string]bool {
+	files := make(map[string]bool)
 
-			return nil, fmt.Errorf("loading packages: %w", err)
+	for _, pkg := range pkgs {
+		if pkg.Name != "main" {
+			continue
+		}
+
+		for _, file := range pkg.GoFiles {
+			files[file] = true
 		}
 	}
 
-	// 3. Build dependency graph.
-	dg := graph.NewDependencyGraph()
+	return files
+}
+
+// dropSuppressed removes the violations originating from a symbol or file marked with
+// analyzer.IgnoreDirective.
+func dropSuppressed(dg *graph.DependencyGraph, violations []Violation, absWorkDir string) []Violation {
+	return slices.DeleteFunc(violations, func(v Violation) bool {
+		return dg.IsIgnoredSymbol(v.StagedSymbol) || dg.IsIgnoredFile(filepath.Join(absWorkDir, v.StagedFile))
+	})
+}
+
+// generatedFiles returns the absolute paths of files carrying the standard
+// "// Code generated ... DO NOT EDIT." header. The headers of packages loaded without
+// syntax, as those served from the analysis cache, are parsed from disk.
+func generatedFiles(pkgs []*packages.Package) map[string]bool {
+	files := make(map[string]bool)
+
 	for _, pkg := range pkgs {
-		dg.AnalyzePackage(pkg)
+		if pkg.Syntax == nil {
+			for _, path := range pkg.GoFiles {
+				file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly|parser.ParseComments)
+				if err == nil && ast.IsGenerated(file) {
+					files[path] = true
+				}
+			}
+		}
+
+		for _, file := range pkg.Syntax {
+			if ast.IsGenerated(file) {
+				files[pkg.Fset.Position(file.Package).Filename] = true
+			}
+		}
+	}
+
+	return files
+}
+
+// excludeFiles returns files not present in excluded.
+func excludeFiles(files []string, excluded map[string]bool) []string {
+	var result []string
+
+	for _, file := range files {
+		if !excluded[file] {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// excludeViolations drops violations whose staged file is in excluded (absolute paths).
+func excludeViolations(violations []Violation, excluded map[string]bool, absWorkDir string) []Violation {
+	var result []Violation
+
+	for _, v := range violations {
+		if !excluded[filepath.Join(absWorkDir, v.StagedFile)] {
+			result = append(result, v)
+		}
 	}
 
-	// 4. For each staged file, check dependencies.
-	return findViolations(dg, stagedGo, stagedSet, notStagedSet, absWorkDir), nil
+	return result
 }
 
 //nolint:nonamedreturns // Named returns clarify same-type values.
@@ -171,14 +590,16 @@ func findViolations(
 	stagedGo []string,
 	stagedSet, notStagedSet map[string]bool,
 	absWorkDir string,
+	maxDepth int,
 ) []Violation {
 	var violations []Violation
 
 	for _, file := range stagedGo {
 		symbols := dg.FileSyms[file]
 		for _, symID := range symbols {
-			deps := dg.TransitiveDeps(symID)
-			for _, depID := range deps {
+			paths := dg.ShortestPathsWithin(symID, maxDepth)
+
+			for depID := range paths.Reached() {
 				depSym := dg.Symbols[depID]
 				if depSym == nil {
 					continue // External dependency, skip.
@@ -188,7 +609,9 @@ func findViolations(
 
 				// Check if dependency is not staged (either unstaged or untracked).
 				if !stagedSet[depFile] && isNotStaged(depFile, notStagedSet) {
-					violations = append(violations, newViolation(file, symID, depFile, depID, absWorkDir))
+					if chain := paths.To(depID); len(chain) > 1 {
+						violations = append(violations, newViolation(dg, file, chain, depFile, absWorkDir))
+					}
 				}
 			}
 		}
@@ -197,7 +620,9 @@ func findViolations(
 	return violations
 }
 
-func newViolation(file, symID, depFile, depID, absWorkDir string) Violation {
+// newViolation reports that file's symbol chain[0] reaches chain[len(chain)-1],
+// defined in the not staged depFile, through chain.
+func newViolation(dg *graph.DependencyGraph, file string, chain []string, depFile, absWorkDir string) Violation {
 	// Convert to relative path for better display.
 	relFile, err := filepath.Rel(absWorkDir, file)
 	if err != nil {
@@ -209,12 +634,26 @@ func newViolation(file, symID, depFile, depID, absWorkDir string) Violation {
 		relDepFile = depFile
 	}
 
+	// The staged code itself uses the first step of the chain.
+	usePos := dg.UsePos(chain[0], chain[1])
+	stagedSym, missingSym := dg.Symbols[chain[0]], dg.Symbols[chain[len(chain)-1]]
+
 	return Violation{
 		StagedFile:    relFile,
-		StagedSymbol:  symID,
+		StagedSymbol:  chain[0],
+		StagedKind:    stagedSym.Kind,
 		MissingFile:   relDepFile,
-		MissingSymbol: depID,
-	}
+		MissingSymbol: chain[len(chain)-1],
+		MissingKind:   missingSym.Kind,
+		MissingLine:   missingSym.Pos.Line,
+		MissingColumn: missingSym.Pos.Column,
+		Reason:        "",
+		Line:          usePos.Line,
+		Column:        usePos.Column,
+		Chain:         chain,
+		Direct:        len(chain) == 2, //nolint:mnd // A direct use has only both ends.
+		Fingerprint:   "",
+	}.withFingerprint()
 }
 
 // isNotStaged checks if a file is not staged, handling directory prefixes
@@ -248,7 +687,53 @@ func ensureTrailingSlash(dir string) string {
 // Returns the first independent file (sorted lexicographically).
 // If includeDependants is true, also returns direct dependants that only depend on
 // the base file and committed code.
-func FindCommittableSet(ctx context.Context, workDir string, includeDependants bool) ([]string, error) {
+func FindCommittableSet(
+	ctx context.Context, workDir string, includeDependants bool, opts ...Option,
+) ([]string, error) {
+	cfg := newOptions(opts)
+
+	ca, err := analyzeCandidates(ctx, workDir, cfg)
+	if err != nil || len(ca.candidates) == 0 {
+		return nil, err
+	}
+
+	// Find first independent file and optionally its dependants.
+	return findCommittableSet(ca.dg, ca.candidates, ca.statuses, ca.absWorkDir, includeDependants, cfg), nil
+}
+
+// FindIndependentFiles returns every unstaged or untracked file that can be committed
+// on its own right now, sorted lexicographically: the files FindCommittableSet could
+// pick as its base file.
+func FindIndependentFiles(ctx context.Context, workDir string, opts ...Option) ([]string, error) {
+	ca, err := analyzeCandidates(ctx, workDir, newOptions(opts))
+	if err != nil || len(ca.candidates) == 0 {
+		return nil, err
+	}
+
+	changesetFiles := buildChangesetMap(ca.absWorkDir, ca.statuses)
+
+	var independent []string
+
+	for _, file := range sortFilesCopy(ca.candidates) {
+		if isIndependent(ca.dg, file, changesetFiles) {
+			independent = append(independent, file)
+		}
+	}
+
+	return convertToRelativePaths(independent, ca.absWorkDir), nil
+}
+
+// candidateAnalysis holds the inputs of committable selection.
+type candidateAnalysis struct {
+	absWorkDir string
+	statuses   map[string]git.FileStatus
+	candidates []string // Absolute paths of unstaged and untracked Go files.
+	dg         *graph.DependencyGraph
+}
+
+// analyzeCandidates finds the committable candidates of workDir and builds the
+// dependency graph they are selected with.
+func analyzeCandidates(ctx context.Context, workDir string, cfg options) (*candidateAnalysis, error) {
 	// Convert workDir to absolute path for proper relative path calculations.
 	absWorkDir, err := filepath.Abs(workDir)
 	if err != nil {
@@ -256,39 +741,37 @@ func FindCommittableSet(ctx context.Context, workDir string, includeDependants b
 	}
 
 	// 1. Get file statuses from git.
-	statuses, err := git.GetAllFileStatus(ctx, absWorkDir)
+	statuses, err := git.GetFileStatus(ctx, absWorkDir, cfg.untrackedDepth)
 	if err != nil {
 		return nil, fmt.Errorf("getting file status: %w", err)
 	}
 
-	// 2. Extract candidates (unstaged/untracked files only).
-	candidates := getCandidates(absWorkDir, statuses)
+	ca := &candidateAnalysis{absWorkDir: absWorkDir, statuses: statuses, candidates: nil, dg: nil}
 
-	// Filter to .go files.
-	candidatesGo := git.FilterGoFiles(candidates)
+	// 2. Extract candidates (unstaged/untracked files only).
+	candidatesGo := git.FilterGoFiles(getCandidates(absWorkDir, statuses))
 	if len(candidatesGo) == 0 {
-		return nil, nil // No candidates.
+		return ca, nil // No candidates.
 	}
 
 	// 3. Build overlay for partially-staged files (MM status).
 	overlay := buildOverlay(ctx, absWorkDir, statuses)
 
-	// 4. Load all packages in the repo.
-	pkgs, err := analyzer.LoadPackages(absWorkDir, overlay, "./...")
+	// 4. Load all packages in the repo and build their dependency graph.
+	pkgs, dg, err := loadGraph(ctx, absWorkDir, overlay, cfg)
 	if err != nil && !errors.Is(err, analyzer.ErrPackagesContainErrors) {
 		return nil, fmt.Errorf("loading packages: %w", err)
 	}
 	// Package errors in unstaged files are tolerated: analysis continues with
 	// the packages that compiled successfully.
 
-	// 5. Build dependency graph.
-	dg := graph.NewDependencyGraph()
-	for _, pkg := range pkgs {
-		dg.AnalyzePackage(pkg)
+	if cfg.ignoreMain {
+		candidatesGo = excludeFiles(candidatesGo, mainPackageFiles(pkgs))
 	}
 
-	// 6. Find first independent file and optionally its dependants.
-	return findCommittableSet(dg, candidatesGo, statuses, absWorkDir, includeDependants), nil
+	ca.candidates, ca.dg = candidatesGo, dg
+
+	return ca, nil
 }
 
 // getCandidates extracts files that are candidates for committable selection.
@@ -318,7 +801,9 @@ func getCandidates(absWorkDir string, statuses map[string]git.FileStatus) []stri
 }
 
 // findCommittableSet finds the first independent file from candidates.
-// If includeDependants is true, also includes direct dependants.
+// If includeDependants is true, also includes direct dependants; with cfg.cluster, the
+// set then grows with the candidates cohesive with it, and with cfg.withTests with the
+// test files of the set. The set is capped at cfg.maxFiles files.
 // Files are sorted lexicographically by path, and the first independent file is selected.
 // Returns relative paths, or nil if none found.
 //
@@ -329,6 +814,7 @@ func findCommittableSet(
 	statuses map[string]git.FileStatus,
 	absWorkDir string,
 	includeDependants bool,
+	cfg options,
 ) []string {
 	sortedCandidates := sortFilesCopy(candidates)
 	changesetFiles := buildChangesetMap(absWorkDir, statuses)
@@ -337,6 +823,18 @@ func findCommittableSet(
 	for _, file := range sortedCandidates {
 		if isIndependent(dg, file, changesetFiles) {
 			result := buildCommittableSet(dg, file, changesetFiles, includeDependants)
+			if cfg.cluster {
+				result = growCluster(dg, result, sortedCandidates, changesetFiles)
+			}
+
+			if cfg.withTests {
+				result = addTests(dg, result, sortedCandidates, changesetFiles)
+			}
+
+			// Every file only depends on files before it, so any prefix is atomic.
+			if cfg.maxFiles > 0 && len(result) > cfg.maxFiles {
+				result = result[:cfg.maxFiles]
+			}
 
 			return convertToRelativePaths(result, absWorkDir)
 		}
@@ -540,7 +1038,7 @@ func collectSymbolDependants(
 	changesetFiles map[string]bool,
 	dependantFiles map[string]bool,
 ) {
-	for dependentSymID := range dg.InEdges[baseSymID] {
+	for dependentSymID := range dg.InEdges(baseSymID) {
 		dependentSym := dg.Symbols[dependentSymID]
 		if dependentSym == nil {
 			continue
@@ -574,3 +1072,38 @@ func filterCommittableWithBase(
 
 	return result
 }
+
+// ViolationsForFiles runs the full validation but only returns violations whose staged
+// file is one of files (absolute or relative to workDir), e.g. the buffers open in an
+// editor. Dependencies are still resolved across the whole repository.
+func ViolationsForFiles(ctx context.Context, workDir string, files []string, opts ...Option) ([]Violation, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving work dir: %w", err)
+	}
+
+	violations, err := ValidateAtomicCommit(ctx, absWorkDir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(absWorkDir, file)
+		}
+
+		wanted[filepath.Clean(file)] = true
+	}
+
+	var result []Violation
+
+	for _, v := range violations {
+		if wanted[filepath.Join(absWorkDir, v.StagedFile)] {
+			result = append(result, v)
+		}
+	}
+
+	return result, nil
+}
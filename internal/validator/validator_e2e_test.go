@@ -1,13 +1,17 @@
 package validator_test
 
 import (
+	"errors"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
 
+	"dario.cat/darna/internal/analyzer"
 	"dario.cat/darna/internal/validator"
 )
 
@@ -45,7 +49,7 @@ func setupTestRepo(t *testing.T) string {
 		"alpha.go", "beta.go", "gamma.go",
 		"constants.go", "variables.go", "consumer.go",
 		"calculator.go", "calculator_user.go",
-		"processor.go",
+		"processor.go", "generic.go", "units.go",
 	}
 	for _, file := range files {
 		src := filepath.Join(testdataDir, file)
@@ -713,6 +717,148 @@ func TestValidateAtomicCommit_SpecificSymbol_TransitiveChain(t *testing.T) {
 	)
 }
 
+func TestValidateAtomicCommit_TransitiveViolationChain(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Transitive Violation Chain",
+		"gamma.go (GammaFunc) -> beta.go (BetaFunc) -> alpha.go (AlphaFunc)",
+		"Modified [gamma.go, alpha.go] | Staged [gamma.go] | Unstaged [alpha.go]",
+		"GammaFunc -> AlphaFunc violation carries the chain through BetaFunc")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	stageFiles(t, repoDir, "gamma.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	want := []string{
+		"example.com/testproject.GammaFunc",
+		"example.com/testproject.BetaFunc",
+		"example.com/testproject.AlphaFunc",
+	}
+
+	for _, v := range violations {
+		if v.StagedSymbol == want[0] && v.MissingSymbol == want[2] {
+			if !slices.Equal(v.Chain, want) {
+				t.Errorf("Chain = %v, want %v", v.Chain, want)
+			}
+
+			if v.Direct || v.StagedKind != "func" || v.MissingKind != "func" || v.MissingLine == 0 {
+				t.Errorf("Expected a transitive func to func violation with a definition line, got %+v", v)
+			}
+
+			if v.Fingerprint == "" {
+				t.Error("Expected a fingerprint")
+			}
+
+			return
+		}
+	}
+
+	t.Errorf("Expected a GammaFunc -> AlphaFunc violation, violations: %+v", violations)
+}
+
+func TestValidateAtomicCommit_MaxDepth(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Depth-Limited Transitive Violation",
+		"gamma.go (GammaFunc) -> beta.go (BetaFunc) -> alpha.go (AlphaFunc)",
+		"Modified [gamma.go, alpha.go] | Staged [gamma.go] | Unstaged [alpha.go]",
+		"Violation with WithMaxDepth(2), none with WithMaxDepth(1)")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	stageFiles(t, repoDir, "gamma.go")
+
+	for depth, want := range map[int]bool{1: false, 2: true} {
+		violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithMaxDepth(depth))
+		if err != nil {
+			t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		}
+
+		got := slices.ContainsFunc(violations, func(v validator.Violation) bool { return v.MissingFile == "alpha.go" })
+		if got != want {
+			t.Errorf("WithMaxDepth(%d): violation requiring alpha.go = %v, want %v: %+v", depth, got, want, violations)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_Exclude(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Excluded Missing File",
+		"consumer.go (ConsumeConstant func) -> constants.go (MaxRetries const)",
+		"Modified [consumer.go, constants.go] | Staged [consumer.go] | Unstaged [constants.go]",
+		"No violations - constants.go matches an exclude glob")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "consumer.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	stageFiles(t, repoDir, "consumer.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithExclude("const*.go"))
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	for _, v := range violations {
+		if v.MissingFile == "constants.go" {
+			t.Errorf("Expected constants.go to be excluded, got %+v", v)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_IncludeExclude(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Staged Files Outside Include Or Inside Exclude",
+		"consumer.go (ConsumeConstant func) -> constants.go (MaxRetries const)",
+		"Modified [consumer.go, constants.go] | Staged [consumer.go] | Unstaged [constants.go]",
+		"No violations when consumer.go is not included or is excluded, violations when included")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "consumer.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	stageFiles(t, repoDir, "consumer.go")
+
+	tests := []struct {
+		name string
+		opts []validator.Option
+		want bool
+	}{
+		{name: "not included", opts: []validator.Option{validator.WithInclude("internal", "main.go")}, want: false},
+		{name: "included", opts: []validator.Option{validator.WithInclude("consumer.go")}, want: true},
+		{name: "excluded", opts: []validator.Option{validator.WithExclude("*er.go")}, want: false},
+	}
+
+	for _, tt := range tests {
+		violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: ValidateAtomicCommit failed: %v", tt.name, err)
+		}
+
+		got := slices.ContainsFunc(violations, func(v validator.Violation) bool {
+			return v.StagedFile == fileConsumerGo
+		})
+		if got != tt.want {
+			t.Errorf("%s: consumer.go violations reported = %v, want %v: %+v", tt.name, got, tt.want, violations)
+		}
+	}
+}
+
 func TestValidateAtomicCommit_SpecificSymbol_Constant(t *testing.T) {
 	t.Parallel()
 
@@ -801,1210 +947,3938 @@ func TestValidateAtomicCommit_SpecificSymbol_Variable(t *testing.T) {
 	}
 }
 
-func TestValidateAtomicCommit_SpecificSymbol_Method(t *testing.T) {
+func TestValidateAtomicCommit_GenericUnionConstraint(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Method Symbol Dependency",
-		"calculator_user.go (UseCalculator func) -> calculator.go (Calculator type + Add method)",
-		"Modified [calculator_user.go, calculator.go] | Staged [calculator_user.go] | Unstaged [calculator.go]",
-		"Violation tracking method: UseCalculator -> Calculator.Add or Calculator type")
+		"Generic Union Constraint Dependency",
+		"generic.go (Sum func, Reading type constrained by int | Celsius) -> units.go (Celsius type)",
+		"Modified [generic.go, units.go] | Staged [generic.go] | Unstaged [units.go]",
+		"Violations tracking Celsius from both Sum and Reading")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify calculator_user.go (uses Calculator.Add from calculator.go) and calculator.go.
-	modifyFile(t, filepath.Join(repoDir, "calculator_user.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "calculator.go"), testComment)
-
-	// Stage only calculator_user.go.
-	stageFiles(t, repoDir, "calculator_user.go")
+	modifyFile(t, filepath.Join(repoDir, "generic.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "units.go"), testComment)
+	stageFiles(t, repoDir, "generic.go")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations for method dependency, got none")
-	}
-
-	// Verify method or type symbol is tracked (could be Calculator type or Add method).
-	found := false
+	found := make(map[string]bool)
 
 	for _, v := range violations {
-		if v.StagedFile == "calculator_user.go" && v.MissingFile == "calculator.go" {
-			// Accept either Calculator type or Calculator.Add method.
-			if v.MissingSymbol == "example.com/testproject.Calculator" ||
-				v.MissingSymbol == "example.com/testproject.Calculator.Add" {
-				found = true
-
-				break
-			}
+		if v.MissingFile == "units.go" && v.MissingSymbol == "example.com/testproject.Celsius" {
+			found[v.StagedSymbol] = true
 		}
 	}
 
-	if !found {
-		t.Errorf("Expected violation tracking Calculator type or Add method, violations: %+v", violations)
+	for _, symbol := range []string{"example.com/testproject.Sum", "example.com/testproject.Reading"} {
+		if !found[symbol] {
+			t.Errorf("Expected violation from %s to Celsius, violations: %+v", symbol, violations)
+		}
 	}
 }
 
-func TestValidateAtomicCommit_MultipleSymbols_PartialViolation(t *testing.T) {
+func TestValidateAtomicCommit_NewRequirementNotStaged(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Partial Violation (Multiple Symbols in One File)",
-		"consumer.go has 2 funcs: ConsumeConstant -> MaxRetries (OK), ConsumeVariable -> DefaultTimeout (VIOLATION)",
-		"Modified [consumer.go, constants.go, variables.go] | Staged [consumer.go, constants.go] | Unstaged [variables.go]",
-		"Only ConsumeVariable violates (depends on unstaged DefaultTimeout), ConsumeConstant is fine")
+		"New Module Requirement",
+		"alpha.go imports example.com/dep, required only by the working tree go.mod",
+		"Modified [alpha.go, go.mod] | Staged [alpha.go] | Unstaged [go.mod]",
+		"Violation: the new import requires staging go.mod")
 
 	repoDir := setupTestRepo(t)
 
-	// Consumer.go has two functions: ConsumeConstant and ConsumeVariable
-	// Modify consumer.go, constants.go, but not variables.go.
-	modifyFile(t, filepath.Join(repoDir, "consumer.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "variables.go"), testComment)
+	depDir := createUntrackedSubpackage(t, repoDir, "dep")
+	copyFile(t, filepath.Join("testdata", "dep", "go.mod"), filepath.Join(depDir, "go.mod"))
+	copyFile(t, filepath.Join("testdata", "dep", "dep.go"), filepath.Join(depDir, "dep.go"))
 
-	// Stage consumer.go and constants.go (ConsumeConstant satisfied, ConsumeVariable not).
-	stageFiles(t, repoDir, "consumer.go", "constants.go")
+	modifyFile(t, filepath.Join(repoDir, "go.mod"),
+		"\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ./dep\n")
+	writeFileContent(t, filepath.Join(repoDir, "alpha.go"),
+		"package main\n\nimport \"example.com/dep\"\n\n"+
+			"// AlphaFunc greets through the new dependency.\nfunc AlphaFunc() string {\n\treturn dep.Greeting()\n}\n")
+	stageFiles(t, repoDir, "alpha.go")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations, got none")
+	want := validator.Violation{
+		StagedFile:    "alpha.go",
+		StagedSymbol:  "example.com/dep",
+		StagedKind:    "",
+		MissingFile:   "go.mod",
+		MissingSymbol: "example.com/dep",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "is a new import that requires staging go.mod (example.com/dep)",
+		Line:          3,
+		Column:        8,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
 	}
 
-	// Should have violation for ConsumeVariable -> DefaultTimeout.
-	foundVariable := false
-	foundConstant := false
-
-	for _, v := range violations {
-		if v.StagedFile == fileConsumerGo {
-			if v.MissingSymbol == "example.com/testproject.DefaultTimeout" {
-				foundVariable = true
-			}
+	isWant := func(v validator.Violation) bool {
+		v.Fingerprint = ""
 
-			if v.MissingSymbol == "example.com/testproject.MaxRetries" {
-				foundConstant = true
-			}
-		}
+		return reflect.DeepEqual(v, want)
+	}
+	if !slices.ContainsFunc(violations, isWant) {
+		t.Errorf("Expected violation %+v, got %+v", want, violations)
 	}
 
-	if !foundVariable {
-		t.Error("Expected violation for ConsumeVariable depending on DefaultTimeout")
+	// Staging go.mod too makes the commit complete.
+	stageFiles(t, repoDir, "go.mod")
+
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if foundConstant {
-		t.Error("Should NOT have violation for ConsumeConstant since constants.go is staged")
+	if slices.ContainsFunc(violations, isWant) {
+		t.Errorf("Expected no go.mod violation once staged, got %+v", violations)
 	}
 }
 
-func TestValidateAtomicCommit_DependencyOnCommittedFile(t *testing.T) {
+func TestValidateAtomicCommit_GoModStagedWithoutGoSum(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Dependency on Already-Committed File (No Violation)",
-		"main.go (main func) -> utils.go (Helper func - ALREADY COMMITTED)",
-		"Modified [main.go] | Staged [main.go] | Unmodified [utils.go - already committed]",
-		"No violation - utils.go is already in the repository")
+		"go.mod Staged Without go.sum",
+		"go.mod requires example.com/dep, go.sum records its checksums",
+		"Modified [go.mod, go.sum] | Staged [go.mod] | Unstaged [go.sum]",
+		"Violation: the dependency changes of go.mod need go.sum staged")
 
 	repoDir := setupTestRepo(t)
+	writeFileContent(t, filepath.Join(repoDir, "go.sum"), "")
+	runGit(t, repoDir, "add", "go.sum")
+	runGit(t, repoDir, "commit", "-m", "Add go.sum")
 
-	// Modify and stage only main.go (which depends on utils.go)
-	// utils.go remains unmodified from the initial commit.
-	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Comment in main\n")
-	stageFiles(t, repoDir, "main.go")
+	modifyFile(t, filepath.Join(repoDir, "go.mod"), "\nrequire example.com/dep v1.0.0\n")
+	modifyFile(t, filepath.Join(repoDir, "go.sum"), "example.com/dep v1.0.0/go.mod h1:abc=\n")
+	stageFiles(t, repoDir, "go.mod")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	// Should have no violations - utils.go is already committed.
+	want := []validator.Violation{{
+		StagedFile:    "go.mod",
+		StagedSymbol:  "example.com/testproject",
+		StagedKind:    "",
+		MissingFile:   "go.sum",
+		MissingSymbol: "go.sum",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "has dependency changes whose go.sum checksums are not staged",
+		Line:          0,
+		Column:        0,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
+	}}
+
+	for i := range violations {
+		violations[i].Fingerprint = ""
+	}
+
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("Expected violations %+v, got %+v", want, violations)
+	}
+
+	stageFiles(t, repoDir, "go.sum")
+
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
 	if len(violations) != 0 {
-		t.Errorf("Expected no violations when depending on committed file, got %d: %+v", len(violations), violations)
+		t.Errorf("Expected no violations once go.sum is staged, got %+v", violations)
 	}
 }
 
-func TestValidateAtomicCommit_Subpackage_MainToHelper(t *testing.T) {
+func TestValidateAtomicCommit_GoSumStagedWithoutGoMod(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Main Package -> Helper Subpackage Violation",
-		"processor.go (main) -> helper/validator.go (ValidatePositive, ValidateLength)",
-		"Modified [processor.go, helper/validator.go] | Staged [processor.go] | Unstaged [helper/validator.go]",
-		"Violation detected - main package file depends on unstaged helper subpackage")
+		"go.sum Staged Without go.mod",
+		"go.sum records the checksums of example.com/dep, which only the working tree go.mod requires",
+		"Modified [go.mod, go.sum] | Staged [go.sum] | Unstaged [go.mod]",
+		"No violation while go.mod only gains a comment; violation once it requires example.com/dep")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify processor.go and helper/validator.go.
-	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
-
-	// Stage only processor.go.
-	stageFiles(t, repoDir, "processor.go")
+	modifyFile(t, filepath.Join(repoDir, "go.mod"), testComment)
+	writeFileContent(t, filepath.Join(repoDir, "go.sum"), "example.com/dep v1.0.0/go.mod h1:abc=\n")
+	stageFiles(t, repoDir, "go.sum")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations for main->helper dependency, got none")
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations without go.mod dependency changes, got %+v", violations)
 	}
 
-	// Should have violation from processor.go to helper/validator.go.
-	found := false
-
-	for _, v := range violations {
-		if v.StagedFile == fileProcessorGo && v.MissingFile == fileHelperValidGo {
-			found = true
+	modifyFile(t, filepath.Join(repoDir, "go.mod"), "\nrequire example.com/dep v1.0.0\n")
 
-			break
-		}
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if !found {
-		t.Errorf("Expected violation from processor.go to helper/validator.go, violations: %+v", violations)
+	if len(violations) != 1 || violations[0].StagedFile != "go.sum" || violations[0].MissingFile != "go.mod" ||
+		violations[0].Reason != "has go.sum checksums for go.mod dependency changes that are not staged" {
+		t.Errorf("Expected go.sum to need go.mod, got %+v", violations)
 	}
 }
 
-func TestValidateAtomicCommit_Subpackage_MainToModels(t *testing.T) {
+func TestValidateAtomicCommit_PairedFiles(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Main Package -> Models Subpackage Violation",
-		"processor.go (main) -> models/request.go (Request type)",
-		"Modified [processor.go, models/request.go] | Staged [processor.go] | Unstaged [models/request.go]",
-		"Violation detected - main package file depends on unstaged models subpackage")
+		"Paired Files",
+		"helper/api.pb.go is generated from helper/api.proto, paired by \"*.proto -> *.pb.go\"",
+		"Untracked [helper/api.proto, helper/api.pb.go] | Staged [helper/api.proto] | Unstaged [helper/api.pb.go]",
+		"Violation requiring api.pb.go; once staged, violation requiring the unstaged api.proto changes")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify processor.go and models/request.go.
-	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
+	rule, err := validator.ParsePairRule("*.proto -> *.pb.go")
+	if err != nil {
+		t.Fatalf("ParsePairRule failed: %v", err)
+	}
 
-	// Stage only processor.go.
-	stageFiles(t, repoDir, "processor.go")
+	writeFileContent(t, filepath.Join(repoDir, "helper", "api.proto"), "syntax = \"proto3\";\n")
+	writeFileContent(t, filepath.Join(repoDir, "helper", "api.pb.go"), "package helper\n\ntype API struct{}\n")
+	stageFiles(t, repoDir, "helper/api.proto")
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithPairedFiles(rule))
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations for main->models dependency, got none")
+	if len(violations) != 1 || violations[0].StagedFile != "helper/api.proto" ||
+		violations[0].MissingFile != "helper/api.pb.go" {
+		t.Errorf("Expected api.proto to need api.pb.go, got %+v", violations)
 	}
 
-	// Should have violation from processor.go to models/request.go.
-	found := false
+	stageFiles(t, repoDir, "helper/api.pb.go")
+	modifyFile(t, filepath.Join(repoDir, "helper", "api.proto"), "message API {}\n")
 
-	for _, v := range violations {
-		if v.StagedFile == fileProcessorGo && v.MissingFile == "models/request.go" {
-			found = true
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithPairedFiles(rule))
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
 
-			break
-		}
+	if len(violations) != 1 || violations[0].StagedFile != "helper/api.pb.go" ||
+		violations[0].MissingFile != "helper/api.proto" {
+		t.Errorf("Expected api.pb.go to need the api.proto changes, got %+v", violations)
 	}
 
-	if !found {
-		t.Errorf("Expected violation from processor.go to models/request.go, violations: %+v", violations)
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations without rules, got %+v", violations)
 	}
 }
 
-func TestValidateAtomicCommit_Subpackage_CrossPackage(t *testing.T) {
+func TestValidateAtomicCommit_BuildTags(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Cross-Subpackage Dependency Violation",
-		"models/response.go -> helper/formatter.go (FormatMessage func)",
-		"Modified [models/response.go, helper/formatter.go] | Staged [models/response.go] | Unstaged [helper/formatter.go]",
-		"Violation detected - models subpackage depends on unstaged helper subpackage")
+		"Build-Tagged File",
+		"helper/integration.go (//go:build integration) -> helper/extra.go (Extra func)",
+		"Untracked [helper/integration.go, helper/extra.go] | Staged [helper/integration.go] | Unstaged [helper/extra.go]",
+		"No violation without tags, the file being ignored; violation with the integration tag")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify models/response.go and helper/formatter.go.
-	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
-
-	// Stage only models/response.go.
-	stageFiles(t, repoDir, "models/response.go")
+	writeFileContent(t, filepath.Join(repoDir, "helper", "extra.go"),
+		"package helper\n\nfunc Extra() string { return \"extra\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "helper", "integration.go"),
+		"//go:build integration\n\npackage helper\n\nfunc Integration() string { return Extra() }\n")
+	stageFiles(t, repoDir, "helper/integration.go")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations for models->helper dependency, got none")
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations without the integration tag, got %+v", violations)
 	}
 
-	// Should have violation from models/response.go to helper/formatter.go.
-	found := false
-
-	for _, v := range violations {
-		if v.StagedFile == fileModelsResponse && v.MissingFile == fileHelperFmtGo {
-			found = true
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithBuildTags("integration"))
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) != 1 || violations[0].StagedFile != "helper/integration.go" ||
+		violations[0].MissingFile != "helper/extra.go" {
+		t.Errorf("Expected integration.go to need extra.go, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_Platforms(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Platform-Specific Files",
+		"helper/sys_linux.go, helper/sys_windows.go (Sys func) -> helper/extra.go (Extra func)",
+		"Untracked [helper/sys_*.go, helper/extra.go] | Staged [helper/sys_*.go] | Unstaged [helper/extra.go]",
+		"Violation of sys_linux.go for linux/amd64; of both files for linux/amd64 and windows/amd64")
+
+	repoDir := setupTestRepo(t)
+
+	writeFileContent(t, filepath.Join(repoDir, "helper", "extra.go"),
+		"package helper\n\nfunc Extra() string { return \"extra\" }\n")
+
+	for _, goos := range []string{"linux", "windows"} {
+		writeFileContent(t, filepath.Join(repoDir, "helper", "sys_"+goos+".go"),
+			"package helper\n\nfunc Sys() string { return Extra() }\n")
+	}
+
+	stageFiles(t, repoDir, "helper/sys_linux.go", "helper/sys_windows.go")
+
+	linux := validator.Platform{GOOS: "linux", GOARCH: "amd64"}
+	windows := validator.Platform{GOOS: "windows", GOARCH: "amd64"}
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithPlatforms(linux))
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) != 1 || violations[0].StagedFile != "helper/sys_linux.go" {
+		t.Errorf("Expected a violation of sys_linux.go alone, got %+v", violations)
+	}
+
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithPlatforms(linux, windows))
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	var staged []string
+	for _, v := range violations {
+		staged = append(staged, v.StagedFile)
+	}
+
+	if want := []string{"helper/sys_linux.go", "helper/sys_windows.go"}; !slices.Equal(staged, want) {
+		t.Errorf("Expected violations of %v, got %+v", want, violations)
+	}
+}
+
+func TestValidateAtomicCommit_NestedModule(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Nested Module",
+		"tools/run.go (Run func) -> tools/util.go (Util func), in module example.com/tools without go.work",
+		"Untracked [tools/run.go, tools/util.go] | Staged [tools/run.go] | Unstaged [tools/util.go]",
+		"Violation requiring tools/util.go, from the checkout and from the index")
+
+	repoDir := setupTestRepo(t)
+
+	err := os.Mkdir(filepath.Join(repoDir, "tools"), 0o750)
+	if err != nil {
+		t.Fatalf("Failed to create tools dir: %v", err)
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, "tools", "go.mod"), "module example.com/tools\n\ngo 1.24\n")
+	stageFiles(t, repoDir, "tools/go.mod")
+	runGit(t, repoDir, "commit", "-m", "Add tools module")
+
+	writeFileContent(t, filepath.Join(repoDir, "tools", "util.go"),
+		"package tools\n\nfunc Util() string { return \"util\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "tools", "run.go"),
+		"package tools\n\nfunc Run() string { return Util() }\n")
+	stageFiles(t, repoDir, "tools/run.go")
+
+	for _, opts := range [][]validator.Option{nil, {validator.WithIndexSnapshot()}} {
+		violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, opts...)
+		if err != nil {
+			t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		}
+
+		if len(violations) != 1 || violations[0].StagedFile != "tools/run.go" ||
+			violations[0].MissingFile != "tools/util.go" {
+			t.Errorf("Expected tools/run.go to need tools/util.go, got %+v", violations)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_WithoutModule(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Repository Without go.mod",
+		"scripts/run.go (Run func) -> scripts/util.go (Util func), loaded in GOPATH mode",
+		"Untracked [scripts/run.go, scripts/util.go] | Staged [scripts/run.go] | Unstaged [scripts/util.go]",
+		"Violation requiring scripts/util.go, from the checkout and from the index")
+
+	repoDir := setupTestRepo(t)
+
+	runGit(t, repoDir, "rm", "-q", "go.mod")
+	runGit(t, repoDir, "commit", "-m", "Drop go.mod")
+
+	err := os.Mkdir(filepath.Join(repoDir, "scripts"), 0o750)
+	if err != nil {
+		t.Fatalf("Failed to create scripts dir: %v", err)
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, "scripts", "util.go"),
+		"package scripts\n\nfunc Util() string { return \"util\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "scripts", "run.go"),
+		"package scripts\n\nfunc Run() string { return Util() }\n")
+	stageFiles(t, repoDir, "scripts/run.go")
+
+	for _, opts := range [][]validator.Option{nil, {validator.WithIndexSnapshot()}} {
+		violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, opts...)
+		if err != nil {
+			t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		}
+
+		if len(violations) != 1 || violations[0].StagedFile != "scripts/run.go" ||
+			violations[0].MissingFile != "scripts/util.go" {
+			t.Errorf("Expected scripts/run.go to need scripts/util.go, got %+v", violations)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_SpecificSymbol_Method(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Method Symbol Dependency",
+		"calculator_user.go (UseCalculator func) -> calculator.go (Calculator type + Add method)",
+		"Modified [calculator_user.go, calculator.go] | Staged [calculator_user.go] | Unstaged [calculator.go]",
+		"Violation tracking method: UseCalculator -> Calculator.Add or Calculator type")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify calculator_user.go (uses Calculator.Add from calculator.go) and calculator.go.
+	modifyFile(t, filepath.Join(repoDir, "calculator_user.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "calculator.go"), testComment)
+
+	// Stage only calculator_user.go.
+	stageFiles(t, repoDir, "calculator_user.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations for method dependency, got none")
+	}
+
+	// Verify method or type symbol is tracked (could be Calculator type or Add method).
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == "calculator_user.go" && v.MissingFile == "calculator.go" {
+			// Accept either Calculator type or Calculator.Add method.
+			if v.MissingSymbol == "example.com/testproject.Calculator" ||
+				v.MissingSymbol == "example.com/testproject.Calculator.Add" {
+				found = true
+
+				break
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected violation tracking Calculator type or Add method, violations: %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_MultipleSymbols_PartialViolation(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partial Violation (Multiple Symbols in One File)",
+		"consumer.go has 2 funcs: ConsumeConstant -> MaxRetries (OK), ConsumeVariable -> DefaultTimeout (VIOLATION)",
+		"Modified [consumer.go, constants.go, variables.go] | Staged [consumer.go, constants.go] | Unstaged [variables.go]",
+		"Only ConsumeVariable violates (depends on unstaged DefaultTimeout), ConsumeConstant is fine")
+
+	repoDir := setupTestRepo(t)
+
+	// Consumer.go has two functions: ConsumeConstant and ConsumeVariable
+	// Modify consumer.go, constants.go, but not variables.go.
+	modifyFile(t, filepath.Join(repoDir, "consumer.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "variables.go"), testComment)
+
+	// Stage consumer.go and constants.go (ConsumeConstant satisfied, ConsumeVariable not).
+	stageFiles(t, repoDir, "consumer.go", "constants.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations, got none")
+	}
+
+	// Should have violation for ConsumeVariable -> DefaultTimeout.
+	foundVariable := false
+	foundConstant := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileConsumerGo {
+			if v.MissingSymbol == "example.com/testproject.DefaultTimeout" {
+				foundVariable = true
+			}
+
+			if v.MissingSymbol == "example.com/testproject.MaxRetries" {
+				foundConstant = true
+			}
+		}
+	}
+
+	if !foundVariable {
+		t.Error("Expected violation for ConsumeVariable depending on DefaultTimeout")
+	}
+
+	if foundConstant {
+		t.Error("Should NOT have violation for ConsumeConstant since constants.go is staged")
+	}
+}
+
+func TestValidateAtomicCommit_DependencyOnCommittedFile(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Dependency on Already-Committed File (No Violation)",
+		"main.go (main func) -> utils.go (Helper func - ALREADY COMMITTED)",
+		"Modified [main.go] | Staged [main.go] | Unmodified [utils.go - already committed]",
+		"No violation - utils.go is already in the repository")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify and stage only main.go (which depends on utils.go)
+	// utils.go remains unmodified from the initial commit.
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Comment in main\n")
+	stageFiles(t, repoDir, "main.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	// Should have no violations - utils.go is already committed.
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations when depending on committed file, got %d: %+v", len(violations), violations)
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_MainToHelper(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Main Package -> Helper Subpackage Violation",
+		"processor.go (main) -> helper/validator.go (ValidatePositive, ValidateLength)",
+		"Modified [processor.go, helper/validator.go] | Staged [processor.go] | Unstaged [helper/validator.go]",
+		"Violation detected - main package file depends on unstaged helper subpackage")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify processor.go and helper/validator.go.
+	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
+
+	// Stage only processor.go.
+	stageFiles(t, repoDir, "processor.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations for main->helper dependency, got none")
+	}
+
+	// Should have violation from processor.go to helper/validator.go.
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileProcessorGo && v.MissingFile == fileHelperValidGo {
+			found = true
 
 			break
 		}
 	}
 
 	if !found {
-		t.Errorf("Expected violation from models/response.go to helper/formatter.go, violations: %+v", violations)
+		t.Errorf("Expected violation from processor.go to helper/validator.go, violations: %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_MainToModels(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Main Package -> Models Subpackage Violation",
+		"processor.go (main) -> models/request.go (Request type)",
+		"Modified [processor.go, models/request.go] | Staged [processor.go] | Unstaged [models/request.go]",
+		"Violation detected - main package file depends on unstaged models subpackage")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify processor.go and models/request.go.
+	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
+
+	// Stage only processor.go.
+	stageFiles(t, repoDir, "processor.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations for main->models dependency, got none")
+	}
+
+	// Should have violation from processor.go to models/request.go.
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileProcessorGo && v.MissingFile == "models/request.go" {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected violation from processor.go to models/request.go, violations: %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_CrossPackage(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Cross-Subpackage Dependency Violation",
+		"models/response.go -> helper/formatter.go (FormatMessage func)",
+		"Modified [models/response.go, helper/formatter.go] | Staged [models/response.go] | Unstaged [helper/formatter.go]",
+		"Violation detected - models subpackage depends on unstaged helper subpackage")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify models/response.go and helper/formatter.go.
+	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+
+	// Stage only models/response.go.
+	stageFiles(t, repoDir, "models/response.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations for models->helper dependency, got none")
+	}
+
+	// Should have violation from models/response.go to helper/formatter.go.
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileModelsResponse && v.MissingFile == fileHelperFmtGo {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected violation from models/response.go to helper/formatter.go, violations: %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_TransitiveAcrossPackages(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Transitive Cross-Package Dependency Violation",
+		"processor.go (main) -> models/response.go -> helper/formatter.go (transitive chain)",
+		"Modified [processor.go, models/response.go, helper/formatter.go] | "+
+			"Staged [processor.go, models/response.go] | Unstaged [helper/formatter.go]",
+		"Violations detected - both direct and transitive dependencies on unstaged helper/formatter.go")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify processor.go, models/response.go, and helper/formatter.go.
+	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+
+	// Stage processor.go and models/response.go, but not helper/formatter.go.
+	stageFiles(t, repoDir, "processor.go", "models/response.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations for transitive cross-package dependency, got none")
+	}
+
+	// Should have violations involving helper/formatter.go.
+	hasProcessorToFormatter := false
+	hasResponseToFormatter := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileProcessorGo && v.MissingFile == fileHelperFmtGo {
+			hasProcessorToFormatter = true
+		}
+
+		if v.StagedFile == fileModelsResponse && v.MissingFile == fileHelperFmtGo {
+			hasResponseToFormatter = true
+		}
+	}
+
+	if !hasResponseToFormatter {
+		t.Error("Expected direct violation from models/response.go to helper/formatter.go")
+	}
+
+	if !hasProcessorToFormatter {
+		t.Error("Expected transitive violation from processor.go to helper/formatter.go")
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_CompleteAtomicCommit(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Complete Cross-Package Atomic Commit (No Violations)",
+		"processor.go -> models/response.go -> helper/formatter.go (complete chain)",
+		"Modified [processor.go, models/response.go, helper/formatter.go, "+
+			"models/request.go, helper/validator.go] | Staged [ALL]",
+		"No violations - all cross-package dependencies are staged together")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify all related files.
+	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
+
+	// Stage all files together.
+	stageFiles(t, repoDir, "processor.go", "models/response.go", "models/request.go",
+		"helper/formatter.go", "helper/validator.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations when all cross-package dependencies are staged, got %d: %+v",
+			len(violations), violations)
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_MultipleMainToSubpackages(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Main -> Multiple Subpackages Violation",
+		"processor.go -> helper/validator.go AND processor.go -> models/request.go (multiple dependencies)",
+		"Modified [processor.go, helper/validator.go, models/request.go] | "+
+			"Staged [processor.go] | Unstaged [helper/validator.go, models/request.go]",
+		"Multiple violations - main file depends on multiple unstaged subpackage files")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify processor.go and both subpackage files.
+	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
+
+	// Stage only processor.go.
+	stageFiles(t, repoDir, "processor.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected multiple violations, got none")
+	}
+
+	// Should have violations to both subpackage files.
+	hasHelperViolation := false
+	hasModelsViolation := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileProcessorGo && v.MissingFile == fileHelperValidGo {
+			hasHelperViolation = true
+		}
+
+		if v.StagedFile == fileProcessorGo && v.MissingFile == "models/request.go" {
+			hasModelsViolation = true
+		}
+	}
+
+	if !hasHelperViolation {
+		t.Error("Expected violation from processor.go to helper/validator.go")
+	}
+
+	if !hasModelsViolation {
+		t.Error("Expected violation from processor.go to models/request.go")
+	}
+}
+
+// writeFileContent overwrites a file with the given content.
+func writeFileContent(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600) //nolint:gosec // Test helper writes to temp dir.
+	if err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestValidateAtomicCommit_PartialStaging_NoFalsePositive(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partial Staging - No False Positive (MM file)",
+		"main.go (UsePartialFunc) -> service.go (PartialFunc - staged has it, working tree removes it)",
+		"Modified [main.go (staged), service.go (MM)] | Staged version of service.go has PartialFunc",
+		"No violation - overlay ensures staged version of service.go is analyzed")
+
+	repoDir := setupTestRepo(t)
+
+	// Step 1: Add PartialFunc to service.go and commit it.
+	modifyFile(t, filepath.Join(repoDir, "service.go"), "\nfunc PartialFunc() string { return \"partial\" }\n")
+	stageFiles(t, repoDir, "service.go")
+	runGit(t, repoDir, "commit", "-m", "Add PartialFunc")
+
+	// Step 2: Modify main.go to call PartialFunc and stage it.
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\nfunc UsePartialFunc() { _ = PartialFunc() }\n")
+	stageFiles(t, repoDir, "main.go")
+
+	// Step 3: Also modify service.go (stage the change that keeps PartialFunc).
+	modifyFile(t, filepath.Join(repoDir, "service.go"), "\n// Another comment\n")
+	stageFiles(t, repoDir, "service.go")
+
+	// Step 4: Now remove PartialFunc in the working tree (creating MM state).
+	// Read current content and rewrite without PartialFunc.
+	data, err := os.ReadFile(filepath.Join(repoDir, "service.go")) //nolint:gosec // Test reads temp file.
+	if err != nil {
+		t.Fatalf("Failed to read service.go: %v", err)
+	}
+
+	// Remove the PartialFunc line from working tree.
+	modified := strings.ReplaceAll(string(data), "func PartialFunc() string { return \"partial\" }\n", "")
+	writeFileContent(t, filepath.Join(repoDir, "service.go"), modified)
+
+	// Now service.go is MM: staged version has PartialFunc, working tree does not.
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	// Should have NO violations because the staged version of service.go still has PartialFunc.
+	for _, v := range violations {
+		if v.MissingSymbol == "example.com/testproject.PartialFunc" {
+			t.Errorf("Got false positive violation for PartialFunc (should use staged version): %+v", v)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_PartialStaging_StagedSymbolPresent(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partial Staging - Symbol Present in Both Versions",
+		"main.go (UseHelper) -> utils.go (Helper - present in both staged and working tree)",
+		"Modified [main.go (staged), utils.go (MM)] | Both versions of utils.go have Helper",
+		"No violation - symbol exists in staged version")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify main.go and stage it.
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Comment for staging\n")
+	stageFiles(t, repoDir, "main.go")
+
+	// Modify utils.go and stage it (creates staged change).
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Staged comment\n")
+	stageFiles(t, repoDir, "utils.go")
+
+	// Modify utils.go again without staging (creates MM state).
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Unstaged comment\n")
+
+	// Now utils.go is MM but Helper is present in both versions.
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	// Should have no violations - utils.go is staged and Helper exists.
+	for _, v := range violations {
+		if v.StagedFile == fileMainGo && v.MissingFile == fileUtilsGo {
+			t.Errorf("Unexpected violation for utils.go which is staged: %+v", v)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_UnstagedOnly_StillFlagsViolation(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Unstaged Only - Still Flags Violation",
+		"main.go (staged) -> utils.go (unstaged only, space-M status)",
+		"Modified [main.go (staged), utils.go (unstaged only)] | utils.go has only working tree changes",
+		"Violation detected - overlay logic does not suppress legitimate violations")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify main.go and stage it.
+	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
+	stageFiles(t, repoDir, "main.go")
+
+	// Modify utils.go but do NOT stage it (creates ` M` status, not `MM`).
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Unstaged change\n")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	// Should have violations - utils.go has unstaged changes and is not staged.
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileMainGo && v.MissingFile == fileUtilsGo {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected violation from main.go to utils.go for unstaged-only file, violations: %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_Subpackage_PartialStaging(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partial Subpackage Staging (Within Same Package)",
+		"models/response.go -> helper/formatter.go (cross-package dep)",
+		"Modified [models/response.go, models/request.go, helper/formatter.go] | "+
+			"Staged [models/response.go, models/request.go]",
+		"Violation detected - models package files staged but missing helper dependency")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify both models files and helper/formatter.go.
+	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+
+	// Stage both models files but not helper.
+	stageFiles(t, repoDir, "models/response.go", "models/request.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violations, got none")
+	}
+
+	// Should have violation from models/response.go to helper/formatter.go.
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileModelsResponse && v.MissingFile == fileHelperFmtGo {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected violation from models/response.go to helper/formatter.go, violations: %+v", violations)
+	}
+}
+
+// ===========================.
+// FindCommittableFiles Tests.
+// ===========================.
+
+func TestFindCommittableFiles_IndependentFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Independent Files",
+		"alpha.go and beta.go (both independent, no dependencies)",
+		"Modified [alpha.go, beta.go] | Unstaged [alpha.go, beta.go]",
+		"Should return alpha.go (first lexicographically)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify both alpha.go and beta.go (both are independent).
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	}
+
+	if files[0] != "alpha.go" {
+		t.Errorf("Expected alpha.go, got %s", files[0])
+	}
+}
+
+func TestFindCommittableFiles_WithDependency(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Dependency Chain",
+		"main.go -> utils.go (main depends on utils)",
+		"Modified [main.go, utils.go] | Unstaged [main.go, utils.go]",
+		"Should return utils.go (independent), main.go is not independent")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify main.go (depends on utils.go) and utils.go.
+	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	}
+
+	if files[0] != "utils.go" {
+		t.Errorf("Expected utils.go (independent), got %s", files[0])
+	}
+
+	// Commit utils.go and check again - now main.go should be committable.
+	stageFiles(t, repoDir, "utils.go")
+	runGit(t, repoDir, "commit", "-m", "update utils")
+
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed after committing utils: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file after utils committed, got %d: %v", len(files), files)
+	}
+
+	if files[0] != "main.go" {
+		t.Errorf("Expected main.go after utils committed, got %s", files[0])
+	}
+}
+
+func TestFindCommittableFiles_CircularDependency(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Circular Dependency",
+		"circular_a.go <-> circular_b.go (circular dependency)",
+		"Modified [circular_a.go, circular_b.go] | Unstaged [circular_a.go, circular_b.go]",
+		"Should return empty (neither file is independent)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify both circular files.
+	modifyFile(t, filepath.Join(repoDir, "circular_a.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "circular_b.go"), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected no files for circular dependency, got %d: %v", len(files), files)
+	}
+}
+
+func TestFindCommittableFiles_UntrackedFile(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Untracked File",
+		"newfile.go (untracked, no dependencies)",
+		"Untracked [newfile.go]",
+		"Should return newfile.go (independent)")
+
+	repoDir := setupTestRepo(t)
+
+	// Create a new untracked file with no dependencies.
+	createUntrackedFile(t, repoDir, "newfile.go", `package main
+
+// IndependentFunc is a new independent function.
+func IndependentFunc() string {
+	return "independent"
+}
+`)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	}
+
+	if files[0] != "newfile.go" {
+		t.Errorf("Expected newfile.go, got %s", files[0])
+	}
+}
+
+func TestFindCommittableFiles_NoModifications(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - No Modifications",
+		"N/A - clean working directory",
+		"Modified [] | Unstaged []",
+		"Should return empty (no files to commit)")
+
+	repoDir := setupTestRepo(t)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected no files for clean repo, got %d: %v", len(files), files)
+	}
+}
+
+func TestFindCommittableFiles_ExcludesStagedFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Excludes Staged Files",
+		"alpha.go (staged), beta.go (unstaged)",
+		"Modified [alpha.go, beta.go] | Staged [alpha.go] | Unstaged [beta.go]",
+		"Should return beta.go only (exclude already-staged files)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify and stage alpha.go.
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	stageFiles(t, repoDir, "alpha.go")
+
+	// Modify beta.go but don't stage it.
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	}
+
+	if files[0] != "beta.go" {
+		t.Errorf("Expected beta.go (unstaged), got %s", files[0])
+	}
+}
+
+func TestFindCommittableFiles_ExcludesPartiallyStaged(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Excludes Partially Staged (MM)",
+		"main.go (MM status)",
+		"Modified [main.go] | Staged [main.go (partial)] | Unstaged [main.go (partial)]",
+		"Should NOT return main.go (let standard mode handle MM files)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify and stage main.go.
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Staged comment\n")
+	stageFiles(t, repoDir, "main.go")
+
+	// Modify main.go again without staging (creates MM state).
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Unstaged comment\n")
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed: %v", err)
+	}
+
+	// Should return empty - MM files are excluded from candidates.
+	if len(files) != 0 {
+		t.Errorf("Expected no files for MM status, got %d: %v", len(files), files)
+	}
+}
+
+func TestFindCommittableFiles_ProgressiveCommit(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableFiles - Progressive Commit Workflow",
+		"alpha.go, beta.go, gamma.go (all independent)",
+		"Modified [alpha.go, beta.go, gamma.go] | Unstaged [all]",
+		"Progressive: alpha -> beta -> gamma (lexicographic order)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify all three independent files.
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+
+	// First call should return alpha.go.
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed (1st call): %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "alpha.go" {
+		t.Errorf("Expected alpha.go on first call, got %v", files)
+	}
+
+	// Commit alpha.go.
+	stageFiles(t, repoDir, "alpha.go")
+	runGit(t, repoDir, "commit", "-m", "update alpha")
+
+	// Second call should return beta.go.
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed (2nd call): %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "beta.go" {
+		t.Errorf("Expected beta.go on second call, got %v", files)
+	}
+
+	// Commit beta.go.
+	stageFiles(t, repoDir, "beta.go")
+	runGit(t, repoDir, "commit", "-m", "update beta")
+
+	// Third call should return gamma.go.
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed (3rd call): %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "gamma.go" {
+		t.Errorf("Expected gamma.go on third call, got %v", files)
+	}
+
+	// Commit gamma.go.
+	stageFiles(t, repoDir, "gamma.go")
+	runGit(t, repoDir, "commit", "-m", "update gamma")
+
+	// Fourth call should return empty (all committed).
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableFiles failed (4th call): %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected no files after all committed, got %v", files)
+	}
+}
+
+// TestFindCommittableSet_WithDependants_SingleDependant tests the --dependants flag
+// with a base file and one dependant.
+func TestFindCommittableSet_WithDependants_SingleDependant(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - With Dependants - Single Dependant",
+		"types.go (independent) <- service.go (depends on types.go)",
+		"Modified [types.go, service.go] | Unstaged [types.go, service.go]",
+		"Should return [types.go, service.go] when --dependants is used")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify types.go (independent) and service.go (depends on types.go).
+	modifyFile(t, filepath.Join(repoDir, "types.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "service.go"), testComment)
+
+	// Without dependants flag - should return only types.go.
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "types.go" {
+		t.Fatalf("Without dependants: expected [types.go], got %v", files)
+	}
+
+	// With dependants flag - should return types.go + service.go.
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, true)
+	if err != nil {
+		t.Fatalf("FindCommittableSet with dependants failed: %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("With dependants: expected 2 files, got %d: %v", len(files), files)
+	}
+
+	// Result should include both types.go (base) and service.go (dependant).
+	// The base file comes first, then dependants in sorted order.
+	expectedFiles := map[string]bool{"types.go": true, "service.go": true}
+	for _, f := range files {
+		if !expectedFiles[f] {
+			t.Errorf("Unexpected file in result: %s", f)
+		}
+	}
+}
+
+// TestFindCommittableSet_WithDependants_MultipleDependants tests the --dependants flag
+// with a base file and multiple dependants.
+func TestFindCommittableSet_WithDependants_MultipleDependants(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - With Dependants - Multiple Dependants",
+		"utils.go (independent) <- service.go (depends on utils.go) and main.go (depends on utils.go)",
+		"Modified [utils.go, service.go, main.go] | Unstaged [utils.go, service.go, main.go]",
+		"Should return [main.go, service.go, utils.go] when --dependants is used")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify utils.go (independent), service.go (depends on utils.go), and main.go (depends on utils.go).
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "service.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
+
+	// With dependants flag - should return utils.go + its dependants.
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	if err != nil {
+		t.Fatalf("FindCommittableSet with dependants failed: %v", err)
+	}
+
+	// Should include utils.go, service.go (depends only on utils.go), and main.go (depends on utils.go + service.go).
+	// Note: main.go depends on both utils.go AND service.go, so it should NOT be included.
+	if len(files) < 2 {
+		t.Fatalf("With dependants: expected at least 2 files, got %d: %v", len(files), files)
+	}
+
+	// Verify utils.go is in the result (base file).
+	if !slices.Contains(files, "utils.go") {
+		t.Errorf("With dependants: expected utils.go in results, got %v", files)
+	}
+}
+
+// TestFindCommittableSet_WithDependants_ExcludeTransitiveDependants tests that
+// transitive dependants (dependants of dependants) are excluded.
+func TestFindCommittableSet_WithDependants_ExcludeTransitiveDependants(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - With Dependants - Exclude Transitive",
+		"types.go <- service.go <- main.go (chain)",
+		"Modified [types.go, service.go, main.go] | Unstaged [types.go, service.go, main.go]",
+		"Should return [types.go, service.go] excluding main.go (transitive)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify types.go, service.go (depends on types.go), and main.go (depends on service.go).
+	modifyFile(t, filepath.Join(repoDir, "types.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "service.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
+
+	// With dependants flag.
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	if err != nil {
+		t.Fatalf("FindCommittableSet with dependants failed: %v", err)
+	}
+
+	// Main.go depends on service.go (which is in changeset), so it should be excluded.
+	if slices.Contains(files, "main.go") {
+		t.Errorf("Should NOT include main.go (depends on service.go in changeset), got %v", files)
+	}
+}
+
+// TestFindCommittableSet_WithDependants_CircularDependency tests behavior with circular deps.
+func TestFindCommittableSet_WithDependants_CircularDependency(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - With Dependants - Circular Dependency",
+		"circular_a.go <-> circular_b.go (circular)",
+		"Modified [circular_a.go, circular_b.go] | Unstaged [circular_a.go, circular_b.go]",
+		"Should return empty (no independent file due to circular dependency)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify both files with circular dependency.
+	modifyFile(t, filepath.Join(repoDir, "circular_a.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "circular_b.go"), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected empty result for circular dependency, got %v", files)
+	}
+}
+
+func TestFindCommittableSet_WithTests(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Committable Set - With Tests",
+		"alpha_test.go -> alpha.go; beta_test.go -> beta.go (committed); beta.go -> alpha.go",
+		"Modified [alpha.go, beta.go] | Untracked [alpha_test.go, beta_test.go]",
+		"alpha.go alone by default; with tests alpha_test.go joins, beta_test.go does not")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	createUntrackedFile(t, repoDir, "alpha_test.go",
+		"package main\n\nimport \"testing\"\n\nfunc TestAlpha(t *testing.T) {\n\t_ = AlphaFunc()\n}\n")
+	createUntrackedFile(t, repoDir, "beta_test.go",
+		"package main\n\nimport \"testing\"\n\nfunc TestBeta(t *testing.T) {\n\t_ = BetaFunc()\n}\n")
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if want := []string{"alpha.go"}; !slices.Equal(files, want) {
+		t.Errorf("Expected %v by default, got %v", want, files)
+	}
+
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false, validator.WithTests())
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if want := []string{"alpha.go", "alpha_test.go"}; !slices.Equal(files, want) {
+		t.Errorf("Expected %v with tests, got %v", want, files)
+	}
+}
+
+func TestFindIndependentFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Independent Files",
+		"gamma.go -> beta.go -> alpha.go; helper/formatter.go unrelated",
+		"Modified [alpha.go, beta.go, gamma.go, helper/formatter.go] | Staged []",
+		"Every independent file: alpha.go and helper/formatter.go")
+
+	repoDir := setupTestRepo(t)
+
+	for _, file := range []string{"alpha.go", "beta.go", "gamma.go", fileHelperFmtGo} {
+		modifyFile(t, filepath.Join(repoDir, file), testComment)
+	}
+
+	files, err := validator.FindIndependentFiles(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("FindIndependentFiles failed: %v", err)
+	}
+
+	if want := []string{"alpha.go", fileHelperFmtGo}; !slices.Equal(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+}
+
+func TestFindCommittableSet_Clustering(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Committable Set - Clustering",
+		"gamma.go -> beta.go -> alpha.go; helper/formatter.go unrelated",
+		"Modified [alpha.go, beta.go, gamma.go, helper/formatter.go] | Staged []",
+		"Dependants only add beta.go; clustering adds the whole chain but not the unrelated helper")
+
+	repoDir := setupTestRepo(t)
+
+	for _, file := range []string{"alpha.go", "beta.go", "gamma.go", fileHelperFmtGo} {
+		modifyFile(t, filepath.Join(repoDir, file), testComment)
+	}
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if want := []string{"alpha.go", "beta.go"}; !slices.Equal(files, want) {
+		t.Errorf("Expected %v with dependants, got %v", want, files)
+	}
+
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false, validator.WithClustering())
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if want := []string{"alpha.go", "beta.go", "gamma.go"}; !slices.Equal(files, want) {
+		t.Errorf("Expected %v with clustering, got %v", want, files)
+	}
+
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false,
+		validator.WithClustering(), validator.WithMaxFiles(2))
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if want := []string{"alpha.go", "beta.go"}; !slices.Equal(files, want) {
+		t.Errorf("Expected %v with at most 2 files, got %v", want, files)
+	}
+}
+
+// TestFindCommittableSet_WithDependants_NoDependants tests a file with no dependants.
+func TestFindCommittableSet_WithDependants_NoDependants(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - With Dependants - No Dependants",
+		"alpha.go (independent, no dependants)",
+		"Modified [alpha.go] | Unstaged [alpha.go]",
+		"Should return [alpha.go] only (no dependants exist)")
+
+	repoDir := setupTestRepo(t)
+
+	// Modify alpha.go (independent, no other files depend on it).
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	}
+
+	if files[0] != "alpha.go" {
+		t.Errorf("Expected alpha.go, got %s", files[0])
+	}
+}
+
+// TestValidateAtomicCommit_UntrackedFileDoesNotBreakAnalysis verifies that
+// untracked files referencing symbols absent from the staged codebase do not
+// cause compilation errors during analysis.
+func TestValidateAtomicCommit_UntrackedFileDoesNotBreakAnalysis(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Untracked File With Forward Reference",
+		"utils.go (staged+worktree, MM) | untracked_consumer.go (??, references WorkingTreeFunc)",
+		"Staged [utils.go comment] | WorkingTree [utils.go+WorkingTreeFunc] | Untracked [untracked_consumer.go]",
+		"No compilation error - untracked file is excluded from analysis")
+
+	repoDir := setupTestRepo(t)
+
+	// Stage utils.go with a comment.
+	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), testComment)
+	stageFiles(t, repoDir, fileUtilsGo)
+
+	// Add WorkingTreeFunc to utils.go working tree only (not staged, MM status).
+	wtFunc := "\n// WorkingTreeFunc is only in the working tree.\n" +
+		"func WorkingTreeFunc() string { return \"wt\" }\n"
+	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), wtFunc)
+
+	// Create an untracked file that calls WorkingTreeFunc (only in working tree of utils.go).
+	untrackedContent := "package main\n\n" +
+		"// UntrackedConsumer calls a working-tree-only function.\n" +
+		"func UntrackedConsumer() string { return WorkingTreeFunc() }\n"
+	createUntrackedFile(t, repoDir, "untracked_consumer.go", untrackedContent)
+
+	// Should not return a compilation error - untracked file is neutralised.
+	_, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed (expected no error): %v", err)
+	}
+}
+
+// TestValidateAtomicCommit_StructFieldTypeDependency verifies that a struct type
+// whose field type references an untracked subpackage is flagged as a violation.
+// This tests that type-level (non-function) symbol usages are tracked correctly.
+func TestValidateAtomicCommit_StructFieldTypeDependency(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Struct Field Type Dependency (type-level reference)",
+		"repo.go (staged, defines Repo struct with NewStore field typed as store.Writer) | store/writer.go (untracked)",
+		"Staged [repo.go] | Untracked [store/writer.go defines store.Writer interface]",
+		"Violation detected - Repo struct field type depends on untracked store.Writer")
+
+	repoDir := setupTestRepo(t)
+	storeDir := createUntrackedSubpackage(t, repoDir, "store")
+
+	writeFileContent(t, filepath.Join(storeDir, "writer.go"),
+		"package store\n\n// Writer is a write interface.\ntype Writer interface { Write(p []byte) (int, error) }\n")
+
+	// Create a staged file whose struct field type references store.Writer.
+	writeFileContent(t, filepath.Join(repoDir, "repo.go"),
+		"package main\n\nimport \"example.com/testproject/store\"\n\n"+
+			"// Repo holds repo operations.\ntype Repo struct {\n"+
+			"\tNewWriter func() store.Writer\n}\n")
+	stageFiles(t, repoDir, "repo.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violation for struct field type dependency on untracked store.Writer, got none")
+	}
+
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == "repo.go" && v.MissingFile == "store/writer.go" {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected violation from repo.go to store/writer.go, violations: %+v", violations)
+	}
+}
+
+// TestValidateAtomicCommit_VarTypeDependency verifies that a package-level var
+// whose type or initializer references an untracked subpackage is flagged.
+func TestValidateAtomicCommit_VarTypeDependency(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Var Type + Initializer Dependency",
+		"vars.go (staged, defines var with store.Writer type and store.New initializer) | store/writer.go (untracked)",
+		"Staged [vars.go] | Untracked [store/writer.go defines store.Writer and store.New]",
+		"Violation detected - var type and initializer depend on untracked store package")
+
+	repoDir := setupTestRepo(t)
+	storeDir := createUntrackedSubpackage(t, repoDir, "store")
+
+	writeFileContent(t, filepath.Join(storeDir, "writer.go"),
+		"package store\n\n"+
+			"// Writer is a write interface.\n"+
+			"type Writer interface { Write(p []byte) (int, error) }\n\n"+
+			"// New creates a new Writer.\n"+
+			"func New() Writer { return nil }\n")
+
+	// Create a staged file with a var that depends on store.Writer (type) and store.New (initializer).
+	writeFileContent(t, filepath.Join(repoDir, "vars.go"),
+		"package main\n\nimport \"example.com/testproject/store\"\n\n"+
+			"// DefaultWriter is the default writer.\n"+
+			"var DefaultWriter store.Writer = store.New()\n")
+	stageFiles(t, repoDir, "vars.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected violation for var type dependency on untracked store package, got none")
+	}
+
+	// Check both the type annotation (store.Writer) and initializer (store.New) are caught.
+	foundType := false
+	foundInit := false
+
+	for _, v := range violations {
+		if v.StagedFile == "vars.go" && v.MissingFile == "store/writer.go" {
+			if v.MissingSymbol == "example.com/testproject/store.Writer" {
+				foundType = true
+			}
+
+			if v.MissingSymbol == "example.com/testproject/store.New" {
+				foundInit = true
+			}
+		}
+	}
+
+	if !foundType {
+		t.Errorf("Expected violation for store.Writer type annotation, violations: %+v", violations)
+	}
+
+	if !foundInit {
+		t.Errorf("Expected violation for store.New initializer, violations: %+v", violations)
+	}
+}
+
+// TestValidateAtomicCommit_WorktreeOnlyFileDoesNotBreakAnalysis verifies that
+// files with working-tree-only changes that introduce invalid references do not
+// cause compilation errors during analysis.
+func TestValidateAtomicCommit_WorktreeOnlyFileDoesNotBreakAnalysis(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Working-Tree-Only File With Undefined Reference",
+		"utils.go ( M, worktree-only adds call to undefined func) | main.go staged",
+		"Staged [main.go comment] | WorkingTree [utils.go+undefined call]",
+		"No compilation error - working-tree content replaced with index version")
+
+	repoDir := setupTestRepo(t)
+
+	// Stage main.go with a comment.
+	modifyFile(t, filepath.Join(repoDir, fileMainGo), testComment)
+	stageFiles(t, repoDir, fileMainGo)
+
+	// Add an invalid call to utils.go working tree only (not staged,  M status).
+	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), "\nfunc init() { _ = undefinedFuncThatDoesNotExist() }\n")
+
+	// Should not return a compilation error - working tree content is replaced with index version.
+	_, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed (expected no error): %v", err)
+	}
+}
+
+func TestFindCommittableSet_UntrackedDepthSkipsDeepTree(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - Bounded Untracked Depth",
+		"deps/a/b/c/deep.go (untracked, deep), shallow.go (untracked, root)",
+		"Untracked [deps/a/b/c/deep.go, shallow.go] | Depth [1]",
+		"Deep untracked file is never offered; shallow file is selected")
+
+	repoDir := setupTestRepo(t)
+
+	deepDir := createUntrackedSubpackage(t, repoDir, filepath.Join("deps", "a", "b", "c"))
+	createUntrackedFile(t, deepDir, "deep.go", `package c
+
+// Deep is buried in an untracked tree.
+func Deep() string {
+	return "deep"
+}
+`)
+	createUntrackedFile(t, repoDir, "shallow.go", `package main
+
+// Shallow is a new independent function.
+func Shallow() string {
+	return "shallow"
+}
+`)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false, validator.WithUntrackedDepth(1))
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if !slices.Equal(files, []string{"shallow.go"}) {
+		t.Errorf("Expected [shallow.go], got %v", files)
+	}
+
+	// Without the bound, the deep file sorts first and is selected.
+	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if !slices.Equal(files, []string{"deps/a/b/c/deep.go"}) {
+		t.Errorf("Expected [deps/a/b/c/deep.go], got %v", files)
+	}
+}
+
+// ===========================.
+// Partition Tests.
+// ===========================.
+
+func TestPartition_IndependentFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partition - Independent Files",
+		"alpha.go, constants.go, variables.go (no dependencies between them)",
+		"Modified [alpha.go, constants.go, variables.go] | Unstaged [ALL]",
+		"Three single-file groups, nothing blocked")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "variables.go"), testComment)
+
+	groups, blocked, err := validator.Partition(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+
+	want := [][]string{{"alpha.go"}, {"constants.go"}, {"variables.go"}}
+	if !slices.EqualFunc(groups, want, slices.Equal) {
+		t.Errorf("Expected groups %v, got %v", want, groups)
+	}
+
+	if len(blocked) != 0 {
+		t.Errorf("Expected no blocked files, got %v", blocked)
+	}
+}
+
+func TestPartition_Chain(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partition - Dependency Chain",
+		"gamma.go -> beta.go -> alpha.go",
+		"Modified [alpha.go, beta.go, gamma.go] | Staged [beta.go] | Unstaged [alpha.go, gamma.go]",
+		"One group ordered dependencies first: alpha.go, beta.go, gamma.go")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	stageFiles(t, repoDir, "beta.go")
+
+	groups, blocked, err := validator.Partition(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+
+	want := [][]string{{"alpha.go", "beta.go", "gamma.go"}}
+	if !slices.EqualFunc(groups, want, slices.Equal) {
+		t.Errorf("Expected groups %v, got %v", want, groups)
+	}
+
+	if len(blocked) != 0 {
+		t.Errorf("Expected no blocked files, got %v", blocked)
+	}
+}
+
+func TestChangesetDeps_Chain(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Changeset Dependencies - Chain",
+		"gamma.go -> beta.go -> alpha.go",
+		"Modified [alpha.go, beta.go, gamma.go] | Staged [beta.go] | Unstaged [alpha.go, gamma.go]",
+		"gamma.go needs alpha.go and beta.go, beta.go needs alpha.go, alpha.go needs nothing")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	stageFiles(t, repoDir, "beta.go")
+
+	deps, err := validator.ChangesetDeps(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ChangesetDeps failed: %v", err)
+	}
+
+	want := map[string][]string{
+		"alpha.go": {},
+		"beta.go":  {"alpha.go"},
+		"gamma.go": {"alpha.go", "beta.go"},
+	}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("Expected %v, got %v", want, deps)
+	}
+}
+
+func TestPartition_CycleIsBlocked(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partition - Circular Dependency",
+		"circular_a.go <-> circular_b.go, alpha.go independent",
+		"Modified [alpha.go, circular_a.go, circular_b.go] | Unstaged [ALL]",
+		"alpha.go forms a group, both circular files are blocked")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "circular_a.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "circular_b.go"), testComment)
+
+	groups, blocked, err := validator.Partition(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+
+	want := [][]string{{"alpha.go"}}
+	if !slices.EqualFunc(groups, want, slices.Equal) {
+		t.Errorf("Expected groups %v, got %v", want, groups)
+	}
+
+	if !slices.Equal(blocked, []string{"circular_a.go", "circular_b.go"}) {
+		t.Errorf("Expected circular files to be blocked, got %v", blocked)
+	}
+}
+
+// writeChainPackage writes the untracked files of package chain, each mapped to its source.
+func writeChainPackage(t *testing.T, repoDir string, files map[string]string) {
+	t.Helper()
+
+	err := os.Mkdir(filepath.Join(repoDir, "chain"), 0o750)
+	if err != nil {
+		t.Fatalf("Failed to create chain dir: %v", err)
+	}
+
+	for name, body := range files {
+		writeFileContent(t, filepath.Join(repoDir, "chain", name), "package chain\n\n"+body)
+	}
+}
+
+func TestPartition_FileLevelChain(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partition - File-Level Chain",
+		"a.go (A1) -> b.go (B1); b.go (B2) -> c.go (C1)",
+		"Untracked [chain/a.go, chain/b.go, chain/c.go]",
+		"a.go needs b.go and, through the rest of b.go, c.go: one group c.go, b.go, a.go")
+
+	repoDir := setupTestRepo(t)
+
+	writeChainPackage(t, repoDir, map[string]string{
+		"a.go": "func A1() int { return B1() }\n",
+		"b.go": "func B1() int { return 1 }\n\nfunc B2() int { return C1() }\n",
+		"c.go": "func C1() int { return 2 }\n",
+	})
+
+	deps, err := validator.ChangesetDeps(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ChangesetDeps failed: %v", err)
+	}
+
+	if want := []string{"chain/b.go", "chain/c.go"}; !slices.Equal(deps["chain/a.go"], want) {
+		t.Errorf("Expected chain/a.go to need %v, got %v", want, deps["chain/a.go"])
+	}
+
+	groups, blocked, err := validator.Partition(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+
+	want := [][]string{{"chain/c.go", "chain/b.go", "chain/a.go"}}
+	if !slices.EqualFunc(groups, want, slices.Equal) {
+		t.Errorf("Expected groups %v, got %v", want, groups)
+	}
+
+	if len(blocked) != 0 {
+		t.Errorf("Expected no blocked files, got %v", blocked)
+	}
+}
+
+func TestPartition_ThreeFileCycleIsBlocked(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Partition - Three-File Cycle",
+		"a.go (A1) -> b.go (B1); b.go (B2) -> c.go (C1); c.go (C2) -> a.go (A2)",
+		"Untracked [chain/a.go, chain/b.go, chain/c.go]",
+		"No group, all three files blocked")
+
+	repoDir := setupTestRepo(t)
+
+	writeChainPackage(t, repoDir, map[string]string{
+		"a.go": "func A1() int { return B1() }\n\nfunc A2() int { return 1 }\n",
+		"b.go": "func B1() int { return 2 }\n\nfunc B2() int { return C1() }\n",
+		"c.go": "func C1() int { return 3 }\n\nfunc C2() int { return A2() }\n",
+	})
+
+	groups, blocked, err := validator.Partition(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+
+	if len(groups) != 0 {
+		t.Errorf("Expected no groups, got %v", groups)
+	}
+
+	if want := []string{"chain/a.go", "chain/b.go", "chain/c.go"}; !slices.Equal(blocked, want) {
+		t.Errorf("Expected %v to be blocked, got %v", want, blocked)
+	}
+}
+
+func TestCommitPlan_MaxCommitsMergesIndependentFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"CommitPlan - Max Commits Merges Independent Files",
+		"alpha.go, constants.go, variables.go (no dependencies between them)",
+		"Modified [alpha.go, constants.go, variables.go] | Unstaged [ALL] | MaxCommits 1",
+		"Without a limit three commits; with max 1 a single commit of all three")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "variables.go"), testComment)
+
+	plan, err := validator.CommitPlan(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("CommitPlan failed: %v", err)
+	}
+
+	if len(plan.Commits) != 3 {
+		t.Errorf("Expected 3 commits without a limit, got %v", plan.Commits)
+	}
+
+	plan, err = validator.CommitPlan(t.Context(), repoDir, validator.WithMaxCommits(1))
+	if err != nil {
+		t.Fatalf("CommitPlan failed: %v", err)
+	}
+
+	want := [][]string{{"alpha.go", "constants.go", "variables.go"}}
+	if !slices.EqualFunc(plan.Commits, want, slices.Equal) {
+		t.Errorf("Expected commits %v, got %v", want, plan.Commits)
+	}
+}
+
+func TestCommitPlan_ThreeFileCycleIsOneCommit(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"CommitPlan - Three-File Cycle",
+		"a.go (A1) -> b.go (B1); b.go (B2) -> c.go (C1); c.go (C2) -> a.go (A2); d.go -> a.go; e.go independent",
+		"Untracked [chain/a.go, chain/b.go, chain/c.go, chain/d.go, chain/e.go]",
+		"The cycle is a single commit, next to e.go; d.go, which depends on it, comes after")
+
+	repoDir := setupTestRepo(t)
+
+	writeChainPackage(t, repoDir, map[string]string{
+		"a.go": "func A1() int { return B1() }\n\nfunc A2() int { return 1 }\n",
+		"b.go": "func B1() int { return 2 }\n\nfunc B2() int { return C1() }\n",
+		"c.go": "func C1() int { return 3 }\n\nfunc C2() int { return A2() }\n",
+		"d.go": "func D1() int { return A1() }\n",
+		"e.go": "func E1() int { return 4 }\n",
+	})
+
+	plan, err := validator.CommitPlan(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("CommitPlan failed: %v", err)
+	}
+
+	want := [][]string{{"chain/a.go", "chain/b.go", "chain/c.go"}, {"chain/e.go"}, {"chain/d.go"}}
+	if !slices.EqualFunc(plan.Commits, want, slices.Equal) {
+		t.Errorf("Expected commits %v, got %v", want, plan.Commits)
+	}
+}
+
+func TestCommitPlan_MutualDependencyIsOneCommit(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"CommitPlan - Mutual Dependency",
+		"a.go (A1) -> b.go (B1); b.go (B2) -> a.go (A2)",
+		"Untracked [chain/a.go, chain/b.go]",
+		"A single commit of both files, nothing left out")
+
+	repoDir := setupTestRepo(t)
+
+	writeChainPackage(t, repoDir, map[string]string{
+		"a.go": "func A1() int { return B1() }\n\nfunc A2() int { return 1 }\n",
+		"b.go": "func B1() int { return 2 }\n\nfunc B2() int { return A2() }\n",
+	})
+
+	plan, err := validator.CommitPlan(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("CommitPlan failed: %v", err)
+	}
+
+	if want := [][]string{{"chain/a.go", "chain/b.go"}}; !slices.EqualFunc(plan.Commits, want, slices.Equal) {
+		t.Errorf("Expected commits %v, got %v", want, plan.Commits)
+	}
+}
+
+func TestCommitPlan_MaxCommitsKeepsDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"CommitPlan - Max Commits Keeps Dependency Order",
+		"gamma.go -> beta.go -> alpha.go, constants.go independent",
+		"Modified [alpha.go, beta.go, gamma.go, constants.go] | Unstaged [ALL] | MaxCommits 1",
+		"constants.go joins alpha.go; beta.go and gamma.go stay in separate, later commits")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+
+	plan, err := validator.CommitPlan(t.Context(), repoDir, validator.WithMaxCommits(1))
+	if err != nil {
+		t.Fatalf("CommitPlan failed: %v", err)
+	}
+
+	want := [][]string{{"alpha.go", "constants.go"}, {"beta.go"}, {"gamma.go"}}
+	if !slices.EqualFunc(plan.Commits, want, slices.Equal) {
+		t.Errorf("Expected commits %v, got %v", want, plan.Commits)
+	}
+}
+
+func TestCommitPlan_MaxCommitsKeepsLayersSeparate(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"CommitPlan - Max Commits Keeps Layers Separate",
+		"a.go -> b.go, c.go -> d.go",
+		"Untracked [chain/a.go, chain/b.go, chain/c.go, chain/d.go] | MaxCommits 1",
+		"Two commits, one per dependency layer, above the limit")
+
+	repoDir := setupTestRepo(t)
+
+	writeChainPackage(t, repoDir, map[string]string{
+		"a.go": "func A1() int { return B1() }\n",
+		"b.go": "func B1() int { return 1 }\n",
+		"c.go": "func C1() int { return D1() }\n",
+		"d.go": "func D1() int { return 2 }\n",
+	})
+
+	plan, err := validator.CommitPlan(t.Context(), repoDir, validator.WithMaxCommits(1))
+	if err != nil {
+		t.Fatalf("CommitPlan failed: %v", err)
+	}
+
+	want := [][]string{{"chain/b.go", "chain/d.go"}, {"chain/a.go", "chain/c.go"}}
+	if !slices.EqualFunc(plan.Commits, want, slices.Equal) {
+		t.Errorf("Expected commits %v, got %v", want, plan.Commits)
+	}
+}
+
+func TestValidateIncremental_MatchesFullValidation(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incremental Validation",
+		"gamma.go -> beta.go, then gamma.go is rewritten to drop the dependency",
+		"Modified [beta.go, gamma.go] | Staged [gamma.go] | Unstaged [beta.go]",
+		"Incremental results match a fresh validation before and after the edit")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	stageFiles(t, repoDir, "gamma.go")
+
+	violations, analysis, err := validator.ValidateIncremental(t.Context(), repoDir, nil, nil)
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected gamma.go -> beta.go violations before the edit, got none")
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, "gamma.go"),
+		"package main\n\n// GammaFunc no longer depends on beta.go.\nfunc GammaFunc() string {\n\treturn \"gamma\"\n}\n")
+	stageFiles(t, repoDir, "gamma.go")
+
+	violations, _, err = validator.ValidateIncremental(t.Context(), repoDir, []string{"gamma.go"}, analysis)
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	fresh, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(violations, fresh) {
+		t.Errorf("Incremental violations %+v differ from fresh %+v", violations, fresh)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations after the edit, got %+v", violations)
+	}
+}
+
+func TestValidateIncremental_ReloadsImporters(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incremental Validation of Importers",
+		"processor.go -> helper/validator.go (ValidatePositive), then ValidatePositive is deleted",
+		"Modified [processor.go, helper/validator.go] | Staged [processor.go, then helper/validator.go]",
+		"Only helper/validator.go changes, yet processor.go no longer builds, as a fresh validation finds")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, fileProcessorGo), testComment)
+	stageFiles(t, repoDir, fileProcessorGo)
+
+	_, analysis, err := validator.ValidateIncremental(t.Context(), repoDir, nil, nil)
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, fileHelperValidGo),
+		"package helper\n\n// ValidateLength checks if a string meets minimum length.\n"+
+			"func ValidateLength(s string, minLen int) bool {\n\treturn len(s) >= minLen\n}\n")
+	stageFiles(t, repoDir, fileHelperValidGo)
+
+	_, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		t.Fatalf("ValidateAtomicCommit() error = %v, want package errors", err)
+	}
+
+	_, _, err = validator.ValidateIncremental(t.Context(), repoDir, []string{fileHelperValidGo}, analysis)
+	if !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		t.Errorf("ValidateIncremental() error = %v, want package errors like a fresh validation", err)
+	}
+}
+
+func TestValidateIncremental_RelinksImplementations(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incremental Validation of Implementations",
+		"shapes/shape.go (Shape) <-> square.go (Square), then Shape gains a method Square lacks",
+		"Modified [shapes/shape.go, square.go] | Staged [shapes/shape.go] | Unstaged [square.go]",
+		"Only shapes/shape.go changes, yet Square no longer implements Shape, as a fresh validation finds")
+
+	repoDir := setupTestRepo(t)
+
+	shape := "package shapes\n\n// Shape has an area.\ntype Shape interface {\n\tArea() float64\n}\n\n" +
+		"// Unit is the area of the unit square.\nfunc Unit() float64 { return 1 }\n"
+
+	writeFileContent(t, filepath.Join(createUntrackedSubpackage(t, repoDir, "shapes"), "shape.go"), shape)
+	createUntrackedFile(t, repoDir, "square.go", `package main
+
+import "example.com/testproject/shapes"
+
+// Square is a shape.
+type Square struct{ side float64 }
+
+// Area returns the area of the square.
+func (s Square) Area() float64 {
+	return s.side * s.side * shapes.Unit()
+}
+`)
+	stageFiles(t, repoDir, ".")
+	runGit(t, repoDir, "commit", "-m", "shapes")
+
+	modifyFile(t, filepath.Join(repoDir, "square.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "shapes", "shape.go"), testComment)
+	stageFiles(t, repoDir, filepath.Join("shapes", "shape.go"))
+
+	violations, analysis, err := validator.ValidateIncremental(t.Context(), repoDir, nil, nil,
+		validator.WithImplementations())
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	if !slices.ContainsFunc(violations, func(v validator.Violation) bool { return v.MissingFile == "square.go" }) {
+		t.Fatalf("Expected shapes/shape.go -> square.go violations before the edit, got %+v", violations)
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, "shapes", "shape.go"),
+		strings.Replace(shape, "\tArea() float64\n", "\tArea() float64\n\tPerimeter() float64\n", 1))
+	stageFiles(t, repoDir, filepath.Join("shapes", "shape.go"))
+
+	violations, _, err = validator.ValidateIncremental(t.Context(), repoDir,
+		[]string{filepath.Join("shapes", "shape.go")}, analysis)
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	fresh, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithImplementations())
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(violations, fresh) {
+		t.Errorf("Incremental violations %+v differ from fresh %+v", violations, fresh)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations once Square no longer implements Shape, got %+v", violations)
+	}
+}
+
+func TestValidateIncremental_NestedModule(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incremental Validation of a Nested Module",
+		"tools/run.go (Run func) -> tools/util.go (Util func), in module example.com/tools without go.work",
+		"Untracked [tools/run.go, tools/util.go] | Staged [tools/run.go] | Unstaged [tools/util.go]",
+		"The analysis of the root module misses tools/run.go, yet the violation is found as by a fresh validation")
+
+	repoDir := setupTestRepo(t)
+
+	err := os.Mkdir(filepath.Join(repoDir, "tools"), 0o750)
+	if err != nil {
+		t.Fatalf("Failed to create tools dir: %v", err)
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, "tools", "go.mod"), "module example.com/tools\n\ngo 1.24\n")
+	stageFiles(t, repoDir, "tools/go.mod")
+	runGit(t, repoDir, "commit", "-m", "Add tools module")
+
+	_, analysis, err := validator.ValidateIncremental(t.Context(), repoDir, nil, nil)
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	writeFileContent(t, filepath.Join(repoDir, "tools", "util.go"),
+		"package tools\n\nfunc Util() string { return \"util\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "tools", "run.go"),
+		"package tools\n\nfunc Run() string { return Util() }\n")
+	stageFiles(t, repoDir, "tools/run.go")
+
+	violations, _, err := validator.ValidateIncremental(t.Context(), repoDir,
+		[]string{"tools/run.go", "tools/util.go"}, analysis)
+	if err != nil {
+		t.Fatalf("ValidateIncremental failed: %v", err)
+	}
+
+	fresh, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(violations, fresh) {
+		t.Errorf("Incremental violations %+v differ from fresh %+v", violations, fresh)
+	}
+
+	if len(violations) != 1 || violations[0].StagedFile != "tools/run.go" ||
+		violations[0].MissingFile != "tools/util.go" {
+		t.Errorf("Expected tools/run.go to need tools/util.go, got %+v", violations)
+	}
+}
+
+func TestViolationsForFiles_RestrictsToOpenFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Violations For Open Files",
+		"gamma.go -> beta.go -> alpha.go",
+		"Modified [alpha.go, beta.go, gamma.go] | Staged [beta.go, gamma.go] | Unstaged [alpha.go] | Files [beta.go]",
+		"Only beta.go violations, although gamma.go violates too")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	stageFiles(t, repoDir, "beta.go", "gamma.go")
+
+	all, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if !slices.ContainsFunc(all, func(v validator.Violation) bool { return v.StagedFile == "gamma.go" }) {
+		t.Fatalf("Expected gamma.go to violate too, got %+v", all)
+	}
+
+	violations, err := validator.ViolationsForFiles(t.Context(), repoDir, []string{"beta.go"})
+	if err != nil {
+		t.Fatalf("ViolationsForFiles failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected beta.go violations, got none")
+	}
+
+	for _, v := range violations {
+		if v.StagedFile != "beta.go" {
+			t.Errorf("Expected only beta.go violations, got %+v", v)
+		}
+	}
+}
+
+func TestFixClosure_FollowsWorkingTreeDependencies(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Fix Closure",
+		"beta.go -> alpha.go, and the working tree alpha.go newly uses MaxRetries from constants.go",
+		"Modified [alpha.go, beta.go, constants.go] | Staged [beta.go] | Unstaged [alpha.go, constants.go]",
+		"Violations only name alpha.go; the closure adds constants.go")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	writeFileContent(t, filepath.Join(repoDir, "alpha.go"),
+		"package main\n\nimport \"strconv\"\n\n"+
+			"// AlphaFunc now depends on constants.go.\n"+
+			"func AlphaFunc() string {\n\treturn \"alpha\" + strconv.Itoa(MaxRetries)\n}\n")
+	stageFiles(t, repoDir, "beta.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	for _, v := range violations {
+		if v.MissingFile != "alpha.go" {
+			t.Errorf("Expected violations to only name alpha.go, got %+v", v)
+		}
+	}
+
+	closure, err := validator.FixClosure(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("FixClosure failed: %v", err)
+	}
+
+	if want := []string{"alpha.go", "constants.go"}; !slices.Equal(closure, want) {
+		t.Errorf("Expected closure %v, got %v", want, closure)
+	}
+}
+
+// moveHelperToNewFile moves Helper from utils.go to a new helpers.go in the working tree.
+func moveHelperToNewFile(t *testing.T, repoDir string) {
+	t.Helper()
+
+	utilsPath := filepath.Join(repoDir, fileUtilsGo)
+
+	data, err := os.ReadFile(utilsPath) //nolint:gosec // Test reads temp file.
+	if err != nil {
+		t.Fatalf("Failed to read utils.go: %v", err)
+	}
+
+	helperDecl := "// Helper is a simple helper function.\nfunc Helper() string {\n\treturn \"helper\"\n}\n"
+	if !strings.Contains(string(data), helperDecl) {
+		t.Fatalf("utils.go does not contain the Helper declaration")
+	}
+
+	writeFileContent(t, utilsPath, strings.Replace(string(data), helperDecl, "", 1))
+	createUntrackedFile(t, repoDir, "helpers.go", "package main\n\n"+helperDecl)
+}
+
+func TestValidateAtomicCommit_IncompleteMove_RemovalNotStaged(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incomplete Move - Old Definition Not Removed",
+		"Helper moved utils.go -> helpers.go, main.go uses Helper",
+		"Modified [main.go, utils.go] | Untracked [helpers.go] | Staged [main.go, helpers.go] | Unstaged [utils.go]",
+		"Violation - helpers.go duplicates Helper because its removal from utils.go is not staged")
+
+	repoDir := setupTestRepo(t)
+
+	moveHelperToNewFile(t, repoDir)
+	modifyFile(t, filepath.Join(repoDir, fileMainGo), testComment)
+	stageFiles(t, repoDir, fileMainGo, "helpers.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == "helpers.go" && v.MissingFile == fileUtilsGo &&
+			v.StagedSymbol == "main.Helper" && v.Reason != "" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected incomplete move violation from helpers.go to utils.go, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_IncompleteMove_NewLocationNotStaged(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incomplete Move - New Location Not Staged",
+		"Helper moved utils.go -> helpers.go",
+		"Modified [utils.go] | Untracked [helpers.go] | Staged [utils.go] | Unstaged [helpers.go]",
+		"Violation - utils.go drops Helper while helpers.go, which now defines it, is not staged")
+
+	repoDir := setupTestRepo(t)
+
+	moveHelperToNewFile(t, repoDir)
+	stageFiles(t, repoDir, fileUtilsGo)
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == fileUtilsGo && v.MissingFile == "helpers.go" && v.StagedSymbol == "main.Helper" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected incomplete move violation from utils.go to helpers.go, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_IncompleteMove_GenericMethod(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Incomplete Move - Generic Method",
+		"Pair[K, V].Key moved pair.go -> pair_key.go",
+		"Modified [pair.go] | Untracked [pair_key.go] | Staged [pair.go] | Unstaged [pair_key.go]",
+		"Violation - pair.go drops Pair.Key while pair_key.go, which now defines it, is not staged")
+
+	repoDir := setupTestRepo(t)
+
+	keyDecl := "func (p *Pair[K, V]) Key() K {\n\treturn p.key\n}\n"
+	pairDecl := "package main\n\ntype Pair[K comparable, V any] struct {\n\tkey K\n\tvalue V\n}\n"
+
+	createUntrackedFile(t, repoDir, "pair.go", pairDecl+"\n"+keyDecl)
+	stageFiles(t, repoDir, "pair.go")
+	runGit(t, repoDir, "commit", "-m", "Add Pair")
+
+	writeFileContent(t, filepath.Join(repoDir, "pair.go"), pairDecl)
+	createUntrackedFile(t, repoDir, "pair_key.go", "package main\n\n"+keyDecl)
+	stageFiles(t, repoDir, "pair.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	found := false
+
+	for _, v := range violations {
+		if v.StagedFile == "pair.go" && v.MissingFile == "pair_key.go" && v.StagedSymbol == "main.Pair.Key" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected incomplete move violation of Pair.Key from pair.go to pair_key.go, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Test Policy",
+		"fixture moved fixtures.go -> fixtures_test.go; helper_test.go uses Helper from utils.go",
+		"Modified [fixtures.go, utils.go] | Untracked [fixtures_test.go, helper_test.go] | "+
+			"Staged [fixtures.go, helper_test.go] | Unstaged [fixtures_test.go, utils.go]",
+		"Strict blocks fixtures.go on fixtures_test.go; lenient only reports helper_test.go -> utils.go")
+
+	repoDir := setupTestRepo(t)
+
+	fixtureDecl := "func fixture() string {\n\treturn \"fixture\"\n}\n"
+	createUntrackedFile(t, repoDir, "fixtures.go", "package main\n\n"+fixtureDecl)
+	runGit(t, repoDir, "add", "fixtures.go")
+	runGit(t, repoDir, "commit", "-m", "Add fixture")
+
+	writeFileContent(t, filepath.Join(repoDir, "fixtures.go"), "package main\n")
+	createUntrackedFile(t, repoDir, "fixtures_test.go", "package main\n\n"+fixtureDecl)
+	createUntrackedFile(t, repoDir, "helper_test.go",
+		"package main\n\nimport \"testing\"\n\nfunc TestHelper(t *testing.T) {\n\t_ = Helper()\n}\n")
+	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), testComment)
+	stageFiles(t, repoDir, "fixtures.go", "helper_test.go")
+
+	tests := []struct {
+		policy      validator.TestPolicy
+		wantFixture bool
+	}{
+		{policy: validator.TestPolicyStrict, wantFixture: true},
+		{policy: validator.TestPolicyLenient, wantFixture: false},
+	}
+
+	for _, tt := range tests {
+		violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithTestPolicy(tt.policy))
+		if err != nil {
+			t.Fatalf("%s: ValidateAtomicCommit failed: %v", tt.policy, err)
+		}
+
+		gotFixture := slices.ContainsFunc(violations, func(v validator.Violation) bool {
+			return v.StagedFile == "fixtures.go" && v.MissingFile == "fixtures_test.go"
+		})
+		if gotFixture != tt.wantFixture {
+			t.Errorf("%s: fixtures.go -> fixtures_test.go reported = %v, want %v: %+v",
+				tt.policy, gotFixture, tt.wantFixture, violations)
+		}
+
+		if !slices.ContainsFunc(violations, func(v validator.Violation) bool {
+			return v.StagedFile == "helper_test.go" && v.MissingFile == fileUtilsGo
+		}) {
+			t.Errorf("%s: expected helper_test.go -> utils.go, got %+v", tt.policy, violations)
+		}
+	}
+}
+
+func TestValidateAtomicCommit_SkipGenerated(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Generated Missing File",
+		"consumer.go (UseGenerated) -> zz_generated.go (Generated, \"Code generated ... DO NOT EDIT.\")",
+		"Modified [consumer.go, zz_generated.go] | Staged [consumer.go] | Unstaged [zz_generated.go]",
+		"Violation by default, none with WithSkipGenerated")
+
+	repoDir := setupTestRepo(t)
+
+	generated := "// Code generated by gen; DO NOT EDIT.\n\n" +
+		"package main\n\nfunc Generated() string {\n\treturn \"gen\"\n}\n"
+	createUntrackedFile(t, repoDir, "zz_generated.go", generated)
+	modifyFile(t, filepath.Join(repoDir, fileConsumerGo), "\nfunc UseGenerated() string {\n\treturn Generated()\n}\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "Add generated code")
+
+	modifyFile(t, filepath.Join(repoDir, "zz_generated.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, fileConsumerGo), testComment)
+	stageFiles(t, repoDir, fileConsumerGo)
+
+	isGenerated := func(v validator.Violation) bool { return v.MissingFile == "zz_generated.go" }
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if !slices.ContainsFunc(violations, isGenerated) {
+		t.Errorf("Expected a violation requiring zz_generated.go, got %+v", violations)
+	}
+
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithSkipGenerated())
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if slices.ContainsFunc(violations, isGenerated) {
+		t.Errorf("Expected zz_generated.go to be skipped, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_IgnoreDirective(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Suppressed Dependencies",
+		"consumer.go (UseLater, //darna:ignore) -> later.go (Later); wired.go (//darna:ignore header) -> later.go",
+		"Untracked [later.go] | Staged [consumer.go, wired.go]",
+		"No violations - both staged usages are suppressed")
+
+	repoDir := setupTestRepo(t)
+
+	createUntrackedFile(t, repoDir, "later.go", "package main\n\nfunc Later() string {\n\treturn \"later\"\n}\n")
+	modifyFile(t, filepath.Join(repoDir, fileConsumerGo),
+		"\n//darna:ignore Later lands in the next commit.\nfunc UseLater() string {\n\treturn Later()\n}\n")
+	createUntrackedFile(t, repoDir, "wired.go",
+		"//darna:ignore Wiring only.\n\npackage main\n\nfunc Wired() string {\n\treturn Later()\n}\n")
+	stageFiles(t, repoDir, fileConsumerGo, "wired.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("Expected suppressed violations, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_CompleteMove_NoViolation(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Complete Move",
+		"Helper moved utils.go -> helpers.go",
+		"Modified [utils.go] | Untracked [helpers.go] | Staged [utils.go, helpers.go]",
+		"No violations - both halves of the move are staged")
+
+	repoDir := setupTestRepo(t)
+
+	moveHelperToNewFile(t, repoDir)
+	stageFiles(t, repoDir, fileUtilsGo, "helpers.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %+v", violations)
+	}
+}
+
+func TestValidateAtomicCommit_IgnoreMain(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Ignore Main Package",
+		"processor.go (main) -> models/response.go -> helper/formatter.go",
+		"Modified [processor.go, models/response.go, helper/formatter.go] | "+
+			"Staged [processor.go, models/response.go] | Unstaged [helper/formatter.go]",
+		"Only the library violation remains - processor.go belongs to package main")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, fileProcessorGo), testComment)
+	modifyFile(t, filepath.Join(repoDir, fileModelsResponse), testComment)
+	modifyFile(t, filepath.Join(repoDir, fileHelperFmtGo), testComment)
+	stageFiles(t, repoDir, fileProcessorGo, fileModelsResponse)
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if !slices.ContainsFunc(violations, func(v validator.Violation) bool {
+		return v.StagedFile == fileProcessorGo
+	}) {
+		t.Fatalf("Expected a violation from processor.go without --ignore-main, got %+v", violations)
+	}
+
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithIgnoreMain())
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
+
+	if len(violations) == 0 {
+		t.Fatal("Expected the models/response.go violation to remain, got none")
+	}
+
+	for _, v := range violations {
+		if v.StagedFile != fileModelsResponse {
+			t.Errorf("Expected only models/response.go violations, got %+v", v)
+		}
+	}
+}
+
+func TestFindCommittableSet_IgnoreMain(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"FindCommittableSet - Ignore Main Package",
+		"alpha.go (main, independent), helper/formatter.go (independent)",
+		"Modified [alpha.go, helper/formatter.go] | Unstaged [ALL]",
+		"helper/formatter.go is selected because main files are not candidates")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, fileHelperFmtGo), testComment)
+
+	files, err := validator.FindCommittableSet(t.Context(), repoDir, false, validator.WithIgnoreMain())
+	if err != nil {
+		t.Fatalf("FindCommittableSet failed: %v", err)
+	}
+
+	if !slices.Equal(files, []string{fileHelperFmtGo}) {
+		t.Errorf("Expected [%s], got %v", fileHelperFmtGo, files)
+	}
+}
+
+func TestImpact_UtilsDependents(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Impact - Blast Radius",
+		"main.go -> utils.go (Helper), service.go -> utils.go (ValidateConfig, FormatUser)",
+		"Clean working tree",
+		"utils.go impacts symbols in main.go and service.go")
+
+	repoDir := setupTestRepo(t)
+
+	report, err := validator.Impact(t.Context(), repoDir, fileUtilsGo)
+	if err != nil {
+		t.Fatalf("Impact failed: %v", err)
+	}
+
+	if report.File != fileUtilsGo {
+		t.Errorf("Expected report for %s, got %s", fileUtilsGo, report.File)
+	}
+
+	files := make([]string, 0, len(report.Files))
+	total := 0
+
+	for _, fi := range report.Files {
+		files = append(files, fi.File)
+		total += fi.Symbols
+	}
+
+	slices.Sort(files)
+
+	if !slices.Equal(files, []string{fileMainGo, "service.go"}) {
+		t.Errorf("Expected affected files [main.go service.go], got %v", files)
+	}
+
+	if report.Symbols < 2 || total != report.Symbols {
+		t.Errorf("Expected at least 2 dependent symbols matching per-file counts, got %d (sum %d)",
+			report.Symbols, total)
+	}
+}
+
+// gitOutput runs a git command in the specified directory and returns its trimmed output.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.CommandContext(t.Context(), "git", args...) //nolint:gosec // Test helper for git commands.
+	cmd.Dir = dir
+
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+
+	return strings.TrimSpace(string(output))
 }
 
-func TestValidateAtomicCommit_Subpackage_TransitiveAcrossPackages(t *testing.T) {
+func TestValidateRefUpdate_BareRepository(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Transitive Cross-Package Dependency Violation",
-		"processor.go (main) -> models/response.go -> helper/formatter.go (transitive chain)",
-		"Modified [processor.go, models/response.go, helper/formatter.go] | "+
-			"Staged [processor.go, models/response.go] | Unstaged [helper/formatter.go]",
-		"Violations detected - both direct and transitive dependencies on unstaged helper/formatter.go")
+		"Ref Update In Bare Repository",
+		"main.go (UseNewThing) -> newthing.go (NewThing)",
+		"Commit 1 [main.go uses NewThing] | Commit 2 [add newthing.go] | Pushed to a bare clone",
+		"Commit 1 is not atomic, commit 2 is atomic")
 
 	repoDir := setupTestRepo(t)
+	oldRev := gitOutput(t, repoDir, "rev-parse", "HEAD")
 
-	// Modify processor.go, models/response.go, and helper/formatter.go.
-	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, fileMainGo), "\nfunc UseNewThing() string { return NewThing() }\n")
+	stageFiles(t, repoDir, fileMainGo)
+	runGit(t, repoDir, "commit", "-m", "Use NewThing")
 
-	// Stage processor.go and models/response.go, but not helper/formatter.go.
-	stageFiles(t, repoDir, "processor.go", "models/response.go")
+	createUntrackedFile(t, repoDir, "newthing.go", "package main\n\nfunc NewThing() string { return \"new\" }\n")
+	stageFiles(t, repoDir, "newthing.go")
+	runGit(t, repoDir, "commit", "-m", "Add NewThing")
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	newRev := gitOutput(t, repoDir, "rev-parse", "HEAD")
+
+	bareDir := filepath.Join(t.TempDir(), "bare.git")
+	runGit(t, repoDir, "clone", "--bare", repoDir, bareDir)
+
+	reports, err := validator.ValidateRefUpdate(t.Context(), bareDir, oldRev, newRev)
 	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		t.Fatalf("ValidateRefUpdate failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations for transitive cross-package dependency, got none")
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 commit reports, got %d: %+v", len(reports), reports)
 	}
 
-	// Should have violations involving helper/formatter.go.
-	hasProcessorToFormatter := false
-	hasResponseToFormatter := false
-
-	for _, v := range violations {
-		if v.StagedFile == fileProcessorGo && v.MissingFile == fileHelperFmtGo {
-			hasProcessorToFormatter = true
-		}
+	found := false
 
-		if v.StagedFile == fileModelsResponse && v.MissingFile == fileHelperFmtGo {
-			hasResponseToFormatter = true
+	for _, v := range reports[0].Violations {
+		if v.StagedFile == fileMainGo && v.MissingFile == "newthing.go" {
+			found = true
 		}
 	}
 
-	if !hasResponseToFormatter {
-		t.Error("Expected direct violation from models/response.go to helper/formatter.go")
+	if !found {
+		t.Errorf("Expected first commit to depend on newthing.go, got %+v", reports[0].Violations)
 	}
 
-	if !hasProcessorToFormatter {
-		t.Error("Expected transitive violation from processor.go to helper/formatter.go")
+	if len(reports[1].Violations) != 0 {
+		t.Errorf("Expected second commit to be atomic, got %+v", reports[1].Violations)
 	}
 }
 
-func TestValidateAtomicCommit_Subpackage_CompleteAtomicCommit(t *testing.T) {
+func TestValidateRefUpdate_AtomicCommits(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Complete Cross-Package Atomic Commit (No Violations)",
-		"processor.go -> models/response.go -> helper/formatter.go (complete chain)",
-		"Modified [processor.go, models/response.go, helper/formatter.go, "+
-			"models/request.go, helper/validator.go] | Staged [ALL]",
-		"No violations - all cross-package dependencies are staged together")
+		"Ref Update With Atomic Commits",
+		"main.go (UseNewThing) -> newthing.go (NewThing)",
+		"Commit 1 [add newthing.go] | Commit 2 [main.go uses NewThing]",
+		"No violations - dependencies land first")
 
 	repoDir := setupTestRepo(t)
+	oldRev := gitOutput(t, repoDir, "rev-parse", "HEAD")
 
-	// Modify all related files.
-	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
+	createUntrackedFile(t, repoDir, "newthing.go", "package main\n\nfunc NewThing() string { return \"new\" }\n")
+	stageFiles(t, repoDir, "newthing.go")
+	runGit(t, repoDir, "commit", "-m", "Add NewThing")
 
-	// Stage all files together.
-	stageFiles(t, repoDir, "processor.go", "models/response.go", "models/request.go",
-		"helper/formatter.go", "helper/validator.go")
+	modifyFile(t, filepath.Join(repoDir, fileMainGo), "\nfunc UseNewThing() string { return NewThing() }\n")
+	stageFiles(t, repoDir, fileMainGo)
+	runGit(t, repoDir, "commit", "-m", "Use NewThing")
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	reports, err := validator.ValidateRefUpdate(t.Context(), repoDir, oldRev, "HEAD")
 	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		t.Fatalf("ValidateRefUpdate failed: %v", err)
 	}
 
-	if len(violations) != 0 {
-		t.Errorf("Expected no violations when all cross-package dependencies are staged, got %d: %+v",
-			len(violations), violations)
+	for _, report := range reports {
+		if len(report.Violations) != 0 {
+			t.Errorf("Expected commit %s to be atomic, got %+v", report.Commit, report.Violations)
+		}
 	}
 }
 
-func TestValidateAtomicCommit_Subpackage_MultipleMainToSubpackages(t *testing.T) {
+func TestValidateRefUpdate_RunsStagedChecks(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Main -> Multiple Subpackages Violation",
-		"processor.go -> helper/validator.go AND processor.go -> models/request.go (multiple dependencies)",
-		"Modified [processor.go, helper/validator.go, models/request.go] | "+
-			"Staged [processor.go] | Unstaged [helper/validator.go, models/request.go]",
-		"Multiple violations - main file depends on multiple unstaged subpackage files")
+		"Ref Update Runs The Staged Checks",
+		"Helper moved utils.go -> helpers.go; api.proto paired with api.pb.go",
+		"Commit 1 [helpers.go, api.proto] | Commit 2 [utils.go, api.pb.go] | Pairs [*.proto -> *.pb.go]",
+		"Commit 1 duplicates Helper and misses api.pb.go, as if staged; commit 2 is atomic")
 
 	repoDir := setupTestRepo(t)
+	oldRev := gitOutput(t, repoDir, "rev-parse", "HEAD")
 
-	// Modify processor.go and both subpackage files.
-	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
+	moveHelperToNewFile(t, repoDir)
+	createUntrackedFile(t, repoDir, "api.proto", "syntax = \"proto3\";\n")
+	stageFiles(t, repoDir, "helpers.go", "api.proto")
+	runGit(t, repoDir, "commit", "-m", "Add helpers.go")
 
-	// Stage only processor.go.
-	stageFiles(t, repoDir, "processor.go")
+	createUntrackedFile(t, repoDir, "api.pb.go", "package main\n")
+	stageFiles(t, repoDir, fileUtilsGo, "api.pb.go")
+	runGit(t, repoDir, "commit", "-m", "Remove Helper from utils.go")
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	rule, err := validator.ParsePairRule("*.proto -> *.pb.go")
 	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		t.Fatalf("ParsePairRule failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected multiple violations, got none")
+	reports, err := validator.ValidateRefUpdate(t.Context(), repoDir, oldRev, "HEAD", validator.WithPairedFiles(rule))
+	if err != nil {
+		t.Fatalf("ValidateRefUpdate failed: %v", err)
 	}
 
-	// Should have violations to both subpackage files.
-	hasHelperViolation := false
-	hasModelsViolation := false
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 commit reports, got %d: %+v", len(reports), reports)
+	}
 
-	for _, v := range violations {
-		if v.StagedFile == fileProcessorGo && v.MissingFile == fileHelperValidGo {
-			hasHelperViolation = true
-		}
+	var missing []string
 
-		if v.StagedFile == fileProcessorGo && v.MissingFile == "models/request.go" {
-			hasModelsViolation = true
+	for _, v := range reports[0].Violations {
+		if v.Reason != "" {
+			missing = append(missing, v.StagedFile+" -> "+v.MissingFile)
 		}
 	}
 
-	if !hasHelperViolation {
-		t.Error("Expected violation from processor.go to helper/validator.go")
+	if want := []string{"api.proto -> api.pb.go", "helpers.go -> utils.go"}; !slices.Equal(missing, want) {
+		t.Errorf("Expected the first commit to report %v, got %+v", want, reports[0].Violations)
 	}
 
-	if !hasModelsViolation {
-		t.Error("Expected violation from processor.go to models/request.go")
+	if len(reports[1].Violations) != 0 {
+		t.Errorf("Expected second commit to be atomic, got %+v", reports[1].Violations)
 	}
 }
 
-// writeFileContent overwrites a file with the given content.
-func writeFileContent(t *testing.T, path, content string) {
+// setupRegistrationPattern commits a plugin registry and leaves a new plugin untracked
+// while a staged file registers it: plugins.go (LoadPlugins) -> register(&MyPlugin{}).
+func setupRegistrationPattern(t *testing.T) string {
 	t.Helper()
 
-	err := os.WriteFile(path, []byte(content), 0o600) //nolint:gosec // Test helper writes to temp dir.
-	if err != nil {
-		t.Fatalf("Failed to write %s: %v", path, err)
-	}
-}
-
-func TestValidateAtomicCommit_PartialStaging_NoFalsePositive(t *testing.T) {
-	t.Parallel()
-
-	logTestPattern(t,
-		"Partial Staging - No False Positive (MM file)",
-		"main.go (UsePartialFunc) -> service.go (PartialFunc - staged has it, working tree removes it)",
-		"Modified [main.go (staged), service.go (MM)] | Staged version of service.go has PartialFunc",
-		"No violation - overlay ensures staged version of service.go is analyzed")
-
 	repoDir := setupTestRepo(t)
 
-	// Step 1: Add PartialFunc to service.go and commit it.
-	modifyFile(t, filepath.Join(repoDir, "service.go"), "\nfunc PartialFunc() string { return \"partial\" }\n")
-	stageFiles(t, repoDir, "service.go")
-	runGit(t, repoDir, "commit", "-m", "Add PartialFunc")
+	createUntrackedFile(t, repoDir, "registry.go", `package main
 
-	// Step 2: Modify main.go to call PartialFunc and stage it.
-	modifyFile(t, filepath.Join(repoDir, "main.go"), "\nfunc UsePartialFunc() { _ = PartialFunc() }\n")
-	stageFiles(t, repoDir, "main.go")
+// Plugin is implemented by every plugin.
+type Plugin interface {
+	Name() string
+}
 
-	// Step 3: Also modify service.go (stage the change that keeps PartialFunc).
-	modifyFile(t, filepath.Join(repoDir, "service.go"), "\n// Another comment\n")
-	stageFiles(t, repoDir, "service.go")
+var plugins []Plugin
 
-	// Step 4: Now remove PartialFunc in the working tree (creating MM state).
-	// Read current content and rewrite without PartialFunc.
-	data, err := os.ReadFile(filepath.Join(repoDir, "service.go")) //nolint:gosec // Test reads temp file.
-	if err != nil {
-		t.Fatalf("Failed to read service.go: %v", err)
-	}
+func register(p Plugin) {
+	plugins = append(plugins, p)
+}
+`)
+	stageFiles(t, repoDir, "registry.go")
+	runGit(t, repoDir, "commit", "-m", "Add plugin registry")
 
-	// Remove the PartialFunc line from working tree.
-	modified := strings.ReplaceAll(string(data), "func PartialFunc() string { return \"partial\" }\n", "")
-	writeFileContent(t, filepath.Join(repoDir, "service.go"), modified)
+	createUntrackedFile(t, repoDir, "myplugin.go", `package main
 
-	// Now service.go is MM: staged version has PartialFunc, working tree does not.
+// MyPlugin is a plugin.
+type MyPlugin struct{}
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
-	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed: %v", err)
-	}
+// Name returns the plugin name.
+func (*MyPlugin) Name() string {
+	return "mine"
+}
+`)
+	createUntrackedFile(t, repoDir, "plugins.go", `package main
 
-	// Should have NO violations because the staged version of service.go still has PartialFunc.
-	for _, v := range violations {
-		if v.MissingSymbol == "example.com/testproject.PartialFunc" {
-			t.Errorf("Got false positive violation for PartialFunc (should use staged version): %+v", v)
-		}
-	}
+// LoadPlugins registers the built-in plugins.
+func LoadPlugins() {
+	register(&MyPlugin{})
 }
+`)
+	stageFiles(t, repoDir, "plugins.go")
 
-func TestValidateAtomicCommit_PartialStaging_StagedSymbolPresent(t *testing.T) {
+	return repoDir
+}
+
+func TestValidateAtomicCommit_DecouplingFunc(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Partial Staging - Symbol Present in Both Versions",
-		"main.go (UseHelper) -> utils.go (Helper - present in both staged and working tree)",
-		"Modified [main.go (staged), utils.go (MM)] | Both versions of utils.go have Helper",
-		"No violation - symbol exists in staged version")
-
-	repoDir := setupTestRepo(t)
-
-	// Modify main.go and stage it.
-	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Comment for staging\n")
-	stageFiles(t, repoDir, "main.go")
+		"Decoupling Function",
+		"plugins.go (LoadPlugins) -> register(&MyPlugin{}) -> myplugin.go (MyPlugin)",
+		"Committed [registry.go] | Staged [plugins.go] | Untracked [myplugin.go]",
+		"Violation by default, suppressed when register is a decoupling function")
 
-	// Modify utils.go and stage it (creates staged change).
-	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Staged comment\n")
-	stageFiles(t, repoDir, "utils.go")
+	repoDir := setupRegistrationPattern(t)
 
-	// Modify utils.go again without staging (creates MM state).
-	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Unstaged comment\n")
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+	}
 
-	// Now utils.go is MM but Helper is present in both versions.
+	if !slices.ContainsFunc(violations, func(v validator.Violation) bool {
+		return v.StagedFile == "plugins.go" && v.MissingFile == "myplugin.go"
+	}) {
+		t.Fatalf("Expected violation from plugins.go to myplugin.go, got %+v", violations)
+	}
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithDecouplingFuncs("register"))
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	// Should have no violations - utils.go is staged and Helper exists.
-	for _, v := range violations {
-		if v.StagedFile == fileMainGo && v.MissingFile == fileUtilsGo {
-			t.Errorf("Unexpected violation for utils.go which is staged: %+v", v)
-		}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations with register as decoupling function, got %+v", violations)
 	}
 }
 
-func TestValidateAtomicCommit_UnstagedOnly_StillFlagsViolation(t *testing.T) {
+func TestValidateAtomicCommit_BlankImport(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Unstaged Only - Still Flags Violation",
-		"main.go (staged) -> utils.go (unstaged only, space-M status)",
-		"Modified [main.go (staged), utils.go (unstaged only)] | utils.go has only working tree changes",
-		"Violation detected - overlay logic does not suppress legitimate violations")
+		"Blank Import",
+		`drivers.go (_ "example.com/testproject/driver") -> driver/driver.go (init)`,
+		"Staged [drivers.go] | Untracked [driver/driver.go]",
+		"Violation detected - the blank import needs the initialization of the untracked package")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify main.go and stage it.
-	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
-	stageFiles(t, repoDir, "main.go")
+	driverDir := createUntrackedSubpackage(t, repoDir, "driver")
+	createUntrackedFile(t, driverDir, "driver.go", `package driver
 
-	// Modify utils.go but do NOT stage it (creates ` M` status, not `MM`).
-	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Unstaged change\n")
+// Registered tells whether the driver registered itself.
+var Registered bool
+
+func init() {
+	Registered = true
+}
+`)
+	createUntrackedFile(t, repoDir, "drivers.go", "package main\n\nimport _ \"example.com/testproject/driver\"\n")
+	stageFiles(t, repoDir, "drivers.go")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	// Should have violations - utils.go has unstaged changes and is not staged.
-	found := false
-
-	for _, v := range violations {
-		if v.StagedFile == fileMainGo && v.MissingFile == fileUtilsGo {
-			found = true
-
-			break
-		}
-	}
-
-	if !found {
-		t.Errorf("Expected violation from main.go to utils.go for unstaged-only file, violations: %+v", violations)
+	if !slices.ContainsFunc(violations, func(v validator.Violation) bool {
+		return v.StagedFile == "drivers.go" && v.MissingFile == filepath.Join("driver", "driver.go") &&
+			v.StagedSymbol == `example.com/testproject._ "example.com/testproject/driver"` && v.Line == 3
+	}) {
+		t.Errorf("Expected violation from the blank import in drivers.go to driver/driver.go, got %+v", violations)
 	}
 }
 
-func TestValidateAtomicCommit_Subpackage_PartialStaging(t *testing.T) {
+func TestValidateAtomicCommit_EmbeddedFile(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Partial Subpackage Staging (Within Same Package)",
-		"models/response.go -> helper/formatter.go (cross-package dep)",
-		"Modified [models/response.go, models/request.go, helper/formatter.go] | "+
-			"Staged [models/response.go, models/request.go]",
-		"Violation detected - models package files staged but missing helper dependency")
+		"Embedded File",
+		"pages.go (pages) -> //go:embed templates/*.html -> templates/index.html",
+		"Committed [templates/index.html] | Staged [pages.go] | Unstaged [templates/index.html]",
+		"Violation detected - the staged variable embeds a file with unstaged changes")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify both models files and helper/formatter.go.
-	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "models", "request.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+	templatesDir := createUntrackedSubpackage(t, repoDir, "templates")
+	createUntrackedFile(t, templatesDir, "index.html", "<h1>Hello</h1>\n")
+	stageFiles(t, repoDir, filepath.Join("templates", "index.html"))
+	runGit(t, repoDir, "commit", "-m", "Add templates")
 
-	// Stage both models files but not helper.
-	stageFiles(t, repoDir, "models/response.go", "models/request.go")
+	modifyFile(t, filepath.Join(templatesDir, "index.html"), "<p>{{.Name}}</p>\n")
+	createUntrackedFile(t, repoDir, "pages.go", `package main
+
+import "embed"
+
+//go:embed templates/*.html
+var pages embed.FS
+`)
+	stageFiles(t, repoDir, "pages.go")
 
 	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
 		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violations, got none")
+	if !slices.ContainsFunc(violations, func(v validator.Violation) bool {
+		return v.StagedFile == "pages.go" && v.MissingFile == filepath.Join("templates", "index.html") &&
+			v.MissingKind == "file" && v.MissingSymbol == "example.com/testproject/templates/index.html"
+	}) {
+		t.Errorf("Expected violation from pages.go to templates/index.html, got %+v", violations)
 	}
 
-	// Should have violation from models/response.go to helper/formatter.go.
-	found := false
-
-	for _, v := range violations {
-		if v.StagedFile == fileModelsResponse && v.MissingFile == fileHelperFmtGo {
-			found = true
+	stageFiles(t, repoDir, filepath.Join("templates", "index.html"))
 
-			break
-		}
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if !found {
-		t.Errorf("Expected violation from models/response.go to helper/formatter.go, violations: %+v", violations)
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations with the template staged, got %+v", violations)
 	}
 }
 
-// ===========================.
-// FindCommittableFiles Tests.
-// ===========================.
-
-func TestFindCommittableFiles_IndependentFiles(t *testing.T) {
+func TestValidateAtomicCommit_Implementations(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableFiles - Independent Files",
-		"alpha.go and beta.go (both independent, no dependencies)",
-		"Modified [alpha.go, beta.go] | Unstaged [alpha.go, beta.go]",
-		"Should return alpha.go (first lexicographically)")
+		"Interface Implementations",
+		"shape.go (Shape) <-> square.go (Square)",
+		"Staged [shape.go] | Untracked [square.go]",
+		"Violation only with WithImplementations - the interface needs its implementation")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify both alpha.go and beta.go (both are independent).
-	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	createUntrackedFile(t, repoDir, "shape.go", `package main
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+// Shape has an area.
+type Shape interface {
+	Area() float64
+}
+`)
+	createUntrackedFile(t, repoDir, "square.go", `package main
+
+// Square is a shape.
+type Square struct{ side float64 }
+
+// Area returns the area of the square.
+func (s Square) Area() float64 {
+	return s.side * s.side
+}
+`)
+	stageFiles(t, repoDir, "shape.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations by default, got %+v", violations)
+	}
+
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithImplementations())
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if files[0] != "alpha.go" {
-		t.Errorf("Expected alpha.go, got %s", files[0])
+	if !slices.ContainsFunc(violations, func(v validator.Violation) bool {
+		return v.StagedFile == "shape.go" && v.MissingFile == "square.go" &&
+			v.MissingSymbol == "example.com/testproject.Square"
+	}) {
+		t.Errorf("Expected violation from shape.go to square.go, got %+v", violations)
 	}
 }
 
-func TestFindCommittableFiles_WithDependency(t *testing.T) {
+func TestSuggestHunks(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableFiles - Dependency Chain",
-		"main.go -> utils.go (main depends on utils)",
-		"Modified [main.go, utils.go] | Unstaged [main.go, utils.go]",
-		"Should return utils.go (independent), main.go is not independent")
+		"Hunk Suggestions",
+		"beta.go (BetaFunc) -> utils.go (Greeting), extra.go (Extra)",
+		"Modified [beta.go, utils.go (Greeting and ValidateConfig)] | Untracked [extra.go]",
+		"Only the BetaFunc, Greeting and extra.go hunks are suggested, and they stage cleanly")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify main.go (depends on utils.go) and utils.go.
-	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "utils.go"), testComment)
+	utils := filepath.Join(repoDir, fileUtilsGo)
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	data, err := os.ReadFile(utils) //nolint:gosec // Test reads temp file.
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
+		t.Fatalf("Failed to read utils.go: %v", err)
 	}
 
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	writeFileContent(t, utils, strings.Replace(string(data), "c.Port > 0", "c.Port > 1024", 1)+
+		"\n// Greeting is used by BetaFunc.\nfunc Greeting() string {\n\treturn \"hello\"\n}\n")
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on AlphaFunc, Greeting and Extra.\n"+
+			"func BetaFunc() string {\n\treturn \"beta-\" + AlphaFunc() + Greeting() + Extra()\n}\n")
+	createUntrackedFile(t, repoDir, "extra.go", "package main\n\nfunc Extra() string { return \"extra\" }\n")
+
+	hunks, err := validator.SuggestHunks(t.Context(), repoDir, "BetaFunc")
+	if err != nil {
+		t.Fatalf("SuggestHunks failed: %v", err)
 	}
 
-	if files[0] != "utils.go" {
-		t.Errorf("Expected utils.go (independent), got %s", files[0])
+	var ranges []string
+	for _, h := range hunks {
+		ranges = append(ranges, h.Range())
 	}
 
-	// Commit utils.go and check again - now main.go should be committable.
-	stageFiles(t, repoDir, "utils.go")
-	runGit(t, repoDir, "commit", "-m", "update utils")
+	want := []string{"beta.go:3", "beta.go:5", "extra.go:1-3", "utils.go:19-23"}
+	if !slices.Equal(ranges, want) {
+		t.Fatalf("Expected hunks %v, got %v", want, ranges)
+	}
 
-	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	cmd := exec.CommandContext(t.Context(), "git", "apply", "--cached", "--unidiff-zero", "-")
+	cmd.Dir = repoDir
+	cmd.Stdin = strings.NewReader(validator.FormatPatch(hunks))
+
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed after committing utils: %v", err)
+		t.Fatalf("git apply failed: %v\n%s\n%s", err, output, validator.FormatPatch(hunks))
 	}
 
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file after utils committed, got %d: %v", len(files), files)
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if files[0] != "main.go" {
-		t.Errorf("Expected main.go after utils committed, got %s", files[0])
+	if len(violations) != 0 {
+		t.Errorf("Expected staged hunks to be atomic, got %+v", violations)
 	}
-}
 
-func TestFindCommittableFiles_CircularDependency(t *testing.T) {
-	t.Parallel()
+	diff := exec.CommandContext(t.Context(), "git", "diff", "--cached", "--", fileUtilsGo)
+	diff.Dir = repoDir
 
-	logTestPattern(t,
-		"FindCommittableFiles - Circular Dependency",
-		"circular_a.go <-> circular_b.go (circular dependency)",
-		"Modified [circular_a.go, circular_b.go] | Unstaged [circular_a.go, circular_b.go]",
-		"Should return empty (neither file is independent)")
+	output, err = diff.Output()
+	if err != nil {
+		t.Fatalf("git diff failed: %v", err)
+	}
 
-	repoDir := setupTestRepo(t)
+	if strings.Contains(string(output), "1024") {
+		t.Errorf("Expected the ValidateConfig hunk to stay unstaged, got:\n%s", output)
+	}
+}
 
-	// Modify both circular files.
-	modifyFile(t, filepath.Join(repoDir, "circular_a.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "circular_b.go"), testComment)
+func TestSuggestHunks_UnknownTarget(t *testing.T) {
+	t.Parallel()
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
-	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
-	}
+	repoDir := setupTestRepo(t)
 
-	if len(files) != 0 {
-		t.Errorf("Expected no files for circular dependency, got %d: %v", len(files), files)
+	_, err := validator.SuggestHunks(t.Context(), repoDir, "NoSuchFunc")
+	if !errors.Is(err, validator.ErrUnknownTarget) {
+		t.Errorf("Expected ErrUnknownTarget, got %v", err)
 	}
 }
 
-func TestFindCommittableFiles_UntrackedFile(t *testing.T) {
+func TestValidateAtomicCommit_ChangedSymbolsOnly(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableFiles - Untracked File",
-		"newfile.go (untracked, no dependencies)",
-		"Untracked [newfile.go]",
-		"Should return newfile.go (independent)")
+		"Changed Symbols Only",
+		"beta.go (BetaFunc) -> utils.go (Helper unchanged, ValidateConfig changed)",
+		"Staged [beta.go] | Modified [utils.go (ValidateConfig body)]",
+		"Helper is only a violation by default; ValidateConfig always is")
 
 	repoDir := setupTestRepo(t)
 
-	// Create a new untracked file with no dependencies.
-	createUntrackedFile(t, repoDir, "newfile.go", `package main
-
-// IndependentFunc is a new independent function.
-func IndependentFunc() string {
-	return "independent"
-}
-`)
+	utils := filepath.Join(repoDir, fileUtilsGo)
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	data, err := os.ReadFile(utils) //nolint:gosec // Test reads temp file.
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
+		t.Fatalf("Failed to read utils.go: %v", err)
 	}
 
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	writeFileContent(t, utils, strings.Replace(string(data), "c.Port > 0", "c.Port > 1024", 1))
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on AlphaFunc and Helper.\n"+
+			"func BetaFunc() string {\n\treturn \"beta-\" + AlphaFunc() + Helper()\n}\n")
+	stageFiles(t, repoDir, "beta.go")
+
+	missing := func(opts ...validator.Option) []string {
+		t.Helper()
+
+		violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, opts...)
+		if err != nil {
+			t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		}
+
+		var symbols []string
+		for _, v := range violations {
+			symbols = append(symbols, v.MissingSymbol)
+		}
+
+		return symbols
 	}
 
-	if files[0] != "newfile.go" {
-		t.Errorf("Expected newfile.go, got %s", files[0])
+	helper := "example.com/testproject.Helper"
+	if got := missing(); !slices.Equal(got, []string{helper}) {
+		t.Errorf("Expected a violation on Helper by default, got %v", got)
 	}
-}
 
-func TestFindCommittableFiles_NoModifications(t *testing.T) {
-	t.Parallel()
+	if got := missing(validator.WithChangedSymbolsOnly()); len(got) != 0 {
+		t.Errorf("Expected no violations for the unchanged Helper, got %v", got)
+	}
 
-	logTestPattern(t,
-		"FindCommittableFiles - No Modifications",
-		"N/A - clean working directory",
-		"Modified [] | Unstaged []",
-		"Should return empty (no files to commit)")
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on AlphaFunc and ValidateConfig.\n"+
+			"func BetaFunc() string {\n\t_ = ValidateConfig(Config{})\n\n\treturn \"beta-\" + AlphaFunc()\n}\n")
+	stageFiles(t, repoDir, "beta.go")
 
-	repoDir := setupTestRepo(t)
+	validateConfig := "example.com/testproject.ValidateConfig"
+	if got := missing(validator.WithChangedSymbolsOnly()); !slices.Equal(got, []string{validateConfig}) {
+		t.Errorf("Expected a violation on the changed ValidateConfig, got %v", got)
+	}
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	// A declaration deleted from the working tree changed too.
+	data, err = os.ReadFile(utils) //nolint:gosec // Test reads temp file.
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
+		t.Fatalf("Failed to read utils.go: %v", err)
 	}
 
-	if len(files) != 0 {
-		t.Errorf("Expected no files for clean repo, got %d: %v", len(files), files)
+	writeFileContent(t, utils, strings.Replace(string(data), "c.Port > 1024", "c.Port > 0", 1))
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on AlphaFunc and Helper.\n"+
+			"func BetaFunc() string {\n\treturn \"beta-\" + AlphaFunc() + Helper()\n}\n")
+	stageFiles(t, repoDir, "beta.go")
+
+	helperDecl := "\n// Helper is a simple helper function.\nfunc Helper() string {\n\treturn \"helper\"\n}\n"
+	writeFileContent(t, utils, strings.Replace(string(data), helperDecl, "", 1))
+
+	if got := missing(validator.WithChangedSymbolsOnly()); !slices.Equal(got, []string{helper}) {
+		t.Errorf("Expected a violation on Helper once deleted from the working tree, got %v", got)
 	}
 }
 
-func TestFindCommittableFiles_ExcludesStagedFiles(t *testing.T) {
+func TestValidateAtomicCommit_RemovedSymbolStillUsed(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableFiles - Excludes Staged Files",
-		"alpha.go (staged), beta.go (unstaged)",
-		"Modified [alpha.go, beta.go] | Staged [alpha.go] | Unstaged [beta.go]",
-		"Should return beta.go only (exclude already-staged files)")
+		"Removed Symbol Still Used",
+		"main.go (main) -> utils.go (Helper, removed)",
+		"Staged [utils.go without Helper] | Committed [main.go]",
+		"Violation: committed main.go still uses the removed Helper, until its update is staged too")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify and stage alpha.go.
-	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
-	stageFiles(t, repoDir, "alpha.go")
-
-	// Modify beta.go but don't stage it.
-	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	utils := filepath.Join(repoDir, fileUtilsGo)
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	data, err := os.ReadFile(utils) //nolint:gosec // Test reads temp file.
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
+		t.Fatalf("Failed to read utils.go: %v", err)
 	}
 
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
-	}
+	helper := "\n// Helper is a simple helper function.\nfunc Helper() string {\n\treturn \"helper\"\n}\n"
+	writeFileContent(t, utils, strings.Replace(string(data), helper, "", 1))
+	stageFiles(t, repoDir, fileUtilsGo)
 
-	if files[0] != "beta.go" {
-		t.Errorf("Expected beta.go (unstaged), got %s", files[0])
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
-}
 
-func TestFindCommittableFiles_ExcludesPartiallyStaged(t *testing.T) {
-	t.Parallel()
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d: %+v", len(violations), violations)
+	}
 
-	logTestPattern(t,
-		"FindCommittableFiles - Excludes Partially Staged (MM)",
-		"main.go (MM status)",
-		"Modified [main.go] | Staged [main.go (partial)] | Unstaged [main.go (partial)]",
-		"Should NOT return main.go (let standard mode handle MM files)")
+	v := violations[0]
+	if v.StagedFile != fileUtilsGo || v.StagedSymbol != "example.com/testproject.Helper" ||
+		v.MissingFile != fileMainGo || v.MissingSymbol != "example.com/testproject.main" || v.MissingLine != 24 {
+		t.Errorf("Unexpected violation: %+v", v)
+	}
 
-	repoDir := setupTestRepo(t)
+	main := filepath.Join(repoDir, fileMainGo)
 
-	// Modify and stage main.go.
-	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Staged comment\n")
-	stageFiles(t, repoDir, "main.go")
+	data, err = os.ReadFile(main) //nolint:gosec // Test reads temp file.
+	if err != nil {
+		t.Fatalf("Failed to read main.go: %v", err)
+	}
 
-	// Modify main.go again without staging (creates MM state).
-	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Unstaged comment\n")
+	writeFileContent(t, main, strings.Replace(string(data), "\tfmt.Println(Helper())\n", "", 1))
+	stageFiles(t, repoDir, fileMainGo)
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed: %v", err)
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	// Should return empty - MM files are excluded from candidates.
-	if len(files) != 0 {
-		t.Errorf("Expected no files for MM status, got %d: %v", len(files), files)
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations once main.go is staged, got %+v", violations)
 	}
 }
 
-func TestFindCommittableFiles_ProgressiveCommit(t *testing.T) {
+func TestCheckStagedBuild(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableFiles - Progressive Commit Workflow",
-		"alpha.go, beta.go, gamma.go (all independent)",
-		"Modified [alpha.go, beta.go, gamma.go] | Unstaged [all]",
-		"Progressive: alpha -> beta -> gamma (lexicographic order)")
+		"Staged Build Check",
+		"beta.go (BetaFunc) -> extra.go (Extra)",
+		"Staged [beta.go] | Untracked [extra.go] | Modified [alpha.go (does not parse)]",
+		"Only the undefined Extra breaks the staged build; unstaged and untracked content is ignored")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify all three independent files.
-	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), "\nfunc broken( {\n")
 
-	// First call should return alpha.go.
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
+	errs, err := validator.CheckStagedBuild(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed (1st call): %v", err)
+		t.Fatalf("CheckStagedBuild failed: %v", err)
 	}
 
-	if len(files) != 1 || files[0] != "alpha.go" {
-		t.Errorf("Expected alpha.go on first call, got %v", files)
+	if len(errs) != 0 {
+		t.Fatalf("Expected the staged snapshot to build, got %v", errs)
 	}
 
-	// Commit alpha.go.
-	stageFiles(t, repoDir, "alpha.go")
-	runGit(t, repoDir, "commit", "-m", "update alpha")
+	createUntrackedFile(t, repoDir, "extra.go", "package main\n\nfunc Extra() string { return \"extra\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on Extra.\nfunc BetaFunc() string {\n\treturn \"beta-\" + Extra()\n}\n")
+	stageFiles(t, repoDir, "beta.go")
 
-	// Second call should return beta.go.
-	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	errs, err = validator.CheckStagedBuild(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed (2nd call): %v", err)
+		t.Fatalf("CheckStagedBuild failed: %v", err)
 	}
 
-	if len(files) != 1 || files[0] != "beta.go" {
-		t.Errorf("Expected beta.go on second call, got %v", files)
+	want := []validator.BuildError{{File: "beta.go", Line: 5, Column: 19, Message: "undefined: Extra"}}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Expected %v, got %v", want, errs)
 	}
+}
 
-	// Commit beta.go.
+func TestValidateAtomicCommit_IndexSnapshot(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Index Snapshot",
+		"beta.go (BetaFunc) -> extra.go (Extra), ignored.go (Ignored)",
+		"Staged [beta.go] | Untracked [extra.go] | Ignored [ignored.go]",
+		"Untracked dependencies are still reported; ignored files are no longer silently loaded")
+
+	repoDir := setupTestRepo(t)
+
+	createUntrackedFile(t, repoDir, "extra.go", "package main\n\nfunc Extra() string { return \"extra\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on Extra.\nfunc BetaFunc() string {\n\treturn \"beta-\" + Extra()\n}\n")
 	stageFiles(t, repoDir, "beta.go")
-	runGit(t, repoDir, "commit", "-m", "update beta")
 
-	// Third call should return gamma.go.
-	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithIndexSnapshot())
 	if err != nil {
-		t.Fatalf("FindCommittableFiles failed (3rd call): %v", err)
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(files) != 1 || files[0] != "gamma.go" {
-		t.Errorf("Expected gamma.go on third call, got %v", files)
+	if len(violations) != 1 || violations[0].StagedFile != "beta.go" || violations[0].MissingFile != "extra.go" {
+		t.Fatalf("Expected beta.go to need extra.go, got %+v", violations)
 	}
 
-	// Commit gamma.go.
-	stageFiles(t, repoDir, "gamma.go")
-	runGit(t, repoDir, "commit", "-m", "update gamma")
+	writeFileContent(t, filepath.Join(repoDir, ".gitignore"), "ignored.go\n")
+	writeFileContent(t, filepath.Join(repoDir, "ignored.go"), "package main\n\nfunc Ignored() string { return \"\" }\n")
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on Ignored.\nfunc BetaFunc() string {\n\treturn \"beta-\" + Ignored()\n}\n")
+	stageFiles(t, repoDir, "beta.go")
 
-	// Fourth call should return empty (all committed).
-	files, err = validator.FindCommittableSet(t.Context(), repoDir, false)
-	if err != nil {
-		t.Fatalf("FindCommittableFiles failed (4th call): %v", err)
+	// The checkout hides the dependency on a file that will never be committed.
+	violations, err = validator.ValidateAtomicCommit(t.Context(), repoDir)
+	if err != nil || len(violations) != 0 {
+		t.Fatalf("Expected the checkout to satisfy Ignored, got %+v, %v", violations, err)
 	}
 
-	if len(files) != 0 {
-		t.Errorf("Expected no files after all committed, got %v", files)
+	_, err = validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithIndexSnapshot())
+	if !errors.Is(err, analyzer.ErrPackagesContainErrors) {
+		t.Errorf("Expected the index snapshot not to build without ignored.go, got %v", err)
 	}
 }
 
-// TestFindCommittableSet_WithDependants_SingleDependant tests the --dependants flag
-// with a base file and one dependant.
-func TestFindCommittableSet_WithDependants_SingleDependant(t *testing.T) {
+func TestValidateCommit(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableSet - With Dependants - Single Dependant",
-		"types.go (independent) <- service.go (depends on types.go)",
-		"Modified [types.go, service.go] | Unstaged [types.go, service.go]",
-		"Should return [types.go, service.go] when --dependants is used")
+		"Existing Commit",
+		"beta.go (BetaFunc) -> extra.go (Extra)",
+		"HEAD commits [beta.go] | Untracked [extra.go], then committed in a later commit",
+		"The beta.go commit is not atomic, whether extra.go is uncommitted or only in a later commit")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify types.go (independent) and service.go (depends on types.go).
-	modifyFile(t, filepath.Join(repoDir, "types.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "service.go"), testComment)
+	writeFileContent(t, filepath.Join(repoDir, "beta.go"),
+		"package main\n\n// BetaFunc depends on Extra.\nfunc BetaFunc() string {\n\treturn \"beta-\" + Extra()\n}\n")
+	stageFiles(t, repoDir, "beta.go")
+	runGit(t, repoDir, "commit", "-m", "Use Extra")
+	createUntrackedFile(t, repoDir, "extra.go", "package main\n\nfunc Extra() string { return \"extra\" }\n")
 
-	// Without dependants flag - should return only types.go.
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, false)
-	if err != nil {
-		t.Fatalf("FindCommittableSet failed: %v", err)
+	wantMissing := func(rev, missing string) {
+		t.Helper()
+
+		report, err := validator.ValidateCommit(t.Context(), repoDir, rev)
+		if err != nil {
+			t.Fatalf("ValidateCommit(%s) failed: %v", rev, err)
+		}
+
+		var files []string
+		for _, v := range report.Violations {
+			files = append(files, v.MissingFile)
+		}
+
+		if want := slices.DeleteFunc([]string{missing}, func(s string) bool { return s == "" }); !slices.Equal(files, want) {
+			t.Errorf("ValidateCommit(%s) missing files = %v, want %v", rev, files, want)
+		}
 	}
 
-	if len(files) != 1 || files[0] != "types.go" {
-		t.Fatalf("Without dependants: expected [types.go], got %v", files)
+	wantMissing("HEAD", "extra.go")
+
+	stageFiles(t, repoDir, "extra.go")
+	runGit(t, repoDir, "commit", "-m", "Add Extra")
+
+	wantMissing("HEAD~1", "extra.go")
+	wantMissing("HEAD", "")
+
+	_, err := validator.ValidateCommit(t.Context(), repoDir, "no-such-rev")
+	if err == nil {
+		t.Error("Expected an error for an unknown revision")
 	}
+}
 
-	// With dependants flag - should return types.go + service.go.
-	files, err = validator.FindCommittableSet(t.Context(), repoDir, true)
+func TestValidateAtomicCommit_WithFiles(t *testing.T) {
+	t.Parallel()
+
+	logTestPattern(t,
+		"Given Staged Files",
+		"models/response.go -> helper/formatter.go, processor.go -> helper/validator.go",
+		"Staged [models/response.go, processor.go] | Unstaged [helper/formatter.go, helper/validator.go]",
+		"Only models/response.go is validated, loading its package and the helper package it imports")
+
+	repoDir := setupTestRepo(t)
+
+	modifyFile(t, filepath.Join(repoDir, "models", "response.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "processor.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "formatter.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "helper", "validator.go"), testComment)
+	stageFiles(t, repoDir, fileModelsResponse, "processor.go")
+
+	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithFiles(fileModelsResponse))
 	if err != nil {
-		t.Fatalf("FindCommittableSet with dependants failed: %v", err)
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(files) != 2 {
-		t.Fatalf("With dependants: expected 2 files, got %d: %v", len(files), files)
+	if len(violations) == 0 {
+		t.Fatal("Expected a violation from models/response.go to helper/formatter.go, got none")
 	}
 
-	// Result should include both types.go (base) and service.go (dependant).
-	// The base file comes first, then dependants in sorted order.
-	expectedFiles := map[string]bool{"types.go": true, "service.go": true}
-	for _, f := range files {
-		if !expectedFiles[f] {
-			t.Errorf("Unexpected file in result: %s", f)
+	for _, v := range violations {
+		if v.StagedFile != fileModelsResponse || v.MissingFile != fileHelperFmtGo {
+			t.Errorf("Unexpected violation %s -> %s", v.StagedFile, v.MissingFile)
 		}
 	}
 }
 
-// TestFindCommittableSet_WithDependants_MultipleDependants tests the --dependants flag
-// with a base file and multiple dependants.
-func TestFindCommittableSet_WithDependants_MultipleDependants(t *testing.T) {
+func TestFindCommittableSetIncremental_MatchesFull(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableSet - With Dependants - Multiple Dependants",
-		"utils.go (independent) <- service.go (depends on utils.go) and main.go (depends on utils.go)",
-		"Modified [utils.go, service.go, main.go] | Unstaged [utils.go, service.go, main.go]",
-		"Should return [main.go, service.go, utils.go] when --dependants is used")
+		"Incremental Committable Set",
+		"gamma.go -> beta.go",
+		"Modified [beta.go, gamma.go] | Unstaged [beta.go, gamma.go], then beta.go is committed",
+		"Incremental committable sets match fresh ones before and after the commit")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify utils.go (independent), service.go (depends on utils.go), and main.go (depends on utils.go).
-	modifyFile(t, filepath.Join(repoDir, "utils.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "service.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "beta.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
 
-	// With dependants flag - should return utils.go + its dependants.
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
-	if err != nil {
-		t.Fatalf("FindCommittableSet with dependants failed: %v", err)
-	}
+	check := func(changed []string, prev *validator.Analysis) *validator.Analysis {
+		t.Helper()
 
-	// Should include utils.go, service.go (depends only on utils.go), and main.go (depends on utils.go + service.go).
-	// Note: main.go depends on both utils.go AND service.go, so it should NOT be included.
-	if len(files) < 2 {
-		t.Fatalf("With dependants: expected at least 2 files, got %d: %v", len(files), files)
+		files, analysis, err := validator.FindCommittableSetIncremental(t.Context(), repoDir, changed, prev, true)
+		if err != nil {
+			t.Fatalf("FindCommittableSetIncremental failed: %v", err)
+		}
+
+		fresh, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+		if err != nil {
+			t.Fatalf("FindCommittableSet failed: %v", err)
+		}
+
+		if !slices.Equal(files, fresh) || len(files) == 0 {
+			t.Errorf("Incremental committable set %v differs from fresh %v", files, fresh)
+		}
+
+		return analysis
 	}
 
-	// Verify utils.go is in the result (base file).
-	if !slices.Contains(files, "utils.go") {
-		t.Errorf("With dependants: expected utils.go in results, got %v", files)
+	analysis := check(nil, nil)
+
+	first, _, err := validator.FindCommittableSetIncremental(t.Context(), repoDir, nil, analysis, false)
+	if err != nil || len(first) == 0 {
+		t.Fatalf("FindCommittableSetIncremental() = %v, %v", first, err)
 	}
+
+	stageFiles(t, repoDir, first...)
+	runGit(t, repoDir, "commit", "-m", "First set")
+
+	check(first, analysis)
 }
 
-// TestFindCommittableSet_WithDependants_ExcludeTransitiveDependants tests that
-// transitive dependants (dependants of dependants) are excluded.
-func TestFindCommittableSet_WithDependants_ExcludeTransitiveDependants(t *testing.T) {
+func TestValidateAtomicCommit_CacheDir(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableSet - With Dependants - Exclude Transitive",
-		"types.go <- service.go <- main.go (chain)",
-		"Modified [types.go, service.go, main.go] | Unstaged [types.go, service.go, main.go]",
-		"Should return [types.go, service.go] excluding main.go (transitive)")
+		"Analysis Cache",
+		"main.go (main func) -> utils.go (Helper func), then main.go -> newthing.go (NewThing func)",
+		"Modified [main.go, utils.go] | Staged [main.go] | Unstaged [utils.go], validated twice, then edited",
+		"Cached runs report the same violations as uncached ones, and edits are not served stale")
 
 	repoDir := setupTestRepo(t)
+	cacheDir := t.TempDir()
 
-	// Modify types.go, service.go (depends on types.go), and main.go (depends on service.go).
-	modifyFile(t, filepath.Join(repoDir, "types.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "service.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "main.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Comment in main\n")
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Comment in utils\n")
+	stageFiles(t, repoDir, "main.go")
 
-	// With dependants flag.
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
-	if err != nil {
-		t.Fatalf("FindCommittableSet with dependants failed: %v", err)
+	compare := func() []validator.Violation {
+		t.Helper()
+
+		want, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+		if err != nil {
+			t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		}
+
+		// The first cached run fills the cache for what changed, the second reads it.
+		for range 2 {
+			got, err := validator.ValidateAtomicCommit(t.Context(), repoDir, validator.WithCacheDir(cacheDir))
+			if err != nil {
+				t.Fatalf("ValidateAtomicCommit with cache failed: %v", err)
+			}
+
+			// Violations come in no particular order.
+			byFingerprint := func(a, b validator.Violation) int { return strings.Compare(a.Fingerprint, b.Fingerprint) }
+			slices.SortFunc(got, byFingerprint)
+			slices.SortFunc(want, byFingerprint)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Cached violations %+v differ from uncached %+v", got, want)
+			}
+		}
+
+		return want
 	}
 
-	// Main.go depends on service.go (which is in changeset), so it should be excluded.
-	if slices.Contains(files, "main.go") {
-		t.Errorf("Should NOT include main.go (depends on service.go in changeset), got %v", files)
+	if violations := compare(); len(violations) == 0 {
+		t.Fatal("Expected violations, got none")
+	}
+
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "graph", "*", "*.json"))
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("Expected cache entries, got %v (%v)", entries, err)
+	}
+
+	createUntrackedFile(t, repoDir, "newthing.go", "package main\n\nfunc NewThing() {}\n")
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\nfunc useNewThing() { NewThing() }\n")
+	stageFiles(t, repoDir, "main.go")
+
+	if !slices.ContainsFunc(compare(), func(v validator.Violation) bool { return v.MissingFile == "newthing.go" }) {
+		t.Error("Expected a violation for newthing.go after the edit")
 	}
 }
 
-// TestFindCommittableSet_WithDependants_CircularDependency tests behavior with circular deps.
-func TestFindCommittableSet_WithDependants_CircularDependency(t *testing.T) {
+func TestValidateAtomicCommit_Progress(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableSet - With Dependants - Circular Dependency",
-		"circular_a.go <-> circular_b.go (circular)",
-		"Modified [circular_a.go, circular_b.go] | Unstaged [circular_a.go, circular_b.go]",
-		"Should return empty (no independent file due to circular dependency)")
+		"Progress Reporting",
+		"main.go (main func) -> utils.go (Helper func)",
+		"Modified [main.go, utils.go] | Staged [main.go] | Unstaged [utils.go]",
+		"Loading is reported, then each analyzed package until all are")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify both files with circular dependency.
-	modifyFile(t, filepath.Join(repoDir, "circular_a.go"), testComment)
-	modifyFile(t, filepath.Join(repoDir, "circular_b.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "main.go"), "\n// Comment in main\n")
+	modifyFile(t, filepath.Join(repoDir, "utils.go"), "\n// Comment in utils\n")
+	stageFiles(t, repoDir, "main.go")
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	var reports []validator.Progress
+
+	_, err := validator.ValidateAtomicCommit(t.Context(), repoDir,
+		validator.WithProgress(func(p validator.Progress) { reports = append(reports, p) }))
 	if err != nil {
-		t.Fatalf("FindCommittableSet failed: %v", err)
+		t.Fatalf("ValidateAtomicCommit failed: %v", err)
 	}
 
-	if len(files) != 0 {
-		t.Errorf("Expected empty result for circular dependency, got %v", files)
+	if len(reports) < 3 || reports[0].Stage != validator.StageLoading || reports[1].Stage != validator.StageLoaded {
+		t.Fatalf("Expected loading then loaded first, got %+v", reports)
+	}
+
+	last := reports[len(reports)-1]
+	if last.Stage != validator.StageAnalyzing || last.Done != last.Total || last.Total != reports[1].Total {
+		t.Errorf("Expected the last report to analyze all %d loaded packages, got %+v", reports[1].Total, last)
 	}
 }
 
-// TestFindCommittableSet_WithDependants_NoDependants tests a file with no dependants.
-func TestFindCommittableSet_WithDependants_NoDependants(t *testing.T) {
+func TestExportGraph_ChangedScopeKeepsPathsBetweenChanges(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"FindCommittableSet - With Dependants - No Dependants",
-		"alpha.go (independent, no dependants)",
-		"Modified [alpha.go] | Unstaged [alpha.go]",
-		"Should return [alpha.go] only (no dependants exist)")
+		"ExportGraph - Changed Scope Keeps Paths Between Changes",
+		"gamma.go -> beta.go -> alpha.go",
+		"Modified [alpha.go, gamma.go] | Staged [gamma.go]",
+		"GammaFunc, BetaFunc and AlphaFunc exported with their states; nothing else with the changed scope")
 
 	repoDir := setupTestRepo(t)
 
-	// Modify alpha.go (independent, no other files depend on it).
 	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	modifyFile(t, filepath.Join(repoDir, "gamma.go"), testComment)
+	stageFiles(t, repoDir, "gamma.go")
 
-	files, err := validator.FindCommittableSet(t.Context(), repoDir, true)
+	export, err := validator.ExportGraph(t.Context(), repoDir, validator.ScopeChanged)
 	if err != nil {
-		t.Fatalf("FindCommittableSet failed: %v", err)
+		t.Fatalf("ExportGraph failed: %v", err)
 	}
 
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
+	states := make(map[string]validator.FileState, len(export.Nodes))
+	for _, node := range export.Nodes {
+		states[node.Name] = node.Status
 	}
 
-	if files[0] != "alpha.go" {
-		t.Errorf("Expected alpha.go, got %s", files[0])
+	wantStates := map[string]validator.FileState{
+		"AlphaFunc": validator.StateUnstaged,
+		"BetaFunc":  validator.StateCommitted,
+		"GammaFunc": validator.StateStaged,
+	}
+	if !maps.Equal(states, wantStates) {
+		t.Errorf("Expected nodes %v, got %v", wantStates, states)
+	}
+
+	wantEdges := []validator.GraphEdge{
+		{From: "example.com/testproject.BetaFunc", To: "example.com/testproject.AlphaFunc"},
+		{From: "example.com/testproject.GammaFunc", To: "example.com/testproject.BetaFunc"},
+	}
+	if !slices.Equal(export.Edges, wantEdges) {
+		t.Errorf("Expected edges %v, got %v", wantEdges, export.Edges)
+	}
+
+	export, err = validator.ExportGraph(t.Context(), repoDir, validator.ScopeAll)
+	if err != nil {
+		t.Fatalf("ExportGraph failed: %v", err)
+	}
+
+	if len(export.Nodes) <= len(wantStates) {
+		t.Errorf("Expected the whole module with the all scope, got %v", export.Nodes)
 	}
 }
 
-// TestValidateAtomicCommit_UntrackedFileDoesNotBreakAnalysis verifies that
-// untracked files referencing symbols absent from the staged codebase do not
-// cause compilation errors during analysis.
-func TestValidateAtomicCommit_UntrackedFileDoesNotBreakAnalysis(t *testing.T) {
+func TestStagedImpact_FollowsDependentsOfTouchedDeclarations(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Untracked File With Forward Reference",
-		"utils.go (staged+worktree, MM) | untracked_consumer.go (??, references WorkingTreeFunc)",
-		"Staged [utils.go comment] | WorkingTree [utils.go+WorkingTreeFunc] | Untracked [untracked_consumer.go]",
-		"No compilation error - untracked file is excluded from analysis")
+		"StagedImpact - Follows Dependents Of Touched Declarations",
+		"gamma.go -> beta.go -> alpha.go",
+		"Modified [alpha.go (AlphaFunc body), constants.go (trailing comment)] | Staged [alpha.go, constants.go]",
+		"AlphaFunc touched; alpha.go, beta.go and gamma.go affected; the comment touches nothing")
 
 	repoDir := setupTestRepo(t)
 
-	// Stage utils.go with a comment.
-	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), testComment)
-	stageFiles(t, repoDir, fileUtilsGo)
+	writeFileContent(t, filepath.Join(repoDir, "alpha.go"),
+		"package main\n\n// AlphaFunc is a simple function with no dependencies.\n"+
+			"func AlphaFunc() string {\n\treturn \"ALPHA\"\n}\n")
+	modifyFile(t, filepath.Join(repoDir, "constants.go"), testComment)
+	stageFiles(t, repoDir, "alpha.go", "constants.go")
 
-	// Add WorkingTreeFunc to utils.go working tree only (not staged, MM status).
-	wtFunc := "\n// WorkingTreeFunc is only in the working tree.\n" +
-		"func WorkingTreeFunc() string { return \"wt\" }\n"
-	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), wtFunc)
+	report, err := validator.StagedImpact(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("StagedImpact failed: %v", err)
+	}
 
-	// Create an untracked file that calls WorkingTreeFunc (only in working tree of utils.go).
-	untrackedContent := "package main\n\n" +
-		"// UntrackedConsumer calls a working-tree-only function.\n" +
-		"func UntrackedConsumer() string { return WorkingTreeFunc() }\n"
-	createUntrackedFile(t, repoDir, "untracked_consumer.go", untrackedContent)
+	if want := []string{"example.com/testproject.AlphaFunc"}; !slices.Equal(report.Changed, want) {
+		t.Errorf("Expected changed symbols %v, got %v", want, report.Changed)
+	}
 
-	// Should not return a compilation error - untracked file is neutralised.
-	_, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
-	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed (expected no error): %v", err)
+	if want := []string{"alpha.go", "beta.go", "gamma.go"}; !slices.Equal(report.Files, want) {
+		t.Errorf("Expected affected files %v, got %v", want, report.Files)
+	}
+
+	if want := []string{"example.com/testproject"}; !slices.Equal(report.Packages, want) {
+		t.Errorf("Expected affected packages %v, got %v", want, report.Packages)
+	}
+
+	want := []string{
+		"example.com/testproject.AlphaFunc", "example.com/testproject.BetaFunc", "example.com/testproject.GammaFunc",
+	}
+	if !slices.Equal(report.Exported, want) {
+		t.Errorf("Expected affected exported symbols %v, got %v", want, report.Exported)
 	}
 }
 
-// TestValidateAtomicCommit_StructFieldTypeDependency verifies that a struct type
-// whose field type references an untracked subpackage is flagged as a violation.
-// This tests that type-level (non-function) symbol usages are tracked correctly.
-func TestValidateAtomicCommit_StructFieldTypeDependency(t *testing.T) {
+func TestDeadCode_ReportsSymbolsLosingTheirLastUse(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Struct Field Type Dependency (type-level reference)",
-		"repo.go (staged, defines Repo struct with NewStore field typed as store.Writer) | store/writer.go (untracked)",
-		"Staged [repo.go] | Untracked [store/writer.go defines store.Writer interface]",
-		"Violation detected - Repo struct field type depends on untracked store.Writer")
+		"DeadCode - Reports Symbols Losing Their Last Use",
+		"gamma.go -> beta.go -> alpha.go",
+		"Modified [gamma.go (drops the BetaFunc call), alpha.go (comment)] | Staged [gamma.go] | Unstaged [alpha.go]",
+		"BetaFunc reported as unused; AlphaFunc, still used by BetaFunc, is not")
 
 	repoDir := setupTestRepo(t)
-	storeDir := createUntrackedSubpackage(t, repoDir, "store")
-
-	writeFileContent(t, filepath.Join(storeDir, "writer.go"),
-		"package store\n\n// Writer is a write interface.\ntype Writer interface { Write(p []byte) (int, error) }\n")
 
-	// Create a staged file whose struct field type references store.Writer.
-	writeFileContent(t, filepath.Join(repoDir, "repo.go"),
-		"package main\n\nimport \"example.com/testproject/store\"\n\n"+
-			"// Repo holds repo operations.\ntype Repo struct {\n"+
-			"\tNewWriter func() store.Writer\n}\n")
-	stageFiles(t, repoDir, "repo.go")
+	writeFileContent(t, filepath.Join(repoDir, "gamma.go"),
+		"package main\n\n// GammaFunc no longer depends on BetaFunc.\nfunc GammaFunc() string {\n\treturn \"gamma\"\n}\n")
+	modifyFile(t, filepath.Join(repoDir, "alpha.go"), testComment)
+	stageFiles(t, repoDir, "gamma.go")
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	advisories, err := validator.DeadCode(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed: %v", err)
-	}
-
-	if len(violations) == 0 {
-		t.Fatal("Expected violation for struct field type dependency on untracked store.Writer, got none")
-	}
-
-	found := false
-
-	for _, v := range violations {
-		if v.StagedFile == "repo.go" && v.MissingFile == "store/writer.go" {
-			found = true
-
-			break
-		}
+		t.Fatalf("DeadCode failed: %v", err)
 	}
 
-	if !found {
-		t.Errorf("Expected violation from repo.go to store/writer.go, violations: %+v", violations)
+	want := []validator.Advisory{{
+		File:    "beta.go",
+		Line:    4,
+		Symbol:  "example.com/testproject.BetaFunc",
+		Kind:    "func",
+		Message: "is no longer used: the staged changes remove its last use, from gamma.go",
+	}}
+	if !slices.Equal(advisories, want) {
+		t.Errorf("Expected advisories %+v, got %+v", want, advisories)
 	}
 }
 
-// TestValidateAtomicCommit_VarTypeDependency verifies that a package-level var
-// whose type or initializer references an untracked subpackage is flagged.
-func TestValidateAtomicCommit_VarTypeDependency(t *testing.T) {
+func TestUnusedExports_ReportsNewSymbolsWithoutStagedUsers(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Var Type + Initializer Dependency",
-		"vars.go (staged, defines var with store.Writer type and store.New initializer) | store/writer.go (untracked)",
-		"Staged [vars.go] | Untracked [store/writer.go defines store.Writer and store.New]",
-		"Violation detected - var type and initializer depend on untracked store package")
+		"UnusedExports - Reports New Symbols Without Staged Users",
+		"gamma.go -> delta.go (DeltaFunc -> DeltaHelper)",
+		"Modified [gamma.go (calls DeltaFunc)] | Staged [delta.go (new)] | Unstaged [gamma.go]",
+		"DeltaFunc reported as unused; DeltaHelper, used by the staged DeltaFunc, is not")
 
 	repoDir := setupTestRepo(t)
-	storeDir := createUntrackedSubpackage(t, repoDir, "store")
-
-	writeFileContent(t, filepath.Join(storeDir, "writer.go"),
-		"package store\n\n"+
-			"// Writer is a write interface.\n"+
-			"type Writer interface { Write(p []byte) (int, error) }\n\n"+
-			"// New creates a new Writer.\n"+
-			"func New() Writer { return nil }\n")
 
-	// Create a staged file with a var that depends on store.Writer (type) and store.New (initializer).
-	writeFileContent(t, filepath.Join(repoDir, "vars.go"),
-		"package main\n\nimport \"example.com/testproject/store\"\n\n"+
-			"// DefaultWriter is the default writer.\n"+
-			"var DefaultWriter store.Writer = store.New()\n")
-	stageFiles(t, repoDir, "vars.go")
+	writeFileContent(t, filepath.Join(repoDir, "delta.go"),
+		"package main\n\n// DeltaFunc is new.\nfunc DeltaFunc() string {\n\treturn DeltaHelper()\n}\n\n"+
+			"// DeltaHelper is new.\nfunc DeltaHelper() string {\n\treturn \"delta\"\n}\n")
+	writeFileContent(t, filepath.Join(repoDir, "gamma.go"),
+		"package main\n\n// GammaFunc now depends on DeltaFunc.\nfunc GammaFunc() string {\n"+
+			"\treturn BetaFunc() + DeltaFunc()\n}\n")
+	stageFiles(t, repoDir, "delta.go")
 
-	violations, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	advisories, err := validator.UnusedExports(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed: %v", err)
+		t.Fatalf("UnusedExports failed: %v", err)
 	}
 
-	if len(violations) == 0 {
-		t.Fatal("Expected violation for var type dependency on untracked store package, got none")
+	want := []validator.Advisory{{
+		File:    "delta.go",
+		Line:    4,
+		Symbol:  "example.com/testproject.DeltaFunc",
+		Kind:    "func",
+		Message: "is new but unused: no staged or committed code references it",
+	}}
+	if !slices.Equal(advisories, want) {
+		t.Errorf("Expected advisories %+v, got %+v", want, advisories)
 	}
+}
 
-	// Check both the type annotation (store.Writer) and initializer (store.New) are caught.
-	foundType := false
-	foundInit := false
+func TestCheckAPI_ReportsStagedBreakingChanges(t *testing.T) {
+	t.Parallel()
 
-	for _, v := range violations {
-		if v.StagedFile == "vars.go" && v.MissingFile == "store/writer.go" {
-			if v.MissingSymbol == "example.com/testproject/store.Writer" {
-				foundType = true
-			}
+	logTestPattern(t,
+		"CheckAPI - Reports Staged Breaking Changes",
+		"helper and models packages, used by package main",
+		"Modified [helper/formatter.go, models/request.go, models/response.go] | "+
+			"Staged [helper/formatter.go, models/request.go] | Unstaged [models/response.go]",
+		"Staged removals and incompatible changes reported; additions, renamed parameters and unstaged changes are not")
 
-			if v.MissingSymbol == "example.com/testproject/store.New" {
-				foundInit = true
-			}
-		}
-	}
+	repoDir := setupTestRepo(t)
 
-	if !foundType {
-		t.Errorf("Expected violation for store.Writer type annotation, violations: %+v", violations)
+	writeFileContent(t, filepath.Join(repoDir, "helper", "formatter.go"),
+		"package helper\n\nimport \"fmt\"\n\n// FormatNumber formats a number as a string.\n"+
+			"func FormatNumber(n int64) string {\n\treturn fmt.Sprintf(\"#%d\", n)\n}\n\n"+
+			"// FormatBool is new.\nfunc FormatBool(b bool) string {\n\treturn fmt.Sprint(b)\n}\n")
+	writeFileContent(t, filepath.Join(repoDir, "models", "request.go"),
+		"package models\n\n// Request represents an API request.\ntype Request struct {\n\tID      string\n"+
+			"\tPayload string\n\tHeader  string\n}\n\n// NewRequest creates a new request.\n"+
+			"func NewRequest(key int, body string) *Request {\n\treturn &Request{ID: \"\", Payload: body, Header: \"\"}\n}\n")
+	writeFileContent(t, filepath.Join(repoDir, "models", "response.go"), "package models\n")
+	stageFiles(t, repoDir, "helper/formatter.go", "models/request.go")
+
+	changes, err := validator.CheckAPI(t.Context(), repoDir)
+	if err != nil {
+		t.Fatalf("CheckAPI failed: %v", err)
 	}
 
-	if !foundInit {
-		t.Errorf("Expected violation for store.New initializer, violations: %+v", violations)
+	want := []validator.APIChange{
+		{Package: "example.com/testproject/helper", Symbol: "FormatMessage", Message: "removed"},
+		{
+			Package: "example.com/testproject/helper",
+			Symbol:  "FormatNumber",
+			Message: "signature changed from func(int) string to func(int64) string",
+		},
+		{
+			Package: "example.com/testproject/models",
+			Symbol:  "Request.ID",
+			Message: "field type changed from int to string",
+		},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("Expected changes %+v, got %+v", want, changes)
 	}
 }
 
-// TestValidateAtomicCommit_WorktreeOnlyFileDoesNotBreakAnalysis verifies that
-// files with working-tree-only changes that introduce invalid references do not
-// cause compilation errors during analysis.
-func TestValidateAtomicCommit_WorktreeOnlyFileDoesNotBreakAnalysis(t *testing.T) {
+func TestSuggestSemver_BreakingV0OnlyBumpsMinor(t *testing.T) {
 	t.Parallel()
 
 	logTestPattern(t,
-		"Working-Tree-Only File With Undefined Reference",
-		"utils.go ( M, worktree-only adds call to undefined func) | main.go staged",
-		"Staged [main.go comment] | WorkingTree [utils.go+undefined call]",
-		"No compilation error - working-tree content replaced with index version")
+		"SuggestSemver - Breaking v0 Only Bumps Minor",
+		"helper package, tagged v0.3.0",
+		"Modified [helper/formatter.go (FormatBool added, FormatNumber removed)] | Staged [helper/formatter.go]",
+		"Major bump recommended, next version v0.4.0")
 
 	repoDir := setupTestRepo(t)
+	runGit(t, repoDir, "tag", "v0.3.0")
 
-	// Stage main.go with a comment.
-	modifyFile(t, filepath.Join(repoDir, fileMainGo), testComment)
-	stageFiles(t, repoDir, fileMainGo)
-
-	// Add an invalid call to utils.go working tree only (not staged,  M status).
-	modifyFile(t, filepath.Join(repoDir, fileUtilsGo), "\nfunc init() { _ = undefinedFuncThatDoesNotExist() }\n")
+	writeFileContent(t, filepath.Join(repoDir, "helper", "formatter.go"),
+		"package helper\n\nimport \"fmt\"\n\n// FormatMessage formats a message with a prefix.\n"+
+			"func FormatMessage(msg string) string {\n\treturn fmt.Sprintf(\"[HELPER] %s\", msg)\n}\n\n"+
+			"// FormatBool is new.\nfunc FormatBool(b bool) string {\n\treturn fmt.Sprint(b)\n}\n")
+	stageFiles(t, repoDir, "helper/formatter.go")
 
-	// Should not return a compilation error - working tree content is replaced with index version.
-	_, err := validator.ValidateAtomicCommit(t.Context(), repoDir)
+	report, err := validator.SuggestSemver(t.Context(), repoDir)
 	if err != nil {
-		t.Fatalf("ValidateAtomicCommit failed (expected no error): %v", err)
+		t.Fatalf("SuggestSemver failed: %v", err)
+	}
+
+	want := validator.SemverReport{
+		Bump:    validator.BumpMajor,
+		Current: "v0.3.0",
+		Next:    "v0.4.0",
+		Incompatible: []validator.APIChange{
+			{Package: "example.com/testproject/helper", Symbol: "FormatNumber", Message: "removed"},
+		},
+		Compatible: []validator.APIChange{
+			{Package: "example.com/testproject/helper", Symbol: "FormatBool", Message: "added"},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Expected report %+v, got %+v", want, report)
 	}
 }
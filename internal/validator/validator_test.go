@@ -1,6 +1,9 @@
 package validator_test
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
 	"dario.cat/darna/internal/validator"
@@ -12,8 +15,18 @@ func TestViolation(t *testing.T) {
 	v := validator.Violation{
 		StagedFile:    "foo.go",
 		StagedSymbol:  "pkg.Foo",
+		StagedKind:    "",
 		MissingFile:   "bar.go",
 		MissingSymbol: "pkg.Bar",
+		MissingKind:   "",
+		MissingLine:   0,
+		MissingColumn: 0,
+		Reason:        "",
+		Line:          0,
+		Column:        0,
+		Chain:         nil,
+		Direct:        true,
+		Fingerprint:   "",
 	}
 
 	if v.StagedFile != "foo.go" {
@@ -32,3 +45,147 @@ func TestViolation(t *testing.T) {
 		t.Errorf("Expected missing symbol 'pkg.Bar', got %s", v.MissingSymbol)
 	}
 }
+
+func TestViolationLocation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line, column int
+		want         string
+	}{
+		{line: 42, column: 10, want: "foo.go:42:10"},
+		{line: 42, column: 0, want: "foo.go:42"},
+		{line: 0, column: 0, want: "foo.go"},
+	}
+
+	for _, tt := range tests {
+		v := validator.Violation{
+			StagedFile:    "foo.go",
+			StagedSymbol:  "pkg.Foo",
+			StagedKind:    "",
+			MissingFile:   "bar.go",
+			MissingSymbol: "pkg.Bar",
+			MissingKind:   "",
+			MissingLine:   0,
+			MissingColumn: 0,
+			Reason:        "",
+			Line:          tt.line,
+			Column:        tt.column,
+			Chain:         nil,
+			Direct:        true,
+			Fingerprint:   "",
+		}
+
+		if got := v.Location(); got != tt.want {
+			t.Errorf("Location() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestParsePairRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rule string
+		want string // Empty when invalid.
+	}{
+		{rule: "*.proto->*.pb.go", want: "*.proto -> *.pb.go"},
+		{rule: " queries.sql -> queries.sql.go ", want: "queries.sql -> queries.sql.go"},
+		{rule: "*.go -> *_string.go", want: "*.go -> *_string.go"},
+		{rule: "api/*.yaml -> gen/*.go", want: "api/*.yaml -> gen/*.go"},
+		{rule: "*.proto", want: ""},
+		{rule: "*.proto -> ", want: ""},
+		{rule: "*.*.proto -> *.go", want: ""},
+		{rule: "schema.sql -> *.go", want: ""},
+		{rule: "api/*.yaml -> *.go", want: ""},
+	}
+
+	for _, tt := range tests {
+		rule, err := validator.ParsePairRule(tt.rule)
+
+		switch {
+		case tt.want == "" && !errors.Is(err, validator.ErrInvalidPairRule):
+			t.Errorf("ParsePairRule(%q) = %v, %v, want ErrInvalidPairRule", tt.rule, rule, err)
+		case tt.want != "" && (err != nil || rule.String() != tt.want):
+			t.Errorf("ParsePairRule(%q) = %v, %v, want %s", tt.rule, rule, err, tt.want)
+		}
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	t.Parallel()
+
+	for _, platform := range []string{"linux/amd64", " windows/arm64 "} {
+		p, err := validator.ParsePlatform(platform)
+		if err != nil || p.String() != strings.TrimSpace(platform) {
+			t.Errorf("ParsePlatform(%q) = %v, %v", platform, p, err)
+		}
+	}
+
+	for _, platform := range []string{"linux", "linux/", "/amd64", "linux/amd64/v2"} {
+		_, err := validator.ParsePlatform(platform)
+		if !errors.Is(err, validator.ErrInvalidPlatform) {
+			t.Errorf("ParsePlatform(%q) error = %v, want ErrInvalidPlatform", platform, err)
+		}
+	}
+}
+
+func TestAggregateViolations(t *testing.T) {
+	t.Parallel()
+
+	violation := func(staged, stagedSym, missing, missingSym string, line int) validator.Violation {
+		return validator.Violation{
+			StagedFile:    staged,
+			StagedSymbol:  stagedSym,
+			StagedKind:    "",
+			MissingFile:   missing,
+			MissingSymbol: missingSym,
+			MissingKind:   "",
+			MissingLine:   0,
+			MissingColumn: 0,
+			Reason:        "",
+			Line:          line,
+			Column:        1,
+			Chain:         nil,
+			Direct:        true,
+			Fingerprint:   "",
+		}
+	}
+
+	pairs := validator.AggregateViolations([]validator.Violation{
+		violation("foo.go", "pkg.Foo2", "bar.go", "pkg.Bar", 9),
+		violation("foo.go", "pkg.Foo1", "bar.go", "pkg.Bar", 3),
+		violation("foo.go", "pkg.Foo1", "bar.go", "pkg.Bar", 3),
+		violation("baz.go", "pkg.Baz", "bar.go", "pkg.Bar", 5),
+		violation("foo.go", "pkg.Foo1", "alpha.go", "pkg.Alpha", 4),
+	})
+
+	want := []struct {
+		staged, missing string
+		symbols         []string
+	}{
+		{staged: "foo.go", missing: "alpha.go", symbols: []string{"pkg.Foo1"}},
+		{staged: "baz.go", missing: "bar.go", symbols: []string{"pkg.Baz"}},
+		{staged: "foo.go", missing: "bar.go", symbols: []string{"pkg.Foo1", "pkg.Foo2"}},
+	}
+
+	if len(pairs) != len(want) {
+		t.Fatalf("AggregateViolations() returned %d pairs, want %d: %+v", len(pairs), len(want), pairs)
+	}
+
+	for i, w := range want {
+		pair := pairs[i]
+		if pair.StagedFile != w.staged || pair.MissingFile != w.missing {
+			t.Errorf("pairs[%d] = %s -> %s, want %s -> %s", i, pair.StagedFile, pair.MissingFile, w.staged, w.missing)
+		}
+
+		symbols := make([]string, 0, len(pair.Violations))
+		for _, v := range pair.Violations {
+			symbols = append(symbols, v.StagedSymbol)
+		}
+
+		if !slices.Equal(symbols, w.symbols) {
+			t.Errorf("pairs[%d] symbols = %v, want %v", i, symbols, w.symbols)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package validator
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"dario.cat/darna/internal/git"
+)
+
+// fileVersions reads the versions of a changed file the checks compare: head before the
+// change, staged in the change and worktree in the final state the rest of the code is
+// in. Each fails for a file missing from its version. Files are relative to the
+// directory the change is checked in.
+type fileVersions struct {
+	head     func(file string) ([]byte, error)
+	staged   func(file string) ([]byte, error)
+	worktree func(file string) ([]byte, error)
+	diff     func(file string) (string, error) // Staged to worktree, without context lines.
+
+	// overlay holds the worktree version of the files that are not on disk, by absolute
+	// path, to load packages with.
+	overlay map[string][]byte
+
+	// strict fails the checks when the staged files do not build. A past commit may
+	// well not build against the final state of the files around it.
+	strict bool
+}
+
+// workTreeVersions reads the versions of the files of the working tree at absWorkDir:
+// HEAD, the index and the working tree itself.
+func workTreeVersions(ctx context.Context, absWorkDir string) fileVersions {
+	return fileVersions{
+		head: func(file string) ([]byte, error) {
+			return git.GetFileAtRevision(ctx, absWorkDir, "HEAD", file) //nolint:wrapcheck // Only absence matters.
+		},
+		staged: func(file string) ([]byte, error) {
+			return git.GetStagedContent(ctx, absWorkDir, file) //nolint:wrapcheck // Only absence matters.
+		},
+		worktree: func(file string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(absWorkDir, file)) //nolint:gosec,wrapcheck // Path comes from git.
+		},
+		diff: func(file string) (string, error) {
+			return git.GetUnstagedDiff(ctx, absWorkDir, file) //nolint:wrapcheck // Only absence matters.
+		},
+		overlay: nil,
+		strict:  true,
+	}
+}
+
+// commitVersions reads the versions of the files of commit in the repository at gitDir:
+// parent, committed and the final state tip, which is toRev, or the working tree when
+// toRev is empty. root is the directory tip is loaded in.
+func commitVersions(
+	ctx context.Context, gitDir, commit, toRev, root string, parent, committed, tip map[string][]byte,
+) fileVersions {
+	overlay := make(map[string][]byte, len(tip))
+	for path, content := range tip {
+		overlay[filepath.Join(root, path)] = content
+	}
+
+	return fileVersions{
+		head: func(file string) ([]byte, error) {
+			return versionContent(parent, file, commit+"^")
+		},
+		staged: func(file string) ([]byte, error) {
+			return versionContent(committed, file, commit)
+		},
+		worktree: func(file string) ([]byte, error) {
+			return versionContent(tip, file, cmp.Or(toRev, "the working tree"))
+		},
+		diff: func(file string) (string, error) {
+			return git.GetDiff(ctx, gitDir, commit, toRev, file) //nolint:wrapcheck // Only absence matters.
+		},
+		overlay: overlay,
+		strict:  false,
+	}
+}
+
+// versionContent returns the content of file in the version holding contents.
+func versionContent(contents map[string][]byte, file, version string) ([]byte, error) {
+	content, ok := contents[file]
+	if !ok {
+		return nil, fmt.Errorf("%s in %s: %w", file, version, fs.ErrNotExist)
+	}
+
+	return content, nil
+}